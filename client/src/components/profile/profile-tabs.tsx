@@ -1,12 +1,14 @@
 import { Button } from "@/components/ui/button";
 import Icon from "@/components/ui/icon";
+import { ProfileType } from "@/lib/pprof";
 
 interface ProfileTabsProps {
   activeView: string;
   onChange: (view: string) => void;
+  profileType?: ProfileType;
 }
 
-export default function ProfileTabs({ activeView, onChange }: ProfileTabsProps) {
+export default function ProfileTabs({ activeView, onChange, profileType }: ProfileTabsProps) {
   const tabs = [
     { id: "overview", label: "Overview" },
     { id: "flamegraph", label: "Flamegraph" },
@@ -15,6 +17,11 @@ export default function ProfileTabs({ activeView, onChange }: ProfileTabsProps)
     { id: "timeline", label: "Timeline" },
   ];
   
+  // Only heap profiles have the alloc and inuse samples the retention view compares
+  if (profileType === "heap") {
+    tabs.push({ id: "retention", label: "Retention" });
+  }
+  
   return (
     <div className="bg-white border-b border-neutral-200 px-4 flex text-sm">
       {tabs.map((tab) => (
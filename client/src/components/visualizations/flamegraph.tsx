@@ -1,13 +1,26 @@
 import { useEffect, useRef } from "react";
 import { Card, CardContent } from "@/components/ui/card";
 import { FlameRect } from "@/lib/d3-utils";
+import { profileUtils } from "@/lib/pprof";
 import * as d3 from "d3";
 
+interface LegendItem {
+  color: string;
+  label: string;
+}
+
 interface FlamegraphProps {
   data: FlameRect[];
+  legend?: LegendItem[];
 }
 
-export default function Flamegraph({ data }: FlamegraphProps) {
+const defaultLegend: LegendItem[] = [
+  { color: "#0078D4", label: "Runtime" },
+  { color: "#107C10", label: "Application" },
+  { color: "#D13438", label: "System Calls" }
+];
+
+export default function Flamegraph({ data, legend: legendData = defaultLegend }: FlamegraphProps) {
   const containerRef = useRef<HTMLDivElement>(null);
   
   useEffect(() => {
@@ -57,9 +70,15 @@ export default function Flamegraph({ data }: FlamegraphProps) {
           .attr("stroke-width", 2)
           .attr("stroke", "#000");
         
+        // Retention frames report bytes and how much of them is still in use
+        const details = d.ratio !== undefined
+          ? `<div>Allocated: ${profileUtils.formatBytes(d.value)}</div>
+             <div>In use: ${(d.ratio * 100).toFixed(1)}%</div>`
+          : `<div>Value: ${d.value.toFixed(2)}</div>`;
+        
         tooltip.html(`
           <div class="font-medium">${d.name}</div>
-          <div>Value: ${d.value.toFixed(2)}</div>
+          ${details}
           <div>Percent: ${(d.width / 10).toFixed(2)}%</div>
         `)
         .style("opacity", 1)
@@ -103,12 +122,6 @@ export default function Flamegraph({ data }: FlamegraphProps) {
     const legend = svg.append("g")
       .attr("transform", `translate(${width - 120}, ${height - 80})`);
     
-    const legendData = [
-      { color: "#0078D4", label: "Runtime" },
-      { color: "#107C10", label: "Application" },
-      { color: "#D13438", label: "System Calls" }
-    ];
-    
     legendData.forEach((item, i) => {
       legend.append("rect")
         .attr("x", 0)
@@ -138,7 +151,7 @@ export default function Flamegraph({ data }: FlamegraphProps) {
       .attr("rx", 4)
       .lower(); // Move to back
     
-  }, [data, containerRef.current?.clientWidth, containerRef.current?.clientHeight]);
+  }, [data, legendData, containerRef.current?.clientWidth, containerRef.current?.clientHeight]);
   
   if (!data || data.length === 0) {
     return (
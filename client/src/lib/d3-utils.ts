@@ -1,5 +1,5 @@
 import * as d3 from 'd3';
-import { Profile, PprofFunction, ProfileMetadata } from './pprof';
+import { Profile, PprofFunction, ProfileMetadata, RatioNode } from './pprof';
 
 // D3 types for TypeScript
 type D3Selection = d3.Selection<d3.BaseType, unknown, HTMLElement, any>;
@@ -21,6 +21,7 @@ export interface FlameRect {
   value: number;
   depth: number;
   color: string;
+  ratio?: number; // Fraction of the frame's allocated bytes still in use, for retention flame graphs
   children?: FlameRect[];
 }
 
@@ -413,6 +414,48 @@ export function generateFlameGraphFromMetadata(metadata: ProfileMetadata): Flame
   return result;
 }
 
+// Colors at either end of the retention scale
+export const churnedColor = '#0078D4';
+export const retainedColor = '#D13438';
+
+// Lay out a heap profile's retention flame graph, with each frame as wide as
+// its allocated bytes and colored from churned (blue) to retained (red)
+export function generateRatioFlameGraph(root: RatioNode): FlameRect[] {
+  if (root.value <= 0) {
+    return [];
+  }
+  
+  const baseHeight = 30;
+  const color = d3.interpolateRgb(churnedColor, retainedColor);
+  const result: FlameRect[] = [];
+  
+  const addFrame = (node: RatioNode, id: string, x: number, depth: number) => {
+    const width = 1000 * (node.value / root.value);
+    result.push({
+      id,
+      name: node.name,
+      x,
+      y: depth * baseHeight,
+      width,
+      height: baseHeight,
+      value: node.value,
+      depth,
+      color: color(node.ratio),
+      ratio: node.ratio
+    });
+    
+    // Children sit side by side under their parent
+    let childX = x;
+    for (const child of node.children || []) {
+      addFrame(child, `${id};${child.name}`, childX, depth + 1);
+      childX += 1000 * (child.value / root.value);
+    }
+  };
+  
+  addFrame(root, root.name, 0, 0);
+  return result;
+}
+
 // Helper function to generate random colors
 function getRandomColor(): string {
   const colors = [
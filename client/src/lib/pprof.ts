@@ -36,6 +36,15 @@ export interface Profile {
   data: string; // base64 encoded profile data
 }
 
+// One frame of a heap profile's retention flame graph: value is the bytes
+// allocated beneath it and ratio the fraction of them still in use
+export interface RatioNode {
+  name: string;
+  value: number;
+  ratio: number;
+  children?: RatioNode[];
+}
+
 // Connection to a pprof endpoint
 export interface Connection {
   id: number;
@@ -71,6 +80,12 @@ export const profileApi = {
     return res.json();
   },
 
+  // Get the retention flame graph of a heap profile
+  async getHeapRatio(id: number): Promise<RatioNode> {
+    const res = await apiRequest("GET", `/api/profiles/${id}/heap-ratio`);
+    return res.json();
+  },
+
   // Upload a profile file
   async uploadProfile(file: File, description?: string, profileType: ProfileType = 'cpu', isSaved: boolean = false): Promise<Profile> {
     const formData = new FormData();
@@ -15,8 +15,14 @@ import TimelineChart from "@/components/visualizations/timeline-chart";
 import DistributionChart from "@/components/visualizations/distribution-chart";
 import Icon from "@/components/ui/icon";
 import { queryClient } from "@/lib/queryClient";
-import { profileApi, profileUtils } from "@/lib/pprof";
-import { generateFlameGraphFromMetadata } from "@/lib/d3-utils";
+import { profileApi, profileUtils, RatioNode } from "@/lib/pprof";
+import { churnedColor, generateFlameGraphFromMetadata, generateRatioFlameGraph, retainedColor } from "@/lib/d3-utils";
+
+// Legend for the retention flame graph's color scale
+const retentionLegend = [
+  { color: retainedColor, label: "Retained" },
+  { color: churnedColor, label: "Churned" }
+];
 
 export default function ProfileView() {
   const { id } = useParams<{ id: string }>();
@@ -29,6 +35,16 @@ export default function ProfileView() {
     queryKey: [`/api/profiles/${profileId}`],
   });
   
+  // Query for the retention flame graph, fetched once its view is opened
+  const {
+    data: heapRatio,
+    isLoading: isHeapRatioLoading,
+    error: heapRatioError
+  } = useQuery<RatioNode>({
+    queryKey: [`/api/profiles/${profileId}/heap-ratio`],
+    enabled: profile?.profileType === "heap" && activeView === "retention",
+  });
+  
   // Query for recent profiles
   const { data: recentProfiles } = useQuery({
     queryKey: ["/api/profiles/recent"],
@@ -61,6 +77,8 @@ export default function ProfileView() {
     ? generateFlameGraphFromMetadata(profile.metadata)
     : [];
   
+  const retentionData = heapRatio ? generateRatioFlameGraph(heapRatio) : [];
+  
   if (isLoading) {
     return (
       <div className="min-h-screen flex flex-col">
@@ -117,7 +135,7 @@ export default function ProfileView() {
             onSave={handleSaveProfile}
           />
           
-          <ProfileTabs activeView={activeView} onChange={setActiveView} />
+          <ProfileTabs activeView={activeView} onChange={setActiveView} profileType={profile.profileType} />
           
           <div className="overflow-y-auto p-4 flex-1 bg-neutral-100">
             {/* Use conditional rendering instead of TabsContent */}
@@ -292,6 +310,34 @@ export default function ProfileView() {
               </div>
             )}
             
+            {activeView === "retention" && (
+              <div className="bg-white rounded-lg shadow-sm p-4">
+                <div className="flex justify-between items-center mb-4">
+                  <h2 className="font-medium text-neutral-800">Heap Retention Flamegraph</h2>
+                </div>
+                <div className="h-[calc(100vh-300px)] relative overflow-hidden">
+                  {isHeapRatioLoading ? (
+                    <div className="h-full flex items-center justify-center">
+                      <Icon name="mdi-loading" spin className="text-4xl text-primary" />
+                    </div>
+                  ) : heapRatioError ? (
+                    <div className="h-full flex items-center justify-center text-neutral-600">
+                      {heapRatioError instanceof Error ? heapRatioError.message : "Failed to load the retention view"}
+                    </div>
+                  ) : (
+                    <Flamegraph data={retentionData} legend={retentionLegend} />
+                  )}
+                </div>
+                <div className="mt-2 text-sm text-neutral-500 flex items-center">
+                  <Icon name="mdi-information-outline" className="mr-1" />
+                  <span>
+                    Width is bytes allocated (alloc_space); color is the share still in use (inuse_space / alloc_space),
+                    so red frames retain their allocations and blue frames churn through them
+                  </span>
+                </div>
+              </div>
+            )}
+            
             {activeView === "timeline" && (
               <div className="bg-white rounded-lg shadow-sm p-4">
                 <div className="flex justify-between items-center mb-4">
@@ -0,0 +1,60 @@
+// Command heapratio prints the allocation flame graph of a heap profile as
+// JSON, each frame annotated with the fraction of its allocated bytes still in
+// use. The visualizer's retention view runs it on stored heap profiles.
+//
+//	go run ./cmd/heapratio heap.pb.gz
+//	curl -s localhost:8081/debug/pprof/heap | go run ./cmd/heapratio
+//
+// With no file argument it reads the profile from stdin.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"pprofviz/examples/internal/analysis"
+)
+
+// writeRatioTree parses the heap profile in r and writes its ratio tree to w
+// as JSON in the {name, value, ratio, children} shape
+func writeRatioTree(w io.Writer, r io.Reader) error {
+	p, err := analysis.ParseProfile(r)
+	if err != nil {
+		return fmt.Errorf("parsing profile: %w", err)
+	}
+	tree, err := analysis.RatioTree(p)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(tree)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: heapratio [heap-profile]")
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	in := os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "heapratio: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	if err := writeRatioTree(os.Stdout, in); err != nil {
+		fmt.Fprintf(os.Stderr, "heapratio: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+
+	"pprofviz/examples/internal/analysis"
+)
+
+// heapProfile encodes a heap profile where main.retain keeps everything it
+// allocates and main.churn frees almost all of it
+func heapProfile(t *testing.T, sampleTypes ...string) []byte {
+	t.Helper()
+	p := &profile.Profile{}
+	for _, typ := range sampleTypes {
+		p.SampleType = append(p.SampleType, &profile.ValueType{Type: typ, Unit: "bytes"})
+	}
+	names := []string{"main.main", "main.retain", "main.churn"}
+	locations := make([]*profile.Location, len(names))
+	for i, name := range names {
+		fn := &profile.Function{ID: uint64(i + 1), Name: name}
+		locations[i] = &profile.Location{ID: uint64(i + 1), Line: []profile.Line{{Function: fn}}}
+		p.Function = append(p.Function, fn)
+		p.Location = append(p.Location, locations[i])
+	}
+	values := [][]int64{{1000, 1000}, {9000, 90}}
+	for i, leaf := range locations[1:] {
+		p.Sample = append(p.Sample, &profile.Sample{
+			Location: []*profile.Location{leaf, locations[0]},
+			Value:    values[i][:len(sampleTypes)],
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteRatioTree(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeRatioTree(&out, bytes.NewReader(heapProfile(t, "alloc_space", "inuse_space"))); err != nil {
+		t.Fatalf("writeRatioTree failed: %v", err)
+	}
+
+	var root analysis.RatioNode
+	if err := json.Unmarshal(out.Bytes(), &root); err != nil {
+		t.Fatalf("Failed to decode %s: %v", out.String(), err)
+	}
+	if root.Name != "root" || root.Value != 10000 || len(root.Children) != 1 {
+		t.Fatalf("Unexpected root %+v", root)
+	}
+	frames := root.Children[0].Children
+	if len(frames) != 2 || frames[0].Name != "main.churn" || frames[1].Name != "main.retain" {
+		t.Fatalf("Expected the churn and retain frames under main.main, got %+v", frames)
+	}
+	if frames[0].Ratio != 0.01 || frames[1].Ratio != 1 {
+		t.Errorf("Expected ratios 0.01 and 1, got %v and %v", frames[0].Ratio, frames[1].Ratio)
+	}
+}
+
+func TestWriteRatioTreeErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"Not a profile", []byte("not a profile"), "parsing profile"},
+		{"No inuse samples", heapProfile(t, "alloc_space"), "inuse_space"},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		err := writeRatioTree(&out, bytes.NewReader(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.expected, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%s: expected no output, got %s", tc.name, out.String())
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Command statuswatch polls an example app's /status endpoint and prints its
+// memory stats as a rolling table or CSV, for watching heap growth while a
+// demo such as /start-leak runs.
+//
+//	go run ./cmd/statuswatch -url http://localhost:8081/status.json -interval 2s
+//
+// It understands both the human-readable /status text and /status.json.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// memStats is one sample of a server's memory stats, in MiB
+type memStats struct {
+	Alloc      float64
+	TotalAlloc float64
+	Sys        float64
+	NumGC      uint64
+}
+
+// parseStatusText reads memory stats from the text served at /status, which
+// has one "Name: value" line per stat with sizes in whole MiB
+func parseStatusText(r io.Reader) (memStats, error) {
+	var stats memStats
+	found := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch name {
+		case "Alloc":
+			stats.Alloc, err = strconv.ParseFloat(fields[0], 64)
+		case "TotalAlloc":
+			stats.TotalAlloc, err = strconv.ParseFloat(fields[0], 64)
+		case "Sys":
+			stats.Sys, err = strconv.ParseFloat(fields[0], 64)
+		case "NumGC":
+			stats.NumGC, err = strconv.ParseUint(fields[0], 10, 64)
+		default:
+			continue
+		}
+		if err != nil {
+			return memStats{}, fmt.Errorf("invalid %s value %q", name, fields[0])
+		}
+		found[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return memStats{}, err
+	}
+
+	for _, name := range []string{"Alloc", "TotalAlloc", "Sys", "NumGC"} {
+		if !found[name] {
+			return memStats{}, fmt.Errorf("status is missing %s", name)
+		}
+	}
+	return stats, nil
+}
+
+// parseStatusJSON reads memory stats from /status.json, which reports them
+// in MiB next to the app's own fields
+func parseStatusJSON(r io.Reader) (memStats, error) {
+	var status struct {
+		AllocMiB      *float64 `json:"allocMiB"`
+		TotalAllocMiB *float64 `json:"totalAllocMiB"`
+		SysMiB        *float64 `json:"sysMiB"`
+		NumGC         *uint64  `json:"numGC"`
+	}
+	if err := json.NewDecoder(r).Decode(&status); err != nil {
+		return memStats{}, fmt.Errorf("invalid status JSON: %v", err)
+	}
+	if status.AllocMiB == nil || status.TotalAllocMiB == nil || status.SysMiB == nil || status.NumGC == nil {
+		return memStats{}, errors.New("status is missing memory stats")
+	}
+
+	return memStats{
+		Alloc:      *status.AllocMiB,
+		TotalAlloc: *status.TotalAllocMiB,
+		Sys:        *status.SysMiB,
+		NumGC:      *status.NumGC,
+	}, nil
+}
+
+// fetchStats requests url and parses the response as JSON or text depending
+// on its content type
+func fetchStats(ctx context.Context, client *http.Client, url string) (memStats, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return memStats{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return memStats{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return memStats{}, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" {
+		return parseStatusJSON(resp.Body)
+	}
+	return parseStatusText(resp.Body)
+}
+
+// sampleWriter prints samples as an aligned table or as CSV
+type sampleWriter struct {
+	w   io.Writer
+	csv *csv.Writer // Nil for table output
+}
+
+func newSampleWriter(w io.Writer, asCSV bool) *sampleWriter {
+	sw := &sampleWriter{w: w}
+	if asCSV {
+		sw.csv = csv.NewWriter(w)
+	}
+	return sw
+}
+
+// header writes the column names
+func (sw *sampleWriter) header() error {
+	if sw.csv != nil {
+		sw.csv.Write([]string{"time", "alloc_mib", "total_alloc_mib", "sys_mib", "num_gc", "alloc_delta_mib"})
+		sw.csv.Flush()
+		return sw.csv.Error()
+	}
+	_, err := fmt.Fprintf(sw.w, "%-8s  %10s  %12s  %10s  %7s  %10s\n",
+		"TIME", "ALLOC MiB", "TOTAL MiB", "SYS MiB", "NUM GC", "DELTA MiB")
+	return err
+}
+
+// row writes a sample taken at t along with its Alloc growth since the first sample
+func (sw *sampleWriter) row(t time.Time, s memStats, allocDelta float64) error {
+	if sw.csv != nil {
+		sw.csv.Write([]string{
+			t.Format(time.RFC3339),
+			strconv.FormatFloat(s.Alloc, 'f', 2, 64),
+			strconv.FormatFloat(s.TotalAlloc, 'f', 2, 64),
+			strconv.FormatFloat(s.Sys, 'f', 2, 64),
+			strconv.FormatUint(s.NumGC, 10),
+			strconv.FormatFloat(allocDelta, 'f', 2, 64),
+		})
+		sw.csv.Flush()
+		return sw.csv.Error()
+	}
+	_, err := fmt.Fprintf(sw.w, "%-8s  %10.2f  %12.2f  %10.2f  %7d  %+10.2f\n",
+		t.Format("15:04:05"), s.Alloc, s.TotalAlloc, s.Sys, s.NumGC, allocDelta)
+	return err
+}
+
+// watch polls url every interval and writes a row per sample to out until ctx
+// is done or count samples have been written (0 means no limit). Failed polls
+// are reported to errOut and skipped.
+func watch(ctx context.Context, out, errOut io.Writer, url string, interval time.Duration, count int, asCSV bool) error {
+	client := &http.Client{Timeout: interval + 5*time.Second}
+	sw := newSampleWriter(out, asCSV)
+	if err := sw.header(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var first *memStats
+	written := 0
+	for {
+		stats, err := fetchStats(ctx, client, url)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil:
+			fmt.Fprintf(errOut, "statuswatch: %v\n", err)
+		default:
+			if first == nil {
+				first = &stats
+			}
+			if err := sw.row(time.Now(), stats, stats.Alloc-first.Alloc); err != nil {
+				return err
+			}
+			if written++; written == count {
+				return nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func main() {
+	url := flag.String("url", "http://localhost:8081/status", "status endpoint to poll (/status or /status.json)")
+	interval := flag.Duration("interval", 2*time.Second, "time between polls")
+	count := flag.Int("count", 0, "stop after this many samples (0 polls until interrupted)")
+	asCSV := flag.Bool("csv", false, "print CSV instead of a table")
+	flag.Parse()
+
+	if *interval <= 0 || *count < 0 {
+		fmt.Fprintln(os.Stderr, "statuswatch: -interval must be positive and -count 0 or more")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := watch(ctx, os.Stdout, os.Stderr, *url, *interval, *count, *asCSV); err != nil {
+		fmt.Fprintf(os.Stderr, "statuswatch: %v\n", err)
+		os.Exit(1)
+	}
+}
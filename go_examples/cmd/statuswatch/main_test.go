@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Sample /status bodies from the example apps
+const (
+	memoryAppStatus = `Memory App Status
+----------------
+Cache size: 12 items
+Alloc: 27 MiB
+TotalAlloc: 310 MiB
+Sys: 52 MiB
+NumGC: 41
+`
+	webserviceStatus = `Server is running
+NumGoroutine: 7
+Alloc: 3 MiB
+TotalAlloc: 9 MiB
+Sys: 14 MiB
+NumGC: 2
+`
+)
+
+func TestParseStatusText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected memStats
+	}{
+		{"Memory app", memoryAppStatus, memStats{Alloc: 27, TotalAlloc: 310, Sys: 52, NumGC: 41}},
+		{"Web service", webserviceStatus, memStats{Alloc: 3, TotalAlloc: 9, Sys: 14, NumGC: 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stats, err := parseStatusText(strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("parseStatusText returned error: %v", err)
+			}
+			if stats != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, stats)
+			}
+		})
+	}
+}
+
+func TestParseStatusTextErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"Missing stat", "Alloc: 1 MiB\nTotalAlloc: 2 MiB\nSys: 3 MiB\n", "missing NumGC"},
+		{"Bad number", "Alloc: lots MiB\nTotalAlloc: 2 MiB\nSys: 3 MiB\nNumGC: 1\n", `invalid Alloc value "lots"`},
+		{"Not a status page", "404 page not found\n", "missing Alloc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseStatusText(strings.NewReader(tc.body))
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseStatusJSON(t *testing.T) {
+	body := `{"cacheItems": 3, "allocMiB": 3, "totalAllocMiB": 10, "sysMiB": 15, "numGC": 4}`
+	stats, err := parseStatusJSON(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("parseStatusJSON returned error: %v", err)
+	}
+	if expected := (memStats{Alloc: 3, TotalAlloc: 10, Sys: 15, NumGC: 4}); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+
+	if _, err := parseStatusJSON(strings.NewReader(`{"goroutines": 4}`)); err == nil {
+		t.Error("Expected an error for JSON without memory stats")
+	}
+	if _, err := parseStatusJSON(strings.NewReader(memoryAppStatus)); err == nil {
+		t.Error("Expected an error for a text body")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	// Alloc grows by 5 MiB per poll; the second poll fails
+	var polls int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&polls, 1)
+		if n == 2 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"allocMiB": %d, "totalAllocMiB": %d, "sysMiB": 64, "numGC": %d}`, n*5, n*10, n)
+	}))
+	defer srv.Close()
+
+	var out, errOut bytes.Buffer
+	err := watch(context.Background(), &out, &errOut, srv.URL+"/status.json", 10*time.Millisecond, 2, true)
+	if err != nil {
+		t.Fatalf("watch returned error: %v", err)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV output: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %v", records)
+	}
+	if records[1][1] != "5.00" || records[2][1] != "15.00" || records[2][5] != "10.00" {
+		t.Errorf("Unexpected rows: %v", records[1:])
+	}
+	if !strings.Contains(errOut.String(), "503") {
+		t.Errorf("Expected the failed poll to be reported, got %q", errOut.String())
+	}
+}
+
+func TestWatchTable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, memoryAppStatus)
+	}))
+	defer srv.Close()
+
+	var out, errOut bytes.Buffer
+	if err := watch(context.Background(), &out, &errOut, srv.URL+"/status", time.Millisecond, 1, false); err != nil {
+		t.Fatalf("watch returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "TIME") {
+		t.Fatalf("Expected a header and one row, got %q", out.String())
+	}
+	if fields := strings.Fields(lines[1]); len(fields) != 6 || fields[1] != "27.00" || fields[4] != "41" {
+		t.Errorf("Unexpected row: %q", lines[1])
+	}
+}
+
+func TestWatchStopsOnCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, webserviceStatus)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var out, errOut bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- watch(ctx, &out, &errOut, srv.URL, 10*time.Millisecond, 0, true)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected watch to stop cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop after its context was cancelled")
+	}
+}
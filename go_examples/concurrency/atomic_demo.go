@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runAtomicDemo has every worker increment a shared counter iterations times
+// with atomic.AddInt64. It is the lock-free baseline for runMutexDemo: its
+// profiles should show essentially no mutex or block samples.
+func runAtomicDemo(ctx context.Context, numWorkers, iterations int) (int64, time.Duration) {
+	slog.InfoContext(ctx, "Starting atomic demo", "workers", numWorkers, "iterations", iterations)
+
+	var counter int64
+	start := time.Now()
+	var atomicWg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		atomicWg.Add(1)
+		go withWorkerLabel(ctx, i, func(ctx context.Context) {
+			defer atomicWg.Done()
+			rng := workerRand(ctx)
+			for j := 0; j < iterations; j++ {
+				// Simulate the same work the mutex demo does before locking
+				sleepUpTo(rng, 5*time.Millisecond)
+				atomic.AddInt64(&counter, 1)
+			}
+		})
+	}
+	atomicWg.Wait()
+	elapsed := time.Since(start)
+
+	slog.InfoContext(ctx, "Atomic demo completed", "counter", counter, "elapsed", elapsed)
+	return counter, elapsed
+}
+
+// HTTP handler that runs the atomic demo and reports the counter and elapsed time
+func atomicDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, iterations, err := workerParams(r, 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	counter, elapsed := runAtomicDemo(withSeed(r.Context(), seed), numWorkers, iterations)
+
+	fmt.Fprintf(w, "Atomic demo with %d workers, %d iterations each\n", numWorkers, iterations)
+	fmt.Fprintf(w, "Final counter value: %d\n", counter)
+	fmt.Fprintf(w, "Elapsed: %v\n", elapsed)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAtomicDemo(t *testing.T) {
+	testCases := []struct {
+		workers    int
+		iterations int
+	}{
+		{1, 1},
+		{5, 10},
+		{50, 20},
+	}
+
+	for _, tc := range testCases {
+		counter, elapsed := runAtomicDemo(context.Background(), tc.workers, tc.iterations)
+
+		// Atomic increments are never lost, so the count is exact
+		if counter != int64(tc.workers*tc.iterations) {
+			t.Errorf("workers=%d iterations=%d: expected counter %d, got %d",
+				tc.workers, tc.iterations, tc.workers*tc.iterations, counter)
+		}
+		if elapsed <= 0 {
+			t.Errorf("Expected a positive elapsed time, got %v", elapsed)
+		}
+	}
+}
+
+func TestAtomicDemoHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/atomic-demo?workers=4&iterations=3", nil)
+	recorder := httptest.NewRecorder()
+
+	atomicDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	for _, expected := range []string{"4 workers, 3 iterations each", "Final counter value: 12", "Elapsed:"} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Expected body to contain %q, got: %s", expected, recorder.Body.String())
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/atomic-demo?iterations=0", nil)
+	recorder = httptest.NewRecorder()
+	atomicDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for iterations=0, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Bounds for the backpressure demo. A slow consumer can take a long time over
+// many items, so runs are cut off after maxBackpressureDuration.
+const (
+	maxBackpressureBuffer    = 1000
+	maxBackpressureConsumeMs = 1000
+	maxBackpressureDuration  = time.Minute
+)
+
+// backpressureStats summarizes a backpressure demo run
+type backpressureStats struct {
+	Producers         int     `json:"producers"`
+	Consumers         int     `json:"consumers"`
+	Buffer            int     `json:"buffer"`
+	Produced          int64   `json:"produced"`
+	Consumed          int64   `json:"consumed"`
+	MaxChannelLength  int64   `json:"maxChannelLength"`  // Most items seen in the work channel after a send
+	ProducerBlockedMs float64 `json:"producerBlockedMs"` // Time all producers spent waiting to send
+	ConsumerIdleMs    float64 `json:"consumerIdleMs"`
+	DurationMs        float64 `json:"durationMs"`
+}
+
+// runBackpressureDemo runs the channel demo's producers against consumers
+// that work up to consumeSleep on each item, over a work channel of the given
+// capacity. With more producers than the consumers can keep up with, the
+// channel fills and the producers' sends block, so the block profile is
+// dominated by the send in produceItems.
+func runBackpressureDemo(ctx context.Context, numProducers, numConsumers, itemsPerProducer, buffer int, consumeSleep time.Duration) backpressureStats {
+	slog.InfoContext(ctx, "Starting backpressure demo", "producers", numProducers, "consumers", numConsumers,
+		"buffer", buffer, "consumeSleep", consumeSleep)
+
+	stats := runChannelWorkers(ctx, channelWorkers{"backpressure", produceItems, slowConsumer(consumeSleep)},
+		numProducers, numConsumers, itemsPerProducer, buffer, defaultChannelBuffer, 0)
+
+	return backpressureStats{
+		Producers:         numProducers,
+		Consumers:         numConsumers,
+		Buffer:            buffer,
+		Produced:          stats.Produced,
+		Consumed:          stats.Consumed,
+		MaxChannelLength:  stats.PeakOccupancy,
+		ProducerBlockedMs: float64(stats.ProducerBlocked) / float64(time.Millisecond),
+		ConsumerIdleMs:    float64(stats.ConsumerIdle) / float64(time.Millisecond),
+		DurationMs:        float64(stats.Elapsed) / float64(time.Millisecond),
+	}
+}
+
+// HTTP handler that runs the backpressure demo and returns its summary as JSON
+func backpressureDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numProducers, err := queryInt(r, "producers", 5, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	numConsumers, err := queryInt(r, "consumers", 1, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	items, err := queryInt(r, "items", 20, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	buffer, err := queryInt(r, "buffer", 10, 0, maxBackpressureBuffer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	consumeMs, err := queryInt(r, "consumeMs", 50, 1, maxBackpressureConsumeMs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), maxBackpressureDuration)
+	defer cancel()
+	writeJSON(w, runBackpressureDemo(ctx, numProducers, numConsumers, items, buffer, time.Duration(consumeMs)*time.Millisecond))
+}
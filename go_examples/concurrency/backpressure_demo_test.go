@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"pprofviz/examples/internal/analysis"
+	"pprofviz/examples/internal/profrate"
+	"pprofviz/examples/internal/testutil"
+)
+
+// producerBlockEvents counts the block profile's blocking events in
+// produceItems so far
+func producerBlockEvents(t *testing.T) int64 {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("block").WriteTo(&buf, 0); err != nil {
+		t.Fatalf("Failed to write block profile: %v", err)
+	}
+	p, err := analysis.ParseProfile(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse block profile: %v", err)
+	}
+	index, err := analysis.SampleIndex(p, "contentions")
+	if err != nil {
+		t.Fatalf("Unexpected block profile: %v", err)
+	}
+
+	var events int64
+	for _, sample := range p.Sample {
+		for _, loc := range sample.Location {
+			if len(loc.Line) > 0 && strings.HasSuffix(loc.Line[0].Function.Name, ".produceItems") {
+				events += sample.Value[index]
+				break
+			}
+		}
+	}
+	return events
+}
+
+func TestBackpressureDemo(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	// Record every blocking event for the duration of the test
+	profrate.SetBlockRate(1)
+	defer profrate.SetBlockRate(0)
+	before := producerBlockEvents(t)
+
+	stats := runBackpressureDemo(context.Background(), 4, 1, 10, 2, 10*time.Millisecond)
+
+	if stats.Produced != 40 || stats.Consumed != 40 {
+		t.Errorf("Expected all 40 items produced and consumed, got %+v", stats)
+	}
+	if stats.MaxChannelLength != 2 {
+		t.Errorf("Expected the 2 item work channel to fill, got a maximum length of %d", stats.MaxChannelLength)
+	}
+	if stats.ProducerBlockedMs <= 0 {
+		t.Errorf("Expected producers to block, got %+v", stats)
+	}
+	if events := producerBlockEvents(t) - before; events == 0 {
+		t.Error("Expected block profile samples for producers blocked sending, got none")
+	}
+}
+
+func TestBackpressureDemoHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	backpressureDemoHandler(recorder, httptest.NewRequest("GET", "/backpressure-demo?producers=3&consumers=1&items=5&buffer=1&consumeMs=20", nil))
+
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"maxChannelLength":1`) {
+		t.Errorf("Unexpected response: %d %s", recorder.Code, recorder.Body.String())
+	}
+
+	for _, query := range []string{"producers=0", "buffer=1001", "consumeMs=0", "items=abc"} {
+		recorder := httptest.NewRecorder()
+		backpressureDemoHandler(recorder, httptest.NewRequest("GET", "/backpressure-demo?"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// cancelReport summarizes the teardown of a cancel demo tree
+type cancelReport struct {
+	Spawned         int     `json:"spawned"`
+	PeakGoroutines  int     `json:"peakGoroutines"`
+	AfterGoroutines int     `json:"afterGoroutines"`
+	TeardownMs      float64 `json:"teardownMs"`
+}
+
+// cancelTree is a running tree of goroutines sharing one context
+type cancelTree struct {
+	cancel  context.CancelFunc
+	done    chan struct{} // Closed once every goroutine in the tree has exited
+	spawned int
+	peak    int
+}
+
+// spawnNode starts a goroutine that starts fanout[0] children (each with
+// fanout[1:]), waits for ctx to be cancelled and returns after its children
+func spawnNode(ctx context.Context, nodes, started *sync.WaitGroup, fanout []int) {
+	nodes.Add(1)
+	started.Add(1)
+	go func() {
+		defer nodes.Done()
+
+		// Children derive from the same context, so one cancel reaches them all
+		var children sync.WaitGroup
+		if len(fanout) > 0 {
+			for i := 0; i < fanout[0]; i++ {
+				spawnNode(ctx, &children, started, fanout[1:])
+			}
+		}
+		started.Done()
+
+		<-ctx.Done()
+		children.Wait()
+	}()
+}
+
+// startCancelTree starts parents goroutines, each with children goroutines,
+// each with grandchildren goroutines, and returns once all are running
+func startCancelTree(parents, children, grandchildren int) *cancelTree {
+	ctx, cancel := context.WithCancel(context.Background())
+	tree := &cancelTree{
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		spawned: parents + parents*children + parents*children*grandchildren,
+	}
+
+	var nodes, started sync.WaitGroup
+	for i := 0; i < parents; i++ {
+		spawnNode(ctx, &nodes, &started, []int{children, grandchildren})
+	}
+	started.Wait()
+	tree.peak = runtime.NumGoroutine()
+
+	go func() {
+		nodes.Wait()
+		close(tree.done)
+	}()
+	return tree
+}
+
+// stop cancels the tree's context and reports how long it took to unwind
+func (t *cancelTree) stop() cancelReport {
+	start := time.Now()
+	t.cancel()
+	<-t.done
+	teardown := time.Since(start)
+
+	return cancelReport{
+		Spawned:         t.spawned,
+		PeakGoroutines:  t.peak,
+		AfterGoroutines: runtime.NumGoroutine(),
+		TeardownMs:      float64(teardown) / float64(time.Millisecond),
+	}
+}
+
+// The running cancel demo tree, nil when none is running
+var (
+	cancelDemoMutex  sync.Mutex
+	activeCancelTree *cancelTree
+)
+
+// Errors for starting or stopping the cancel demo twice
+var (
+	errCancelDemoRunning    = errors.New("cancel demo already running")
+	errCancelDemoNotRunning = errors.New("cancel demo not running")
+)
+
+// startCancelDemo starts a cancel demo tree unless one is already running
+func startCancelDemo(parents, children, grandchildren int) (*cancelTree, error) {
+	cancelDemoMutex.Lock()
+	defer cancelDemoMutex.Unlock()
+
+	if activeCancelTree != nil {
+		return nil, errCancelDemoRunning
+	}
+	activeCancelTree = startCancelTree(parents, children, grandchildren)
+	return activeCancelTree, nil
+}
+
+// stopCancelDemo cancels the running cancel demo tree
+func stopCancelDemo() (cancelReport, error) {
+	cancelDemoMutex.Lock()
+	defer cancelDemoMutex.Unlock()
+
+	if activeCancelTree == nil {
+		return cancelReport{}, errCancelDemoNotRunning
+	}
+	report := activeCancelTree.stop()
+	activeCancelTree = nil
+	return report, nil
+}
+
+// HTTP handler that starts the cancel demo tree
+func cancelDemoHandler(w http.ResponseWriter, r *http.Request) {
+	parents, err := queryInt(r, "parents", 5, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	children, err := queryInt(r, "children", 5, 0, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	grandchildren, err := queryInt(r, "grandchildren", 5, 0, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if total := parents + parents*children + parents*children*grandchildren; total > maxDemoParam {
+		http.Error(w, fmt.Sprintf("tree of %d goroutines exceeds the limit of %d", total, maxDemoParam), http.StatusBadRequest)
+		return
+	}
+
+	tree, err := startCancelDemo(parents, children, grandchildren)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	fmt.Fprintf(w, "Started cancel demo with %d goroutines (%d goroutines running); stop it with /cancel-demo/stop\n",
+		tree.spawned, tree.peak)
+}
+
+// HTTP handler that cancels the cancel demo tree and reports the teardown as JSON
+func cancelDemoStopHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := stopCancelDemo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, report)
+}
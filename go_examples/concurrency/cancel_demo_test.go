@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCancelTree(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	tree := startCancelTree(3, 4, 5)
+
+	if expected := 3 + 3*4 + 3*4*5; tree.spawned != expected {
+		t.Errorf("Expected %d goroutines spawned, got %d", expected, tree.spawned)
+	}
+	if tree.peak < baseline+tree.spawned {
+		t.Errorf("Expected at least %d goroutines at peak, got %d", baseline+tree.spawned, tree.peak)
+	}
+
+	done := make(chan cancelReport)
+	go func() { done <- tree.stop() }()
+
+	var report cancelReport
+	select {
+	case report = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Cancel demo tree did not unwind within 2s")
+	}
+
+	if report.PeakGoroutines != tree.peak || report.Spawned != tree.spawned {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+		t.Errorf("Expected goroutines back to %d after cancellation, got %d", baseline, n)
+	}
+}
+
+func TestCancelDemoHandlers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cancel-demo", cancelDemoHandler)
+	mux.HandleFunc("/cancel-demo/stop", cancelDemoStopHandler)
+
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Stop before start", "/cancel-demo/stop", http.StatusConflict, "not running"},
+		{"Tree too large", "/cancel-demo?parents=100&children=100", http.StatusBadRequest, "exceeds the limit"},
+		{"Start", "/cancel-demo?parents=2&children=2&grandchildren=2", http.StatusOK, "Started cancel demo with 14 goroutines"},
+		{"Double start", "/cancel-demo", http.StatusConflict, "already running"},
+		{"Stop", "/cancel-demo/stop", http.StatusOK, `"spawned":14`},
+		{"Double stop", "/cancel-demo/stop", http.StatusConflict, "not running"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("POST", tc.url, nil)
+		recorder := httptest.NewRecorder()
+
+		mux.ServeHTTP(recorder, req)
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.expectedStatus, recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+			t.Errorf("%s: expected body to contain %q, got: %s", tc.name, tc.expectedBody, recorder.Body.String())
+		}
+		if tc.name == "Stop" {
+			var report cancelReport
+			if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+				t.Fatalf("Invalid JSON response: %v", err)
+			}
+			if report.AfterGoroutines >= report.PeakGoroutines {
+				t.Errorf("Expected fewer goroutines after cancellation than at peak: %+v", report)
+			}
+		}
+	}
+}
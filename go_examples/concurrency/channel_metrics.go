@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// channelMetrics accumulates a channel demo run's backpressure: how long
+// producers blocked sending, how long consumers sat idle waiting for work and
+// how full the work channel got. Producers and consumers find it in their
+// context, so replacement workers that don't report anything still fit.
+type channelMetrics struct {
+	produced, consumed atomic.Int64
+	producerBlockedNs  atomic.Int64
+	consumerIdleNs     atomic.Int64
+	peakOccupancy      atomic.Int64
+}
+
+type channelMetricsKey struct{}
+
+// withChannelMetrics returns a context carrying m for the demo's workers
+func withChannelMetrics(ctx context.Context, m *channelMetrics) context.Context {
+	return context.WithValue(ctx, channelMetricsKey{}, m)
+}
+
+// channelMetricsFrom returns the run's metrics, or nil outside a run; the
+// methods do nothing on nil
+func channelMetricsFrom(ctx context.Context) *channelMetrics {
+	m, _ := ctx.Value(channelMetricsKey{}).(*channelMetrics)
+	return m
+}
+
+// blocked adds time a producer spent waiting to send
+func (m *channelMetrics) blocked(d time.Duration) {
+	if m != nil {
+		m.producerBlockedNs.Add(int64(d))
+	}
+}
+
+// sent records a successful send that left occupancy items in the channel
+func (m *channelMetrics) sent(occupancy int) {
+	if m == nil {
+		return
+	}
+	m.produced.Add(1)
+	for {
+		peak := m.peakOccupancy.Load()
+		if int64(occupancy) <= peak || m.peakOccupancy.CompareAndSwap(peak, int64(occupancy)) {
+			return
+		}
+	}
+}
+
+// idle adds time a consumer spent waiting for work
+func (m *channelMetrics) idle(d time.Duration) {
+	if m != nil {
+		m.consumerIdleNs.Add(int64(d))
+	}
+}
+
+// received records a work item taken from the channel
+func (m *channelMetrics) received() {
+	if m != nil {
+		m.consumed.Add(1)
+	}
+}
+
+// channelStats summarizes a channel demo run. Every item produced was either
+// consumed or still in the work channel at shutdown.
+type channelStats struct {
+	Produced           int64
+	Consumed           int64
+	Remaining          int64 // Work items left in the channel
+	Results            int64 // Results collected from the consumers
+	PeakOccupancy      int64
+	ProducerBlocked    time.Duration
+	ConsumerIdle       time.Duration
+	ConsumerGoroutines int64 // Fewer than the consumers when they ran as a pool
+	Elapsed            time.Duration
+}
+
+// stats returns the totals with the channel contents counted at shutdown
+func (m *channelMetrics) stats(remaining, results int) channelStats {
+	return channelStats{
+		Produced:        m.produced.Load(),
+		Consumed:        m.consumed.Load(),
+		Remaining:       int64(remaining),
+		Results:         int64(results),
+		PeakOccupancy:   m.peakOccupancy.Load(),
+		ProducerBlocked: time.Duration(m.producerBlockedNs.Load()),
+		ConsumerIdle:    time.Duration(m.consumerIdleNs.Load()),
+	}
+}
+
+// result returns the stats as a run result, with times in nanoseconds
+func (s channelStats) result() map[string]int64 {
+	return map[string]int64{
+		"produced":           s.Produced,
+		"consumed":           s.Consumed,
+		"remainingWork":      s.Remaining,
+		"results":            s.Results,
+		"peakOccupancy":      s.PeakOccupancy,
+		"producerBlockedNs":  int64(s.ProducerBlocked),
+		"consumerIdleNs":     int64(s.ConsumerIdle),
+		"consumerGoroutines": s.ConsumerGoroutines,
+		"elapsedNs":          int64(s.Elapsed),
+	}
+}
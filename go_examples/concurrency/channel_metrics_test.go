@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestChannelDemoStats(t *testing.T) {
+	// Every item is consumed well within the run
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	stats := runChannelDemo(ctx, 2, 3, 5)
+	cancel()
+
+	if stats.Produced != 10 || stats.Consumed != 10 || stats.Remaining != 0 || stats.Results != 10 {
+		t.Errorf("Expected 10 items produced, consumed and collected, got %+v", stats)
+	}
+	// Consumers wait for work most of the run
+	if stats.ConsumerIdle <= 0 || stats.PeakOccupancy < 1 {
+		t.Errorf("Expected idle consumers and at least one queued item, got %+v", stats)
+	}
+}
+
+func TestChannelDemoBackpressure(t *testing.T) {
+	// Four producers outpace one consumer, filling the 100 item work channel
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	stats := runChannelDemo(ctx, 4, 1, 1000)
+	cancel()
+
+	if stats.Produced != stats.Consumed+stats.Remaining {
+		t.Errorf("Expected produced == consumed + remaining, got %d != %d + %d",
+			stats.Produced, stats.Consumed, stats.Remaining)
+	}
+	if stats.PeakOccupancy != 100 || stats.Remaining == 0 {
+		t.Errorf("Expected a full work channel, got peak %d with %d left", stats.PeakOccupancy, stats.Remaining)
+	}
+	if stats.ProducerBlocked < 100*time.Millisecond {
+		t.Errorf("Expected producers to block on the full channel, blocked %v", stats.ProducerBlocked)
+	}
+
+	result := stats.result()
+	if result["produced"] != stats.Produced || result["remainingWork"] != stats.Remaining ||
+		result["producerBlockedNs"] != int64(stats.ProducerBlocked) {
+		t.Errorf("Unexpected run result: %v", result)
+	}
+}
+
+func TestChannelMetricsWithoutRun(t *testing.T) {
+	// Workers started outside runChannelDemo have nothing to report to
+	metrics := channelMetricsFrom(context.Background())
+	if metrics != nil {
+		t.Fatalf("Expected no metrics, got %+v", metrics)
+	}
+	metrics.blocked(time.Millisecond)
+	metrics.sent(1)
+	metrics.idle(time.Millisecond)
+	metrics.received()
+
+	var m channelMetrics
+	ctx := withChannelMetrics(context.Background(), &m)
+	channelMetricsFrom(ctx).sent(3)
+	channelMetricsFrom(ctx).sent(2)
+	if stats := m.stats(0, 0); stats.Produced != 2 || stats.PeakOccupancy != 3 {
+		t.Errorf("Expected 2 sends with a peak of 3, got %+v", stats)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Capacity of the cond demo queue, small so producers regularly wait
+const condQueueCapacity = 5
+
+// condStats summarizes a cond demo run
+type condStats struct {
+	Produced int64
+	Consumed int64
+	Waits    int64 // Calls to Cond.Wait by producers and consumers
+	Signals  int64 // Calls to Cond.Signal and Cond.Broadcast
+}
+
+// boundedQueue is a fixed-capacity FIFO guarded by two condition variables
+type boundedQueue struct {
+	mutex    sync.Mutex
+	notFull  *sync.Cond
+	notEmpty *sync.Cond
+	items    []int
+	capacity int
+	closed   bool
+	stats    *condStats
+}
+
+// newBoundedQueue creates an empty queue holding at most capacity items
+func newBoundedQueue(capacity int, stats *condStats) *boundedQueue {
+	q := &boundedQueue{capacity: capacity, stats: stats}
+	q.notFull = sync.NewCond(&q.mutex)
+	q.notEmpty = sync.NewCond(&q.mutex)
+	return q
+}
+
+// Put appends an item, waiting while the queue is full
+func (q *boundedQueue) Put(item int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.items) == q.capacity {
+		atomic.AddInt64(&q.stats.Waits, 1)
+		q.notFull.Wait()
+	}
+	q.items = append(q.items, item)
+	atomic.AddInt64(&q.stats.Produced, 1)
+
+	atomic.AddInt64(&q.stats.Signals, 1)
+	q.notEmpty.Signal()
+}
+
+// Get removes the oldest item, waiting while the queue is empty. It returns
+// false once the queue is closed and drained.
+func (q *boundedQueue) Get() (int, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.items) == 0 {
+		if q.closed {
+			return 0, false
+		}
+		atomic.AddInt64(&q.stats.Waits, 1)
+		q.notEmpty.Wait()
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	atomic.AddInt64(&q.stats.Consumed, 1)
+
+	atomic.AddInt64(&q.stats.Signals, 1)
+	q.notFull.Signal()
+	return item, true
+}
+
+// Close wakes every waiting consumer so they can exit once the queue drains
+func (q *boundedQueue) Close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	atomic.AddInt64(&q.stats.Signals, 1)
+	q.notEmpty.Broadcast()
+}
+
+// runCondDemo passes items through a sync.Cond bounded queue and returns
+// once every item has been consumed and all goroutines have exited
+func runCondDemo(numProducers, numConsumers, itemsPerProducer int) condStats {
+	slog.Info("Starting cond demo", "producers", numProducers, "consumers", numConsumers)
+
+	var stats condStats
+	queue := newBoundedQueue(condQueueCapacity, &stats)
+	var producers, consumers sync.WaitGroup
+
+	for i := 0; i < numProducers; i++ {
+		producers.Add(1)
+		go func() {
+			defer producers.Done()
+			for j := 0; j < itemsPerProducer; j++ {
+				queue.Put(j)
+			}
+		}()
+	}
+
+	for i := 0; i < numConsumers; i++ {
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			for {
+				if _, ok := queue.Get(); !ok {
+					return
+				}
+			}
+		}()
+	}
+
+	producers.Wait()
+	queue.Close()
+	consumers.Wait()
+
+	slog.Info("Cond demo completed", "items", stats.Consumed, "waits", stats.Waits, "signals", stats.Signals)
+	return stats
+}
+
+// HTTP handler that runs the sync.Cond demo and summarizes it
+func condDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numProducers, err := queryInt(r, "producers", 3, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	numConsumers, err := queryInt(r, "consumers", 3, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	itemsPerProducer, err := queryInt(r, "items", 100, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats := runCondDemo(numProducers, numConsumers, itemsPerProducer)
+
+	fmt.Fprintf(w, "Cond demo with %d producers and %d consumers, %d items each\n",
+		numProducers, numConsumers, itemsPerProducer)
+	fmt.Fprintf(w, "Produced: %d, Consumed: %d\n", stats.Produced, stats.Consumed)
+	fmt.Fprintf(w, "Wait calls: %d\n", stats.Waits)
+	fmt.Fprintf(w, "Signal/Broadcast calls: %d\n", stats.Signals)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCondDemo(t *testing.T) {
+	testCases := []struct {
+		producers int
+		consumers int
+		items     int
+	}{
+		{1, 1, 10},
+		{4, 1, 50},
+		{1, 4, 50},
+		{3, 3, 100},
+	}
+
+	for _, tc := range testCases {
+		baseline := runtime.NumGoroutine()
+
+		done := make(chan condStats)
+		go func() { done <- runCondDemo(tc.producers, tc.consumers, tc.items) }()
+
+		var stats condStats
+		select {
+		case stats = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("producers=%d consumers=%d: cond demo did not terminate", tc.producers, tc.consumers)
+		}
+
+		expected := int64(tc.producers * tc.items)
+		if stats.Produced != expected || stats.Consumed != expected {
+			t.Errorf("producers=%d consumers=%d: expected %d items produced and consumed, got %d and %d",
+				tc.producers, tc.consumers, expected, stats.Produced, stats.Consumed)
+		}
+		if stats.Signals < expected {
+			t.Errorf("Expected at least one signal per item (%d), got %d", expected, stats.Signals)
+		}
+
+		if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+			t.Errorf("producers=%d consumers=%d: expected goroutines back to %d, got %d",
+				tc.producers, tc.consumers, baseline, n)
+		}
+	}
+}
+
+func TestCondDemoHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/cond-demo?producers=2&consumers=1&items=20", nil)
+	recorder := httptest.NewRecorder()
+
+	condDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	for _, expected := range []string{"Produced: 40, Consumed: 40", "Wait calls:", "Signal/Broadcast calls:"} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Expected body to contain %q, got: %s", expected, recorder.Body.String())
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/cond-demo?items=0", nil)
+	recorder = httptest.NewRecorder()
+	condDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid items, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Upper bound for /cpuspin-demo's seconds parameter, since the request holds
+// every spinning goroutine on a CPU until it returns
+const maxCPUSpinSeconds = 30
+
+// Iterations between each spinning goroutine's checks of the stop flag
+const cpuSpinBatch = 1024
+
+// Keeps the spin loops' results live so the compiler can't drop their work
+var cpuSpinSink uint64
+
+// cpuSpinStats summarizes a CPU spin demo run
+type cpuSpinStats struct {
+	Goroutines       int     `json:"goroutines"`
+	GOMAXPROCS       int     `json:"gomaxprocs"`
+	DurationMs       float64 `json:"durationMs"`
+	Iterations       int64   `json:"iterations"`
+	IterationsPerSec float64 `json:"iterationsPerSec"`
+}
+
+// spin advances a xorshift generator until stop is set, returning the
+// iterations done and the generator's final state
+func spin(seed uint64, stop *int32) (iterations int64, state uint64) {
+	state = seed
+	for atomic.LoadInt32(stop) == 0 {
+		for i := 0; i < cpuSpinBatch; i++ {
+			state ^= state << 13
+			state ^= state >> 7
+			state ^= state << 17
+		}
+		iterations += cpuSpinBatch
+	}
+	return iterations, state
+}
+
+// runCPUSpinDemo busy-loops numGoroutines goroutines for duration, or until
+// ctx is cancelled, and reports their combined iterations. Unlike the other
+// demos nothing sleeps or blocks, so a CPU profile taken meanwhile is all
+// on-CPU time.
+func runCPUSpinDemo(ctx context.Context, numGoroutines int, duration time.Duration) cpuSpinStats {
+	slog.InfoContext(ctx, "Starting CPU spin demo", "goroutines", numGoroutines, "duration", duration)
+
+	var stop int32
+	var total int64
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	withDemoLabel(ctx, "cpuspin", func(ctx context.Context) {
+		for i := 0; i < numGoroutines; i++ {
+			wg.Add(1)
+			withWorkerLabel(ctx, i, func(ctx context.Context) {
+				go func(seed uint64) {
+					defer wg.Done()
+					iterations, state := spin(seed, &stop)
+					atomic.AddInt64(&total, iterations)
+					atomic.AddUint64(&cpuSpinSink, state)
+				}(uint64(i) + 1)
+			})
+		}
+	})
+
+	timer := time.NewTimer(duration)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	stats := cpuSpinStats{
+		Goroutines:       numGoroutines,
+		GOMAXPROCS:       runtime.GOMAXPROCS(0),
+		DurationMs:       float64(elapsed) / float64(time.Millisecond),
+		Iterations:       total,
+		IterationsPerSec: float64(total) / elapsed.Seconds(),
+	}
+	slog.InfoContext(ctx, "CPU spin demo completed", "iterations", total, "elapsed", elapsed)
+	return stats
+}
+
+// HTTP handler that runs the CPU spin demo and returns its summary as JSON
+func cpuSpinDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numGoroutines, err := queryInt(r, "goroutines", runtime.GOMAXPROCS(0), 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seconds, err := queryInt(r, "seconds", 5, 1, maxCPUSpinSeconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runCPUSpinDemo(r.Context(), numGoroutines, time.Duration(seconds)*time.Second))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCPUSpinDemoDuration(t *testing.T) {
+	const duration = 100 * time.Millisecond
+	start := time.Now()
+	stats := runCPUSpinDemo(context.Background(), 2, duration)
+	elapsed := time.Since(start)
+
+	if elapsed < duration || elapsed > duration+time.Second {
+		t.Errorf("Expected the demo to run for about %v, took %v", duration, elapsed)
+	}
+	if stats.Goroutines != 2 || stats.GOMAXPROCS < 1 {
+		t.Errorf("Unexpected CPU spin demo stats: %+v", stats)
+	}
+	if stats.Iterations <= 0 || stats.IterationsPerSec <= 0 {
+		t.Errorf("Expected positive iterations, got %d (%v/s)", stats.Iterations, stats.IterationsPerSec)
+	}
+}
+
+func TestCPUSpinDemoCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	stats := runCPUSpinDemo(ctx, 2, time.Minute)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancellation to stop the demo, took %v", elapsed)
+	}
+	if stats.Iterations <= 0 {
+		t.Errorf("Expected positive iterations before cancellation, got %d", stats.Iterations)
+	}
+}
+
+func TestCPUSpinDemoHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	cpuSpinDemoHandler(recorder, httptest.NewRequest("GET", "/cpuspin-demo?goroutines=2&seconds=1", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var stats cpuSpinStats
+	if err := json.Unmarshal(recorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode CPU spin demo stats: %v", err)
+	}
+	if stats.Goroutines != 2 || stats.DurationMs < 1000 || stats.Iterations <= 0 {
+		t.Errorf("Unexpected CPU spin demo stats: %+v", stats)
+	}
+
+	for _, url := range []string{"/cpuspin-demo?seconds=0", "/cpuspin-demo?seconds=31", "/cpuspin-demo?goroutines=0"} {
+		recorder := httptest.NewRecorder()
+		cpuSpinDemoHandler(recorder, httptest.NewRequest("GET", url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+}
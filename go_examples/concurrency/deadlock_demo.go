@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The deadlock demo counts as deadlocked once its goroutines are alive but
+// neither has taken its lock pair for this long. Unforced iterations take
+// around 110ms.
+const deadlockStallAfter = time.Second
+
+// Unforced, the deadlock demo's second goroutine starts this much later than
+// the first, so each takes its lock pair while the other is sleeping
+const deadlockOffset = 50 * time.Millisecond
+
+// deadlockHierarchy is the order the deadlock demo's locks are declared to be
+// taken in. Its second goroutine takes them the other way round.
+var deadlockHierarchy = []string{"mutex1", "mutex2"}
+
+// deadlockDemo is a handle on the two goroutines started by potentialDeadlock
+type deadlockDemo struct {
+	force        bool
+	order        *LockOrder
+	acquisitions [2]int64 // Lock pairs taken by each goroutine
+	lastProgress int64    // UnixNano of the latest pair, or of the start
+	alive        int32
+	done         chan struct{}
+}
+
+// deadlockStatus is a snapshot of a deadlock demo
+type deadlockStatus struct {
+	Force           bool     `json:"force"`
+	Goroutines      int32    `json:"goroutines"`   // Still running
+	Acquisitions    [2]int64 `json:"acquisitions"` // Lock pairs taken by each goroutine
+	SinceProgressMs int64    `json:"sinceProgressMs"`
+	Deadlocked      bool     `json:"deadlocked"`
+	Detect          bool     `json:"detect"`
+	Violations      []string `json:"violations,omitempty"`
+}
+
+// Function that might deadlock (for demonstration). Two goroutines take the
+// same pair of locks in opposite orders until ctx is cancelled. Unforced, the
+// second goroutine starts deadlockOffset after the first and each sleeps
+// between pairs, so they usually miss each other and keep making progress.
+// With force, the staggering sleeps are removed and both goroutines take
+// their first lock before either takes its second, so the deadlock always
+// happens. With an order, the mutexes are checked against deadlockHierarchy
+// in it, so the second goroutine's first pair is reported as a violation
+// whether or not it goes on to deadlock; with nil they are not tracked.
+//
+// Every combination is safe to run in tests: cancelling ctx stops both
+// goroutines even when they are deadlocked.
+func potentialDeadlock(ctx context.Context, force bool, order *LockOrder) *deadlockDemo {
+	if order != nil {
+		order.Declare(deadlockHierarchy...)
+	}
+	mutex1 := NewOrderedMutex(deadlockHierarchy[0], order)
+	mutex2 := NewOrderedMutex(deadlockHierarchy[1], order)
+
+	d := &deadlockDemo{
+		force:        force,
+		order:        order,
+		lastProgress: time.Now().UnixNano(),
+		alive:        2,
+		done:         make(chan struct{}),
+	}
+
+	// Sleeps that make the two goroutines usually miss each other. They
+	// only sleep between lock pairs, never while holding a lock.
+	stagger := func(duration time.Duration) {
+		if !force {
+			time.Sleep(duration)
+		}
+	}
+
+	// When forced, each goroutine waits here holding its first lock
+	var firstLocks sync.WaitGroup
+	firstLocks.Add(2)
+	holdFirst := func() {
+		if force {
+			firstLocks.Done()
+			firstLocks.Wait()
+		}
+	}
+
+	run := func(id int, first, second *OrderedMutex, offset time.Duration) {
+		defer func() {
+			if atomic.AddInt32(&d.alive, -1) == 0 {
+				close(d.done)
+			}
+		}()
+
+		stagger(offset)
+		for iteration := 0; ctx.Err() == nil; iteration++ {
+			first.Lock()
+			if iteration == 0 {
+				holdFirst()
+			}
+
+			// The second lock is where a deadlock happens, so it is the one
+			// that has to give up on cancellation
+			if !second.LockContext(ctx) {
+				first.Unlock()
+				return
+			}
+
+			// Critical section
+			time.Sleep(time.Millisecond * 10)
+			atomic.AddInt64(&d.acquisitions[id], 1)
+			atomic.StoreInt64(&d.lastProgress, time.Now().UnixNano())
+
+			second.Unlock()
+			first.Unlock()
+
+			stagger(time.Millisecond * 100)
+		}
+	}
+
+	// Create two goroutines that acquire locks in opposite order
+	go run(0, mutex1, mutex2, 0)
+	go run(1, mutex2, mutex1, deadlockOffset)
+
+	return d
+}
+
+// Done is closed once both goroutines have exited
+func (d *deadlockDemo) Done() <-chan struct{} {
+	return d.done
+}
+
+// Status reports the goroutines' progress. Goroutines that are alive but have
+// stopped taking their locks are deadlocked.
+func (d *deadlockDemo) Status() deadlockStatus {
+	status := deadlockStatus{
+		Force:      d.force,
+		Detect:     d.order != nil,
+		Goroutines: atomic.LoadInt32(&d.alive),
+		Acquisitions: [2]int64{
+			atomic.LoadInt64(&d.acquisitions[0]),
+			atomic.LoadInt64(&d.acquisitions[1]),
+		},
+	}
+	sinceProgress := time.Since(time.Unix(0, atomic.LoadInt64(&d.lastProgress)))
+	status.SinceProgressMs = sinceProgress.Milliseconds()
+	status.Deadlocked = status.Goroutines > 0 && sinceProgress > deadlockStallAfter
+	if d.order != nil {
+		status.Violations = d.order.Violations()
+	}
+	return status
+}
+
+// Violations returns the lock order violations the demo has run into, with
+// the stacks of the goroutines on both sides, or none without detection
+func (d *deadlockDemo) Violations() []LockOrderViolation {
+	if d.order == nil {
+		return nil
+	}
+	return d.order.Details()
+}
+
+// The deadlock demo started over HTTP, at most one at a time
+var (
+	deadlockDemoMutex  sync.Mutex
+	activeDeadlock     *deadlockDemo
+	stopActiveDeadlock context.CancelFunc
+)
+
+var (
+	errDeadlockDemoRunning    = errors.New("deadlock demo already running")
+	errDeadlockDemoNotRunning = errors.New("deadlock demo not running")
+)
+
+// startDeadlockDemo starts the deadlock demo unless one is already running,
+// checking its lock order when detect is set
+func startDeadlockDemo(force, detect bool) error {
+	deadlockDemoMutex.Lock()
+	defer deadlockDemoMutex.Unlock()
+
+	if activeDeadlock != nil {
+		return errDeadlockDemoRunning
+	}
+	var order *LockOrder
+	if detect {
+		order = NewLockOrder()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	activeDeadlock = potentialDeadlock(ctx, force, order)
+	stopActiveDeadlock = cancel
+	return nil
+}
+
+// stopDeadlockDemo cancels the running deadlock demo, waits for its
+// goroutines to exit and returns its final status
+func stopDeadlockDemo() (deadlockStatus, error) {
+	deadlockDemoMutex.Lock()
+	defer deadlockDemoMutex.Unlock()
+
+	if activeDeadlock == nil {
+		return deadlockStatus{}, errDeadlockDemoNotRunning
+	}
+	stopActiveDeadlock()
+	<-activeDeadlock.Done()
+	status := activeDeadlock.Status()
+	activeDeadlock, stopActiveDeadlock = nil, nil
+	return status, nil
+}
+
+// deadlockDemoStatus returns the status of the running deadlock demo
+func deadlockDemoStatus() (deadlockStatus, error) {
+	deadlockDemoMutex.Lock()
+	defer deadlockDemoMutex.Unlock()
+
+	if activeDeadlock == nil {
+		return deadlockStatus{}, errDeadlockDemoNotRunning
+	}
+	return activeDeadlock.Status(), nil
+}
+
+// deadlockDemoViolations returns the running deadlock demo's lock order
+// violations, or none when it isn't running
+func deadlockDemoViolations() []LockOrderViolation {
+	deadlockDemoMutex.Lock()
+	defer deadlockDemoMutex.Unlock()
+
+	if activeDeadlock == nil {
+		return nil
+	}
+	return activeDeadlock.Violations()
+}
+
+// HTTP handler that starts the deadlock demo. force=true always deadlocks
+// the two goroutines until /deadlock-demo/stop. detect=true runs them with
+// OrderedMutexes, so the lock order violation is logged and shows up in the
+// status and at /deadlock-report whether or not they deadlock.
+func deadlockDemoHandler(w http.ResponseWriter, r *http.Request) {
+	force, err := queryBool(r, "force")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	detect, err := queryBool(r, "detect")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := startDeadlockDemo(force, detect); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	fmt.Fprintf(w, "Started potential deadlock demo (force=%t, detect=%t)\n", force, detect)
+}
+
+// HTTP handler that reports the running deadlock demo's progress
+func deadlockDemoStatusHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := deadlockDemoStatus()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, status)
+}
+
+// HTTP handler that stops the deadlock demo and reports its final progress
+func deadlockDemoStopHandler(w http.ResponseWriter, r *http.Request) {
+	status, err := stopDeadlockDemo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, status)
+}
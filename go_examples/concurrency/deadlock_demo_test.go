@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPotentialDeadlockStops(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	for _, force := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		demo := potentialDeadlock(ctx, force, nil)
+
+		select {
+		case <-demo.Done():
+		case <-time.After(2 * time.Second):
+			t.Fatalf("force=%t: goroutines still running 2s after the context timed out", force)
+		}
+		cancel()
+
+		status := demo.Status()
+		if status.Goroutines != 0 || status.Deadlocked {
+			t.Errorf("force=%t: expected both goroutines gone, got %+v", force, status)
+		}
+		if force && (status.Acquisitions[0] != 0 || status.Acquisitions[1] != 0) {
+			t.Errorf("Expected no lock pairs taken when forced, got %v", status.Acquisitions)
+		}
+	}
+
+	// Abandoned lock attempts clean up after themselves too
+	if n := waitForGoroutines(2*time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+		t.Errorf("Expected goroutines back to %d, got %d", baseline, n)
+	}
+}
+
+func TestDeadlockDemoStatusDetectsStall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	demo := potentialDeadlock(ctx, true, nil)
+	defer func() {
+		cancel()
+		<-demo.Done()
+	}()
+
+	if status := demo.Status(); status.Deadlocked {
+		t.Errorf("Expected no deadlock reported right after starting, got %+v", status)
+	}
+
+	deadline := time.Now().Add(deadlockStallAfter + 2*time.Second)
+	for !demo.Status().Deadlocked {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the forced demo to be reported deadlocked, got %+v", demo.Status())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if status := demo.Status(); status.Goroutines != 2 || status.SinceProgressMs < deadlockStallAfter.Milliseconds() {
+		t.Errorf("Expected two stalled goroutines, got %+v", status)
+	}
+}
+
+func TestDeadlockDemoEndpoints(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/deadlock-demo", deadlockDemoHandler)
+	mux.HandleFunc("/deadlock-demo/status", deadlockDemoStatusHandler)
+	mux.HandleFunc("/deadlock-demo/stop", deadlockDemoStopHandler)
+
+	serve := func(url string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("GET", url, nil))
+		return recorder
+	}
+
+	if code := serve("/deadlock-demo/status").Code; code != http.StatusConflict {
+		t.Errorf("Expected 409 for status with no demo running, got %d", code)
+	}
+	if code := serve("/deadlock-demo/stop").Code; code != http.StatusConflict {
+		t.Errorf("Expected 409 for stop with no demo running, got %d", code)
+	}
+
+	if code := serve("/deadlock-demo?force=true&detect=true").Code; code != http.StatusOK {
+		t.Fatalf("Expected 200 starting the demo, got %d", code)
+	}
+	if code := serve("/deadlock-demo").Code; code != http.StatusConflict {
+		t.Errorf("Expected 409 starting a second demo, got %d", code)
+	}
+
+	// Both goroutines reach their second lock, reporting the inversion
+	var status deadlockStatus
+	deadline := time.Now().Add(2 * time.Second)
+	for len(status.Violations) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected a lock order violation in the status, got %+v", status)
+		}
+		recorder := serve("/deadlock-demo/status")
+		if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+			t.Fatalf("Failed to decode status: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !status.Force || !status.Detect || status.Goroutines != 2 {
+		t.Errorf("Unexpected status for a running forced demo: %+v", status)
+	}
+
+	// Stopping works even though the goroutines are deadlocked
+	recorder := serve("/deadlock-demo/stop")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200 stopping the demo, got %d", recorder.Code)
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode final status: %v", err)
+	}
+	if status.Goroutines != 0 || len(status.Violations) == 0 {
+		t.Errorf("Expected no goroutines left and a recorded violation, got %+v", status)
+	}
+}
+
+func TestDeadlockDemoViolationStacks(t *testing.T) {
+	// Forced, the first goroutine is sure to hold mutex1 when the second
+	// takes it out of order
+	order := NewLockOrder()
+	ctx, cancel := context.WithCancel(context.Background())
+	demo := potentialDeadlock(ctx, true, order)
+	defer func() {
+		cancel()
+		<-demo.Done()
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(demo.Violations()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a lock order violation from the forced deadlock demo")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	v := demo.Violations()[0]
+	if !strings.Contains(v.Message, "mutex1 acquired while holding mutex2") {
+		t.Errorf("Unexpected violation message: %s", v.Message)
+	}
+	if v.OtherGoroutine == 0 || v.OtherGoroutine == v.Goroutine {
+		t.Errorf("Expected the other goroutine to be a different one, got %d and %d", v.Goroutine, v.OtherGoroutine)
+	}
+	for _, stack := range []string{v.Stack, v.OtherStack} {
+		if !strings.Contains(stack, "potentialDeadlock") {
+			t.Errorf("Expected both stacks to be in the demo, got:\n%s", stack)
+		}
+	}
+}
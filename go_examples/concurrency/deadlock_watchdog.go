@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often the watchdog started by main samples goroutine stacks
+const deadlockCheckInterval = time.Second
+
+// Goroutine states runtime.Stack reports for a goroutine waiting on a lock.
+// Older runtimes report every semaphore wait as "semacquire".
+var lockWaitReasons = map[string]bool{
+	"sync.Mutex.Lock":    true,
+	"sync.RWMutex.Lock":  true,
+	"sync.RWMutex.RLock": true,
+	"semacquire":         true,
+}
+
+// Call arguments and pc offsets differ between goroutines stuck at the same
+// place, so they are stripped before stacks are grouped
+var (
+	stackArgs   = regexp.MustCompile(`\([^()]*\)$`)
+	stackOffset = regexp.MustCompile(` \+0x[0-9a-f]+$`)
+)
+
+// stuckGroup is a set of goroutines blocked on a lock at the same stack
+type stuckGroup struct {
+	Count      int      `json:"count"`
+	Reason     string   `json:"reason"`
+	BlockedMs  int64    `json:"blockedMs"` // Longest wait in the group
+	Goroutines []uint64 `json:"goroutines"`
+	Stack      string   `json:"stack"`
+}
+
+// deadlockReport lists goroutines blocked on locks for longer than the
+// watchdog threshold, and lock order violations, as of the last check
+type deadlockReport struct {
+	CheckedAt   time.Time            `json:"checkedAt"`
+	ThresholdMs int64                `json:"thresholdMs"`
+	Count       int                  `json:"count"`
+	Groups      []stuckGroup         `json:"groups"`
+	Violations  []LockOrderViolation `json:"violations,omitempty"`
+}
+
+// lockWait is a goroutine seen waiting on a lock
+type lockWait struct {
+	reason string
+	stack  string
+	since  time.Time
+}
+
+// deadlockWatchdog samples every goroutine's stack and flags those that stay
+// blocked on a lock for longer than threshold. Stack headers only give wait
+// times in whole minutes, so waits are timed from the first sample that saw them.
+// Lock order violations come from violations, when set, and show up in the
+// report whether or not the goroutines involved ever block.
+type deadlockWatchdog struct {
+	mutex      sync.Mutex
+	threshold  time.Duration
+	waiting    map[uint64]lockWait
+	report     deadlockReport
+	violations func() []LockOrderViolation
+}
+
+// newDeadlockWatchdog creates a watchdog flagging lock waits longer than threshold
+func newDeadlockWatchdog(threshold time.Duration) *deadlockWatchdog {
+	return &deadlockWatchdog{
+		threshold: threshold,
+		waiting:   make(map[uint64]lockWait),
+		report:    deadlockReport{ThresholdMs: threshold.Milliseconds(), Groups: []stuckGroup{}},
+	}
+}
+
+// start checks the goroutines every interval until the returned stop is called
+func (d *deadlockWatchdog) start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				d.check(now)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// check samples the goroutines and rebuilds the report as of now. Goroutines
+// no longer waiting are forgotten, so the report clears once they unblock.
+func (d *deadlockWatchdog) check(now time.Time) {
+	waits := lockWaits(allStacks())
+	var violations []LockOrderViolation
+	if d.violations != nil {
+		violations = d.violations()
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	groups := make(map[string]*stuckGroup)
+	for id, wait := range waits {
+		// Keep the first sighting unless the goroutine now waits somewhere else
+		if seen, ok := d.waiting[id]; ok && seen.stack == wait.stack {
+			wait.since = seen.since
+		} else {
+			wait.since = now
+		}
+		waits[id] = wait
+
+		blocked := now.Sub(wait.since)
+		if blocked < d.threshold {
+			continue
+		}
+		group, ok := groups[wait.stack]
+		if !ok {
+			group = &stuckGroup{Reason: wait.reason, Stack: wait.stack}
+			groups[wait.stack] = group
+		}
+		group.Count++
+		group.Goroutines = append(group.Goroutines, id)
+		if ms := blocked.Milliseconds(); ms > group.BlockedMs {
+			group.BlockedMs = ms
+		}
+	}
+	d.waiting = waits
+
+	report := deadlockReport{CheckedAt: now, ThresholdMs: d.threshold.Milliseconds(), Groups: []stuckGroup{}, Violations: violations}
+	for _, group := range groups {
+		sort.Slice(group.Goroutines, func(i, j int) bool { return group.Goroutines[i] < group.Goroutines[j] })
+		report.Count += group.Count
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Count != report.Groups[j].Count {
+			return report.Groups[i].Count > report.Groups[j].Count
+		}
+		return report.Groups[i].Stack < report.Groups[j].Stack
+	})
+	d.report = report
+}
+
+// Report returns the findings of the last check
+func (d *deadlockWatchdog) Report() deadlockReport {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.report
+}
+
+// HTTP handler that serves the watchdog's last report as JSON
+func (d *deadlockWatchdog) reportHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, d.Report())
+}
+
+// allStacks returns the stacks of every goroutine, growing the buffer until
+// they fit
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// lockWaits parses runtime.Stack output into the goroutines waiting on a lock,
+// keyed by goroutine ID, with normalized stacks
+func lockWaits(stacks []byte) map[uint64]lockWait {
+	waits := make(map[uint64]lockWait)
+	for _, block := range bytes.Split(stacks, []byte("\n\n")) {
+		lines := strings.Split(strings.TrimSpace(string(block)), "\n")
+
+		// Header: "goroutine 18 [sync.Mutex.Lock, 2 minutes]:"
+		header := lines[0]
+		open, end := strings.IndexByte(header, '['), strings.LastIndexByte(header, ']')
+		if !strings.HasPrefix(header, "goroutine ") || open < 0 || end < open {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(header[len("goroutine "):open]), 10, 64)
+		if err != nil {
+			continue
+		}
+		reason := header[open+1 : end]
+		if i := strings.IndexByte(reason, ','); i >= 0 {
+			reason = reason[:i]
+		}
+		if !lockWaitReasons[reason] {
+			continue
+		}
+
+		frames := make([]string, 0, len(lines)-1)
+		for _, line := range lines[1:] {
+			line = stackOffset.ReplaceAllString(line, "")
+			line = stackArgs.ReplaceAllString(line, "()")
+			frames = append(frames, line)
+		}
+		waits[id] = lockWait{reason: reason, stack: strings.Join(frames, "\n")}
+	}
+	return waits
+}
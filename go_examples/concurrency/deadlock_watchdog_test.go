@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// deadlockPair locks a then b in one goroutine and b then a in another, so
+// both end up waiting forever. The caller breaks the cycle by unlocking a on
+// behalf of the first goroutine.
+func deadlockPair(a, b *sync.Mutex) (done chan struct{}) {
+	done = make(chan struct{})
+	var bothHeld sync.WaitGroup
+	bothHeld.Add(2)
+
+	var finished sync.WaitGroup
+	finished.Add(2)
+	go func() {
+		defer finished.Done()
+		a.Lock()
+		bothHeld.Done()
+		bothHeld.Wait()
+		b.Lock()
+		b.Unlock()
+	}()
+	go func() {
+		defer finished.Done()
+		b.Lock()
+		bothHeld.Done()
+		bothHeld.Wait()
+		a.Lock()
+		a.Unlock()
+		b.Unlock()
+	}()
+	go func() {
+		finished.Wait()
+		close(done)
+	}()
+	return done
+}
+
+func TestDeadlockWatchdog(t *testing.T) {
+	var a, b sync.Mutex
+	done := deadlockPair(&a, &b)
+
+	// Wait for both goroutines to park on their second lock
+	watchdog := newDeadlockWatchdog(time.Minute)
+	start := time.Now()
+	deadline := start.Add(5 * time.Second)
+	for {
+		if len(stuckInTest(lockWaits(allStacks()))) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Deadlocked goroutines never showed up as lock waits")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Within the threshold nothing is reported
+	watchdog.check(start)
+	if report := watchdog.Report(); report.Count != 0 {
+		t.Fatalf("Expected no findings before the threshold, got %+v", report)
+	}
+
+	// Past the threshold both goroutines are reported with their stacks
+	watchdog.check(start.Add(90 * time.Second))
+	report := watchdog.Report()
+	var found int
+	for _, group := range report.Groups {
+		if strings.Contains(group.Stack, "deadlockPair") {
+			found += group.Count
+			if group.BlockedMs != 90000 || group.Reason == "" {
+				t.Errorf("Expected a 90s lock wait with a reason, got %+v", group)
+			}
+			if strings.Contains(group.Stack, "+0x") {
+				t.Errorf("Expected pc offsets stripped from stack:\n%s", group.Stack)
+			}
+		}
+	}
+	if found != 2 {
+		t.Fatalf("Expected 2 deadlocked goroutines reported, got %d in %+v", found, report)
+	}
+
+	// The report is served as JSON
+	recorder := httptest.NewRecorder()
+	watchdog.reportHandler(recorder, httptest.NewRequest("GET", "/deadlock-report", nil))
+	var served deadlockReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &served); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if served.Count != report.Count || served.ThresholdMs != 60000 {
+		t.Errorf("Expected served report to match, got %+v", served)
+	}
+
+	// Break the cycle; once the goroutines exit the report clears
+	a.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Goroutines still deadlocked after unlocking")
+	}
+	watchdog.check(start.Add(100 * time.Second))
+	for _, group := range watchdog.Report().Groups {
+		if strings.Contains(group.Stack, "deadlockPair") {
+			t.Errorf("Expected the report to clear, still has %+v", group)
+		}
+	}
+}
+
+// stuckInTest filters lock waits down to the goroutines started by deadlockPair
+func stuckInTest(waits map[uint64]lockWait) map[uint64]lockWait {
+	stuck := make(map[uint64]lockWait)
+	for id, wait := range waits {
+		if strings.Contains(wait.stack, "deadlockPair") {
+			stuck[id] = wait
+		}
+	}
+	return stuck
+}
+
+func TestLockWaits(t *testing.T) {
+	stacks := []byte(`goroutine 1 [running]:
+main.main()
+	/app/main.go:20 +0x17b
+
+goroutine 7 [sync.Mutex.Lock, 3 minutes]:
+sync.(*Mutex).Lock(0xc000010000)
+	/usr/local/go/src/sync/mutex.go:70 +0x25
+main.worker(0x2)
+	/app/main.go:40 +0x31
+
+goroutine 8 [chan receive]:
+main.consumer()
+	/app/main.go:50 +0x10
+
+goroutine 9 [sync.RWMutex.RLock]:
+sync.(*RWMutex).RLock(...)
+	/usr/local/go/src/sync/rwmutex.go:74
+main.worker(0x3)
+	/app/main.go:40 +0x99
+`)
+
+	waits := lockWaits(stacks)
+	if len(waits) != 2 {
+		t.Fatalf("Expected 2 lock waits, got %+v", waits)
+	}
+	if waits[7].reason != "sync.Mutex.Lock" || waits[9].reason != "sync.RWMutex.RLock" {
+		t.Errorf("Unexpected wait reasons: %+v", waits)
+	}
+
+	expected := "sync.(*Mutex).Lock()\n\t/usr/local/go/src/sync/mutex.go:70\nmain.worker()\n\t/app/main.go:40"
+	if waits[7].stack != expected {
+		t.Errorf("Expected normalized stack:\n%s\ngot:\n%s", expected, waits[7].stack)
+	}
+}
+
+func TestDeadlockWatchdogViolations(t *testing.T) {
+	watchdog := newDeadlockWatchdog(time.Minute)
+	watchdog.violations = deadlockDemoViolations
+
+	// The unforced demo rarely hangs, but still takes its locks out of order
+	if err := startDeadlockDemo(false, true); err != nil {
+		t.Fatalf("Failed to start deadlock demo: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		watchdog.check(time.Now())
+		if len(watchdog.Report().Violations) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			stopDeadlockDemo()
+			t.Fatal("Expected the deadlock demo's lock order violation in the report")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := watchdog.Report().Violations[0]; !strings.Contains(v.Message, "mutex1 acquired while holding mutex2") || v.Stack == "" {
+		t.Errorf("Unexpected violation: %+v", v)
+	}
+
+	// Violations go with the demo
+	if _, err := stopDeadlockDemo(); err != nil {
+		t.Fatalf("Failed to stop deadlock demo: %v", err)
+	}
+	watchdog.check(time.Now())
+	if v := watchdog.Report().Violations; len(v) != 0 {
+		t.Errorf("Expected no violations once the demo stopped, got %+v", v)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// How long each stage of an errgroup demo worker takes
+const errGroupStage = 10 * time.Millisecond
+
+// workerFailure is the error returned by the errgroup demo's failing worker
+type workerFailure struct {
+	Worker    int
+	Iteration int
+}
+
+func (f *workerFailure) Error() string {
+	return fmt.Sprintf("worker %d failed at iteration %d", f.Worker, f.Iteration)
+}
+
+// errGroupStats summarizes an errgroup demo run
+type errGroupStats struct {
+	Workers      int     `json:"workers"`
+	Iterations   int     `json:"iterations"`
+	FailAt       int     `json:"failAt"`
+	FailedWorker int     `json:"failedWorker"` // -1 if every worker finished
+	Error        string  `json:"error,omitempty"`
+	Completed    []int   `json:"completed"` // Iterations completed by each worker
+	DurationMs   float64 `json:"durationMs"`
+}
+
+// runErrGroupDemo runs workers in an errgroup. Worker failAt, if any, fails
+// halfway through its iterations; the group then cancels its context and the
+// other workers stop at their next stage.
+func runErrGroupDemo(numWorkers, iterations, failAt int) errGroupStats {
+	return errGroupDemo(numWorkers, iterations, failAt, errGroupStage)
+}
+
+// errGroupDemo runs the errgroup demo with stages of the given length
+func errGroupDemo(numWorkers, iterations, failAt int, stage time.Duration) errGroupStats {
+	slog.Info("Starting errgroup demo", "workers", numWorkers, "iterations", iterations, "failAt", failAt)
+
+	stats := errGroupStats{
+		Workers:      numWorkers,
+		Iterations:   iterations,
+		FailAt:       failAt,
+		FailedWorker: -1,
+		Completed:    make([]int, numWorkers),
+	}
+	start := time.Now()
+
+	g, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i < numWorkers; i++ {
+		id := i
+		g.Go(func() error {
+			for j := 0; j < iterations; j++ {
+				if id == failAt && j == iterations/2 {
+					return &workerFailure{Worker: id, Iteration: j}
+				}
+
+				// Each stage blocks until it is done or the group is cancelled
+				timer := time.NewTimer(stage)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+				// Each worker only writes its own slot
+				stats.Completed[id]++
+			}
+			return nil
+		})
+	}
+
+	// Wait returns the first error once every worker has returned
+	err := g.Wait()
+	stats.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	if err != nil {
+		stats.Error = err.Error()
+		var failure *workerFailure
+		if errors.As(err, &failure) {
+			stats.FailedWorker = failure.Worker
+		}
+	}
+
+	slog.Info("errgroup demo completed", "durationMs", stats.DurationMs, "err", err)
+	return stats
+}
+
+// HTTP handler that runs the errgroup demo and returns its stats as JSON
+func errGroupDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, iterations, err := workerParams(r, 5)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	failAt, err := queryInt(r, "failAt", -1, -1, numWorkers-1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runErrGroupDemo(numWorkers, iterations, failAt))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestErrGroupDemoNoFailure(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	stats := errGroupDemo(4, 5, -1, time.Millisecond)
+
+	if stats.FailedWorker != -1 || stats.Error != "" {
+		t.Errorf("Expected no failure, got worker %d: %q", stats.FailedWorker, stats.Error)
+	}
+	for id, completed := range stats.Completed {
+		if completed != 5 {
+			t.Errorf("Expected worker %d to complete 5 iterations, got %d", id, completed)
+		}
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+		t.Errorf("Expected all workers to exit, %d goroutines remain of %d before", n, baseline)
+	}
+}
+
+func TestErrGroupDemoEarlyFailure(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	// Without the failure the run would take 100 stages of 5ms
+	stats := errGroupDemo(4, 100, 2, 5*time.Millisecond)
+
+	if stats.FailedWorker != 2 || stats.Error != "worker 2 failed at iteration 50" {
+		t.Fatalf("Expected worker 2 to fail, got worker %d: %q", stats.FailedWorker, stats.Error)
+	}
+	if stats.Completed[2] != 50 {
+		t.Errorf("Expected the failing worker to complete 50 iterations, got %d", stats.Completed[2])
+	}
+	// The others stop at their next stage once the group is cancelled
+	for id, completed := range stats.Completed {
+		if id != 2 && (completed < 40 || completed > 60) {
+			t.Errorf("Expected worker %d to stop around iteration 50, got %d", id, completed)
+		}
+	}
+	if stats.DurationMs >= 500 {
+		t.Errorf("Expected the run to end early, took %.1fms", stats.DurationMs)
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+		t.Errorf("Expected all workers to exit, %d goroutines remain of %d before", n, baseline)
+	}
+}
+
+func TestErrGroupDemoHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	errGroupDemoHandler(recorder, httptest.NewRequest("GET", "/errgroup-demo?workers=3&iterations=4&failAt=0", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var stats errGroupStats
+	if err := json.Unmarshal(recorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode errgroup demo stats: %v", err)
+	}
+	if stats.FailedWorker != 0 || len(stats.Completed) != 3 || stats.Completed[0] != 2 {
+		t.Errorf("Unexpected errgroup demo stats: %+v", stats)
+	}
+
+	// failAt must name one of the workers
+	for _, url := range []string{"/errgroup-demo?workers=3&failAt=3", "/errgroup-demo?failAt=-2"} {
+		recorder := httptest.NewRecorder()
+		errGroupDemoHandler(recorder, httptest.NewRequest("GET", url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+}
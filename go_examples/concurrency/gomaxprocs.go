@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime"
+)
+
+// gomaxprocsStatus is the GET response of /runtime/gomaxprocs
+type gomaxprocsStatus struct {
+	GOMAXPROCS int `json:"gomaxprocs"`
+	NumCPU     int `json:"numCPU"`
+	Max        int `json:"max"` // Largest value POST accepts
+}
+
+// gomaxprocsHandler serves GOMAXPROCS: GET reads it along with the CPU count
+// and POST ?n=N changes it, rejecting values outside [1, limit]. Contention
+// profiles look very different with fewer Ps, so this allows experimenting
+// without a restart.
+func gomaxprocsHandler(limit int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, gomaxprocsStatus{
+				GOMAXPROCS: runtime.GOMAXPROCS(0),
+				NumCPU:     runtime.NumCPU(),
+				Max:        limit,
+			})
+
+		case http.MethodPost:
+			if r.URL.Query().Get("n") == "" {
+				http.Error(w, "missing n parameter", http.StatusBadRequest)
+				return
+			}
+			n, err := queryInt(r, "n", 0, 1, limit)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			old := runtime.GOMAXPROCS(n)
+			slog.Info("GOMAXPROCS changed", "old", old, "new", n)
+			writeJSON(w, rateChange{Old: old, New: n})
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestGOMAXPROCSEndpoint(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	handler := gomaxprocsHandler(8)
+
+	req := httptest.NewRequest("POST", "/runtime/gomaxprocs?n=3", nil)
+	recorder := httptest.NewRecorder()
+	previous := runtime.GOMAXPROCS(0)
+	handler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var change rateChange
+	if err := json.Unmarshal(recorder.Body.Bytes(), &change); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if change.Old != previous || change.New != 3 || runtime.GOMAXPROCS(0) != 3 {
+		t.Errorf("Expected GOMAXPROCS to change from %d to 3, got %+v (now %d)", previous, change, runtime.GOMAXPROCS(0))
+	}
+
+	req = httptest.NewRequest("GET", "/runtime/gomaxprocs", nil)
+	recorder = httptest.NewRecorder()
+	handler(recorder, req)
+
+	var status gomaxprocsStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if status.GOMAXPROCS != 3 || status.NumCPU != runtime.NumCPU() || status.Max != 8 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestGOMAXPROCSEndpointErrors(t *testing.T) {
+	before := runtime.GOMAXPROCS(0)
+	handler := gomaxprocsHandler(8)
+
+	testCases := []struct {
+		method         string
+		url            string
+		expectedStatus int
+	}{
+		{"POST", "/runtime/gomaxprocs", http.StatusBadRequest},
+		{"POST", "/runtime/gomaxprocs?n=0", http.StatusBadRequest},
+		{"POST", "/runtime/gomaxprocs?n=-2", http.StatusBadRequest},
+		{"POST", "/runtime/gomaxprocs?n=9", http.StatusBadRequest},
+		{"POST", "/runtime/gomaxprocs?n=many", http.StatusBadRequest},
+		{"DELETE", "/runtime/gomaxprocs", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.url, nil)
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.url, tc.expectedStatus, recorder.Code)
+		}
+	}
+	if now := runtime.GOMAXPROCS(0); now != before {
+		t.Errorf("Expected GOMAXPROCS to stay %d after rejected requests, got %d", before, now)
+	}
+}
+
+func TestRunRecordsGOMAXPROCS(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+
+	reg := newRunRegistry(maxRuns)
+	id := reg.launch("mutex", nil, func(ctx context.Context) map[string]int64 { return nil })
+
+	if run, _ := reg.get(id); run.GOMAXPROCS != 2 {
+		t.Errorf("Expected the run to record GOMAXPROCS 2, got %d", run.GOMAXPROCS)
+	}
+}
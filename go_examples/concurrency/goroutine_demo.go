@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Upper bound on how long goroutine demo goroutines stay parked
+const maxGoroutineDemoSeconds = 600
+
+// leakedGoroutine is a goroutine parked by the leak demo
+type leakedGoroutine struct {
+	release chan struct{} // Never closed until the leak is stopped
+	done    chan struct{} // Closed when the goroutine exits
+}
+
+// Goroutines deliberately leaked by /goroutine-leak
+var (
+	leakMutex sync.Mutex
+	leaked    []leakedGoroutine
+)
+
+// runGoroutineDemo parks count goroutines on a shared unbuffered channel for
+// the given duration, then closes it so every goroutine exits. It returns once
+// all of them have finished.
+func runGoroutineDemo(count int, duration time.Duration) {
+	release := make(chan struct{})
+	var demoWg sync.WaitGroup
+
+	for i := 0; i < count; i++ {
+		demoWg.Add(1)
+		go func() {
+			defer demoWg.Done()
+			// Nothing is ever sent, so each goroutine blocks until release is closed
+			<-release
+		}()
+	}
+
+	time.Sleep(duration)
+	close(release)
+	demoWg.Wait()
+	slog.Info("Goroutine demo completed", "goroutines", count)
+}
+
+// HTTP handler that starts the goroutine demo
+func goroutineDemoHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := queryInt(r, "count", 100, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seconds, err := queryInt(r, "seconds", 30, 1, maxGoroutineDemoSeconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	go runGoroutineDemo(count, time.Duration(seconds)*time.Second)
+
+	fmt.Fprintf(w, "Started goroutine demo with %d goroutines for %d seconds\n", count, seconds)
+}
+
+// startGoroutineLeak parks count more goroutines that never exit on their own
+// and returns how many are now outstanding
+func startGoroutineLeak(count int) int {
+	leakMutex.Lock()
+	defer leakMutex.Unlock()
+
+	for i := 0; i < count; i++ {
+		g := leakedGoroutine{
+			release: make(chan struct{}),
+			done:    make(chan struct{}),
+		}
+		go func() {
+			defer close(g.done)
+			<-g.release
+		}()
+		leaked = append(leaked, g)
+	}
+	return len(leaked)
+}
+
+// stopGoroutineLeak releases every leaked goroutine, waits for them to exit
+// and returns how many were stopped
+func stopGoroutineLeak() int {
+	leakMutex.Lock()
+	stopping := leaked
+	leaked = nil
+	leakMutex.Unlock()
+
+	for _, g := range stopping {
+		close(g.release)
+	}
+	for _, g := range stopping {
+		<-g.done
+	}
+	return len(stopping)
+}
+
+// leakedGoroutineCount returns the number of leaked goroutines still outstanding
+func leakedGoroutineCount() int {
+	leakMutex.Lock()
+	defer leakMutex.Unlock()
+	return len(leaked)
+}
+
+// HTTP handler that leaks count goroutines per call
+func goroutineLeakHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := queryInt(r, "count", 10, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outstanding := startGoroutineLeak(count)
+
+	fmt.Fprintf(w, "Leaked %d goroutines (%d outstanding)\n", count, outstanding)
+}
+
+// HTTP handler that stops all leaked goroutines
+func goroutineLeakStopHandler(w http.ResponseWriter, r *http.Request) {
+	stopped := stopGoroutineLeak()
+
+	fmt.Fprintf(w, "Stopped %d leaked goroutines\n", stopped)
+}
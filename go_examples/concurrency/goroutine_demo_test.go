@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForGoroutines polls until cond holds for the goroutine count or the timeout expires
+func waitForGoroutines(timeout time.Duration, cond func(n int) bool) int {
+	deadline := time.Now().Add(timeout)
+	n := runtime.NumGoroutine()
+	for !cond(n) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	return n
+}
+
+func TestGoroutineDemo(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	count := 200
+
+	done := make(chan struct{})
+	go func() {
+		runGoroutineDemo(count, 200*time.Millisecond)
+		close(done)
+	}()
+
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n >= baseline+count }); n < baseline+count {
+		t.Errorf("Expected at least %d goroutines during the demo, got %d", baseline+count, n)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Goroutine demo did not finish")
+	}
+
+	// Allow a little slack for runtime and test goroutines
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline+5 }); n > baseline+5 {
+		t.Errorf("Expected goroutine count to return near %d, got %d", baseline, n)
+	}
+}
+
+func TestGoroutineDemoHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/goroutine-demo", goroutineDemoHandler)
+
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Custom params", "/goroutine-demo?count=3&seconds=1", http.StatusOK, "3 goroutines for 1 seconds"},
+		{"Invalid count", "/goroutine-demo?count=abc", http.StatusBadRequest, "invalid count parameter"},
+		{"Zero seconds", "/goroutine-demo?seconds=0", http.StatusBadRequest, "invalid seconds parameter"},
+		{"Seconds too large", "/goroutine-demo?seconds=100000", http.StatusBadRequest, "invalid seconds parameter"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			recorder := httptest.NewRecorder()
+
+			mux.ServeHTTP(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected body to contain %q, got: %s", tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestGoroutineLeak(t *testing.T) {
+	defer stopGoroutineLeak()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/goroutine-leak", goroutineLeakHandler)
+	mux.HandleFunc("/goroutine-leak/stop", goroutineLeakStopHandler)
+
+	baseline := runtime.NumGoroutine()
+
+	// Each call leaks more goroutines on top of the previous ones
+	for i, expected := range []string{"Leaked 50 goroutines (50 outstanding)", "Leaked 50 goroutines (100 outstanding)"} {
+		req := httptest.NewRequest("GET", "/goroutine-leak?count=50", nil)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Call %d: expected body to contain %q, got: %s", i+1, expected, recorder.Body.String())
+		}
+	}
+
+	if n := leakedGoroutineCount(); n != 100 {
+		t.Errorf("Expected 100 outstanding leaked goroutines, got %d", n)
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n >= baseline+100 }); n < baseline+100 {
+		t.Errorf("Expected at least %d goroutines after leaking, got %d", baseline+100, n)
+	}
+
+	req := httptest.NewRequest("POST", "/goroutine-leak/stop", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if !strings.Contains(recorder.Body.String(), "Stopped 100 leaked goroutines") {
+		t.Errorf("Unexpected stop response: %s", recorder.Body.String())
+	}
+	if n := leakedGoroutineCount(); n != 0 {
+		t.Errorf("Expected no outstanding leaked goroutines after stop, got %d", n)
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline+5 }); n > baseline+5 {
+		t.Errorf("Expected goroutine count to return near %d after stop, got %d", baseline, n)
+	}
+}
+
+func TestGoroutineLeakInvalidCount(t *testing.T) {
+	req := httptest.NewRequest("GET", "/goroutine-leak?count=0", nil)
+	recorder := httptest.NewRecorder()
+
+	goroutineLeakHandler(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+	if n := leakedGoroutineCount(); n != 0 {
+		t.Errorf("Expected no goroutines leaked for an invalid count, got %d", n)
+	}
+}
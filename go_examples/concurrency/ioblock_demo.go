@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Bounds for /io-block-demo's parameters
+const (
+	maxIOGoroutines = 256
+	maxIOChunkKB    = 4096
+	maxIOBytes      = 1 << 30 // Across every goroutine's file
+)
+
+// ioBlockStats summarizes an I/O blocking demo run
+type ioBlockStats struct {
+	Goroutines   int     `json:"goroutines"`
+	Chunks       int     `json:"chunks"`     // Per goroutine
+	ChunkBytes   int     `json:"chunkBytes"` // Written and fsynced at a time
+	BytesWritten int64   `json:"bytesWritten"`
+	Fsyncs       int64   `json:"fsyncs"`
+	FsyncNs      int64   `json:"fsyncNs"` // Summed over every goroutine
+	FsyncMaxNs   int64   `json:"fsyncMaxNs"`
+	DurationMs   float64 `json:"durationMs"`
+}
+
+// ioBlockWriter writes chunks of chunk to a new file in dir, fsyncing after
+// each, then removes the file. It stops early once ctx is cancelled.
+func ioBlockWriter(ctx context.Context, dir string, chunk []byte, chunks int, stats *ioBlockStats) error {
+	f, err := os.CreateTemp(dir, "writer-*.dat")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	for i := 0; i < chunks && ctx.Err() == nil; i++ {
+		n, err := f.Write(chunk)
+		atomic.AddInt64(&stats.BytesWritten, int64(n))
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		elapsed := int64(time.Since(start))
+		atomic.AddInt64(&stats.Fsyncs, 1)
+		atomic.AddInt64(&stats.FsyncNs, elapsed)
+		for {
+			prev := atomic.LoadInt64(&stats.FsyncMaxNs)
+			if elapsed <= prev || atomic.CompareAndSwapInt64(&stats.FsyncMaxNs, prev, elapsed) {
+				break
+			}
+		}
+	}
+	return f.Close()
+}
+
+// runIOBlockDemo has goroutines each write and fsync chunks of chunkSize
+// bytes to their own file in a new directory under dir, until done or ctx is
+// cancelled. The directory and its files are removed before it returns.
+func runIOBlockDemo(ctx context.Context, dir string, goroutines, chunks, chunkSize int) (ioBlockStats, error) {
+	runDir, err := os.MkdirTemp(dir, "io-block-demo-")
+	if err != nil {
+		return ioBlockStats{}, fmt.Errorf("I/O demo directory %q is not writable: %w", dir, err)
+	}
+	defer os.RemoveAll(runDir)
+
+	slog.InfoContext(ctx, "Starting I/O blocking demo", "goroutines", goroutines, "chunks", chunks,
+		"chunkBytes", chunkSize, "dir", runDir)
+
+	stats := ioBlockStats{Goroutines: goroutines, Chunks: chunks, ChunkBytes: chunkSize}
+	chunk := make([]byte, chunkSize)
+	for i := range chunk {
+		chunk[i] = byte(i)
+	}
+
+	var group errgroup.Group
+	start := time.Now()
+	withDemoLabel(ctx, "io-block", func(ctx context.Context) {
+		for i := 0; i < goroutines; i++ {
+			withWorkerLabel(ctx, i, func(ctx context.Context) {
+				group.Go(func() error {
+					return ioBlockWriter(ctx, runDir, chunk, chunks, &stats)
+				})
+			})
+		}
+	})
+	err = group.Wait()
+	stats.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	if err != nil {
+		return stats, fmt.Errorf("I/O demo failed: %w", err)
+	}
+
+	slog.InfoContext(ctx, "I/O blocking demo completed", "bytes", stats.BytesWritten,
+		"fsync", time.Duration(stats.FsyncNs))
+	return stats, nil
+}
+
+// ioBlockDemoHandler serves /io-block-demo, writing its files under dir
+func ioBlockDemoHandler(dir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goroutines, err := queryInt(r, "goroutines", 4, 1, maxIOGoroutines)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		chunks, err := queryInt(r, "chunks", 64, 1, maxDemoParam)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		chunkKB, err := queryInt(r, "chunkKB", 64, 1, maxIOChunkKB)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if total := int64(goroutines) * int64(chunks) * int64(chunkKB) * 1024; total > maxIOBytes {
+			http.Error(w, fmt.Sprintf("Demo would write %d bytes, more than the limit of %d", total, maxIOBytes),
+				http.StatusBadRequest)
+			return
+		}
+
+		stats, err := runIOBlockDemo(r.Context(), dir, goroutines, chunks, chunkKB*1024)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, stats)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIOBlockDemoRemovesFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	stats, err := runIOBlockDemo(context.Background(), dir, 3, 4, 1024)
+	if err != nil {
+		t.Fatalf("runIOBlockDemo failed: %v", err)
+	}
+	if stats.BytesWritten != 3*4*1024 || stats.Fsyncs != 3*4 {
+		t.Errorf("Expected 12 chunks of 1KB written and fsynced, got %+v", stats)
+	}
+	if stats.FsyncMaxNs <= 0 || stats.FsyncMaxNs > stats.FsyncNs {
+		t.Errorf("Expected the longest fsync within the total, got %+v", stats)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the demo to remove its files, found %d entries such as %s", len(entries), entries[0].Name())
+	}
+}
+
+func TestIOBlockDemoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stats, err := runIOBlockDemo(ctx, t.TempDir(), 2, 1000, 1024)
+	if err != nil || stats.BytesWritten != 0 {
+		t.Errorf("Expected a cancelled run to write nothing, got %+v, %v", stats, err)
+	}
+}
+
+func TestIOBlockDemoUnwritableDir(t *testing.T) {
+	// A regular file stands in for the directory, which fails even as root
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatalf("Failed to create %s: %v", file, err)
+	}
+
+	if _, err := runIOBlockDemo(context.Background(), file, 1, 1, 1024); err == nil || !strings.Contains(err.Error(), "not writable") {
+		t.Errorf("Expected a not writable error, got %v", err)
+	}
+}
+
+func TestIOBlockDemoHandler(t *testing.T) {
+	dir := t.TempDir()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/io-block-demo", ioBlockDemoHandler(dir))
+
+	req := httptest.NewRequest("GET", "/io-block-demo?goroutines=2&chunks=2&chunkKB=1", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body)
+	}
+	var stats ioBlockStats
+	if err := json.Unmarshal(recorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if stats.BytesWritten != 4*1024 {
+		t.Errorf("Expected 4KB written, got %+v", stats)
+	}
+
+	for _, url := range []string{
+		"/io-block-demo?goroutines=0",
+		"/io-block-demo?chunkKB=8192",
+		"/io-block-demo?goroutines=256&chunks=10000&chunkKB=4096",
+	} {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", url, http.StatusBadRequest, recorder.Code)
+		}
+	}
+
+	unwritable := http.NewServeMux()
+	unwritable.HandleFunc("/io-block-demo", ioBlockDemoHandler(filepath.Join(dir, "missing")))
+	req = httptest.NewRequest("GET", "/io-block-demo?goroutines=1&chunks=1&chunkKB=1", nil)
+	recorder = httptest.NewRecorder()
+	unwritable.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusInternalServerError || !strings.Contains(recorder.Body.String(), "not writable") {
+		t.Errorf("Expected a 500 naming the unwritable directory, got %d: %s", recorder.Code, recorder.Body)
+	}
+}
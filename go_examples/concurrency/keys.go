@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// How many of the hottest keys a lock demo run reports
+const maxTopKeys = 10
+
+// keyCount is how many writes a data key received during a run
+type keyCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// Without Keys, readers read the keys of the first few workers
+const defaultReadKeys = 5
+
+// writeKey is the data key writer id updates. Workers share Keys keys round
+// robin, so Keys=1 makes one hot key; zero gives each writer its own.
+func (cfg DemoConfig) writeKey(id int) string {
+	if cfg.Keys > 0 {
+		return workerKey(id % cfg.Keys)
+	}
+	return workerKey(id)
+}
+
+// readKey is the data key reader id reads: one of the shared Keys keys, or
+// without them one of a small fixed set
+func (cfg DemoConfig) readKey(id int) string {
+	if cfg.Keys > 0 {
+		return workerKey(id % cfg.Keys)
+	}
+	return workerKey(id % defaultReadKeys)
+}
+
+// keysParam reads a lock demo's keys parameter, zero when not given
+func keysParam(r *http.Request) (int, error) {
+	return queryInt(r, "keys", 0, 1, maxDemoParam)
+}
+
+// topKeyCounts returns the n keys with the highest counts in data, highest
+// first and ties by key
+func topKeyCounts(data map[string]int, n int) []keyCount {
+	counts := make([]keyCount, 0, len(data))
+	for key, count := range data {
+		counts = append(counts, keyCount{key, count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// snapshotKeys copies r's data, read under lock, so the writes of a run can
+// later be told apart from earlier runs'
+func (r *SharedResource) snapshotKeys(lock sync.Locker) map[string]int {
+	lock.Lock()
+	defer lock.Unlock()
+	snapshot := make(map[string]int, len(r.data))
+	for key, count := range r.data {
+		snapshot[key] = count
+	}
+	return snapshot
+}
+
+// topKeysSince returns the n keys of r's data, read under lock, that gained
+// the most writes since snapshot was taken
+func (r *SharedResource) topKeysSince(lock sync.Locker, snapshot map[string]int, n int) []keyCount {
+	lock.Lock()
+	defer lock.Unlock()
+	added := make(map[string]int)
+	for key, count := range r.data {
+		if count > snapshot[key] {
+			added[key] = count - snapshot[key]
+		}
+	}
+	return topKeyCounts(added, n)
+}
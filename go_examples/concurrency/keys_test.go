@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDemoKeyCardinality(t *testing.T) {
+	const numWorkers, iterations = 9, 20
+
+	for _, keys := range []int{1, 3, numWorkers} {
+		cfg := DemoConfig{Keys: keys}
+
+		basicResource = &SharedResource{
+			data: make(map[string]int),
+		}
+		runMutexDemo(context.Background(), cfg, numWorkers, iterations)
+		if n := len(basicResource.data); n > keys {
+			t.Errorf("mutex demo with keys=%d: expected at most %d keys in the data map, got %d", keys, keys, n)
+		}
+
+		rwResource = &SharedResource{
+			data: make(map[string]int),
+		}
+		runRWMutexDemo(context.Background(), cfg, numWorkers, iterations)
+		if n := len(rwResource.data); n > keys {
+			t.Errorf("rwmutex demo with keys=%d: expected at most %d keys in the data map, got %d", keys, keys, n)
+		}
+	}
+
+	// A single key takes every write
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+	runMutexDemo(context.Background(), DemoConfig{Keys: 1}, numWorkers, iterations)
+	if count := basicResource.data[workerKey(0)]; count != basicResource.counter {
+		t.Errorf("Expected the one key to count all %d writes, got %d", basicResource.counter, count)
+	}
+}
+
+func TestDemoReadKeys(t *testing.T) {
+	// Without Keys, readers read the shared keys of the first writers,
+	// which write their own
+	var cfg DemoConfig
+	for _, id := range []int{0, 3, 7, 11} {
+		if key, expected := cfg.readKey(id), workerKey(id%defaultReadKeys); key != expected {
+			t.Errorf("reader %d: expected key %q, got %q", id, expected, key)
+		}
+		if key := cfg.writeKey(id); key != workerKey(id) {
+			t.Errorf("writer %d: expected its own key, got %q", id, key)
+		}
+	}
+
+	// With Keys, readers and writers share the same keys
+	cfg.Keys = 4
+	for _, id := range []int{0, 5, 10} {
+		if cfg.readKey(id) != cfg.writeKey(id) || cfg.readKey(id) != workerKey(id%4) {
+			t.Errorf("worker %d: expected key %q, got %q to read and %q to write", id, workerKey(id%4), cfg.readKey(id), cfg.writeKey(id))
+		}
+	}
+}
+
+func TestTopKeysSince(t *testing.T) {
+	r := &SharedResource{data: map[string]int{"a": 4, "b": 2}}
+	before := r.snapshotKeys(&r.mutex)
+	r.data["a"] += 1
+	r.data["c"] = 3
+
+	expected := []keyCount{{"c", 3}, {"a", 1}}
+	if top := r.topKeysSince(&r.mutex, before, maxTopKeys); !reflect.DeepEqual(top, expected) {
+		t.Errorf("Expected %v, got %v", expected, top)
+	}
+}
+
+func TestTopKeyCounts(t *testing.T) {
+	data := map[string]int{"a": 1, "b": 5, "c": 3, "d": 5}
+
+	expected := []keyCount{{"b", 5}, {"d", 5}, {"c", 3}}
+	if top := topKeyCounts(data, 3); !reflect.DeepEqual(top, expected) {
+		t.Errorf("Expected %v, got %v", expected, top)
+	}
+	if top := topKeyCounts(data, maxTopKeys); len(top) != len(data) {
+		t.Errorf("Expected all %d keys when there are fewer than %d, got %v", len(data), maxTopKeys, top)
+	}
+}
+
+func TestMutexDemoKeysParam(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/rwmutex-demo", rwMutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+
+	for _, demo := range []string{"/mutex-demo", "/rwmutex-demo"} {
+		basicResource = &SharedResource{
+			data: make(map[string]int),
+		}
+		rwResource = &SharedResource{
+			data: make(map[string]int),
+		}
+
+		req := httptest.NewRequest("GET", demo+"?workers=15&iterations=10&keys=2&preSleepMs=0&holdMs=0&readMs=0", nil)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+
+		run := waitForRun(t, mux, recorder.Header().Get("Location"))
+		if run.Params["keys"] != 2 {
+			t.Errorf("%s: expected keys=2 in the run params, got %v", demo, run.Params)
+		}
+		if len(run.Keys) == 0 || len(run.Keys) > 2 {
+			t.Errorf("%s: expected the counts of at most 2 keys, got %v", demo, run.Keys)
+		}
+
+		// A second run reports only its own writes, not the first run's too
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("GET", demo+"?workers=15&iterations=10&keys=2&preSleepMs=0&holdMs=0&readMs=0", nil))
+		second := waitForRun(t, mux, recorder.Header().Get("Location"))
+		written, secondWritten := 0, 0
+		for _, key := range run.Keys {
+			written += key.Count
+		}
+		for _, key := range second.Keys {
+			secondWritten += key.Count
+		}
+		if written == 0 || secondWritten != written {
+			t.Errorf("%s: expected both runs to report the same %d writes, got %v then %v", demo, written, run.Keys, second.Keys)
+		}
+
+		// Without keys every writer has its own key, and only the top ones are reported
+		req = httptest.NewRequest("GET", demo+"?workers=60&iterations=5&preSleepMs=0&holdMs=0&readMs=0", nil)
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+
+		run = waitForRun(t, mux, recorder.Header().Get("Location"))
+		if _, ok := run.Params["keys"]; ok || len(run.Keys) != maxTopKeys {
+			t.Errorf("%s: expected no keys param and the top %d keys reported, got %v and %v", demo, maxTopKeys, run.Params, run.Keys)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/mutex-demo?keys=0", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for keys=0, got %d", recorder.Code)
+	}
+}
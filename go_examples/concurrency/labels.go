@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+// Profile label keys identifying which demo, which run of it and which of its
+// workers a sample came from, for filtering with tagfocus when several demos
+// run at once
+const (
+	demoLabel   = "demo"
+	runIDLabel  = "run_id"
+	workerLabel = "worker"
+)
+
+// withDemoLabel calls f with the demo label set to demo. Goroutines started by
+// f inherit the label, so their CPU, goroutine, block and mutex samples carry
+// it too.
+func withDemoLabel(ctx context.Context, demo string, f func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels(demoLabel, demo), f)
+}
+
+// withRunLabel calls f with the run_id label set to the registry's ID for the
+// run
+func withRunLabel(ctx context.Context, id int, f func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels(runIDLabel, strconv.Itoa(id)), f)
+}
+
+// withWorkerLabel calls f with the worker label set to worker, for starting
+// that worker's goroutine. In a seeded run the context also carries the
+// worker's own random source. In a launched run a panic in f fails the run
+// rather than crashing the server.
+func withWorkerLabel(ctx context.Context, worker int, f func(ctx context.Context)) {
+	defer recoverWorker(ctx)
+	pprof.Do(withWorkerRand(ctx, worker), pprof.Labels(workerLabel, strconv.Itoa(worker)), f)
+}
+
+// runLabels returns the labels every goroutine of a registry run carries;
+// each worker adds its own worker label
+func runLabels(demo string, id int) map[string]string {
+	return map[string]string{demoLabel: demo, runIDLabel: strconv.Itoa(id)}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestDemoLabelsInProfile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var demos sync.WaitGroup
+	demos.Add(3)
+	go func() {
+		defer demos.Done()
+		runMutexDemo(ctx, defaultDemoConfig, 3, 100000)
+	}()
+	go func() {
+		defer demos.Done()
+		runRWMutexDemo(ctx, defaultDemoConfig, 3, 100000)
+	}()
+	go func() {
+		defer demos.Done()
+		runChannelDemo(ctx, 2, 2, 100000)
+	}()
+	defer func() {
+		cancel()
+		demos.Wait()
+	}()
+
+	// The demos mostly sleep and block, so the goroutine profile is a more
+	// reliable place to find their labels than a short CPU profile
+	time.Sleep(50 * time.Millisecond)
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 0); err != nil {
+		t.Fatalf("Failed to write goroutine profile: %v", err)
+	}
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse goroutine profile: %v", err)
+	}
+
+	labelled := make(map[string]int64)
+	for _, s := range p.Sample {
+		for _, demo := range s.Label[demoLabel] {
+			labelled[demo] += s.Value[0]
+		}
+	}
+	for _, demo := range []string{"mutex", "rwmutex", "channel"} {
+		if labelled[demo] == 0 {
+			t.Errorf("Expected goroutines labelled %s=%s, got %v", demoLabel, demo, labelled)
+		}
+	}
+}
+
+func TestWithDemoLabel(t *testing.T) {
+	withDemoLabel(context.Background(), "mutex", func(ctx context.Context) {
+		if demo, ok := pprof.Label(ctx, demoLabel); !ok || demo != "mutex" {
+			t.Errorf("Expected label %s=mutex, got %q", demoLabel, demo)
+		}
+	})
+}
+
+func TestRunLabelsInGoroutineProfile(t *testing.T) {
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/mutex-demo?workers=4&iterations=10000", nil))
+	location := recorder.Header().Get("Location")
+	id, err := strconv.Atoi(strings.TrimPrefix(location, "/runs/"))
+	if err != nil {
+		t.Fatalf("Expected a run location, got %q", location)
+	}
+	defer func() {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", location, nil))
+		run := waitForRun(t, mux, location)
+
+		// The run documents the labels its goroutines carried
+		expected := map[string]string{demoLabel: "mutex", runIDLabel: strconv.Itoa(id)}
+		if fmt.Sprint(run.Labels) != fmt.Sprint(expected) {
+			t.Errorf("Expected run labels %v, got %v", expected, run.Labels)
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatalf("Failed to write goroutine profile: %v", err)
+	}
+	text := buf.String()
+	for worker := 0; worker < 4; worker++ {
+		labels := fmt.Sprintf(`"demo":"mutex", "run_id":"%d", "worker":"%d"`, id, worker)
+		if !strings.Contains(text, labels) {
+			t.Errorf("Expected a goroutine labelled {%s} in the goroutine profile", labels)
+		}
+	}
+}
+
+func TestWithWorkerLabel(t *testing.T) {
+	withRunLabel(context.Background(), 7, func(ctx context.Context) {
+		withWorkerLabel(ctx, 3, func(ctx context.Context) {
+			run, _ := pprof.Label(ctx, runIDLabel)
+			worker, _ := pprof.Label(ctx, workerLabel)
+			if run != "7" || worker != "3" {
+				t.Errorf("Expected run_id=7 and worker=3, got %q and %q", run, worker)
+			}
+		})
+	})
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// lockTiming accumulates how long writers waited for a lock and then held
+// it. Each writer owns one, so measuring adds no contention of its own; a
+// run merges them once its workers are done. It gives a ground truth to
+// compare with what the mutex profile reports.
+type lockTiming struct {
+	Acquisitions int64
+	Wait         time.Duration
+	MaxWait      time.Duration
+	Hold         time.Duration
+	MaxHold      time.Duration
+}
+
+// record adds one lock acquisition
+func (lt *lockTiming) record(wait, hold time.Duration) {
+	lt.Acquisitions++
+	lt.Wait += wait
+	lt.Hold += hold
+	lt.MaxWait = max(lt.MaxWait, wait)
+	lt.MaxHold = max(lt.MaxHold, hold)
+}
+
+// merge adds other's acquisitions to lt
+func (lt *lockTiming) merge(other lockTiming) {
+	lt.Acquisitions += other.Acquisitions
+	lt.Wait += other.Wait
+	lt.Hold += other.Hold
+	lt.MaxWait = max(lt.MaxWait, other.MaxWait)
+	lt.MaxHold = max(lt.MaxHold, other.MaxHold)
+}
+
+// addTo adds the timings to a run result in nanoseconds
+func (lt lockTiming) addTo(result map[string]int64) map[string]int64 {
+	result["lockAcquisitions"] = lt.Acquisitions
+	result["lockWaitNs"] = int64(lt.Wait)
+	result["lockWaitMaxNs"] = int64(lt.MaxWait)
+	result["lockHoldNs"] = int64(lt.Hold)
+	result["lockHoldMaxNs"] = int64(lt.MaxHold)
+	return result
+}
+
+// roleWaits accumulates how long the readers and the writers of a run waited
+// to acquire the same lock. All workers share one, so it uses atomics rather
+// than a lock that would add contention of its own.
+type roleWaits struct {
+	readers, readerWait atomic.Int64
+	writers, writerWait atomic.Int64
+}
+
+// reader adds one reader acquisition after waiting wait
+func (rw *roleWaits) reader(wait time.Duration) {
+	rw.readers.Add(1)
+	rw.readerWait.Add(int64(wait))
+}
+
+// writer adds one writer acquisition after waiting wait
+func (rw *roleWaits) writer(wait time.Duration) {
+	rw.writers.Add(1)
+	rw.writerWait.Add(int64(wait))
+}
+
+// stats returns the totals so far
+func (rw *roleWaits) stats() roleWaitStats {
+	return roleWaitStats{
+		ReaderAcquisitions: rw.readers.Load(),
+		ReaderWait:         time.Duration(rw.readerWait.Load()),
+		WriterAcquisitions: rw.writers.Load(),
+		WriterWait:         time.Duration(rw.writerWait.Load()),
+	}
+}
+
+// roleWaitStats is the total time readers and writers spent waiting for a
+// lock, and how often each acquired it
+type roleWaitStats struct {
+	ReaderAcquisitions int64
+	ReaderWait         time.Duration
+	WriterAcquisitions int64
+	WriterWait         time.Duration
+}
+
+// avgReaderWait returns the mean wait per reader acquisition
+func (s roleWaitStats) avgReaderWait() time.Duration {
+	if s.ReaderAcquisitions == 0 {
+		return 0
+	}
+	return s.ReaderWait / time.Duration(s.ReaderAcquisitions)
+}
+
+// avgWriterWait returns the mean wait per writer acquisition
+func (s roleWaitStats) avgWriterWait() time.Duration {
+	if s.WriterAcquisitions == 0 {
+		return 0
+	}
+	return s.WriterWait / time.Duration(s.WriterAcquisitions)
+}
+
+// addTo adds the reader waits to a run result in nanoseconds. The writer
+// waits are already there from lockTiming.addTo.
+func (s roleWaitStats) addTo(result map[string]int64) map[string]int64 {
+	result["readerLockAcquisitions"] = s.ReaderAcquisitions
+	result["readerLockWaitNs"] = int64(s.ReaderWait)
+	return result
+}
+
+// workerStats is what one mutex demo worker got done, sent to the run when
+// the worker exits. Side by side they show the skew between workers that the
+// mutex profile attributes to their stacks.
+type workerStats struct {
+	Worker         int    `json:"worker"`
+	Role           string `json:"role"` // "writer" or "reader"
+	Iterations     int64  `json:"iterations"`
+	LockWaitNs     int64  `json:"lockWaitNs"`
+	MaxLockWaitNs  int64  `json:"maxLockWaitNs"`
+	MapGrowthBytes int64  `json:"mapGrowthBytes"` // Estimated size of the map entries the worker added
+}
+
+// record adds one completed iteration that waited wait for the lock
+func (ws *workerStats) record(wait time.Duration) {
+	ws.Iterations++
+	ws.LockWaitNs += int64(wait)
+	ws.MaxLockWaitNs = max(ws.MaxLockWaitNs, int64(wait))
+}
+
+// mapEntryBytes estimates how much adding key grows a map[string]int: the
+// string header and the value stored in the bucket, plus the key's bytes
+func mapEntryBytes(key string) int64 {
+	return int64(unsafe.Sizeof(key)+unsafe.Sizeof(0)) + int64(len(key))
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLockTimingMerge(t *testing.T) {
+	var a, b lockTiming
+	a.record(2*time.Millisecond, 5*time.Millisecond)
+	a.record(4*time.Millisecond, time.Millisecond)
+	b.record(time.Millisecond, 8*time.Millisecond)
+
+	a.merge(b)
+	expected := lockTiming{
+		Acquisitions: 3,
+		Wait:         7 * time.Millisecond,
+		MaxWait:      4 * time.Millisecond,
+		Hold:         14 * time.Millisecond,
+		MaxHold:      8 * time.Millisecond,
+	}
+	if a != expected {
+		t.Errorf("Expected %+v, got %+v", expected, a)
+	}
+
+	result := a.addTo(map[string]int64{"counter": 1})
+	if result["counter"] != 1 || result["lockAcquisitions"] != 3 || result["lockHoldNs"] != int64(14*time.Millisecond) ||
+		result["lockWaitMaxNs"] != int64(4*time.Millisecond) {
+		t.Errorf("Unexpected run result: %v", result)
+	}
+}
+
+func TestMutexDemoLockTiming(t *testing.T) {
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+
+	// A single writer never waits on another, and holds the lock for a random
+	// time below holdMs each iteration, 2ms on average
+	const iterations = 50
+	hold := 4 * time.Millisecond
+	start := time.Now()
+	_, _, timing, _, _ := runMutexDemo(context.Background(), DemoConfig{HoldSleepMax: hold}, 1, iterations)
+	elapsed := time.Since(start)
+
+	if timing.Acquisitions != iterations {
+		t.Errorf("Expected %d acquisitions, got %d", iterations, timing.Acquisitions)
+	}
+	expected := iterations * hold / 2
+	if timing.Hold < expected/2 || timing.Hold > elapsed {
+		t.Errorf("Expected total hold time around %v (at most the %v run), got %v", expected, elapsed, timing.Hold)
+	}
+	if timing.MaxHold < hold/4 || timing.MaxHold > timing.Hold {
+		t.Errorf("Unexpected max hold time %v of %v total", timing.MaxHold, timing.Hold)
+	}
+	if timing.Wait > timing.Hold {
+		t.Errorf("Expected an uncontended writer to barely wait, waited %v", timing.Wait)
+	}
+}
+
+func TestRWMutexDemoLockTiming(t *testing.T) {
+	rwResource = &SharedResource{
+		data: make(map[string]int),
+	}
+
+	// Workers 0 and 5 write and contend with each other and the readers
+	_, _, timing := runRWMutexDemo(context.Background(), DemoConfig{HoldSleepMax: 2 * time.Millisecond}, 10, 20)
+
+	if timing.Acquisitions != 2*20 {
+		t.Errorf("Expected 40 writer acquisitions, got %d", timing.Acquisitions)
+	}
+	if timing.Wait <= 0 || timing.MaxWait > timing.Wait || timing.Hold <= 0 {
+		t.Errorf("Unexpected writer lock timing: %+v", timing)
+	}
+}
+
+func TestRoleWaits(t *testing.T) {
+	var waits roleWaits
+	waits.reader(time.Millisecond)
+	waits.reader(3 * time.Millisecond)
+	waits.writer(9 * time.Millisecond)
+
+	stats := waits.stats()
+	if stats.ReaderAcquisitions != 2 || stats.ReaderWait != 4*time.Millisecond ||
+		stats.WriterAcquisitions != 1 || stats.WriterWait != 9*time.Millisecond {
+		t.Errorf("Unexpected role waits: %+v", stats)
+	}
+	if stats.avgReaderWait() != 2*time.Millisecond || stats.avgWriterWait() != 9*time.Millisecond {
+		t.Errorf("Unexpected averages %v and %v", stats.avgReaderWait(), stats.avgWriterWait())
+	}
+	if (roleWaitStats{}).avgReaderWait() != 0 {
+		t.Error("Expected no average wait without acquisitions")
+	}
+
+	result := stats.addTo(map[string]int64{})
+	if result["readerLockAcquisitions"] != 2 || result["readerLockWaitNs"] != int64(4*time.Millisecond) {
+		t.Errorf("Unexpected run result: %v", result)
+	}
+}
+
+func TestMutexDemoRoleWaits(t *testing.T) {
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+
+	// Worker 0 is the only writer, so it only ever waits for the readers'
+	// near-empty critical sections while they queue behind its holds. The
+	// readers' own work keeps them arriving throughout the writer's run
+	// rather than finishing before its first hold.
+	const iterations = 30
+	cfg := DemoConfig{HoldSleepMax: 4 * time.Millisecond, ReadSleepMax: 2 * time.Millisecond}
+	_, _, timing, waits, _ := runMutexDemo(context.Background(), cfg, 3, iterations)
+
+	if waits.WriterAcquisitions != iterations || waits.ReaderAcquisitions != 2*iterations {
+		t.Fatalf("Expected %d writer and %d reader acquisitions, got %+v", iterations, 2*iterations, waits)
+	}
+	if waits.WriterWait != timing.Wait {
+		t.Errorf("Expected writer waits %v to match the lock timing %v", waits.WriterWait, timing.Wait)
+	}
+	if waits.avgReaderWait() <= waits.avgWriterWait() {
+		t.Errorf("Expected readers to wait longer than the writer on average, got %v and %v",
+			waits.avgReaderWait(), waits.avgWriterWait())
+	}
+}
+
+func TestMutexDemoWorkerStats(t *testing.T) {
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+
+	const numWorkers, iterations = 7, 40
+	_, completed, timing, _, workers := runMutexDemo(context.Background(), DemoConfig{}, numWorkers, iterations)
+
+	if len(workers) != numWorkers {
+		t.Fatalf("Expected stats for %d workers, got %+v", numWorkers, workers)
+	}
+	var total, writerWait int64
+	for i, ws := range workers {
+		if ws.Worker != i {
+			t.Errorf("Expected the workers in order, got worker %d at %d", ws.Worker, i)
+		}
+		if ws.MaxLockWaitNs > ws.LockWaitNs {
+			t.Errorf("Worker %d: max wait %dns exceeds its total %dns", i, ws.MaxLockWaitNs, ws.LockWaitNs)
+		}
+		total += ws.Iterations
+		switch ws.Role {
+		case "writer":
+			writerWait += ws.LockWaitNs
+			// Each writer adds its own key on its first iteration
+			if expected := mapEntryBytes(workerKey(i)); ws.MapGrowthBytes != expected {
+				t.Errorf("Writer %d: expected %d bytes of map growth, got %d", i, expected, ws.MapGrowthBytes)
+			}
+		case "reader":
+			if ws.MapGrowthBytes != 0 {
+				t.Errorf("Reader %d: expected no map growth, got %d bytes", i, ws.MapGrowthBytes)
+			}
+		default:
+			t.Errorf("Worker %d: unexpected role %q", i, ws.Role)
+		}
+	}
+	if total != numWorkers*iterations || total != completed {
+		t.Errorf("Expected the per-worker iterations to sum to %d, got %d (completed %d)",
+			numWorkers*iterations, total, completed)
+	}
+	if writerWait != int64(timing.Wait) {
+		t.Errorf("Expected the writers' waits to sum to the lock timing %v, got %v", timing.Wait, time.Duration(writerWait))
+	}
+}
+
+func TestMutexDemoRunWorkers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+
+	req := httptest.NewRequest("GET", "/mutex-demo?workers=5&iterations=30&preSleepMs=0&holdMs=0&readMs=0", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	run := waitForRun(t, mux, recorder.Header().Get("Location"))
+	if len(run.Workers) != 5 {
+		t.Fatalf("Expected 5 workers in the run, got %+v", run.Workers)
+	}
+	var total int64
+	for _, ws := range run.Workers {
+		total += ws.Iterations
+	}
+	if total != 5*30 || total != run.Result["iterations"] {
+		t.Errorf("Expected the workers' iterations to sum to 150, got %d (result %v)", total, run.Result)
+	}
+}
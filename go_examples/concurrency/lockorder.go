@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// LockOrder records the order in which goroutines acquire OrderedMutexes and
+// warns when two locks are taken in opposite orders, which can deadlock. Locks
+// given a rank with Declare are checked against that hierarchy instead, so the
+// first acquisition out of order is reported even if no goroutine has taken
+// them in the right order yet.
+type LockOrder struct {
+	mutex      sync.Mutex
+	held       map[uint64][]string       // Locks currently held, per goroutine
+	before     map[[2]string]lockWitness // {a, b} means a was held while acquiring b
+	rank       map[string]int            // Declared position in the hierarchy
+	violations []LockOrderViolation
+	seen       map[[2]string]int // Index in violations of each {held, acquired} pair
+}
+
+// lockWitness is the first goroutine seen holding one lock while acquiring
+// another, and its stack at the time
+type lockWitness struct {
+	gid   uint64
+	stack string
+}
+
+// LockOrderViolation is a lock taken out of order, with the stacks of the
+// goroutine that first took it and of the goroutine on the other side of the
+// potential deadlock: the first one seen taking the pair in the opposite
+// order, or else the one holding the lock at the time. Other is empty when
+// there was neither. Each pair of locks is recorded once, counting how often
+// it was taken out of order; Last is the most recent time after the first.
+type LockOrderViolation struct {
+	Message        string `json:"message"`
+	Count          int    `json:"count"`
+	Goroutine      uint64 `json:"goroutine"`
+	Stack          string `json:"stack"`
+	OtherGoroutine uint64 `json:"otherGoroutine,omitempty"`
+	OtherStack     string `json:"otherStack,omitempty"`
+	LastGoroutine  uint64 `json:"lastGoroutine,omitempty"`
+	LastStack      string `json:"lastStack,omitempty"`
+}
+
+// NewLockOrder creates an empty lock order tracker
+func NewLockOrder() *LockOrder {
+	return &LockOrder{
+		held:   make(map[uint64][]string),
+		before: make(map[[2]string]lockWitness),
+		rank:   make(map[string]int),
+		seen:   make(map[[2]string]int),
+	}
+}
+
+// Declare sets the order in which the named locks must be acquired: a
+// goroutine holding one may only acquire those after it in names
+func (o *LockOrder) Declare(names ...string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for i, name := range names {
+		o.rank[name] = i
+	}
+}
+
+// Violations returns the messages of the inconsistent orderings seen so far
+func (o *LockOrder) Violations() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	messages := make([]string, len(o.violations))
+	for i, v := range o.violations {
+		messages[i] = v.Message
+	}
+	return messages
+}
+
+// Details returns the inconsistent orderings seen so far with their stacks
+func (o *LockOrder) Details() []LockOrderViolation {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]LockOrderViolation(nil), o.violations...)
+}
+
+// acquiring checks name against the locks the goroutine already holds. It runs
+// before blocking on the lock so an inversion is reported even if it deadlocks.
+// The goroutine's stack is only captured when there is something to record.
+func (o *LockOrder) acquiring(gid uint64, name string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var stack string
+	for _, held := range o.held[gid] {
+		heldRank, heldDeclared := o.rank[held]
+		nameRank, nameDeclared := o.rank[name]
+		var message string
+		switch {
+		case heldDeclared && nameDeclared:
+			if nameRank < heldRank {
+				message = fmt.Sprintf("lock hierarchy violation: %s acquired while holding %s, but %s must be acquired first",
+					name, held, name)
+			}
+		case o.hasWitness(name, held):
+			message = fmt.Sprintf("lock order inversion: %s acquired while holding %s, but %s was previously held while acquiring %s",
+				name, held, name, held)
+		}
+		newWitness := !o.hasWitness(held, name)
+		if message == "" && !newWitness {
+			continue
+		}
+		if stack == "" {
+			stack = string(currentStack())
+		}
+
+		if message != "" {
+			o.record(message, gid, stack, name, held)
+		}
+		if newWitness {
+			o.before[[2]string{held, name}] = lockWitness{gid: gid, stack: stack}
+		}
+	}
+}
+
+// record adds a violation for acquiring name while holding held, warning the
+// first time the pair is seen and counting it after that. Called with o.mutex
+// held.
+func (o *LockOrder) record(message string, gid uint64, stack, name, held string) {
+	pair := [2]string{held, name}
+	if i, ok := o.seen[pair]; ok {
+		v := &o.violations[i]
+		v.Count++
+		v.LastGoroutine, v.LastStack = gid, stack
+		return
+	}
+
+	v := o.violation(message, gid, stack, name, held)
+	v.Count = 1
+	o.seen[pair] = len(o.violations)
+	o.violations = append(o.violations, v)
+	slog.Warn("Lock order violation", "violation", message, "goroutine", gid)
+}
+
+// hasWitness reports whether a goroutine has held first while acquiring second
+func (o *LockOrder) hasWitness(first, second string) bool {
+	_, ok := o.before[[2]string{first, second}]
+	return ok
+}
+
+// violation describes goroutine gid acquiring name while holding held, out of
+// order, finding the goroutine on the other side. Called with o.mutex held.
+func (o *LockOrder) violation(message string, gid uint64, stack, name, held string) LockOrderViolation {
+	v := LockOrderViolation{Message: message, Goroutine: gid, Stack: stack}
+	if witness, ok := o.before[[2]string{name, held}]; ok {
+		v.OtherGoroutine, v.OtherStack = witness.gid, witness.stack
+		return v
+	}
+	for other, locks := range o.held {
+		for _, lock := range locks {
+			if lock == name && other != gid {
+				v.OtherGoroutine = other
+				v.OtherStack = goroutineStack(allStacks(), other)
+				return v
+			}
+		}
+	}
+	return v
+}
+
+// acquired records that the goroutine now holds name
+func (o *LockOrder) acquired(gid uint64, name string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.held[gid] = append(o.held[gid], name)
+}
+
+// released records that the goroutine no longer holds name
+func (o *LockOrder) released(gid uint64, name string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	held := o.held[gid]
+	for i := len(held) - 1; i >= 0; i-- {
+		if held[i] == name {
+			held = append(held[:i], held[i+1:]...)
+			break
+		}
+	}
+	if len(held) == 0 {
+		delete(o.held, gid)
+	} else {
+		o.held[gid] = held
+	}
+}
+
+// OrderedMutex is a sync.Mutex that reports its acquisitions to a LockOrder.
+// Without one it is a plain named mutex.
+type OrderedMutex struct {
+	mutex sync.Mutex
+	name  string
+	order *LockOrder
+}
+
+// NewOrderedMutex creates a named mutex tracked by order, if not nil
+func NewOrderedMutex(name string, order *LockOrder) *OrderedMutex {
+	return &OrderedMutex{name: name, order: order}
+}
+
+// Lock acquires the mutex, warning first if it inverts an earlier lock order
+func (m *OrderedMutex) Lock() {
+	if m.order == nil {
+		m.mutex.Lock()
+		return
+	}
+	gid := goroutineID()
+	m.order.acquiring(gid, m.name)
+	m.mutex.Lock()
+	m.order.acquired(gid, m.name)
+}
+
+// LockContext acquires the mutex like Lock, or gives up and returns false
+// once ctx is done
+func (m *OrderedMutex) LockContext(ctx context.Context) bool {
+	if m.order == nil {
+		return lockContext(ctx, &m.mutex)
+	}
+	gid := goroutineID()
+	m.order.acquiring(gid, m.name)
+	if !lockContext(ctx, &m.mutex) {
+		return false
+	}
+	m.order.acquired(gid, m.name)
+	return true
+}
+
+// Unlock releases the mutex
+func (m *OrderedMutex) Unlock() {
+	if m.order != nil {
+		m.order.released(goroutineID(), m.name)
+	}
+	m.mutex.Unlock()
+}
+
+// lockContext acquires m, or gives up and returns false once ctx is done.
+// The attempt blocks in a real Lock call on a helper goroutine, so a deadlock
+// still looks like one in goroutine and mutex profiles; an abandoned attempt
+// releases the lock as soon as it gets it.
+func lockContext(ctx context.Context, m sync.Locker) bool {
+	acquired := make(chan struct{})
+	go func() {
+		m.Lock()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		return true
+	case <-ctx.Done():
+		go func() {
+			<-acquired
+			m.Unlock()
+		}()
+		return false
+	}
+}
+
+// currentStack returns the calling goroutine's stack
+func currentStack() []byte {
+	buf := make([]byte, 4<<10)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineStack picks goroutine id's stack out of runtime.Stack output for
+// all goroutines, or returns "" if it isn't there
+func goroutineStack(stacks []byte, id uint64) string {
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, block := range bytes.Split(stacks, []byte("\n\n")) {
+		if bytes.HasPrefix(block, header) {
+			return string(bytes.TrimSpace(block))
+		}
+	}
+	return ""
+}
+
+// goroutineID parses the current goroutine's ID from its stack header
+// ("goroutine 18 [running]:"). The runtime doesn't expose it otherwise.
+func goroutineID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pprofviz/examples/internal/logging"
+)
+
+func TestLockOrderConsistent(t *testing.T) {
+	order := NewLockOrder()
+	a := NewOrderedMutex("a", order)
+	b := NewOrderedMutex("b", order)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				a.Lock()
+				b.Lock()
+				b.Unlock()
+				a.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := order.Violations(); len(v) != 0 {
+		t.Errorf("Expected no violations for a consistent order, got %v", v)
+	}
+}
+
+func TestLockOrderInversion(t *testing.T) {
+	order := NewLockOrder()
+	a := NewOrderedMutex("a", order)
+	b := NewOrderedMutex("b", order)
+
+	// Sequential, so the inversion is recorded without risking a real deadlock
+	a.Lock()
+	b.Lock()
+	b.Unlock()
+	a.Unlock()
+
+	b.Lock()
+	a.Lock()
+	a.Unlock()
+	b.Unlock()
+
+	violations := order.Violations()
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d: %v", len(violations), violations)
+	}
+	if !strings.Contains(violations[0], "a acquired while holding b") {
+		t.Errorf("Unexpected violation message: %s", violations[0])
+	}
+}
+
+func TestLockOrderRepeatedInversion(t *testing.T) {
+	oldLogger := slog.Default()
+	defer slog.SetDefault(oldLogger)
+	var logs bytes.Buffer
+	slog.SetDefault(logging.New(&logs, slog.LevelWarn))
+
+	order := NewLockOrder()
+	a := NewOrderedMutex("a", order)
+	b := NewOrderedMutex("b", order)
+	a.Lock()
+	b.Lock()
+	b.Unlock()
+	a.Unlock()
+
+	// The same inverted pair taken many times is one violation
+	const inversions = 1000
+	for i := 0; i < inversions; i++ {
+		b.Lock()
+		a.Lock()
+		a.Unlock()
+		b.Unlock()
+	}
+
+	violations := order.Details()
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d", len(violations))
+	}
+	if v := violations[0]; v.Count != inversions || v.Stack == "" || v.LastStack == "" {
+		t.Errorf("Expected the violation counted %d times with its first and last stacks, got count %d", inversions, v.Count)
+	}
+	if n := strings.Count(logs.String(), "Lock order violation"); n != 1 {
+		t.Errorf("Expected a single warning, got %d", n)
+	}
+}
+
+func TestLockOrderRelease(t *testing.T) {
+	order := NewLockOrder()
+	a := NewOrderedMutex("a", order)
+	b := NewOrderedMutex("b", order)
+
+	// Taking b after a is released isn't nested, so no order is recorded
+	a.Lock()
+	a.Unlock()
+	b.Lock()
+	a.Lock()
+	a.Unlock()
+	b.Unlock()
+
+	if v := order.Violations(); len(v) != 0 {
+		t.Errorf("Expected no violations, got %v", v)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	id := goroutineID()
+	if id == 0 {
+		t.Fatal("Expected a non-zero goroutine ID")
+	}
+
+	other := make(chan uint64)
+	go func() { other <- goroutineID() }()
+	if otherID := <-other; otherID == id || otherID == 0 {
+		t.Errorf("Expected a distinct goroutine ID, got %d and %d", id, otherID)
+	}
+}
+
+func TestLockContext(t *testing.T) {
+	var m sync.Mutex
+	m.Lock()
+
+	// Gives up on a held lock once the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if lockContext(ctx, &m) {
+		t.Fatal("Expected lockContext to give up on a held lock")
+	}
+
+	// The abandoned attempt releases the lock after getting it
+	m.Unlock()
+	deadline := time.Now().Add(time.Second)
+	for !m.TryLock() {
+		if time.Now().After(deadline) {
+			t.Fatal("Abandoned lock attempt never released the mutex")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	m.Unlock()
+
+	if !lockContext(context.Background(), &m) {
+		t.Fatal("Expected lockContext to acquire a free lock")
+	}
+	m.Unlock()
+}
+
+func TestOrderedMutexLockContext(t *testing.T) {
+	order := NewLockOrder()
+	a := NewOrderedMutex("a", order)
+	b := NewOrderedMutex("b", order)
+
+	// Attributed to the calling goroutine, so the inversion is still seen
+	a.Lock()
+	if !b.LockContext(context.Background()) {
+		t.Fatal("Expected LockContext to acquire a free lock")
+	}
+	b.Unlock()
+	a.Unlock()
+
+	b.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if a.LockContext(ctx) {
+		a.Unlock()
+	}
+	b.Unlock()
+
+	if v := order.Violations(); len(v) != 1 {
+		t.Errorf("Expected one violation, got %v", v)
+	}
+}
+
+func TestLockHierarchyCorrectOrder(t *testing.T) {
+	order := NewLockOrder()
+	order.Declare("a", "b", "c")
+	a := NewOrderedMutex("a", order)
+	b := NewOrderedMutex("b", order)
+	c := NewOrderedMutex("c", order)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Skipping a level is still in order
+			first := a
+			if i%2 == 1 {
+				first = b
+			}
+			for j := 0; j < 50; j++ {
+				first.Lock()
+				c.Lock()
+				c.Unlock()
+				first.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if v := order.Details(); len(v) != 0 {
+		t.Errorf("Expected no violations for the declared order, got %+v", v)
+	}
+}
+
+func TestLockHierarchyInverted(t *testing.T) {
+	order := NewLockOrder()
+	order.Declare("a", "b")
+	a := NewOrderedMutex("a", order)
+	b := NewOrderedMutex("b", order)
+
+	// Another goroutine holds a, so it is the other side of the violation
+	held := make(chan uint64)
+	release := make(chan struct{})
+	go holdLock(a, held, release)
+	holder := <-held
+
+	// No goroutine has taken a then b, but the hierarchy says a comes first
+	b.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if a.LockContext(ctx) {
+		t.Fatal("Expected LockContext to give up on a held lock")
+	}
+	b.Unlock()
+	close(release)
+
+	violations := order.Details()
+	if len(violations) != 1 {
+		t.Fatalf("Expected 1 violation, got %d: %+v", len(violations), violations)
+	}
+	v := violations[0]
+	if !strings.Contains(v.Message, "a acquired while holding b") {
+		t.Errorf("Unexpected violation message: %s", v.Message)
+	}
+	if v.Goroutine != goroutineID() || !strings.Contains(v.Stack, "TestLockHierarchyInverted") {
+		t.Errorf("Expected this goroutine's stack, got goroutine %d:\n%s", v.Goroutine, v.Stack)
+	}
+	if v.OtherGoroutine != holder || !strings.Contains(v.OtherStack, "holdLock") {
+		t.Errorf("Expected the holder's stack, got goroutine %d:\n%s", v.OtherGoroutine, v.OtherStack)
+	}
+}
+
+// holdLock locks m and sends its goroutine ID on held, then unlocks m once
+// release is closed
+func holdLock(m *OrderedMutex, held chan<- uint64, release <-chan struct{}) {
+	m.Lock()
+	held <- goroutineID()
+	<-release
+	m.Unlock()
+}
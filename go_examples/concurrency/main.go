@@ -1,13 +1,30 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"log/slog"
+	"math"
 	"math/rand"
 	"net/http"
 	"net/http/pprof"
+	"os"
+	"os/signal"
 	"runtime"
+	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"pprofviz/examples/internal/logging"
+	"pprofviz/examples/internal/profrate"
+	"pprofviz/examples/internal/randseed"
+	"pprofviz/examples/internal/selfprofile"
+	"pprofviz/examples/internal/server"
 )
 
 // A concurrency-focused application to demonstrate block and mutex profiles
@@ -32,281 +49,877 @@ var (
 	rwResource = &SharedResource{
 		data: make(map[string]int),
 	}
-
-	// Channels for different patterns
-	workChannel  = make(chan int, 100)  // Buffered channel
-	resultChannel = make(chan int, 100) // Buffered channel
-	controlChannel = make(chan bool)    // Unbuffered control channel
-	
-	// WaitGroup for coordination
-	wg sync.WaitGroup
 )
 
+// DemoConfig shapes the lock demos' contention. Each worker sleeps a random
+// duration below each maximum; zero skips that sleep.
+type DemoConfig struct {
+	PreLockSleepMax time.Duration // Writers' work before taking the lock
+	HoldSleepMax    time.Duration // Writers' work while holding the lock
+	ReadSleepMax    time.Duration // Readers' work before taking the lock
+	Keys            int           // Distinct data keys the workers share; zero gives each writer its own
+}
+
+// Contention used by the lock demos unless a request overrides it
+var defaultDemoConfig = DemoConfig{
+	PreLockSleepMax: 5 * time.Millisecond,
+	HoldSleepMax:    10 * time.Millisecond,
+	ReadSleepMax:    3 * time.Millisecond,
+}
+
+// Upper bound for the lock demos' sleep parameters in milliseconds
+const maxDemoSleepMs = 1000
+
+// sleep is time.Sleep, swapped out by tests to record the demos' random sleeps
+var sleep = time.Sleep
+
+// sleepUpTo sleeps for a random duration below max, drawn from rng or the
+// global source when rng is nil, or not at all when max is zero
+func sleepUpTo(rng *rand.Rand, max time.Duration) {
+	if max > 0 {
+		sleep(time.Duration(randInt63n(rng, int64(max))))
+	}
+}
+
+// workerKey is the data key written by worker id
+func workerKey(id int) string {
+	return fmt.Sprintf("worker-%d", id)
+}
+
 // Write to the shared resource with a regular mutex (high contention)
-func writeWithMutex(id int, iterations int) {
+func writeWithMutex(ctx context.Context, wg *sync.WaitGroup, completed *int64, cfg DemoConfig, timing *lockTiming, waits *roleWaits, results chan<- workerStats, id int, iterations int) {
 	defer wg.Done()
+	stats := workerStats{Worker: id, Role: "writer"}
+	defer func() { results <- stats }()
+	rng := workerRand(ctx)
 	
 	for i := 0; i < iterations; i++ {
+		// Stop early once the run is cancelled
+		if ctx.Err() != nil {
+			return
+		}
+		
 		// Simulate some work before acquiring the lock
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(5)))
+		sleepUpTo(rng, cfg.PreLockSleepMax)
 		
+		waitStart := time.Now()
 		basicResource.mutex.Lock()
+		acquired := time.Now()
 		// Critical section - intentionally sleep while holding the lock to create contention
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(10)))
+		sleepUpTo(rng, cfg.HoldSleepMax)
 		
 		// Update data
-		key := fmt.Sprintf("worker-%d", id)
-		basicResource.data[key] = basicResource.data[key] + 1
+		key := cfg.writeKey(id)
+		value, exists := basicResource.data[key]
+		if !exists {
+			stats.MapGrowthBytes += mapEntryBytes(key)
+		}
+		basicResource.data[key] = value + 1
 		basicResource.counter++
 		
+		held := time.Since(acquired)
 		basicResource.mutex.Unlock()
+		timing.record(acquired.Sub(waitStart), held)
+		waits.writer(acquired.Sub(waitStart))
+		stats.record(acquired.Sub(waitStart))
+		atomic.AddInt64(completed, 1)
 	}
 }
 
 // Read from the shared resource with a regular mutex (high contention)
-func readWithMutex(id int, iterations int) {
+func readWithMutex(ctx context.Context, wg *sync.WaitGroup, completed *int64, cfg DemoConfig, waits *roleWaits, results chan<- workerStats, id int, iterations int) {
 	defer wg.Done()
+	stats := workerStats{Worker: id, Role: "reader"}
+	defer func() { results <- stats }()
+	rng := workerRand(ctx)
 	
 	for i := 0; i < iterations; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		
 		// Simulate some work before acquiring the lock
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(3)))
+		sleepUpTo(rng, cfg.ReadSleepMax)
 		
+		waitStart := time.Now()
 		basicResource.mutex.Lock()
+		wait := time.Since(waitStart)
+		waits.reader(wait)
 		// Just read the data
-		key := fmt.Sprintf("worker-%d", id % 5) // Read from a limited set of keys
+		key := cfg.readKey(id)
 		_ = basicResource.data[key]
 		_ = basicResource.counter
 		
 		basicResource.mutex.Unlock()
+		stats.record(wait)
+		atomic.AddInt64(completed, 1)
 	}
 }
 
 // Write to the shared resource with a RWMutex (lower contention for readers)
-func writeWithRWMutex(id int, iterations int) {
+func writeWithRWMutex(ctx context.Context, wg *sync.WaitGroup, completed *int64, cfg DemoConfig, timing *lockTiming, id int, iterations int) {
 	defer wg.Done()
+	rng := workerRand(ctx)
 	
 	for i := 0; i < iterations; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		
 		// Simulate some work before acquiring the lock
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(5)))
+		sleepUpTo(rng, cfg.PreLockSleepMax)
 		
+		waitStart := time.Now()
 		rwResource.rwMutex.Lock()
+		acquired := time.Now()
 		// Critical section - intentionally sleep while holding the lock to create contention
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(10)))
+		sleepUpTo(rng, cfg.HoldSleepMax)
 		
 		// Update data
-		key := fmt.Sprintf("worker-%d", id)
+		key := cfg.writeKey(id)
 		rwResource.data[key] = rwResource.data[key] + 1
 		rwResource.counter++
 		
+		held := time.Since(acquired)
 		rwResource.rwMutex.Unlock()
+		timing.record(acquired.Sub(waitStart), held)
+		atomic.AddInt64(completed, 1)
 	}
 }
 
 // Read from the shared resource with a RWMutex (lower contention)
-func readWithRWMutex(id int, iterations int) {
+func readWithRWMutex(ctx context.Context, wg *sync.WaitGroup, completed *int64, cfg DemoConfig, id int, iterations int) {
 	defer wg.Done()
+	rng := workerRand(ctx)
 	
 	for i := 0; i < iterations; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		
 		// Simulate some work before acquiring the lock
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(3)))
+		sleepUpTo(rng, cfg.ReadSleepMax)
 		
 		rwResource.rwMutex.RLock() // Note: RLock for reading
 		// Just read the data
-		key := fmt.Sprintf("worker-%d", id % 5) // Read from a limited set of keys
+		key := cfg.readKey(id)
 		_ = rwResource.data[key]
 		_ = rwResource.counter
 		
 		rwResource.rwMutex.RUnlock()
+		atomic.AddInt64(completed, 1)
 	}
 }
 
-// Worker that produces work items
-func producer(numItems int) {
+// Upper bounds for the channel demo workers' simulated work per item
+const (
+	produceSleepMax = 10 * time.Millisecond
+	consumeSleepMax = 20 * time.Millisecond
+)
+
+// The channel demo's workers. Tests replace them to drive the demo without
+// the default workers' simulated work.
+var (
+	producer = produceItems
+	consumer = consumeItems
+)
+
+// Worker that produces work items until done or ctx is cancelled
+func produceItems(ctx context.Context, wg *sync.WaitGroup, work chan<- int, numItems int) {
 	defer wg.Done()
+	metrics := channelMetricsFrom(ctx)
+	rng := workerRand(ctx)
 	
 	for i := 0; i < numItems; i++ {
+		// A send can still win the select below after cancellation
+		if ctx.Err() != nil {
+			return
+		}
+		
 		// Create a work item
-		item := rand.Intn(100)
+		item := randIntn(rng, 100)
 		
 		// Try to send it to the channel - this will block if channel is full
+		sendStart := time.Now()
 		select {
-		case workChannel <- item:
+		case work <- item:
 			// Successfully sent
-			fmt.Printf("Produced: %d\n", item)
-		case <-controlChannel:
+			metrics.blocked(time.Since(sendStart))
+			metrics.sent(len(work))
+			slog.DebugContext(ctx, "Produced item", "item", item)
+		case <-ctx.Done():
 			// Received shutdown signal
-			fmt.Println("Producer received shutdown signal")
+			metrics.blocked(time.Since(sendStart))
+			slog.DebugContext(ctx, "Producer received shutdown signal")
 			return
 		}
 		
 		// Simulate variable production rate
-		time.Sleep(time.Millisecond * time.Duration(rand.Intn(10)))
+		sleepUpTo(rng, produceSleepMax)
+	}
+}
+
+// Worker that consumes work items until the work channel is closed or ctx is
+// cancelled
+func consumeItems(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int) {
+	consumeItemsFor(ctx, wg, id, work, results, consumeSleepMax)
+}
+
+// slowConsumer returns a consumer that works up to sleepMax on each item
+// instead of consumeSleepMax, to let the work channel fill up
+func slowConsumer(sleepMax time.Duration) func(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int) {
+	return func(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int) {
+		consumeItemsFor(ctx, wg, id, work, results, sleepMax)
 	}
 }
 
-// Worker that consumes work items
-func consumer(id int) {
+// consumeItems with up to sleepMax of simulated work per item
+func consumeItemsFor(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int, sleepMax time.Duration) {
 	defer wg.Done()
+	metrics := channelMetricsFrom(ctx)
+	rng := workerRand(ctx)
 	
 	for {
+		if ctx.Err() != nil {
+			slog.DebugContext(ctx, "Consumer received shutdown signal", "consumer", id)
+			return
+		}
+		
 		// Try to receive work - this will block if channel is empty
+		waitStart := time.Now()
 		select {
-		case item, ok := <-workChannel:
+		case item, ok := <-work:
+			metrics.idle(time.Since(waitStart))
 			if !ok {
 				// Channel closed
-				fmt.Printf("Consumer %d: channel closed\n", id)
+				slog.DebugContext(ctx, "Work channel closed", "consumer", id)
 				return
 			}
+			metrics.received()
 			
 			// Process the item (simulated work)
-			time.Sleep(time.Millisecond * time.Duration(rand.Intn(20)))
+			sleepUpTo(rng, sleepMax)
 			result := item * 2
 			
-			// Send result
-			resultChannel <- result
-			fmt.Printf("Consumer %d: processed %d -> %d\n", id, item, result)
+			// Send result - this will block if the result channel is full
+			select {
+			case results <- result:
+				slog.DebugContext(ctx, "Consumed item", "consumer", id, "item", item, "result", result)
+			case <-ctx.Done():
+				slog.DebugContext(ctx, "Consumer received shutdown signal", "consumer", id)
+				return
+			}
 			
-		case <-controlChannel:
+		case <-ctx.Done():
 			// Received shutdown signal
-			fmt.Printf("Consumer %d received shutdown signal\n", id)
+			metrics.idle(time.Since(waitStart))
+			slog.DebugContext(ctx, "Consumer received shutdown signal", "consumer", id)
 			return
 		}
 	}
 }
 
-// Function that might deadlock (for demonstration)
-func potentialDeadlock() {
-	var mutex1, mutex2 sync.Mutex
+// Run mutex contention demo until done or ctx is cancelled, returning the
+// final counter value, the number of worker iterations completed, how long
+// the writers waited for and held the lock, how long readers and writers
+// each waited for it and what each worker got done, ordered by worker
+func runMutexDemo(ctx context.Context, cfg DemoConfig, numWorkers, iterations int) (counter int, completed int64, timing lockTiming, waits roleWaitStats, workers []workerStats) {
+	slog.InfoContext(ctx, "Starting mutex demo", "workers", numWorkers, "iterations", iterations)
 	
-	// Create two goroutines that acquire locks in opposite order
-	go func() {
-		for {
-			mutex1.Lock()
-			time.Sleep(time.Millisecond)
-			mutex2.Lock()
-			
-			// Critical section
-			time.Sleep(time.Millisecond * 10)
-			
-			mutex2.Unlock()
-			mutex1.Unlock()
-			
-			time.Sleep(time.Millisecond * 100)
-		}
-	}()
+	// Each run has its own WaitGroup so concurrent runs don't wait on each other
+	var wg sync.WaitGroup
 	
-	go func() {
-		for {
-			// This creates a potential deadlock - acquiring locks in opposite order
-			mutex2.Lock()
-			time.Sleep(time.Millisecond)
-			mutex1.Lock()
-			
-			// Critical section
-			time.Sleep(time.Millisecond * 10)
-			
-			mutex1.Unlock()
-			mutex2.Unlock()
-			
-			time.Sleep(time.Millisecond * 100)
-		}
-	}()
-}
-
-// Run mutex contention demo
-func runMutexDemo(numWorkers, iterations int) {
-	fmt.Printf("Starting mutex demo with %d workers, %d iterations each\n", numWorkers, iterations)
+	// One lock timing accumulator per worker, merged once they are done
+	timings := make([]lockTiming, numWorkers)
+	
+	// Wait times per role, shared by all workers
+	var roles roleWaits
+	
+	// Each worker sends its own stats when it exits, so sends never block
+	results := make(chan workerStats, numWorkers)
 	
 	// Start a mix of readers and writers
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		if i % 3 == 0 {
-			// 1/3 of workers write
-			go writeWithMutex(i, iterations)
-		} else {
-			// 2/3 of workers read
-			go readWithMutex(i, iterations)
+	withDemoLabel(ctx, "mutex", func(ctx context.Context) {
+		for i := 0; i < numWorkers; i++ {
+			wg.Add(1)
+			withWorkerLabel(ctx, i, func(ctx context.Context) {
+				if i % 3 == 0 {
+					// 1/3 of workers write
+					go writeWithMutex(ctx, &wg, &completed, cfg, &timings[i], &roles, results, i, iterations)
+				} else {
+					// 2/3 of workers read
+					go readWithMutex(ctx, &wg, &completed, cfg, &roles, results, i, iterations)
+				}
+			})
 		}
-	}
+	})
 	
 	// Wait for all workers to finish
 	wg.Wait()
+	for _, t := range timings {
+		timing.merge(t)
+	}
+	close(results)
+	for stats := range results {
+		workers = append(workers, stats)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Worker < workers[j].Worker })
+	
+	// Other runs may still be writing, so read the counter under the lock
+	basicResource.mutex.Lock()
+	counter = basicResource.counter
+	basicResource.mutex.Unlock()
+	
+	waits = roles.stats()
 	
-	fmt.Println("Mutex demo completed")
-	fmt.Printf("Final counter value: %d\n", basicResource.counter)
+	slog.InfoContext(ctx, "Mutex demo completed", "counter", counter,
+		"writerWait", timing.Wait, "writerHold", timing.Hold,
+		"readerWait", waits.ReaderWait, "readerWaitPerLock", waits.avgReaderWait(),
+		"writerWaitTotal", waits.WriterWait, "writerWaitPerLock", waits.avgWriterWait())
+	return counter, completed, timing, waits, workers
 }
 
-// Run RWMutex contention demo
-func runRWMutexDemo(numWorkers, iterations int) {
-	fmt.Printf("Starting RWMutex demo with %d workers, %d iterations each\n", numWorkers, iterations)
+// Run RWMutex contention demo until done or ctx is cancelled, returning the
+// final counter value, the number of worker iterations completed and how long
+// the writers waited for and held the lock
+func runRWMutexDemo(ctx context.Context, cfg DemoConfig, numWorkers, iterations int) (counter int, completed int64, timing lockTiming) {
+	slog.InfoContext(ctx, "Starting RWMutex demo", "workers", numWorkers, "iterations", iterations)
+	
+	var wg sync.WaitGroup
+	
+	// One lock timing accumulator per worker, merged once they are done
+	timings := make([]lockTiming, numWorkers)
 	
 	// Start a mix of readers and writers
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		if i % 5 == 0 {
-			// 1/5 of workers write
-			go writeWithRWMutex(i, iterations)
-		} else {
-			// 4/5 of workers read
-			go readWithRWMutex(i, iterations)
+	withDemoLabel(ctx, "rwmutex", func(ctx context.Context) {
+		for i := 0; i < numWorkers; i++ {
+			wg.Add(1)
+			withWorkerLabel(ctx, i, func(ctx context.Context) {
+				if i % 5 == 0 {
+					// 1/5 of workers write
+					go writeWithRWMutex(ctx, &wg, &completed, cfg, &timings[i], i, iterations)
+				} else {
+					// 4/5 of workers read
+					go readWithRWMutex(ctx, &wg, &completed, cfg, i, iterations)
+				}
+			})
 		}
-	}
+	})
 	
 	// Wait for all workers to finish
 	wg.Wait()
+	for _, t := range timings {
+		timing.merge(t)
+	}
+	
+	rwResource.rwMutex.RLock()
+	counter = rwResource.counter
+	rwResource.rwMutex.RUnlock()
 	
-	fmt.Println("RWMutex demo completed")
-	fmt.Printf("Final counter value: %d\n", rwResource.counter)
+	slog.InfoContext(ctx, "RWMutex demo completed", "counter", counter,
+		"writerWait", timing.Wait, "writerHold", timing.Hold)
+	return counter, completed, timing
 }
 
-// Run channel blocking demo
-func runChannelDemo(numProducers, numConsumers, itemsPerProducer int) {
-	fmt.Printf("Starting channel demo with %d producers and %d consumers\n", 
-		numProducers, numConsumers)
-	
-	// Start producers
-	for i := 0; i < numProducers; i++ {
-		wg.Add(1)
-		go producer(itemsPerProducer)
+// Capacity of the channel demo's work and result channels unless a request
+// overrides it
+const defaultChannelBuffer = 100
+
+// Run channel blocking demo until every item is consumed or ctx is cancelled,
+// with the default channel buffers. Returns the items produced and consumed, the work items left
+// unprocessed, the results collected and how much the producers and consumers
+// waited on each other.
+func runChannelDemo(ctx context.Context, numProducers, numConsumers, itemsPerProducer int) channelStats {
+	return runChannelDemoBuffered(ctx, numProducers, numConsumers, itemsPerProducer, defaultChannelBuffer, defaultChannelBuffer, 0)
+}
+
+// Run channel blocking demo with work and result channels of the given
+// capacities; 0 makes a channel unbuffered, so every send waits for a receiver.
+// A pool above 0 runs the consumers as a fixed pool of at most that many
+// goroutines reading from the work channel, rather than one goroutine per
+// consumer, so thousands of consumers don't flood the scheduler.
+func runChannelDemoBuffered(ctx context.Context, numProducers, numConsumers, itemsPerProducer, workBuf, resultBuf, pool int) channelStats {
+	// Read the workers once, before any start, so a test swapping them back
+	// once its items are through can't race with the later spawns
+	return runChannelWorkers(ctx, channelWorkers{"channel", producer, consumer},
+		numProducers, numConsumers, itemsPerProducer, workBuf, resultBuf, pool)
+}
+
+// channelWorkers are the producer and consumer goroutines a channel demo run
+// starts and the demo label they carry
+type channelWorkers struct {
+	demo    string
+	produce func(ctx context.Context, wg *sync.WaitGroup, work chan<- int, numItems int)
+	consume func(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int)
+}
+
+// Run the channel demo's producers and consumers as runChannelDemoBuffered
+// describes, with the given workers
+func runChannelWorkers(ctx context.Context, workers channelWorkers, numProducers, numConsumers, itemsPerProducer, workBuf, resultBuf, pool int) channelStats {
+	consumerGoroutines := numConsumers
+	if pool > 0 && pool < numConsumers {
+		consumerGoroutines = pool
 	}
+	slog.InfoContext(ctx, "Starting channel demo", "demo", workers.demo, "producers", numProducers, "consumers", numConsumers,
+		"consumerGoroutines", consumerGoroutines, "workBuffer", workBuf, "resultBuffer", resultBuf)
 	
-	// Start consumers
-	for i := 0; i < numConsumers; i++ {
-		wg.Add(1)
-		go consumer(i)
-	}
+	start := time.Now()
 	
-	// Wait for all producers to finish
-	time.Sleep(time.Second * 5)
+	// Producers and consumers are waited for separately so the work channel
+	// is only closed once nothing can send on it any more
+	var producers, consumers sync.WaitGroup
 	
-	// Signal all workers to stop
-	for i := 0; i < numProducers + numConsumers; i++ {
-		controlChannel <- true
-	}
+	// Created per run since they are closed at the end
+	workChannel := make(chan int, workBuf)
+	resultChannel := make(chan int, resultBuf)
 	
-	// Wait for all workers to finish
-	wg.Wait()
+	// Backpressure counters the workers find in their context
+	var metrics channelMetrics
+	ctx = withChannelMetrics(ctx, &metrics)
+	
+	produce, consume := workers.produce, workers.consume
+	
+	withDemoLabel(ctx, workers.demo, func(ctx context.Context) {
+		// Start producers
+		for i := 0; i < numProducers; i++ {
+			producers.Add(1)
+			withWorkerLabel(ctx, i, func(ctx context.Context) {
+				go produce(ctx, &producers, workChannel, itemsPerProducer)
+			})
+		}
+		
+		// Start consumers, numbered after the producers
+		for i := 0; i < consumerGoroutines; i++ {
+			consumers.Add(1)
+			withWorkerLabel(ctx, numProducers+i, func(ctx context.Context) {
+				go consume(ctx, &consumers, i, workChannel, resultChannel)
+			})
+		}
+	})
 	
-	// Count remaining items
+	// Collect results as they arrive so consumers only block on a full
+	// result channel for as long as collecting takes
+	var results int
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for range resultChannel {
+			results++
+		}
+	}()
+	
+	// Producers return once they have sent every item or ctx is cancelled.
+	// Closing the work channel then lets the consumers drain it and exit,
+	// unless ctx is cancelled first.
+	producers.Wait()
 	close(workChannel)
+	consumers.Wait()
 	close(resultChannel)
+	<-collected
 	
-	var remainingWork, results int
+	// Count work items left behind by a cancelled run
+	var remainingWork int
 	for range workChannel {
 		remainingWork++
 	}
-	for range resultChannel {
-		results++
+	stats := metrics.stats(remainingWork, results)
+	stats.ConsumerGoroutines = int64(consumerGoroutines)
+	stats.Elapsed = time.Since(start)
+	
+	slog.InfoContext(ctx, "Channel demo completed", "produced", stats.Produced, "consumed", stats.Consumed,
+		"remainingWork", remainingWork, "results", results, "elapsed", stats.Elapsed,
+		"producerBlocked", stats.ProducerBlocked, "consumerIdle", stats.ConsumerIdle, "peakOccupancy", stats.PeakOccupancy)
+	return stats
+}
+
+// Upper bound for demo size parameters so a typo can't spawn millions of goroutines
+const maxDemoParam = 10000
+
+// queryInt parses an integer query parameter within [min, max], returning def when absent
+func queryInt(r *http.Request, name string, def, min, max int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter: %q is not a number", name, value)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("invalid %s parameter: must be between %d and %d", name, min, max)
+	}
+	return n, nil
+}
+
+// queryFloat parses a float query parameter within [min, max], returning def when absent
+func queryFloat(r *http.Request, name string, def, min, max float64) (float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter: %q is not a number", name, value)
+	}
+	if f < min || f > max || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid %s parameter: must be between %g and %g", name, min, max)
+	}
+	return f, nil
+}
+
+// queryDuration parses a duration query parameter such as "2s" within
+// [min, max], returning def when absent
+func queryDuration(r *http.Request, name string, def, min, max time.Duration) (time.Duration, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter: %q is not a duration", name, value)
+	}
+	if d < min || d > max {
+		return 0, fmt.Errorf("invalid %s parameter: must be between %v and %v", name, min, max)
+	}
+	return d, nil
+}
+
+// queryBool parses an optional boolean query parameter, defaulting to false
+func queryBool(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
 	}
 	
-	fmt.Println("Channel demo completed")
-	fmt.Printf("Remaining work items: %d\n", remainingWork)
-	fmt.Printf("Results collected: %d\n", results)
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: %q is not a boolean", name, value)
+	}
+	return b, nil
+}
+
+// workerParams reads the workers and iterations parameters shared by the lock
+// demos, defaulting to defaultWorkers workers of 100 iterations each
+func workerParams(r *http.Request, defaultWorkers int) (numWorkers, iterations int, err error) {
+	numWorkers, err = queryInt(r, "workers", defaultWorkers, 1, maxDemoParam)
+	if err != nil {
+		return 0, 0, err
+	}
+	iterations, err = queryInt(r, "iterations", 100, 1, maxDemoParam)
+	if err != nil {
+		return 0, 0, err
+	}
+	return numWorkers, iterations, nil
+}
+
+// channelBufferParams reads the channel demo's channel capacities (workbuf
+// and resultbuf), where 0 means unbuffered
+func channelBufferParams(r *http.Request) (workBuf, resultBuf int, err error) {
+	workBuf, err = queryInt(r, "workbuf", defaultChannelBuffer, 0, maxDemoParam)
+	if err != nil {
+		return 0, 0, err
+	}
+	resultBuf, err = queryInt(r, "resultbuf", defaultChannelBuffer, 0, maxDemoParam)
+	if err != nil {
+		return 0, 0, err
+	}
+	return workBuf, resultBuf, nil
+}
+
+// demoConfigParams reads the lock demos' sleep parameters (preSleepMs, holdMs
+// and readMs), defaulting to defaultDemoConfig
+func demoConfigParams(r *http.Request) (DemoConfig, error) {
+	var cfg DemoConfig
+	for _, p := range []struct {
+		name  string
+		def   time.Duration
+		value *time.Duration
+	}{
+		{"preSleepMs", defaultDemoConfig.PreLockSleepMax, &cfg.PreLockSleepMax},
+		{"holdMs", defaultDemoConfig.HoldSleepMax, &cfg.HoldSleepMax},
+		{"readMs", defaultDemoConfig.ReadSleepMax, &cfg.ReadSleepMax},
+	} {
+		ms, err := queryInt(r, p.name, int(p.def/time.Millisecond), 0, maxDemoSleepMs)
+		if err != nil {
+			return DemoConfig{}, err
+		}
+		*p.value = time.Duration(ms) * time.Millisecond
+	}
+	return cfg, nil
+}
+
+// lockDemoParams records a lock demo's parameters for its run
+func lockDemoParams(numWorkers, iterations int, cfg DemoConfig) map[string]int {
+	params := map[string]int{
+		"workers":    numWorkers,
+		"iterations": iterations,
+		"preSleepMs": int(cfg.PreLockSleepMax / time.Millisecond),
+		"holdMs":     int(cfg.HoldSleepMax / time.Millisecond),
+		"readMs":     int(cfg.ReadSleepMax / time.Millisecond),
+	}
+	if cfg.Keys > 0 {
+		params["keys"] = cfg.Keys
+	}
+	return params
+}
+
+// mutexDemoRun returns a run of the mutex demo for the run registry
+func mutexDemoRun(cfg DemoConfig, numWorkers, iterations int, seed *int64) func(ctx context.Context) map[string]int64 {
+	return func(ctx context.Context) map[string]int64 {
+		before := basicResource.snapshotKeys(&basicResource.mutex)
+		counter, completed, timing, waits, workers := runMutexDemo(withSeed(ctx, seed), cfg, numWorkers, iterations)
+		setRunWorkers(ctx, workers)
+		setRunKeys(ctx, basicResource.topKeysSince(&basicResource.mutex, before, maxTopKeys))
+		return waits.addTo(timing.addTo(map[string]int64{"counter": int64(counter), "iterations": completed}))
+	}
+}
+
+// rwMutexDemoRun returns a run of the RWMutex demo for the run registry
+func rwMutexDemoRun(cfg DemoConfig, numWorkers, iterations int, seed *int64) func(ctx context.Context) map[string]int64 {
+	return func(ctx context.Context) map[string]int64 {
+		before := rwResource.snapshotKeys(rwResource.rwMutex.RLocker())
+		counter, completed, timing := runRWMutexDemo(withSeed(ctx, seed), cfg, numWorkers, iterations)
+		setRunKeys(ctx, rwResource.topKeysSince(rwResource.rwMutex.RLocker(), before, maxTopKeys))
+		return timing.addTo(map[string]int64{"counter": int64(counter), "iterations": completed})
+	}
+}
+
+// HTTP handler that starts the mutex contention demo
+func mutexDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, iterations, err := workerParams(r, 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg, err := demoConfigParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg.Keys, err = keysParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	id := demoRuns.launch("mutex", withSeedParam(lockDemoParams(numWorkers, iterations, cfg), seed),
+		mutexDemoRun(cfg, numWorkers, iterations, seed))
+	startedRun(w, id)
+	
+	fmt.Fprintf(w, "Started mutex contention demo with %d workers, %d iterations each (mutex profile fraction %d)\n", 
+		numWorkers, iterations, profrate.MutexFraction())
+}
+
+// HTTP handler that starts the RWMutex contention demo
+func rwMutexDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, iterations, err := workerParams(r, 20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg, err := demoConfigParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg.Keys, err = keysParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	id := demoRuns.launch("rwmutex", withSeedParam(lockDemoParams(numWorkers, iterations, cfg), seed),
+		rwMutexDemoRun(cfg, numWorkers, iterations, seed))
+	startedRun(w, id)
+	
+	fmt.Fprintf(w, "Started RWMutex contention demo with %d workers, %d iterations each (mutex profile fraction %d)\n", 
+		numWorkers, iterations, profrate.MutexFraction())
+}
+
+// How long the channel demo started over HTTP may run before it is cut short
+const channelDemoDuration = 5 * time.Second
+
+// HTTP handler that starts the channel blocking demo
+func channelDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numProducers, err := queryInt(r, "producers", 3, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	numConsumers, err := queryInt(r, "consumers", 5, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	itemsPerProducer, err := queryInt(r, "items", 50, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	workBuf, resultBuf, err := channelBufferParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pool, err := queryInt(r, "pool", 0, 0, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wait, err := queryBool(r, "sync")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	
+	params := map[string]int{"producers": numProducers, "consumers": numConsumers, "items": itemsPerProducer,
+		"workbuf": workBuf, "resultbuf": resultBuf, "pool": pool}
+	id := demoRuns.launch("channel", withSeedParam(params, seed), func(ctx context.Context) map[string]int64 {
+		ctx, cancel := context.WithTimeout(withSeed(ctx, seed), channelDemoDuration)
+		defer cancel()
+		return runChannelDemoBuffered(ctx, numProducers, numConsumers, itemsPerProducer, workBuf, resultBuf, pool).result()
+	})
+	
+	// With sync=true the response is the finished run, as /runs/{id} would return it
+	if wait {
+		run, ok := demoRuns.wait(r.Context(), id)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Run %d did not finish", id), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/runs/%d", id))
+		writeJSON(w, run)
+		return
+	}
+	startedRun(w, id)
+	
+	fmt.Fprintf(w, "Started channel demo with %d producers and %d consumers, %d items each\n", 
+		numProducers, numConsumers, itemsPerProducer)
+}
+
+// Response for settings changed at runtime, such as /runtime/gomaxprocs
+type rateChange struct {
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// envInt reads an integer setting from the environment, falling back to def
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Ignoring invalid setting", "name", name, "value", value, "default", def)
+		return def
+	}
+	return n
 }
 
 func main() {
-	// Seed random number generator
-	rand.Seed(time.Now().UnixNano())
+	blockRate := flag.Int("block-profile-rate", envInt("BLOCK_PROFILE_RATE", 1),
+		"block profile rate in nanoseconds (1 records every blocking event, 0 disables)")
+	mutexFraction := flag.Int("mutex-profile-fraction", envInt("MUTEX_PROFILE_FRACTION", 5),
+		"report 1/N of mutex contention events (0 disables mutex profiling)")
+	maxProcs := flag.Int("max-gomaxprocs", envInt("MAX_GOMAXPROCS", 4*runtime.NumCPU()),
+		"largest GOMAXPROCS value accepted by /runtime/gomaxprocs")
+	deadlockThreshold := flag.Duration("deadlock-threshold", 10*time.Second,
+		"report goroutines blocked on a lock for longer than this at /deadlock-report")
+	verbose := flag.Bool("verbose", false,
+		"log every item the channel demo produces and consumes (same as "+logging.EnvVar+"=debug)")
+	runHistory := flag.String("run-history", "demo_runs.jsonl",
+		"file finished demo runs are appended to and loaded from at startup (empty keeps them in memory only)")
+	ioDir := flag.String("io-dir", os.TempDir(),
+		"directory /io-block-demo creates its temporary files in")
+	seedValue := randseed.Flag()
+	profiles := selfprofile.Flags()
+	flag.Parse()
+	
+	// The runtime treats a negative block rate as 0 and ignores a negative
+	// mutex fraction, so neither would take effect as given
+	if *blockRate < 0 || *mutexFraction < 0 {
+		fmt.Fprintln(os.Stderr, "-block-profile-rate and -mutex-profile-fraction must be 0 or more")
+		os.Exit(2)
+	}
+	
+	// Per-item channel demo logs are at debug level, off by default since
+	// formatting them shows up in CPU profiles
+	logLevel := os.Getenv(logging.EnvVar)
+	if *verbose {
+		logLevel = "debug"
+	}
+	if _, err := logging.Setup(logLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	
+	// Seed random number generator. A fixed seed repeats the global source's
+	// draws; ?seed=N on a demo gives its workers their own sources instead.
+	seed, fixed, err := randseed.Apply(*seedValue)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	fmt.Println(randseed.Describe(seed, fixed))
+	
+	// Coordinates CPU profile captures and their sampling rate, including
+	// the -cpuprofile one
+	cpuProfiler := profrate.NewCPUProfiler()
+	
+	// Profile the whole run to files when asked to
+	stopProfiles, err := profiles.Start(cpuProfiler)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	
+	// Enable block profiling before any demo goroutines start so
+	// /debug/pprof/block captures the channel and mutex waits
+	profrate.SetBlockRate(*blockRate)
+	
+	// Likewise for mutex profiling, so /debug/pprof/mutex shows the
+	// contention on basicResource and rwResource
+	profrate.SetMutexFraction(*mutexFraction)
+	
+	// Log the rates read back from the runtime; 0 means disabled
+	rates := profrate.Current()
+	slog.Info("Profiling rates set", "blockRate", rates.Block, "mutexFraction", rates.Mutex)
+	
+	// Keep /runs across restarts so old profiles can be matched to their runs
+	if *runHistory != "" {
+		loaded, skipped, err := demoRuns.loadHistory(*runHistory)
+		if err != nil {
+			slog.Error("Failed to load run history", "file", *runHistory, "err", err)
+		} else {
+			slog.Info("Loaded run history", "file", *runHistory, "runs", loaded, "skippedLines", skipped)
+		}
+	}
 	
 	// Create HTTP server for pprof
 	mux := http.NewServeMux()
@@ -314,71 +927,144 @@ func main() {
 	// Register pprof handlers
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/profile", cpuProfiler.ProfileHandler)
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	
+	// CPU profile sampling rate, applied to the next capture
+	mux.HandleFunc("/config/cpu-hz", cpuProfiler.ConfigHandler)
+	
 	// Register demo endpoints
-	mux.HandleFunc("/mutex-demo", func(w http.ResponseWriter, r *http.Request) {
-		numWorkers := 10   // Default
-		iterations := 100  // Default
-		
-		go runMutexDemo(numWorkers, iterations)
-		
-		fmt.Fprintf(w, "Started mutex contention demo with %d workers, %d iterations each\n", 
-			numWorkers, iterations)
-	})
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/rwmutex-demo", rwMutexDemoHandler)
+	mux.HandleFunc("/atomic-demo", atomicDemoHandler)
+	mux.HandleFunc("/channel-demo", channelDemoHandler)
+	mux.HandleFunc("/semaphore-demo", semaphoreDemoHandler)
+	mux.HandleFunc("/cond-demo", condDemoHandler)
+	mux.HandleFunc("/workerpool-demo", workerPoolDemoHandler)
+	mux.HandleFunc("/starvation-demo", starvationDemoHandler)
+	mux.HandleFunc("/backpressure-demo", backpressureDemoHandler)
+	mux.HandleFunc("/syncmap-demo", syncMapDemoHandler)
+	mux.HandleFunc("/sharded-demo", shardedDemoHandler)
+	mux.HandleFunc("/nested-lock-demo", nestedLockDemoHandler)
+	mux.HandleFunc("/pipeline-demo", pipelineDemoHandler)
+	mux.HandleFunc("/cancel-demo", cancelDemoHandler)
+	mux.HandleFunc("/cancel-demo/stop", cancelDemoStopHandler)
+	mux.HandleFunc("/goroutine-demo", goroutineDemoHandler)
+	mux.HandleFunc("/goroutine-leak", goroutineLeakHandler)
+	mux.HandleFunc("/goroutine-leak/stop", goroutineLeakStopHandler)
+	mux.HandleFunc("/sleepers", sleepersHandler)
+	mux.HandleFunc("/ticker-leak", tickerLeakHandler)
+	mux.HandleFunc("/ticker-leak/stop", tickerLeakStopHandler)
 	
-	mux.HandleFunc("/rwmutex-demo", func(w http.ResponseWriter, r *http.Request) {
-		numWorkers := 20   // Default
-		iterations := 100  // Default
-		
-		go runRWMutexDemo(numWorkers, iterations)
-		
-		fmt.Fprintf(w, "Started RWMutex contention demo with %d workers, %d iterations each\n", 
-			numWorkers, iterations)
-	})
+	// Workers in an errgroup, one of which fails and cancels the rest
+	mux.HandleFunc("/errgroup-demo", errGroupDemoHandler)
 	
-	mux.HandleFunc("/channel-demo", func(w http.ResponseWriter, r *http.Request) {
-		numProducers := 3    // Default
-		numConsumers := 5    // Default
-		itemsPerProducer := 50 // Default
-		
-		go runChannelDemo(numProducers, numConsumers, itemsPerProducer)
-		
-		fmt.Fprintf(w, "Started channel demo with %d producers and %d consumers\n", 
-			numProducers, numConsumers)
-	})
+	// Busy goroutines for an on-CPU profile
+	mux.HandleFunc("/cpuspin-demo", cpuSpinDemoHandler)
+	mux.HandleFunc("/timer-storm", timerStormHandler)
+	mux.HandleFunc("/io-block-demo", ioBlockDemoHandler(*ioDir))
+	
+	// The mutex demo's workload against a sync.Map, as a background run
+	mux.HandleFunc("/syncmap-resource-demo", syncMapResourceDemoHandler)
+	
+	// Execution trace of a single demo run
+	mux.HandleFunc("/trace-run", traceRunHandler)
+	
+	// Every contention demo at once, as child runs of one parent run
+	mux.HandleFunc("/stress", stressHandler)
+	
+	// Status of runs started by the mutex, rwmutex and channel demos
+	mux.HandleFunc("/runs", runsHandler(demoRuns))
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+	mux.HandleFunc("/cancel-demos", cancelDemosHandler(demoRuns))
 	
 	// Deadlock demo (potentially dangerous)
-	mux.HandleFunc("/deadlock-demo", func(w http.ResponseWriter, r *http.Request) {
-		go potentialDeadlock()
-		fmt.Fprintf(w, "Started potential deadlock demo\n")
-	})
+	mux.HandleFunc("/deadlock-demo", deadlockDemoHandler)
+	mux.HandleFunc("/deadlock-demo/status", deadlockDemoStatusHandler)
+	mux.HandleFunc("/deadlock-demo/stop", deadlockDemoStopHandler)
 	
-	// Status endpoint
-	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "Concurrency App Status\n")
-		fmt.Fprintf(w, "--------------------\n")
-		fmt.Fprintf(w, "Goroutines: %d\n", runtime.NumGoroutine())
-		
-		var m runtime.MemStats
-		runtime.ReadMemStats(&m)
-		fmt.Fprintf(w, "Alloc: %v MiB\n", m.Alloc/1024/1024)
-		fmt.Fprintf(w, "TotalAlloc: %v MiB\n", m.TotalAlloc/1024/1024)
-		fmt.Fprintf(w, "Sys: %v MiB\n", m.Sys/1024/1024)
-		fmt.Fprintf(w, "NumGC: %v\n", m.NumGC)
-	})
+	// Watch for goroutines stuck on locks, such as those left by the deadlock demo
+	watchdog := newDeadlockWatchdog(*deadlockThreshold)
+	watchdog.violations = deadlockDemoViolations
+	stopWatchdog := watchdog.start(deadlockCheckInterval)
+	mux.HandleFunc("/deadlock-report", watchdog.reportHandler)
+	
+	// Profiling settings
+	mux.HandleFunc("/profiles/summary", profileSummaryHandler)
+	mux.HandleFunc("/debug/profrate", profrate.Handler)
+	mux.HandleFunc("/runtime/gomaxprocs", gomaxprocsHandler(*maxProcs))
+	
+	// Status endpoints
+	mux.HandleFunc("/status", statusHandler)
+	mux.HandleFunc("/status.json", statusJSONHandler)
 	
 	// Start the server
 	fmt.Println("Starting concurrency demo server on :8082")
 	fmt.Println("Available endpoints:")
-	fmt.Println("  /mutex-demo - Run Mutex contention demo")
-	fmt.Println("  /rwmutex-demo - Run RWMutex contention demo")
-	fmt.Println("  /channel-demo - Run channel blocking demo")
-	fmt.Println("  /deadlock-demo - Run potential deadlock demo")
+	fmt.Println("  /mutex-demo?workers=N&iterations=N&preSleepMs=N&holdMs=N&readMs=N - Run Mutex contention demo")
+	fmt.Println("  /rwmutex-demo?workers=N&iterations=N&preSleepMs=N&holdMs=N&readMs=N - Run RWMutex contention demo")
+	fmt.Println("  /atomic-demo?workers=N&iterations=N - Run lock-free atomic counter baseline")
+	fmt.Println("  /channel-demo?producers=N&consumers=N&items=N&workbuf=N&resultbuf=N&pool=N&sync=true - Run channel blocking demo (buffer 0 is unbuffered, pool caps the consumer goroutines, sync returns the finished run)")
+	fmt.Println("  /semaphore-demo?workers=N&permits=N&iterations=N&sync=B - Run semaphore contention demo, reporting permit wait")
+	fmt.Println("  /cond-demo?producers=N&consumers=N&items=N - Run sync.Cond bounded queue demo")
+	fmt.Println("  /workerpool-demo?workers=N&queue=N&jobs=N - Run bounded worker pool demo (JSON summary)")
+	fmt.Println("  /starvation-demo?senders=N&duration=D - Run unbuffered channel starvation demo with one slow receiver (JSON report)")
+	fmt.Println("  /backpressure-demo?producers=N&consumers=N&items=N&buffer=N&consumeMs=N - Overwhelm slow consumers so producers block on a full channel (JSON report)")
+	fmt.Println("  /syncmap-demo?workers=N&iterations=N&readRatio=F - Compare sync.Map with a mutex-guarded map (JSON report)")
+	fmt.Println("  /sharded-demo?shards=N&workers=N&iterations=N - Run the mutex demo workload against a sharded resource (JSON report)")
+	fmt.Println("  /nested-lock-demo?nested=N&bOnly=N&iterations=N&holdMs=N - Take lock B while holding A next to workers using only B (JSON report)")
+	fmt.Println("  /pipeline-demo?items=N&generators=N&transformers=N&aggregators=N - Run three-stage pipeline demo (JSON report)")
+	fmt.Println("  /cancel-demo?parents=N&children=N&grandchildren=N - Start a goroutine tree sharing one context (stop with /cancel-demo/stop)")
+	fmt.Println("  /goroutine-demo?count=N&seconds=N - Park N goroutines for a while (see /debug/pprof/goroutine)")
+	fmt.Println("  /goroutine-leak?count=N - Leak N more goroutines (stop with /goroutine-leak/stop)")
+	fmt.Println("  /sleepers?count=N&seconds=S - Start up to 200000 goroutines sleeping at a few call sites, for goroutine profiles (JSON)")
+	fmt.Println("  /ticker-leak?count=N&interval_ms=N - Leak N more running tickers (stop with /ticker-leak/stop)")
+	fmt.Println("  /deadlock-demo?force=B&detect=B - Run potential deadlock demo (force always deadlocks; detect reports lock order violations, also at /deadlock-report)")
+	fmt.Println("  /deadlock-demo/status - Lock pairs taken by each deadlock demo goroutine and whether they have stalled (JSON)")
+	fmt.Println("  /deadlock-demo/stop - Stop the deadlock demo, even if it is deadlocked")
+	fmt.Println("  /deadlock-report - Goroutines blocked on a lock longer than -deadlock-threshold (JSON)")
+	fmt.Println("  /profiles/summary - Top 20 block and mutex contention sites by delay (JSON)")
+	fmt.Println("  /debug/profrate - GET or POST block=N and/or mutex=N to change both profiling rates")
+	fmt.Println("  /runtime/gomaxprocs - GET or POST ?n=N to change GOMAXPROCS (up to -max-gomaxprocs)")
+	fmt.Println("  /config/cpu-hz - GET or POST ?hz=N to change the CPU profile sampling rate")
+	fmt.Println("  /errgroup-demo?workers=N&iterations=N&failAt=N - Run workers in an errgroup where worker failAt fails halfway")
+	fmt.Println("  /cpuspin-demo?goroutines=N&seconds=N - Busy-loop N goroutines for a while and report their iterations (JSON)")
+	fmt.Println("  /timer-storm?timers=N&windowMs=N - Fire N time.AfterFunc callbacks spread over a window and report how many ran late (JSON)")
+	fmt.Println("  /io-block-demo?goroutines=N&chunks=N&chunkKB=N - Write and fsync chunks to per-goroutine temp files under -io-dir (JSON)")
+	fmt.Println("  /syncmap-resource-demo?workers=N&iterations=N - Run the mutex demo workload against a sync.Map resource")
+	fmt.Println("  /trace-run?demo=mutex|rwmutex|channel&seconds=N - Run one demo under an execution trace and download the trace")
+	fmt.Println("  /stress?seconds=N&cpuspin=true - Run the mutex, rwmutex and channel demos (and CPU spin) together for N seconds as one parent run")
+	fmt.Println("  /runs - List recent mutex, rwmutex and channel demo runs (JSON); GET /runs/{id} returns one run, DELETE cancels it")
+	fmt.Println("  POST /cancel-demos - Cancel every running mutex, rwmutex and channel demo run")
 	fmt.Println("  /status - View runtime stats")
+	fmt.Println("  /status.json - Runtime stats, active demo runs and profiling rates as JSON")
 	fmt.Println("  /debug/pprof/ - pprof endpoint")
 	
-	http.ListenAndServe(":8082", mux)
+	// Stop on Ctrl-C or SIGTERM, letting in-flight requests and demos finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = server.ListenAndServe(ctx, ":8082", mux, server.DefaultShutdownTimeout, stopDemos,
+		func(context.Context) { stopWatchdog() })
+	
+	// Flush the profiles even if the server failed
+	if err := stopProfiles(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// stopDemos cancels the background demos and waits for the demo runs to
+// return, or for ctx to be done
+func stopDemos(ctx context.Context) {
+	stopCancelDemo()
+	stopDeadlockDemo()
+	stopGoroutineLeak()
+	stopTickerLeak()
+	if n := demoRuns.shutdown(ctx); n > 0 {
+		slog.Warn("Shutdown interrupted demo runs", "runs", n)
+	}
 }
\ No newline at end of file
@@ -1,16 +1,33 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	netpprof "net/http/pprof"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+	
+	"pprofviz/examples/internal/analysis"
+	"pprofviz/examples/internal/logging"
+	"pprofviz/examples/internal/profrate"
+	"pprofviz/examples/internal/server"
+	"pprofviz/examples/internal/testutil"
 )
 
 func TestMutexDemo(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+	
 	// Reset the global resources
 	basicResource = &SharedResource{
 		data: make(map[string]int),
@@ -21,17 +38,14 @@ func TestMutexDemo(t *testing.T) {
 	iterations := 10
 	
 	// Run the demo
-	runMutexDemo(numWorkers, iterations)
+	runMutexDemo(context.Background(), DemoConfig{}, numWorkers, iterations)
 	
 	// Verify that the counter was incremented
 	counterVal := basicResource.counter
 	
-	// We expect writers to increment the counter (numWorkers / 3) * iterations times
-	// 1/3 of workers are writers
-	expectedWriters := numWorkers / 3
-	if expectedWriters == 0 {
-		expectedWriters = 1 // At least one writer
-	}
+	// Workers 0, 3, 6, ... write, so ceil(numWorkers / 3) writers each
+	// increment the counter iterations times
+	expectedWriters := (numWorkers + 2) / 3
 	expectedCounter := expectedWriters * iterations
 	
 	// Allow for some margin of error
@@ -48,6 +62,62 @@ func TestMutexDemo(t *testing.T) {
 	}
 }
 
+// mutexContentions counts the mutex profile's contention events so far by
+// the named functions, which record them when they unlock a contended mutex
+func mutexContentions(t *testing.T, funcs ...string) map[string]int64 {
+	t.Helper()
+	
+	var buf bytes.Buffer
+	if err := pprof.Lookup("mutex").WriteTo(&buf, 0); err != nil {
+		t.Fatalf("Failed to write mutex profile: %v", err)
+	}
+	p, err := analysis.ParseProfile(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse mutex profile: %v", err)
+	}
+	index, err := analysis.SampleIndex(p, "contentions")
+	if err != nil {
+		t.Fatalf("Unexpected mutex profile: %v", err)
+	}
+	
+	counts := make(map[string]int64)
+	for _, sample := range p.Sample {
+		for _, loc := range sample.Location {
+			for _, line := range loc.Line {
+				for _, name := range funcs {
+					if strings.HasSuffix(line.Function.Name, "."+name) {
+						counts[name] += sample.Value[index]
+					}
+				}
+			}
+		}
+	}
+	return counts
+}
+
+func TestMutexDemoMutexProfile(t *testing.T) {
+	// Sample every contention event for the duration of the test
+	previous := runtime.SetMutexProfileFraction(1)
+	defer runtime.SetMutexProfileFraction(previous)
+	
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+	
+	workers := []string{"writeWithMutex", "readWithMutex"}
+	before := mutexContentions(t, workers...)
+	runMutexDemo(context.Background(), defaultDemoConfig, 5, 10)
+	after := mutexContentions(t, workers...)
+	
+	// The contention on basicResource.mutex is attributed to the demo's
+	// writers and readers, which both unlock it
+	for _, name := range workers {
+		if after[name] <= before[name] {
+			t.Errorf("Expected mutex contention recorded in %s, got %d events before the demo and %d after", name, before[name], after[name])
+		}
+	}
+}
+
 func TestRWMutexDemo(t *testing.T) {
 	// Reset the global resources
 	rwResource = &SharedResource{
@@ -59,7 +129,7 @@ func TestRWMutexDemo(t *testing.T) {
 	iterations := 10
 	
 	// Run the demo
-	runRWMutexDemo(numWorkers, iterations)
+	runRWMutexDemo(context.Background(), DemoConfig{}, numWorkers, iterations)
 	
 	// Verify that the counter was incremented
 	counterVal := rwResource.counter
@@ -86,18 +156,57 @@ func TestRWMutexDemo(t *testing.T) {
 	}
 }
 
-func TestChannelDemoSmall(t *testing.T) {
+func TestConcurrentMutexDemos(t *testing.T) {
 	// Reset the global resources
-	workChannel = make(chan int, 100)
-	resultChannel = make(chan int, 100)
-	controlChannel = make(chan bool)
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+	rwResource = &SharedResource{
+		data: make(map[string]int),
+	}
 	
-	var wg sync.WaitGroup
+	// With a shared WaitGroup one run's Wait could return early or panic
+	// when the other reuses it
+	done := make(chan string, 2)
+	go func() {
+		runMutexDemo(context.Background(), DemoConfig{}, 6, 10)
+		done <- "mutex"
+	}()
+	go func() {
+		runRWMutexDemo(context.Background(), DemoConfig{}, 10, 10)
+		done <- "rwmutex"
+	}()
+	
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timeout waiting for concurrent demos to finish")
+		}
+	}
+	
+	// Writers are workers 0 and 3 for the mutex demo, 0 and 5 for the RWMutex demo
+	basicResource.mutex.Lock()
+	if basicResource.counter != 20 {
+		t.Errorf("Expected mutex demo counter 20, got %d", basicResource.counter)
+	}
+	basicResource.mutex.Unlock()
+	
+	rwResource.rwMutex.RLock()
+	if rwResource.counter != 20 {
+		t.Errorf("Expected RWMutex demo counter 20, got %d", rwResource.counter)
+	}
+	rwResource.rwMutex.RUnlock()
+}
+
+func TestChannelDemoSmall(t *testing.T) {
+	// Every producer and consumer exits once the demo is cancelled
+	testutil.AssertNoGoroutineLeak(t)
 	
 	// Create counter for produced items
 	var producedItems int32
 	oldProducer := producer
-	producer = func(numItems int) {
+	producer = func(ctx context.Context, wg *sync.WaitGroup, work chan<- int, numItems int) {
 		defer wg.Done()
 		
 		for i := 0; i < numItems; i++ {
@@ -106,11 +215,11 @@ func TestChannelDemoSmall(t *testing.T) {
 			
 			// Try to send it to the channel
 			select {
-			case workChannel <- item:
+			case work <- item:
 				// Successfully sent
 				atomic.AddInt32(&producedItems, 1)
 				// Don't print in test
-			case <-controlChannel:
+			case <-ctx.Done():
 				// Received shutdown signal
 				return
 			}
@@ -122,13 +231,13 @@ func TestChannelDemoSmall(t *testing.T) {
 	// Create counter for consumed items
 	var consumedItems int32
 	oldConsumer := consumer
-	consumer = func(id int) {
+	consumer = func(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int) {
 		defer wg.Done()
 		
 		for {
 			// Try to receive work
 			select {
-			case item, ok := <-workChannel:
+			case item, ok := <-work:
 				if !ok {
 					return
 				}
@@ -137,10 +246,10 @@ func TestChannelDemoSmall(t *testing.T) {
 				result := item * 2
 				
 				// Send result
-				resultChannel <- result
+				results <- result
 				atomic.AddInt32(&consumedItems, 1)
 				
-			case <-controlChannel:
+			case <-ctx.Done():
 				return
 			}
 		}
@@ -151,8 +260,11 @@ func TestChannelDemoSmall(t *testing.T) {
 	numConsumers := 3
 	itemsPerProducer := 5
 	
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
 	// Start the function under test
-	go runChannelDemo(numProducers, numConsumers, itemsPerProducer)
+	go runChannelDemo(ctx, numProducers, numConsumers, itemsPerProducer)
 	
 	// Wait for expected number of items to be processed or timeout
 	deadline := time.Now().Add(5 * time.Second)
@@ -180,6 +292,277 @@ func TestChannelDemoSmall(t *testing.T) {
 	consumer = oldConsumer
 }
 
+func TestChannelDemoBlockProfile(t *testing.T) {
+	// Record every blocking event for the duration of the test
+	profrate.SetBlockRate(1)
+	defer profrate.SetBlockRate(0)
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+	
+	// Consumers block on the empty work channel while producers start up
+	go func() {
+		runChannelDemo(ctx, 2, 3, 5)
+		close(done)
+	}()
+	
+	deadline := time.Now().Add(5 * time.Second)
+	for pprof.Lookup("block").Count() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected block profile samples after running channel demo, got none")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestChannelDemoCancel(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	
+	// Enough items that the demo can't finish before it is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runChannelDemo(ctx, 4, 2, 1000)
+		close(done)
+	}()
+	
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Channel demo did not return within 2s of cancellation")
+	}
+	
+	// Every producer and consumer has exited once runChannelDemo returns
+	if n := runtime.NumGoroutine(); n > baseline+2 {
+		t.Errorf("Expected goroutine count near %d after cancellation, got %d", baseline, n)
+	}
+}
+
+func TestChannelDemoRepeated(t *testing.T) {
+	// Each run closes only its own channels, so a second run must not panic
+	for run := 1; run <= 2; run++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		stats := runChannelDemo(ctx, 2, 3, 5)
+		cancel()
+		
+		if stats.Remaining != 0 || stats.Results != 10 {
+			t.Errorf("Run %d: expected 0 remaining and 10 results, got %d and %d", run, stats.Remaining, stats.Results)
+		}
+	}
+}
+
+func TestChannelDemoShutdown(t *testing.T) {
+	tests := []struct {
+		name                             string
+		producers, consumers, items, buf int
+	}{
+		// Producers send everything long before the consumers are done
+		{"Producers finish early", 4, 1, 5, 100},
+		{"Producers finish early, unbuffered", 4, 1, 5, 0},
+		// Most consumers never get an item and wait for the channel to close
+		{"Consumers outnumber items", 1, 10, 3, 100},
+		{"Consumers outnumber items, unbuffered", 1, 10, 3, 0},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseline := runtime.NumGoroutine()
+			
+			// The run ends once every item is consumed, well before the timeout
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			done := make(chan channelStats)
+			go func() {
+				done <- runChannelDemoBuffered(ctx, tt.producers, tt.consumers, tt.items, tt.buf, tt.buf, 0)
+			}()
+			
+			var stats channelStats
+			select {
+			case stats = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Channel demo did not finish after its producers were done")
+			}
+			
+			total := int64(tt.producers * tt.items)
+			if stats.Produced != total || stats.Consumed != total || stats.Results != total || stats.Remaining != 0 {
+				t.Errorf("Expected all %d items produced, consumed and collected, got %+v", total, stats)
+			}
+			if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+				t.Errorf("Expected every worker to exit, %d goroutines remain of %d before", n, baseline)
+			}
+		})
+	}
+}
+
+func TestChannelDemoBufferSizes(t *testing.T) {
+	// Four producers feed one slow consumer, unbuffered and with room for
+	// every item
+	blocked := make(map[int]time.Duration)
+	for _, buf := range []int{0, 1000} {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		done := make(chan channelStats)
+		go func() {
+			done <- runChannelDemoBuffered(ctx, 4, 1, 50, buf, buf, 0)
+		}()
+		
+		var stats channelStats
+		select {
+		case stats = <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Channel demo with buffers of %d deadlocked", buf)
+		}
+		cancel()
+		
+		if stats.Produced != stats.Consumed+stats.Remaining || stats.Remaining > int64(buf) {
+			t.Errorf("Buffers of %d: unexpected stats %+v", buf, stats)
+		}
+		blocked[buf] = stats.ProducerBlocked
+	}
+	
+	// Without a buffer every send waits for the consumer
+	if blocked[0] <= blocked[1000] {
+		t.Errorf("Expected producers to block longer on an unbuffered channel, got %v vs %v", blocked[0], blocked[1000])
+	}
+}
+
+func TestChannelDemoPool(t *testing.T) {
+	// Count the consumer goroutines running at once
+	var running, peak, started int64
+	oldConsumer := consumer
+	consumer = func(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int) {
+		atomic.AddInt64(&started, 1)
+		n := atomic.AddInt64(&running, 1)
+		for {
+			old := atomic.LoadInt64(&peak)
+			if n <= old || atomic.CompareAndSwapInt64(&peak, old, n) {
+				break
+			}
+		}
+		defer atomic.AddInt64(&running, -1)
+		consumeItems(ctx, wg, id, work, results)
+	}
+	defer func() { consumer = oldConsumer }()
+	
+	for _, tc := range []struct {
+		pool, goroutines int64
+	}{
+		{0, 50}, // One goroutine per consumer
+		{4, 4},
+		{100, 50}, // A pool bigger than the consumers changes nothing
+	} {
+		atomic.StoreInt64(&started, 0)
+		atomic.StoreInt64(&peak, 0)
+		
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		stats := runChannelDemoBuffered(ctx, 2, 50, 20, defaultChannelBuffer, defaultChannelBuffer, int(tc.pool))
+		cancel()
+		
+		if stats.Consumed != 40 || stats.Results != 40 {
+			t.Errorf("pool=%d: expected all 40 items consumed, got %+v", tc.pool, stats)
+		}
+		if stats.ConsumerGoroutines != tc.goroutines || atomic.LoadInt64(&started) != tc.goroutines {
+			t.Errorf("pool=%d: expected %d consumer goroutines, got %d started (stats %d)",
+				tc.pool, tc.goroutines, atomic.LoadInt64(&started), stats.ConsumerGoroutines)
+		}
+		if p := atomic.LoadInt64(&peak); p > tc.goroutines {
+			t.Errorf("pool=%d: expected at most %d consumer goroutines at once, saw %d", tc.pool, tc.goroutines, p)
+		}
+	}
+}
+
+func TestChannelDemoLogLevel(t *testing.T) {
+	oldLogger := slog.Default()
+	defer slog.SetDefault(oldLogger)
+	
+	for _, tc := range []struct {
+		level   slog.Level
+		perItem bool
+	}{
+		{slog.LevelError, false},
+		{slog.LevelDebug, true},
+	} {
+		var buf bytes.Buffer
+		slog.SetDefault(logging.New(&buf, tc.level))
+		runChannelDemo(context.Background(), 1, 1, 5)
+		
+		out := buf.String()
+		if strings.Contains(out, "Produced item") != tc.perItem || strings.Contains(out, "Consumed item") != tc.perItem {
+			t.Errorf("level %v: expected per-item logs %v, got:\n%s", tc.level, tc.perItem, out)
+		}
+		if tc.level == slog.LevelError && out != "" {
+			t.Errorf("Expected nothing logged at error level, got:\n%s", out)
+		}
+	}
+}
+
+func TestChannelBufferParams(t *testing.T) {
+	req := httptest.NewRequest("GET", "/channel-demo?workbuf=0&resultbuf=10", nil)
+	workBuf, resultBuf, err := channelBufferParams(req)
+	if err != nil || workBuf != 0 || resultBuf != 10 {
+		t.Errorf("Expected buffers 0 and 10, got %d and %d (%v)", workBuf, resultBuf, err)
+	}
+	
+	req = httptest.NewRequest("GET", "/channel-demo", nil)
+	if workBuf, resultBuf, _ := channelBufferParams(req); workBuf != defaultChannelBuffer || resultBuf != defaultChannelBuffer {
+		t.Errorf("Expected default buffers of %d, got %d and %d", defaultChannelBuffer, workBuf, resultBuf)
+	}
+	
+	for _, url := range []string{"/channel-demo?workbuf=-1", "/channel-demo?resultbuf=big", "/channel-demo?pool=-1"} {
+		recorder := httptest.NewRecorder()
+		channelDemoHandler(recorder, httptest.NewRequest("GET", url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+}
+
+func TestChannelDemoSync(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/channel-demo", channelDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+	
+	// More consumers than producers drain every item well within the demo's time limit
+	req := httptest.NewRequest("GET", "/channel-demo?sync=true&producers=2&consumers=4&items=10", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var run demoRun
+	if err := json.Unmarshal(recorder.Body.Bytes(), &run); err != nil {
+		t.Fatalf("Failed to decode run: %v", err)
+	}
+	if run.State != runCompleted {
+		t.Fatalf("Expected the inline run to be completed, got %+v", run)
+	}
+	result := run.Result
+	if result["produced"] != 20 || result["consumed"] != result["produced"] {
+		t.Errorf("Expected all 20 items produced and consumed, got %v", result)
+	}
+	if result["remainingWork"] != 0 || result["results"] != result["consumed"] || result["elapsedNs"] <= 0 {
+		t.Errorf("Expected no work left, a result per item and the elapsed time, got %v", result)
+	}
+	
+	// The same run is served from /runs/{id}
+	if stored := waitForRun(t, mux, recorder.Header().Get("Location")); !reflect.DeepEqual(stored.Result, result) {
+		t.Errorf("Expected /runs to serve the inline result %v, got %v", result, stored.Result)
+	}
+	
+	recorder = httptest.NewRecorder()
+	channelDemoHandler(recorder, httptest.NewRequest("GET", "/channel-demo?sync=maybe", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid sync parameter, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
 func TestHTTPEndpoints(t *testing.T) {
 	// Create a test server
 	mux := http.NewServeMux()
@@ -189,7 +572,7 @@ func TestHTTPEndpoints(t *testing.T) {
 		numWorkers := 2  // Smaller for testing
 		iterations := 5  // Smaller for testing
 		
-		go runMutexDemo(numWorkers, iterations)
+		go runMutexDemo(context.Background(), defaultDemoConfig, numWorkers, iterations)
 		
 		w.Write([]byte("Started mutex contention demo"))
 	})
@@ -198,7 +581,7 @@ func TestHTTPEndpoints(t *testing.T) {
 		numWorkers := 2  // Smaller for testing
 		iterations := 5  // Smaller for testing
 		
-		go runRWMutexDemo(numWorkers, iterations)
+		go runRWMutexDemo(context.Background(), defaultDemoConfig, numWorkers, iterations)
 		
 		w.Write([]byte("Started RWMutex contention demo"))
 	})
@@ -208,7 +591,11 @@ func TestHTTPEndpoints(t *testing.T) {
 		numConsumers := 2    // Smaller for testing
 		itemsPerProducer := 3 // Smaller for testing
 		
-		go runChannelDemo(numProducers, numConsumers, itemsPerProducer)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), channelDemoDuration)
+			defer cancel()
+			runChannelDemo(ctx, numProducers, numConsumers, itemsPerProducer)
+		}()
 		
 		w.Write([]byte("Started channel demo"))
 	})
@@ -244,34 +631,378 @@ func TestHTTPEndpoints(t *testing.T) {
 	}
 }
 
+func TestDemoQueryParams(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/rwmutex-demo", rwMutexDemoHandler)
+	mux.HandleFunc("/channel-demo", channelDemoHandler)
+	
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Custom mutex parameters",
+			url:            "/mutex-demo?workers=2&iterations=3",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "2 workers, 3 iterations each",
+		},
+		{
+			name:           "Custom rwmutex parameters",
+			url:            "/rwmutex-demo?workers=4&iterations=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "4 workers, 1 iterations each",
+		},
+		{
+			name:           "Non-numeric workers",
+			url:            "/mutex-demo?workers=many",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid workers parameter",
+		},
+		{
+			name:           "Zero iterations",
+			url:            "/mutex-demo?iterations=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid iterations parameter",
+		},
+		{
+			name:           "Workers above cap",
+			url:            "/rwmutex-demo?workers=10001",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid workers parameter",
+		},
+		{
+			name:           "Invalid channel items",
+			url:            "/channel-demo?items=-5",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "invalid items parameter",
+		},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			recorder := httptest.NewRecorder()
+			
+			mux.ServeHTTP(recorder, req)
+			
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d for %s, got %d", tc.expectedStatus, tc.url, recorder.Code)
+			}
+			
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Response body doesn't contain expected text.\nExpected to contain: %s\nGot: %s", 
+					tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestWorkerParams(t *testing.T) {
+	testCases := []struct {
+		url                string
+		expectedWorkers    int
+		expectedIterations int
+		expectError        bool
+	}{
+		{"/mutex-demo", 10, 100, false},
+		{"/mutex-demo?workers=3", 3, 100, false},
+		{"/mutex-demo?iterations=7", 10, 7, false},
+		{"/mutex-demo?workers=3&iterations=7", 3, 7, false},
+		{"/mutex-demo?workers=0", 0, 0, true},
+		{"/mutex-demo?iterations=-1", 0, 0, true},
+		{"/mutex-demo?workers=1.5", 0, 0, true},
+	}
+	
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.url, nil)
+		workers, iterations, err := workerParams(req, 10)
+		
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("workerParams(%s) expected error, got none", tc.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("workerParams(%s) returned error: %v", tc.url, err)
+			continue
+		}
+		if workers != tc.expectedWorkers || iterations != tc.expectedIterations {
+			t.Errorf("workerParams(%s) = %d, %d, expected %d, %d", 
+				tc.url, workers, iterations, tc.expectedWorkers, tc.expectedIterations)
+		}
+	}
+}
+
+func TestProfRateEnablesBlockProfile(t *testing.T) {
+	defer profrate.SetBlockRate(0)
+	profrate.SetBlockRate(0)
+	
+	// Input validation is covered by profrate's own tests; this checks the
+	// app's endpoint reaches the runtime
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/profrate", profrate.Handler)
+	mux.HandleFunc("/debug/pprof/", netpprof.Index)
+	
+	req := httptest.NewRequest("POST", "/debug/profrate?block=1", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var rates profrate.Rates
+	if err := json.Unmarshal(recorder.Body.Bytes(), &rates); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rates.Block != 1 {
+		t.Errorf("Expected block profile rate 1, got %d", rates.Block)
+	}
+	
+	// Generate a small blocking workload
+	ch := make(chan int)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ch <- 1
+	}()
+	<-ch
+	
+	req = httptest.NewRequest("GET", "/debug/pprof/block?debug=1", nil)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	
+	// Each sample in the text format is a stack introduced by "@"
+	if !strings.Contains(recorder.Body.String(), "@ 0x") {
+		t.Errorf("Expected block profile samples, got: %s", recorder.Body.String())
+	}
+}
+
+func TestProfRateMutexFractionInDemo(t *testing.T) {
+	previous := runtime.SetMutexProfileFraction(5)
+	defer runtime.SetMutexProfileFraction(previous)
+	
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/profrate", profrate.Handler)
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	
+	req := httptest.NewRequest("POST", "/debug/profrate?mutex=10", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	if fraction := profrate.MutexFraction(); fraction != 10 {
+		t.Errorf("Expected mutex profile fraction 10, got %d", fraction)
+	}
+	
+	// Demo responses note the active fraction
+	req = httptest.NewRequest("GET", "/mutex-demo?workers=1&iterations=1", nil)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	
+	if !strings.Contains(recorder.Body.String(), "mutex profile fraction 10") {
+		t.Errorf("Expected response to note the mutex profile fraction, got: %s", recorder.Body.String())
+	}
+}
+
 func TestDeadlockAvoidance(t *testing.T) {
 	// This test ensures that our deadlock demonstration function doesn't actually deadlock
 	// in the test environment by using a timeout
+	testutil.AssertNoGoroutineLeak(t)
 	
-	done := make(chan bool)
-	timeout := make(chan bool, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
 	
-	go func() {
-		// Run the potential deadlock function briefly
-		go potentialDeadlock()
-		
-		// Let it run for a short time
-		time.Sleep(300 * time.Millisecond)
-		
-		done <- true
+	// Run the potential deadlock function briefly
+	demo := potentialDeadlock(ctx, false, nil)
+	
+	// Both goroutines stop once the context times out
+	select {
+	case <-demo.Done():
+		// Test passed - goroutines exited without deadlocking for good
+	case <-time.After(2 * time.Second):
+		t.Fatal("Deadlock detected - test timed out")
+	}
+	
+	// Unforced, both goroutines keep taking their lock pairs, and nothing
+	// tracks their order
+	status := demo.Status()
+	if status.Acquisitions[0] == 0 || status.Acquisitions[1] == 0 {
+		t.Errorf("Expected both goroutines to take their locks, got %v", status.Acquisitions)
+	}
+	if status.Detect || len(status.Violations) != 0 {
+		t.Errorf("Expected no lock order checks without detection, got %+v", status)
+	}
+}
+
+func TestForcedDeadlockDetected(t *testing.T) {
+	// The forced demo deadlocks for sure, but the inversion is reported
+	// before either goroutine blocks
+	order := NewLockOrder()
+	ctx, cancel := context.WithCancel(context.Background())
+	demo := potentialDeadlock(ctx, true, order)
+	defer func() {
+		cancel()
+		<-demo.Done()
 	}()
 	
-	// Set a timeout
+	deadline := time.Now().Add(2 * time.Second)
+	for len(order.Violations()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a lock order violation from the forced deadlock demo")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeadlockDemoHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Defaults", "/deadlock-demo", http.StatusOK, "force=false"},
+		{"Forced", "/deadlock-demo?force=true", http.StatusOK, "force=true"},
+		{"Invalid force", "/deadlock-demo?force=maybe", http.StatusBadRequest, "invalid force parameter"},
+		{"Detect", "/deadlock-demo?detect=true", http.StatusOK, "detect=true"},
+		{"Invalid detect", "/deadlock-demo?detect=maybe", http.StatusBadRequest, "invalid detect parameter"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			recorder := httptest.NewRecorder()
+			
+			deadlockDemoHandler(recorder, req)
+			
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+			if recorder.Code == http.StatusOK {
+				// Only one demo runs at a time
+				if _, err := stopDeadlockDemo(); err != nil {
+					t.Errorf("Failed to stop deadlock demo: %v", err)
+				}
+			}
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected body to contain %q, got: %s", tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestShutdownStopsDemoRuns(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+	
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	baseURL := "http://" + ln.Addr().String()
+	baseline := runtime.NumGoroutine()
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
 	go func() {
-		time.Sleep(2 * time.Second)
-		timeout <- true
+		served <- server.Serve(ctx, ln, mux, 5*time.Second, stopDemos)
 	}()
 	
-	// Wait for either completion or timeout
+	// Far more work than can finish during the test
+	resp, err := http.Get(baseURL + "/mutex-demo?workers=10&iterations=10000")
+	if err != nil {
+		t.Fatalf("Failed to start demo: %v", err)
+	}
+	resp.Body.Close()
+	location := resp.Header.Get("Location")
+	http.DefaultClient.CloseIdleConnections()
+	
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	
 	select {
-	case <-done:
-		// Test passed - function returned without deadlocking
-	case <-timeout:
-		t.Fatal("Deadlock detected - test timed out")
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
 	}
-}
\ No newline at end of file
+	
+	// The listener is closed and the run was interrupted before Serve returned
+	if conn, err := net.DialTimeout("tcp", ln.Addr().String(), 100*time.Millisecond); err == nil {
+		conn.Close()
+		t.Error("Expected the listener to be closed after shutdown")
+	}
+	run := waitForRun(t, mux, location)
+	if run.State != runCancelled {
+		t.Errorf("Expected the run to be cancelled by shutdown, got %s", run.State)
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline+2 }); n > baseline+2 {
+		t.Errorf("Expected demo goroutines to exit after shutdown, %d goroutines left (baseline %d)", n, baseline)
+	}
+}
+
+func TestDemoConfigParams(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected DemoConfig
+		wantErr  bool
+	}{
+		{"/mutex-demo", defaultDemoConfig, false},
+		{"/mutex-demo?holdMs=0", DemoConfig{PreLockSleepMax: 5 * time.Millisecond, ReadSleepMax: 3 * time.Millisecond}, false},
+		{"/mutex-demo?preSleepMs=1&holdMs=50&readMs=2", DemoConfig{PreLockSleepMax: time.Millisecond, HoldSleepMax: 50 * time.Millisecond, ReadSleepMax: 2 * time.Millisecond}, false},
+		{"/mutex-demo?holdMs=-1", DemoConfig{}, true},
+		{"/mutex-demo?readMs=5000", DemoConfig{}, true},
+		{"/mutex-demo?preSleepMs=soon", DemoConfig{}, true},
+	}
+	
+	for _, tc := range testCases {
+		cfg, err := demoConfigParams(httptest.NewRequest("GET", tc.url, nil))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tc.url, tc.wantErr, err)
+			continue
+		}
+		if cfg != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.url, tc.expected, cfg)
+		}
+	}
+}
+
+func TestMutexDemoSleepParams(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+	
+	// Without sleeps the run finishes almost immediately
+	req := httptest.NewRequest("GET", "/mutex-demo?workers=6&iterations=200&preSleepMs=0&holdMs=0&readMs=0", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	
+	run := waitForRun(t, mux, recorder.Header().Get("Location"))
+	if run.State != runCompleted || run.Result["iterations"] != 6*200 {
+		t.Errorf("Expected a completed run of 1200 iterations, got %+v", run)
+	}
+	if run.Result["lockAcquisitions"] != 2*200 {
+		t.Errorf("Expected lock timings for the 2 writers' 400 acquisitions, got %v", run.Result)
+	}
+	if run.Params["holdMs"] != 0 || run.Params["preSleepMs"] != 0 || run.Params["readMs"] != 0 {
+		t.Errorf("Expected the sleep parameters in the run params, got %v", run.Params)
+	}
+	if elapsed := run.FinishedAt.Sub(run.StartedAt); elapsed > time.Second {
+		t.Errorf("Expected a run without sleeps to finish quickly, took %v", elapsed)
+	}
+	
+	req = httptest.NewRequest("GET", "/rwmutex-demo?holdMs=2000", nil)
+	recorder = httptest.NewRecorder()
+	rwMutexDemoHandler(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for holdMs=2000, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// nestedLocks are the nested lock demo's two resources. Nested workers take
+// a then b, never the other way round, so the demo can't deadlock.
+type nestedLocks struct {
+	a        sync.Mutex
+	aCounter int
+	b        sync.Mutex
+	bCounter int
+}
+
+// nestedLockReport summarizes a nested lock demo run. Nested workers hold A
+// while they wait for B, so contention on B shows up in A's hold time.
+type nestedLockReport struct {
+	NestedWorkers   int     `json:"nestedWorkers"`
+	BOnlyWorkers    int     `json:"bOnlyWorkers"`
+	Iterations      int     `json:"iterations"`
+	NestedCompleted int64   `json:"nestedCompleted"`
+	BOnlyCompleted  int64   `json:"bOnlyCompleted"`
+	ACounter        int     `json:"aCounter"`
+	BCounter        int     `json:"bCounter"`
+	AWaitNs         int64   `json:"aWaitNs"`       // Nested workers waiting for A
+	AHoldNs         int64   `json:"aHoldNs"`       // Nested workers holding A, including their waits for B
+	BWaitNestedNs   int64   `json:"bWaitNestedNs"` // Nested workers waiting for B while holding A
+	BWaitBOnlyNs    int64   `json:"bWaitBOnlyNs"`  // B-only workers waiting for B
+	BWaitNs         int64   `json:"bWaitNs"`       // Both populations waiting for B
+	BHoldNs         int64   `json:"bHoldNs"`
+	DurationMs      float64 `json:"durationMs"`
+}
+
+// lockNested runs iterations of taking A, working, then taking B inside A's
+// critical section, recording A's waits and holds in outer and B's in inner
+func lockNested(ctx context.Context, locks *nestedLocks, hold time.Duration, iterations int, completed *int64, outer, inner *lockTiming) {
+	rng := workerRand(ctx)
+	for i := 0; i < iterations; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		sleepUpTo(rng, hold)
+
+		waitA := time.Now()
+		locks.a.Lock()
+		acquiredA := time.Now()
+		locks.aCounter++
+		sleepUpTo(rng, hold)
+
+		waitB := time.Now()
+		locks.b.Lock()
+		acquiredB := time.Now()
+		locks.bCounter++
+		sleepUpTo(rng, hold)
+		heldB := time.Since(acquiredB)
+		locks.b.Unlock()
+
+		heldA := time.Since(acquiredA)
+		locks.a.Unlock()
+
+		outer.record(acquiredA.Sub(waitA), heldA)
+		inner.record(acquiredB.Sub(waitB), heldB)
+		atomic.AddInt64(completed, 1)
+	}
+}
+
+// lockBOnly runs iterations of taking only B, recording its waits and holds
+// in timing
+func lockBOnly(ctx context.Context, locks *nestedLocks, hold time.Duration, iterations int, completed *int64, timing *lockTiming) {
+	rng := workerRand(ctx)
+	for i := 0; i < iterations; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		sleepUpTo(rng, hold)
+
+		waitB := time.Now()
+		locks.b.Lock()
+		acquiredB := time.Now()
+		locks.bCounter++
+		sleepUpTo(rng, hold)
+		heldB := time.Since(acquiredB)
+		locks.b.Unlock()
+
+		timing.record(acquiredB.Sub(waitB), heldB)
+		atomic.AddInt64(completed, 1)
+	}
+}
+
+// runNestedLockDemo runs numNested workers that take B while holding A next
+// to numBOnly workers that only take B, until done or ctx is cancelled. Every
+// worker sleeps below hold before locking and inside each critical section.
+func runNestedLockDemo(ctx context.Context, numNested, numBOnly, iterations int, hold time.Duration) nestedLockReport {
+	slog.InfoContext(ctx, "Starting nested lock demo", "nested", numNested, "bOnly", numBOnly, "iterations", iterations)
+
+	var locks nestedLocks
+	var wg sync.WaitGroup
+	var nestedCompleted, bOnlyCompleted int64
+
+	// One accumulator per worker and lock, merged once they are done
+	outer := make([]lockTiming, numNested)
+	inner := make([]lockTiming, numNested)
+	bOnly := make([]lockTiming, numBOnly)
+
+	start := time.Now()
+	withDemoLabel(ctx, "nested-lock", func(ctx context.Context) {
+		for i := 0; i < numNested+numBOnly; i++ {
+			wg.Add(1)
+			withWorkerLabel(ctx, i, func(ctx context.Context) {
+				if i < numNested {
+					go func(outer, inner *lockTiming) {
+						defer wg.Done()
+						lockNested(ctx, &locks, hold, iterations, &nestedCompleted, outer, inner)
+					}(&outer[i], &inner[i])
+				} else {
+					go func(timing *lockTiming) {
+						defer wg.Done()
+						lockBOnly(ctx, &locks, hold, iterations, &bOnlyCompleted, timing)
+					}(&bOnly[i-numNested])
+				}
+			})
+		}
+	})
+	wg.Wait()
+
+	var a, bNested, bBOnly lockTiming
+	for i := range outer {
+		a.merge(outer[i])
+		bNested.merge(inner[i])
+	}
+	for _, t := range bOnly {
+		bBOnly.merge(t)
+	}
+
+	report := nestedLockReport{
+		NestedWorkers:   numNested,
+		BOnlyWorkers:    numBOnly,
+		Iterations:      iterations,
+		NestedCompleted: nestedCompleted,
+		BOnlyCompleted:  bOnlyCompleted,
+		ACounter:        locks.aCounter,
+		BCounter:        locks.bCounter,
+		AWaitNs:         int64(a.Wait),
+		AHoldNs:         int64(a.Hold),
+		BWaitNestedNs:   int64(bNested.Wait),
+		BWaitBOnlyNs:    int64(bBOnly.Wait),
+		BWaitNs:         int64(bNested.Wait + bBOnly.Wait),
+		BHoldNs:         int64(bNested.Hold + bBOnly.Hold),
+		DurationMs:      float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	slog.InfoContext(ctx, "Nested lock demo completed", "aWait", a.Wait, "aHold", a.Hold,
+		"bWaitNested", bNested.Wait, "bWaitBOnly", bBOnly.Wait)
+	return report
+}
+
+// HTTP handler that runs the nested lock demo and returns its report as JSON
+func nestedLockDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numNested, err := queryInt(r, "nested", 4, 0, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	numBOnly, err := queryInt(r, "bOnly", 4, 0, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	iterations, err := queryInt(r, "iterations", 50, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	holdMs, err := queryInt(r, "holdMs", 2, 0, maxDemoSleepMs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runNestedLockDemo(r.Context(), numNested, numBOnly, iterations, time.Duration(holdMs)*time.Millisecond))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pprofviz/examples/internal/testutil"
+)
+
+func TestNestedLockDemoCounters(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	const numNested, numBOnly, iterations = 3, 5, 20
+
+	// Consistent A then B ordering means the demo always finishes
+	done := make(chan nestedLockReport, 1)
+	go func() {
+		done <- runNestedLockDemo(context.Background(), numNested, numBOnly, iterations, time.Millisecond)
+	}()
+	var report nestedLockReport
+	select {
+	case report = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timeout waiting for the nested lock demo, possible deadlock")
+	}
+
+	if report.ACounter != numNested*iterations || report.NestedCompleted != numNested*iterations {
+		t.Errorf("Expected %d acquisitions of A, got counter %d and %d completed",
+			numNested*iterations, report.ACounter, report.NestedCompleted)
+	}
+	if report.BCounter != (numNested+numBOnly)*iterations || report.BOnlyCompleted != numBOnly*iterations {
+		t.Errorf("Expected %d acquisitions of B, %d by B-only workers, got %+v",
+			(numNested+numBOnly)*iterations, numBOnly*iterations, report)
+	}
+	if report.BWaitNs != report.BWaitNestedNs+report.BWaitBOnlyNs {
+		t.Errorf("Expected B's wait total to add up its populations, got %+v", report)
+	}
+	// A is held across every wait for and hold of B
+	if report.AHoldNs < report.BWaitNestedNs {
+		t.Errorf("Expected A's hold time to include the waits for B, got %+v", report)
+	}
+}
+
+func TestNestedLockDemoCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := runNestedLockDemo(ctx, 2, 2, 1000, 10*time.Millisecond)
+	if report.NestedCompleted != 0 || report.BOnlyCompleted != 0 {
+		t.Errorf("Expected a cancelled run to do nothing, got %+v", report)
+	}
+}
+
+func TestNestedLockDemoHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/nested-lock-demo", nestedLockDemoHandler)
+
+	req := httptest.NewRequest("GET", "/nested-lock-demo?nested=2&bOnly=3&iterations=10&holdMs=0", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var report nestedLockReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.ACounter != 20 || report.BCounter != 50 {
+		t.Errorf("Expected counters of 20 on A and 50 on B, got %+v", report)
+	}
+
+	for _, url := range []string{"/nested-lock-demo?nested=-1", "/nested-lock-demo?iterations=0", "/nested-lock-demo?holdMs=5000"} {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", url, http.StatusBadRequest, recorder.Code)
+		}
+	}
+}
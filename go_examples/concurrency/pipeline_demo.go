@@ -0,0 +1,160 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Per-item delay in the transform stage, which makes it the bottleneck
+const pipelineTransformDelay = time.Millisecond
+
+// pipelineStageConfig sizes one stage of the pipeline demo
+type pipelineStageConfig struct {
+	Workers int
+	Buffer  int // Capacity of the stage's output channel
+}
+
+// stageReport summarizes one pipeline stage
+type stageReport struct {
+	Name        string  `json:"name"`
+	Workers     int     `json:"workers"`
+	Buffer      int     `json:"buffer"`
+	Items       int64   `json:"items"`
+	CompletedMs float64 `json:"completedMs"` // Since the pipeline started
+	ItemsPerSec float64 `json:"itemsPerSec"`
+}
+
+// pipelineReport summarizes a pipeline demo run
+type pipelineReport struct {
+	Items       int           `json:"items"`
+	Sum         int64         `json:"sum"`
+	CompletedMs float64       `json:"completedMs"`
+	Stages      []stageReport `json:"stages"`
+}
+
+// runStage starts the stage's workers running work and calls done once they
+// have all returned, recording the stage's completion time in report
+func runStage(start time.Time, report *stageReport, work func(), done func()) {
+	var stageWg sync.WaitGroup
+	for i := 0; i < report.Workers; i++ {
+		stageWg.Add(1)
+		go func() {
+			defer stageWg.Done()
+			work()
+		}()
+	}
+	go func() {
+		stageWg.Wait()
+		elapsed := time.Since(start)
+		report.CompletedMs = float64(elapsed) / float64(time.Millisecond)
+		if elapsed > 0 {
+			report.ItemsPerSec = float64(atomic.LoadInt64(&report.Items)) / elapsed.Seconds()
+		}
+		done()
+	}()
+}
+
+// runPipelineDemo passes numbers through generate → transform → aggregate
+// stages connected by channels, with a deliberately slow transform stage
+func runPipelineDemo(items int, generate, transform, aggregate pipelineStageConfig) pipelineReport {
+	return pipelineDemo(items, generate, transform, aggregate, pipelineTransformDelay)
+}
+
+// pipelineDemo runs the pipeline demo with the given transform delay
+func pipelineDemo(items int, generate, transform, aggregate pipelineStageConfig, delay time.Duration) pipelineReport {
+	slog.Info("Starting pipeline demo", "items", items,
+		"generateWorkers", generate.Workers, "transformWorkers", transform.Workers, "aggregateWorkers", aggregate.Workers)
+
+	stages := []stageReport{
+		{Name: "generate", Workers: generate.Workers, Buffer: generate.Buffer},
+		{Name: "transform", Workers: transform.Workers, Buffer: transform.Buffer},
+		{Name: "aggregate", Workers: aggregate.Workers, Buffer: aggregate.Buffer},
+	}
+	generated := make(chan int, generate.Buffer)
+	transformed := make(chan int, transform.Buffer)
+	sums := make(chan int64, aggregate.Buffer)
+	start := time.Now()
+
+	// Generators share a counter so each number is emitted exactly once
+	var next int64
+	runStage(start, &stages[0], func() {
+		for {
+			n := atomic.AddInt64(&next, 1)
+			if n > int64(items) {
+				return
+			}
+			generated <- int(n)
+			atomic.AddInt64(&stages[0].Items, 1)
+		}
+	}, func() { close(generated) })
+
+	runStage(start, &stages[1], func() {
+		for n := range generated {
+			time.Sleep(delay)
+			transformed <- n * 2
+			atomic.AddInt64(&stages[1].Items, 1)
+		}
+	}, func() { close(transformed) })
+
+	// Each aggregator sends its partial sum once its input is drained
+	runStage(start, &stages[2], func() {
+		var sum int64
+		for n := range transformed {
+			sum += int64(n)
+			atomic.AddInt64(&stages[2].Items, 1)
+		}
+		sums <- sum
+	}, func() { close(sums) })
+
+	report := pipelineReport{Items: items}
+	for sum := range sums {
+		report.Sum += sum
+	}
+	report.CompletedMs = float64(time.Since(start)) / float64(time.Millisecond)
+	report.Stages = stages
+
+	slog.Info("Pipeline demo completed", "completedMs", report.CompletedMs)
+	return report
+}
+
+// stageParams reads the workers and buffer parameters for one pipeline stage
+func stageParams(r *http.Request, name string, defaultWorkers int) (pipelineStageConfig, error) {
+	workers, err := queryInt(r, name, defaultWorkers, 1, maxDemoParam)
+	if err != nil {
+		return pipelineStageConfig{}, err
+	}
+	buffer, err := queryInt(r, name+"buf", 10, 0, maxDemoParam)
+	if err != nil {
+		return pipelineStageConfig{}, err
+	}
+	return pipelineStageConfig{Workers: workers, Buffer: buffer}, nil
+}
+
+// HTTP handler that runs the pipeline demo and returns its report as JSON
+func pipelineDemoHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := queryInt(r, "items", 500, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	generate, err := stageParams(r, "generators", 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	transform, err := stageParams(r, "transformers", 2)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	aggregate, err := stageParams(r, "aggregators", 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runPipelineDemo(items, generate, transform, aggregate))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestPipelineDemo(t *testing.T) {
+	testCases := []struct {
+		name                           string
+		generate, transform, aggregate pipelineStageConfig
+	}{
+		{"Unbuffered single workers", pipelineStageConfig{1, 0}, pipelineStageConfig{1, 0}, pipelineStageConfig{1, 0}},
+		{"Buffered fan-out", pipelineStageConfig{2, 10}, pipelineStageConfig{4, 5}, pipelineStageConfig{3, 3}},
+		{"Aggregators outnumber items", pipelineStageConfig{1, 1}, pipelineStageConfig{2, 1}, pipelineStageConfig{50, 0}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			baseline := runtime.NumGoroutine()
+			items := 40
+
+			report := pipelineDemo(items, tc.generate, tc.transform, tc.aggregate, 0)
+
+			// Every number passes through every stage exactly once
+			for _, stage := range report.Stages {
+				if stage.Items != int64(items) {
+					t.Errorf("Stage %s handled %d items, expected %d", stage.Name, stage.Items, items)
+				}
+			}
+			// Transform doubles 1..items, so the sum is items * (items + 1)
+			if expected := int64(items * (items + 1)); report.Sum != expected {
+				t.Errorf("Expected sum %d, got %d", expected, report.Sum)
+			}
+
+			if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+				t.Errorf("Expected goroutines back to %d after the pipeline finished, got %d", baseline, n)
+			}
+		})
+	}
+}
+
+func TestPipelineDemoSlowTransform(t *testing.T) {
+	report := runPipelineDemo(20, pipelineStageConfig{1, 5}, pipelineStageConfig{1, 5}, pipelineStageConfig{1, 5})
+
+	if len(report.Stages) != 3 {
+		t.Fatalf("Expected 3 stages, got %d", len(report.Stages))
+	}
+	// Stages finish in order, and the transform delay dominates the run
+	for i := 1; i < len(report.Stages); i++ {
+		if report.Stages[i].CompletedMs < report.Stages[i-1].CompletedMs {
+			t.Errorf("Stage %s completed before %s", report.Stages[i].Name, report.Stages[i-1].Name)
+		}
+	}
+	if report.Stages[1].CompletedMs < 20 {
+		t.Errorf("Expected transform to take at least 20ms for 20 items, took %.1fms", report.Stages[1].CompletedMs)
+	}
+}
+
+func TestPipelineDemoHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/pipeline-demo?items=10&generators=2&transformersbuf=0&aggregators=2", nil)
+	recorder := httptest.NewRecorder()
+
+	pipelineDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var report pipelineReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if report.Items != 10 || report.Sum != 110 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.Stages[0].Workers != 2 || report.Stages[1].Buffer != 0 || report.Stages[2].Workers != 2 {
+		t.Errorf("Stage config not applied: %+v", report.Stages)
+	}
+
+	req = httptest.NewRequest("GET", "/pipeline-demo?transformers=0", nil)
+	recorder = httptest.NewRecorder()
+	pipelineDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for transformers=0, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime/pprof"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+
+	"pprofviz/examples/internal/analysis"
+	"pprofviz/examples/internal/profrate"
+)
+
+// Contention sites listed per profile by /profiles/summary
+const profileSummaryTop = 20
+
+// contentionSite totals the samples of a block or mutex profile whose first
+// frame outside the runtime and sync packages is the same function
+type contentionSite struct {
+	Function string `json:"function"`
+	Location string `json:"location"` // File and line of the heaviest sample's frame
+	Count    int64  `json:"count"`
+	DelayNs  int64  `json:"delayNs"`
+}
+
+// contentionSummary ranks a profile's contention sites by total delay. When
+// the profile is disabled or empty, Note says why instead.
+type contentionSummary struct {
+	Rate         int              `json:"rate"`
+	TotalCount   int64            `json:"totalCount"`
+	TotalDelayNs int64            `json:"totalDelayNs"`
+	Sites        []contentionSite `json:"sites"`
+	Note         string           `json:"note,omitempty"`
+}
+
+// contentionSummaries is the /profiles/summary response
+type contentionSummaries struct {
+	Block contentionSummary `json:"block"`
+	Mutex contentionSummary `json:"mutex"`
+}
+
+// isRuntimeFrame reports whether a function belongs to the runtime or the
+// sync packages, which every contention stack ends in
+func isRuntimeFrame(function string) bool {
+	for _, prefix := range []string{"runtime.", "sync.", "internal/"} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentionSiteOf returns the first frame of sample outside the runtime and
+// sync packages, or the leaf frame if there is none
+func contentionSiteOf(sample *profile.Sample) (function, location string) {
+	for _, loc := range sample.Location {
+		for _, line := range loc.Line {
+			if line.Function == nil {
+				continue
+			}
+			function = line.Function.Name
+			location = fmt.Sprintf("%s:%d", line.Function.Filename, line.Line)
+			if !isRuntimeFrame(function) {
+				return function, location
+			}
+		}
+	}
+	return function, location
+}
+
+// summarizeContention ranks p's samples into at most top contention sites
+func summarizeContention(p *profile.Profile, top int) (contentionSummary, error) {
+	countIndex, err := analysis.SampleIndex(p, "contentions")
+	if err != nil {
+		return contentionSummary{}, err
+	}
+	delayIndex, err := analysis.SampleIndex(p, "delay")
+	if err != nil {
+		return contentionSummary{}, err
+	}
+
+	var summary contentionSummary
+	sites := make(map[string]*contentionSite)
+	heaviest := make(map[string]int64)
+	for _, sample := range p.Sample {
+		count, delay := sample.Value[countIndex], sample.Value[delayIndex]
+		summary.TotalCount += count
+		summary.TotalDelayNs += delay
+
+		function, location := contentionSiteOf(sample)
+		site, ok := sites[function]
+		if !ok {
+			site = &contentionSite{Function: function}
+			sites[function] = site
+		}
+		site.Count += count
+		site.DelayNs += delay
+		if delay >= heaviest[function] {
+			heaviest[function] = delay
+			site.Location = location
+		}
+	}
+
+	summary.Sites = make([]contentionSite, 0, len(sites))
+	for _, site := range sites {
+		summary.Sites = append(summary.Sites, *site)
+	}
+	sort.Slice(summary.Sites, func(i, j int) bool {
+		a, b := summary.Sites[i], summary.Sites[j]
+		if a.DelayNs != b.DelayNs {
+			return a.DelayNs > b.DelayNs
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Function < b.Function
+	})
+	if len(summary.Sites) > top {
+		summary.Sites = summary.Sites[:top]
+	}
+	return summary, nil
+}
+
+// lookupContention summarizes the named runtime profile, block or mutex,
+// collected at rate. A zero rate records nothing new, which Note explains.
+func lookupContention(name string, rate int, enable string) (contentionSummary, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+		return contentionSummary{}, err
+	}
+	p, err := analysis.ParseProfile(&buf)
+	if err != nil {
+		return contentionSummary{}, err
+	}
+
+	summary, err := summarizeContention(p, profileSummaryTop)
+	if err != nil {
+		return contentionSummary{}, err
+	}
+	summary.Rate = rate
+	switch {
+	case rate <= 0:
+		summary.Note = fmt.Sprintf("%s profiling is disabled, so no new contention is recorded; enable it with %s", name, enable)
+	case len(summary.Sites) == 0:
+		summary.Note = fmt.Sprintf("no %s contention recorded yet; run a demo first", name)
+	}
+	return summary, nil
+}
+
+// HTTP handler that ranks the top block and mutex contention sites as JSON,
+// without a round trip through the raw profiles
+func profileSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	rates := profrate.Current()
+	block, err := lookupContention("block", rates.Block, "POST /debug/profrate?block=1")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	mutex, err := lookupContention("mutex", rates.Mutex, "POST /debug/profrate?mutex=5")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, contentionSummaries{Block: block, Mutex: mutex})
+}
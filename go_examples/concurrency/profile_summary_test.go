@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"pprofviz/examples/internal/profrate"
+)
+
+// serveProfileSummary fetches /profiles/summary
+func serveProfileSummary(t *testing.T) contentionSummaries {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	profileSummaryHandler(recorder, httptest.NewRequest("GET", "/profiles/summary", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var summaries contentionSummaries
+	if err := json.Unmarshal(recorder.Body.Bytes(), &summaries); err != nil {
+		t.Fatalf("Failed to decode profile summary: %v", err)
+	}
+	return summaries
+}
+
+func TestProfileSummary(t *testing.T) {
+	oldBlock := profrate.SetBlockRate(1)
+	oldMutex := profrate.SetMutexFraction(1)
+	defer func() {
+		profrate.SetBlockRate(oldBlock)
+		profrate.SetMutexFraction(oldMutex)
+	}()
+
+	// A short mutex demo with the lock held long enough for writers to queue
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+	cfg := DemoConfig{HoldSleepMax: 2 * time.Millisecond}
+	runMutexDemo(context.Background(), cfg, 4, 20)
+
+	summaries := serveProfileSummary(t)
+	for name, summary := range map[string]contentionSummary{"block": summaries.Block, "mutex": summaries.Mutex} {
+		if summary.Rate != 1 || summary.Note != "" {
+			t.Errorf("%s: expected rate 1 and no note, got %+v", name, summary)
+		}
+		if len(summary.Sites) == 0 || len(summary.Sites) > profileSummaryTop {
+			t.Fatalf("%s: expected between 1 and %d sites, got %d", name, profileSummaryTop, len(summary.Sites))
+		}
+		var found bool
+		for i, site := range summary.Sites {
+			if strings.HasSuffix(site.Function, ".writeWithMutex") {
+				found = true
+				if site.Count <= 0 || site.DelayNs <= 0 || !strings.Contains(site.Location, "main.go:") {
+					t.Errorf("%s: unexpected writeWithMutex site %+v", name, site)
+				}
+			}
+			if i > 0 && site.DelayNs > summary.Sites[i-1].DelayNs {
+				t.Errorf("%s: sites not ranked by delay: %+v", name, summary.Sites)
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected writeWithMutex among the sites, got %+v", name, summary.Sites)
+		}
+	}
+}
+
+func TestProfileSummaryDisabled(t *testing.T) {
+	oldBlock := profrate.SetBlockRate(0)
+	oldMutex := profrate.SetMutexFraction(0)
+	defer func() {
+		profrate.SetBlockRate(oldBlock)
+		profrate.SetMutexFraction(oldMutex)
+	}()
+
+	summaries := serveProfileSummary(t)
+	if s := summaries.Block; s.Rate != 0 || !strings.Contains(s.Note, "block profiling is disabled") {
+		t.Errorf("Expected a note explaining block profiling is off, got %+v", s)
+	}
+	if s := summaries.Mutex; s.Rate != 0 || !strings.Contains(s.Note, "/debug/profrate?mutex=") {
+		t.Errorf("Expected a note on enabling mutex profiling, got %+v", s)
+	}
+}
+
+func TestSummarizeContention(t *testing.T) {
+	fn := func(id uint64, name string) *profile.Function {
+		return &profile.Function{ID: id, Name: name, Filename: "/app/main.go"}
+	}
+	lock, worker, other := fn(1, "sync.(*Mutex).Lock"), fn(2, "main.worker"), fn(3, "main.other")
+	loc := func(id uint64, f *profile.Function, line int64) *profile.Location {
+		return &profile.Location{ID: id, Line: []profile.Line{{Function: f, Line: line}}}
+	}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "contentions", Unit: "count"}, {Type: "delay", Unit: "nanoseconds"}},
+		Sample: []*profile.Sample{
+			{Location: []*profile.Location{loc(1, lock, 70), loc(2, worker, 40)}, Value: []int64{3, 300}},
+			{Location: []*profile.Location{loc(1, lock, 70), loc(3, worker, 44)}, Value: []int64{1, 500}},
+			{Location: []*profile.Location{loc(1, lock, 70), loc(4, other, 10)}, Value: []int64{10, 100}},
+		},
+	}
+
+	summary, err := summarizeContention(p, 1)
+	if err != nil {
+		t.Fatalf("Failed to summarize: %v", err)
+	}
+	if summary.TotalCount != 14 || summary.TotalDelayNs != 900 {
+		t.Errorf("Expected totals of 14 and 900ns, got %+v", summary)
+	}
+	expected := contentionSite{Function: "main.worker", Location: "/app/main.go:44", Count: 4, DelayNs: 800}
+	if len(summary.Sites) != 1 || summary.Sites[0] != expected {
+		t.Errorf("Expected only %+v, got %+v", expected, summary.Sites)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// loadHistory reads the runs recorded in the JSON lines file at path, if it
+// exists, and appends every run finished from now on to it, so /runs keeps
+// listing runs across restarts. It returns how many runs it loaded and how
+// many lines it skipped because they couldn't be decoded. A truncated last
+// line, left by a crash mid-write, is cut off so the next record starts on a
+// line of its own.
+func (reg *runRegistry) loadHistory(path string) (loaded, skipped int, err error) {
+	reg.historyMutex.Lock()
+	defer reg.historyMutex.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, err
+	}
+
+	// Set when the last line has no newline, which appends would run into
+	partial := len(data) > 0 && data[len(data)-1] != '\n'
+
+	var runs []*demoRun
+	for start := 0; start < len(data); {
+		line := data[start:]
+		next := len(data)
+		if end := bytes.IndexByte(line, '\n'); end >= 0 {
+			line, next = line[:end], start+end+1
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			run := &demoRun{}
+			if err := json.Unmarshal(line, run); err == nil && run.State != runRunning {
+				runs = append(runs, run)
+			} else {
+				skipped++
+				if partial && next == len(data) {
+					// Drop the partial record so appends start on a new line
+					if err := os.Truncate(path, int64(start)); err != nil {
+						return 0, 0, err
+					}
+					partial = false
+				}
+			}
+		}
+		start = next
+	}
+	if partial {
+		// The last record is complete but lost its newline
+		if err := appendLine(path, nil); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// Runs are recorded as they finish, but the registry lists them in the
+	// order they started
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+
+	reg.mutex.Lock()
+	for _, run := range runs {
+		reg.nextID = max(reg.nextID, run.ID+1)
+	}
+	reg.runs = append(runs, reg.runs...)
+	if len(reg.runs) > reg.limit {
+		reg.runs = reg.runs[len(reg.runs)-reg.limit:]
+	}
+	reg.mutex.Unlock()
+
+	reg.historyPath = path
+	return len(runs), skipped, nil
+}
+
+// appendHistory writes a finished run to the history file, if there is one
+func (reg *runRegistry) appendHistory(run demoRun) error {
+	reg.historyMutex.Lock()
+	defer reg.historyMutex.Unlock()
+
+	if reg.historyPath == "" {
+		return nil
+	}
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return appendLine(reg.historyPath, line)
+}
+
+// appendLine appends line and a newline to the file at path, creating it if
+// needed
+func appendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForFinished waits until every run in reg has finished
+func waitForFinished(t *testing.T, reg *runRegistry) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		finished := true
+		for _, run := range reg.list() {
+			finished = finished && run.State != runRunning
+		}
+		if finished {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Runs still running after 2s: %+v", reg.list())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo_runs.jsonl")
+
+	reg := newRunRegistry(maxRuns)
+	if loaded, skipped, err := reg.loadHistory(path); err != nil || loaded != 0 || skipped != 0 {
+		t.Fatalf("Expected an empty history from a missing file, got %d and %d (%v)", loaded, skipped, err)
+	}
+	reg.launch("mutex", map[string]int{"workers": 3}, func(ctx context.Context) map[string]int64 {
+		return map[string]int64{"counter": 30}
+	})
+	reg.launch("channel", map[string]int{"producers": 2}, func(ctx context.Context) map[string]int64 {
+		panic("send on closed channel")
+	})
+	cancelled := reg.launch("rwmutex", nil, func(ctx context.Context) map[string]int64 {
+		<-ctx.Done()
+		return map[string]int64{"iterations": 1}
+	})
+	reg.cancel(cancelled)
+	waitForFinished(t, reg)
+
+	// A restarted registry lists the same runs and carries on numbering
+	restarted := newRunRegistry(maxRuns)
+	if loaded, skipped, err := restarted.loadHistory(path); err != nil || loaded != 3 || skipped != 0 {
+		t.Fatalf("Expected 3 runs loaded, got %d and %d skipped (%v)", loaded, skipped, err)
+	}
+	before, after := reg.list(), restarted.list()
+	if len(after) != len(before) {
+		t.Fatalf("Expected %d runs after the restart, got %d", len(before), len(after))
+	}
+	for _, run := range before {
+		loaded, ok := restarted.get(run.ID)
+		if !ok {
+			t.Errorf("Run %d missing after the restart", run.ID)
+			continue
+		}
+		if loaded.Demo != run.Demo || loaded.State != run.State || loaded.Error != run.Error ||
+			!reflect.DeepEqual(loaded.Params, run.Params) || !reflect.DeepEqual(loaded.Result, run.Result) ||
+			loaded.Profiling != run.Profiling || loaded.GOMAXPROCS != run.GOMAXPROCS ||
+			!loaded.StartedAt.Equal(run.StartedAt) || !loaded.FinishedAt.Equal(*run.FinishedAt) {
+			t.Errorf("Run %d changed across the restart: %+v became %+v", run.ID, run, loaded)
+		}
+	}
+	if id := restarted.start("mutex", nil); id != 4 {
+		t.Errorf("Expected the next run to be 4, got %d", id)
+	}
+}
+
+func TestRunHistoryTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo_runs.jsonl")
+	reg := newRunRegistry(maxRuns)
+	reg.loadHistory(path)
+	reg.launch("mutex", nil, func(ctx context.Context) map[string]int64 { return nil })
+	waitForFinished(t, reg)
+
+	// A crash mid-write leaves half a record behind
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":2,"demo":"chan`)
+	f.Close()
+
+	restarted := newRunRegistry(maxRuns)
+	loaded, skipped, err := restarted.loadHistory(path)
+	if err != nil || loaded != 1 || skipped != 1 {
+		t.Fatalf("Expected 1 run loaded and 1 line skipped, got %d and %d (%v)", loaded, skipped, err)
+	}
+
+	// The next record starts on its own line
+	restarted.launch("rwmutex", nil, func(ctx context.Context) map[string]int64 { return nil })
+	waitForFinished(t, restarted)
+	data, _ := os.ReadFile(path)
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || strings.Contains(string(data), "chan") {
+		t.Errorf("Expected 2 complete records, got %q", data)
+	}
+	if loaded, skipped, err := newRunRegistry(maxRuns).loadHistory(path); err != nil || loaded != 2 || skipped != 0 {
+		t.Errorf("Expected 2 runs after the repair, got %d and %d skipped (%v)", loaded, skipped, err)
+	}
+}
+
+func TestRunHistoryKeepsLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "demo_runs.jsonl")
+	reg := newRunRegistry(maxRuns)
+	reg.loadHistory(path)
+	for i := 0; i < 5; i++ {
+		reg.launch("mutex", nil, func(ctx context.Context) map[string]int64 { return nil })
+	}
+	waitForFinished(t, reg)
+
+	// A smaller registry keeps only the newest runs
+	small := newRunRegistry(2)
+	if loaded, _, err := small.loadHistory(path); err != nil || loaded != 5 {
+		t.Fatalf("Expected 5 runs read, got %d (%v)", loaded, err)
+	}
+	runs := small.list()
+	if len(runs) != 2 || runs[0].ID != 4 || runs[1].ID != 5 {
+		t.Errorf("Expected runs 4 and 5, got %+v", runs)
+	}
+}
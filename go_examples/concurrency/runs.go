@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pprofviz/examples/internal/profrate"
+)
+
+// Number of demo runs kept by the registry. Running runs are never evicted,
+// so it can hold more while that many are running.
+const maxRuns = 100
+
+// runState is the lifecycle state of a demo run
+type runState string
+
+const (
+	runRunning   runState = "running"
+	runCompleted runState = "completed"
+	runFailed    runState = "failed"
+	runCancelled runState = "cancelled"
+)
+
+var (
+	errRunNotFound   = errors.New("run not found")
+	errRunNotRunning = errors.New("run is not running")
+)
+
+// demoRun records a single background demo invocation
+type demoRun struct {
+	ID         int               `json:"id"`
+	Demo       string            `json:"demo"`
+	Params     map[string]int    `json:"params"`
+	Labels     map[string]string `json:"labels"`     // Profile labels on the run's goroutines, plus worker=<n> on each worker
+	GOMAXPROCS int               `json:"gomaxprocs"` // When the run started
+	Profiling  profrate.Rates    `json:"profiling"`  // Block and mutex profiling rates when the run started
+	State      runState          `json:"state"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	Result     map[string]int64  `json:"result,omitempty"`
+	Workers    []workerStats     `json:"workers,omitempty"`  // Per-worker stats, for demos that report them
+	Keys       []keyCount        `json:"keys,omitempty"`     // Hottest data keys, for the lock demos
+	Parent     int               `json:"parent,omitempty"`   // Run that started this one, such as a stress run
+	Children   []int             `json:"children,omitempty"` // Runs this one started
+	Error      string            `json:"error,omitempty"`
+
+	cancel          context.CancelFunc
+	cancelRequested bool
+	done            chan struct{} // Closed once a launched run has finished
+}
+
+// runRegistry tracks the most recent demo runs so handlers that return
+// immediately can still be followed up on
+type runRegistry struct {
+	mutex  sync.Mutex
+	nextID int
+	limit  int
+	runs   []*demoRun // Oldest first
+
+	// Demo goroutines still running
+	active sync.WaitGroup
+
+	// File finished runs are appended to, empty to keep them in memory only.
+	// Writes are serialized by historyMutex rather than mutex so a slow disk
+	// doesn't hold up the handlers.
+	historyMutex sync.Mutex
+	historyPath  string
+}
+
+// newRunRegistry creates a registry keeping the last limit runs
+func newRunRegistry(limit int) *runRegistry {
+	return &runRegistry{nextID: 1, limit: limit}
+}
+
+// Runs started by the demo handlers
+var demoRuns = newRunRegistry(maxRuns)
+
+// start records a new running demo and returns its ID, evicting the oldest
+// finished run once the registry is full
+func (reg *runRegistry) start(demo string, params map[string]int) int {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	run := &demoRun{
+		ID:         reg.nextID,
+		Demo:       demo,
+		Params:     params,
+		Labels:     runLabels(demo, reg.nextID),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Profiling:  profrate.Current(),
+		State:      runRunning,
+		StartedAt:  time.Now(),
+	}
+	reg.nextID++
+
+	reg.runs = append(reg.runs, run)
+	reg.evict()
+	return run.ID
+}
+
+// evict drops the oldest finished runs while there are more than the limit.
+// Running runs stay, so they can still be cancelled. The caller must hold
+// the mutex.
+func (reg *runRegistry) evict() {
+	excess := len(reg.runs) - reg.limit
+	if excess <= 0 {
+		return
+	}
+	kept := reg.runs[:0]
+	for _, run := range reg.runs {
+		if excess > 0 && run.State != runRunning {
+			excess--
+			continue
+		}
+		kept = append(kept, run)
+	}
+	for i := len(kept); i < len(reg.runs); i++ {
+		reg.runs[i] = nil
+	}
+	reg.runs = kept
+}
+
+// runDetailsKey is the context key for the *runDetails of the run a demo
+// belongs to
+type runDetailsKey struct{}
+
+// runDetails holds what a run reports besides its result counters. Only the
+// run's own goroutine sets it, before launch reads it.
+type runDetails struct {
+	workers []workerStats
+	keys    []keyCount
+}
+
+// setRunWorkers records per-worker stats for the run ctx belongs to. It does
+// nothing outside a launched run.
+func setRunWorkers(ctx context.Context, workers []workerStats) {
+	if details, ok := ctx.Value(runDetailsKey{}).(*runDetails); ok {
+		details.workers = workers
+	}
+}
+
+// setRunKeys records the hottest data keys for the run ctx belongs to. It
+// does nothing outside a launched run.
+func setRunKeys(ctx context.Context, keys []keyCount) {
+	if details, ok := ctx.Value(runDetailsKey{}).(*runDetails); ok {
+		details.keys = keys
+	}
+}
+
+// finish moves a run out of the running state and appends it to the history
+// file. A run that completes after being cancelled is recorded as cancelled.
+// Runs already evicted are ignored.
+func (reg *runRegistry) finish(id int, state runState, result map[string]int64, details runDetails, errMsg string) {
+	reg.mutex.Lock()
+	run := reg.find(id)
+	if run == nil {
+		reg.mutex.Unlock()
+		return
+	}
+	if state == runCompleted && run.cancelRequested {
+		state = runCancelled
+	}
+	now := time.Now()
+	run.State = state
+	run.FinishedAt = &now
+	run.Result = result
+	run.Workers = details.workers
+	run.Keys = details.keys
+	run.Error = errMsg
+	record := *run
+	reg.evict()
+	reg.mutex.Unlock()
+
+	if err := reg.appendHistory(record); err != nil {
+		slog.Error("Failed to record run in the run history", "run", id, "err", err)
+	}
+}
+
+// runFailureKey is the context key for the *runFailure of the run a demo
+// belongs to
+type runFailureKey struct{}
+
+// runFailure holds the first panic recovered from one of a run's workers
+type runFailure struct {
+	mutex   sync.Mutex
+	message string
+}
+
+// recoverWorker is deferred by the goroutines of a run's workers. It turns a
+// panic into a failure of the run ctx belongs to, leaving the other workers
+// to finish; outside a launched run it lets the panic continue.
+func recoverWorker(ctx context.Context) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	failure, ok := ctx.Value(runFailureKey{}).(*runFailure)
+	if !ok {
+		panic(p)
+	}
+	slog.ErrorContext(ctx, "Demo worker panicked", "panic", p)
+
+	failure.mutex.Lock()
+	defer failure.mutex.Unlock()
+	if failure.message == "" {
+		failure.message = fmt.Sprint(p)
+	}
+}
+
+// err returns the recovered panic's message, or "" if no worker panicked
+func (f *runFailure) err() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.message
+}
+
+// launch records a run and executes it in the background with a context that
+// cancel cancels. The run's goroutines carry its ID as the run_id profile
+// label. A panic in the run's own goroutine, or in a worker started with
+// withWorkerLabel, marks the run failed instead of crashing the server;
+// goroutines the demo starts any other way are not covered.
+func (reg *runRegistry) launch(demo string, params map[string]int, run func(ctx context.Context) map[string]int64) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	id := reg.start(demo, params)
+
+	done := make(chan struct{})
+	reg.mutex.Lock()
+	if record := reg.find(id); record != nil {
+		record.cancel = cancel
+		record.done = done
+	}
+	reg.mutex.Unlock()
+
+	// Demos report anything beyond their result counters through the context
+	details := &runDetails{}
+	ctx = context.WithValue(ctx, runDetailsKey{}, details)
+	failure := &runFailure{}
+	ctx = context.WithValue(ctx, runFailureKey{}, failure)
+
+	reg.active.Add(1)
+	go func() {
+		defer reg.active.Done()
+		defer close(done)
+		defer cancel()
+		defer func() {
+			if p := recover(); p != nil {
+				reg.finish(id, runFailed, nil, runDetails{}, fmt.Sprint(p))
+			}
+		}()
+		var result map[string]int64
+		withRunLabel(ctx, id, func(ctx context.Context) {
+			result = run(ctx)
+		})
+		if message := failure.err(); message != "" {
+			reg.finish(id, runFailed, result, *details, message)
+			return
+		}
+		reg.finish(id, runCompleted, result, *details, "")
+	}()
+
+	return id
+}
+
+// link records child as started by parent
+func (reg *runRegistry) link(parent, child int) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	if run := reg.find(child); run != nil {
+		run.Parent = parent
+	}
+	if run := reg.find(parent); run != nil {
+		run.Children = append(run.Children, child)
+	}
+}
+
+// cancel asks a running run to stop. The run stays in the running state until
+// the demo has wound down and reports what it got done.
+func (reg *runRegistry) cancel(id int) error {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	run := reg.find(id)
+	if run == nil {
+		return errRunNotFound
+	}
+	if run.State != runRunning || run.cancel == nil {
+		return errRunNotRunning
+	}
+	run.cancelRequested = true
+	run.cancel()
+	return nil
+}
+
+// cancelAll asks every running run to stop and returns their IDs
+func (reg *runRegistry) cancelAll() []int {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	cancelled := []int{}
+	for _, run := range reg.runs {
+		if run.State == runRunning && run.cancel != nil {
+			run.cancelRequested = true
+			run.cancel()
+			cancelled = append(cancelled, run.ID)
+		}
+	}
+	return cancelled
+}
+
+// shutdown cancels every running run and waits for their goroutines to
+// return, or for ctx to be done. It returns how many runs it interrupted.
+func (reg *runRegistry) shutdown(ctx context.Context) int {
+	interrupted := len(reg.cancelAll())
+
+	done := make(chan struct{})
+	go func() {
+		reg.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return interrupted
+}
+
+// find returns the run with the given ID; the caller must hold the mutex
+func (reg *runRegistry) find(id int) *demoRun {
+	for _, run := range reg.runs {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+// get returns a copy of a run
+func (reg *runRegistry) get(id int) (demoRun, bool) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	run := reg.find(id)
+	if run == nil {
+		return demoRun{}, false
+	}
+	return *run, true
+}
+
+// wait waits for a run to finish, or for ctx to be done, and returns a copy
+// of it. It reports false if the run is unknown or evicted, or ctx is done
+// first.
+func (reg *runRegistry) wait(ctx context.Context, id int) (demoRun, bool) {
+	reg.mutex.Lock()
+	var done chan struct{}
+	if run := reg.find(id); run != nil {
+		done = run.done
+	}
+	reg.mutex.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return demoRun{}, false
+		}
+	}
+	return reg.get(id)
+}
+
+// list returns copies of every run, oldest first
+func (reg *runRegistry) list() []demoRun {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	runs := make([]demoRun, len(reg.runs))
+	for i, run := range reg.runs {
+		runs[i] = *run
+	}
+	return runs
+}
+
+// startedRun tells the client where to follow up on a run. It sets a header,
+// so it must be called before anything else is written.
+func startedRun(w http.ResponseWriter, id int) {
+	w.Header().Set("Location", fmt.Sprintf("/runs/%d", id))
+	fmt.Fprintf(w, "Run %d: status at /runs/%d\n", id, id)
+}
+
+// HTTP handler that lists runs at /runs, returns a single run at GET /runs/{id}
+// and cancels one with DELETE /runs/{id}
+func runsHandler(reg *runRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idText := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/runs"), "/")
+		if idText == "" {
+			writeJSON(w, reg.list())
+			return
+		}
+
+		id, err := strconv.Atoi(idText)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid run ID %q", idText), http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			// The response shows the run still running while it winds down
+			switch err := reg.cancel(id); err {
+			case nil:
+			case errRunNotFound:
+				http.Error(w, fmt.Sprintf("run %d not found", id), http.StatusNotFound)
+				return
+			default:
+				http.Error(w, fmt.Sprintf("run %d: %v", id, err), http.StatusConflict)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		run, ok := reg.get(id)
+		if !ok {
+			http.Error(w, fmt.Sprintf("run %d not found", id), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, run)
+	}
+}
+
+// HTTP handler that cancels every running demo run with POST /cancel-demos
+// and returns their IDs. Like DELETE /runs/{id} it doesn't wait for them to
+// wind down.
+func cancelDemosHandler(reg *runRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, map[string][]int{"cancelled": reg.cancelAll()})
+	}
+}
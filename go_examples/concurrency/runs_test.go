@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForRun polls /runs/{id} on mux until the run leaves the running state
+func waitForRun(t *testing.T, mux *http.ServeMux, location string) demoRun {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		req := httptest.NewRequest("GET", location, nil)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d: %s", location, recorder.Code, recorder.Body.String())
+		}
+
+		var run demoRun
+		if err := json.Unmarshal(recorder.Body.Bytes(), &run); err != nil {
+			t.Fatalf("Failed to decode run: %v", err)
+		}
+		if run.State != runRunning {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Run at %s still running after 10s", location)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRunsEndpoint(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/runs", runsHandler(demoRuns))
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+
+	// Let runs started by other tests finish before resetting the resource
+	for _, run := range demoRuns.list() {
+		if run.State == runRunning {
+			waitForRun(t, mux, fmt.Sprintf("/runs/%d", run.ID))
+		}
+	}
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+
+	req := httptest.NewRequest("GET", "/mutex-demo?workers=4&iterations=5", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	location := recorder.Header().Get("Location")
+	if !strings.HasPrefix(location, "/runs/") || !strings.Contains(recorder.Body.String(), location) {
+		t.Fatalf("Expected a run location in the header and body, got %q and %s", location, recorder.Body.String())
+	}
+
+	run := waitForRun(t, mux, location)
+	if run.State != runCompleted || run.Demo != "mutex" || run.FinishedAt == nil {
+		t.Fatalf("Expected a completed mutex run, got %+v", run)
+	}
+	if run.Params["workers"] != 4 || run.Params["iterations"] != 5 {
+		t.Errorf("Expected recorded params workers=4 iterations=5, got %v", run.Params)
+	}
+	if run.Result["iterations"] != 20 {
+		t.Errorf("Expected 20 worker iterations completed, got %d", run.Result["iterations"])
+	}
+
+	basicResource.mutex.Lock()
+	counter := basicResource.counter
+	basicResource.mutex.Unlock()
+	// Workers 0 and 3 write
+	if run.Result["counter"] != int64(counter) || counter != 10 {
+		t.Errorf("Expected recorded counter to match resource counter 10, got %d and %d",
+			run.Result["counter"], counter)
+	}
+
+	// The run is also listed
+	req = httptest.NewRequest("GET", "/runs", nil)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	var runs []demoRun
+	if err := json.Unmarshal(recorder.Body.Bytes(), &runs); err != nil {
+		t.Fatalf("Failed to decode run list: %v", err)
+	}
+	if len(runs) == 0 || runs[len(runs)-1].ID != run.ID {
+		t.Errorf("Expected run %d last in the list, got %+v", run.ID, runs)
+	}
+}
+
+func TestCancelRun(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+
+	// Far more work than can finish during the test
+	req := httptest.NewRequest("GET", "/mutex-demo?workers=10&iterations=10000", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	location := recorder.Header().Get("Location")
+
+	time.Sleep(50 * time.Millisecond)
+	req = httptest.NewRequest("DELETE", location, nil)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 cancelling %s, got %d: %s", location, recorder.Code, recorder.Body.String())
+	}
+	cancelledAt := time.Now()
+
+	run := waitForRun(t, mux, location)
+	if elapsed := time.Since(cancelledAt); elapsed > 2*time.Second {
+		t.Errorf("Expected the run to stop within 2s of cancellation, took %v", elapsed)
+	}
+	if run.State != runCancelled {
+		t.Fatalf("Expected a cancelled run, got %+v", run)
+	}
+	if completed, ok := run.Result["iterations"]; !ok || completed >= 10*10000 {
+		t.Errorf("Expected a partial iteration count, got %v", run.Result)
+	}
+
+	// A finished run can't be cancelled again
+	req = httptest.NewRequest("DELETE", location, nil)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 cancelling a finished run, got %d", recorder.Code)
+	}
+}
+
+func TestCancelDemos(t *testing.T) {
+	basicResource = &SharedResource{
+		data: make(map[string]int),
+	}
+	rwResource = &SharedResource{
+		data: make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/rwmutex-demo", rwMutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+	mux.HandleFunc("/cancel-demos", cancelDemosHandler(demoRuns))
+
+	// Workers 0 and 3 (mutex) and 0 and 5 (rwmutex) write 10000 times each
+	var locations []string
+	for _, url := range []string{"/mutex-demo?workers=6&iterations=10000", "/rwmutex-demo?workers=10&iterations=10000"} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("GET", url, nil))
+		locations = append(locations, recorder.Header().Get("Location"))
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("POST", "/cancel-demos", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 cancelling demos, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Cancelled []int `json:"cancelled"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	for _, location := range locations {
+		found := false
+		for _, id := range response.Cancelled {
+			found = found || location == fmt.Sprintf("/runs/%d", id)
+		}
+		if !found {
+			t.Errorf("Expected %s among the cancelled runs %v", location, response.Cancelled)
+		}
+	}
+
+	for _, location := range locations {
+		run := waitForRun(t, mux, location)
+		if run.State != runCancelled {
+			t.Errorf("Expected a cancelled run, got %+v", run)
+		}
+		// The writers stopped long before their 2 * 10000 increments
+		if counter := run.Result["counter"]; counter >= 2*10000/10 {
+			t.Errorf("Expected %s to stop early, counter reached %d", location, counter)
+		}
+	}
+
+	// Nothing is left to cancel
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("POST", "/cancel-demos", nil))
+	if strings.TrimSpace(recorder.Body.String()) != `{"cancelled":[]}` {
+		t.Errorf("Expected no runs left to cancel, got %s", recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/cancel-demos", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET, got %d", recorder.Code)
+	}
+}
+
+func TestRunsEndpointErrors(t *testing.T) {
+	reg := newRunRegistry(maxRuns)
+	id := reg.start("mutex", nil)
+	handler := runsHandler(reg)
+
+	testCases := []struct {
+		method   string
+		url      string
+		expected int
+	}{
+		{"GET", "/runs/abc", http.StatusBadRequest},
+		{"GET", "/runs/42", http.StatusNotFound},
+		{"DELETE", "/runs/42", http.StatusNotFound},
+		{"POST", fmt.Sprintf("/runs/%d", id), http.StatusMethodNotAllowed},
+		// Started without launch, so there is nothing to cancel
+		{"DELETE", fmt.Sprintf("/runs/%d", id), http.StatusConflict},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.url, nil)
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		if recorder.Code != tc.expected {
+			t.Errorf("Expected status %d for %s %s, got %d", tc.expected, tc.method, tc.url, recorder.Code)
+		}
+	}
+
+	// An empty registry lists as [] rather than null
+	req := httptest.NewRequest("GET", "/runs", nil)
+	recorder := httptest.NewRecorder()
+	runsHandler(newRunRegistry(maxRuns))(recorder, req)
+	if body := strings.TrimSpace(recorder.Body.String()); body != "[]" {
+		t.Errorf("Expected an empty list, got %s", body)
+	}
+}
+
+func TestRunRegistryCap(t *testing.T) {
+	reg := newRunRegistry(3)
+	for i := 0; i < 5; i++ {
+		id := reg.start("mutex", nil)
+		reg.finish(id, runCompleted, nil, runDetails{}, "")
+	}
+
+	runs := reg.list()
+	if len(runs) != 3 || runs[0].ID != 3 || runs[2].ID != 5 {
+		t.Errorf("Expected runs 3 to 5 to be kept, got %+v", runs)
+	}
+	if _, ok := reg.get(1); ok {
+		t.Error("Expected run 1 to be evicted")
+	}
+
+	// Finishing an evicted run is a no-op
+	reg.finish(1, runCompleted, nil, runDetails{}, "")
+}
+
+func TestRunRegistryKeepsRunningRuns(t *testing.T) {
+	reg := newRunRegistry(2)
+	release := make(chan struct{})
+	block := func(ctx context.Context) map[string]int64 {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+	first := reg.launch("mutex", nil, block)
+	second := reg.launch("mutex", nil, block)
+	third := reg.launch("mutex", nil, block)
+
+	// Over the limit, but every run can still be found and cancelled
+	if runs := reg.list(); len(runs) != 3 {
+		t.Errorf("Expected all 3 running runs kept, got %+v", runs)
+	}
+	if err := reg.cancel(first); err != nil {
+		t.Errorf("Expected the oldest running run to be cancellable, got %v", err)
+	}
+	// The first is still winding down, so it is cancelled again
+	if cancelled := reg.cancelAll(); len(cancelled) != 3 || cancelled[2] != third {
+		t.Errorf("Expected runs %d to %d cancelled, got %v", first, third, cancelled)
+	}
+	close(release)
+	for _, id := range []int{first, second, third} {
+		reg.wait(context.Background(), id)
+	}
+
+	// Once finished, they are evicted down to the limit
+	if runs := reg.list(); len(runs) != 2 {
+		t.Errorf("Expected 2 runs kept once finished, got %+v", runs)
+	}
+}
+
+func TestRunRegistryFailedRun(t *testing.T) {
+	reg := newRunRegistry(maxRuns)
+	id := reg.launch("channel", nil, func(ctx context.Context) map[string]int64 {
+		panic("send on closed channel")
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		run, _ := reg.get(id)
+		if run.State == runFailed {
+			if run.Error != "send on closed channel" || run.Result != nil {
+				t.Errorf("Expected the panic message as the error, got %+v", run)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected run to fail, got state %s", run.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunRegistryFailedWorker(t *testing.T) {
+	reg := newRunRegistry(maxRuns)
+	id := reg.launch("channel", nil, func(ctx context.Context) map[string]int64 {
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			failing := i == 1
+			go withWorkerLabel(ctx, i, func(ctx context.Context) {
+				defer wg.Done()
+				if failing {
+					panic("worker failed")
+				}
+			})
+		}
+		wg.Wait()
+		return map[string]int64{"workers": 3}
+	})
+
+	run, ok := reg.wait(context.Background(), id)
+	if !ok || run.State != runFailed || run.Error != "worker failed" {
+		t.Errorf("Expected the worker's panic to fail the run, got %+v", run)
+	}
+	if run.Result["workers"] != 3 {
+		t.Errorf("Expected the run's result kept, got %v", run.Result)
+	}
+}
+
+func TestRunRegistryWait(t *testing.T) {
+	reg := newRunRegistry(maxRuns)
+	release := make(chan struct{})
+	id := reg.launch("mutex", nil, func(ctx context.Context) map[string]int64 {
+		<-release
+		return map[string]int64{"iterations": 3}
+	})
+
+	// A context that ends first gives up without the run
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, ok := reg.wait(ctx, id); ok {
+		t.Error("Expected wait to give up once its context is done")
+	}
+
+	close(release)
+	run, ok := reg.wait(context.Background(), id)
+	if !ok || run.State != runCompleted || run.Result["iterations"] != 3 {
+		t.Errorf("Expected the completed run, got %+v (%v)", run, ok)
+	}
+
+	if _, ok := reg.wait(context.Background(), id+1); ok {
+		t.Error("Expected wait to report an unknown run")
+	}
+}
+
+func TestRunRegistryShutdown(t *testing.T) {
+	reg := newRunRegistry(maxRuns)
+	var ids []int
+	for i := 0; i < 3; i++ {
+		ids = append(ids, reg.launch("mutex", nil, func(ctx context.Context) map[string]int64 {
+			<-ctx.Done()
+			return map[string]int64{"iterations": 1}
+		}))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if n := reg.shutdown(ctx); n != 3 {
+		t.Errorf("Expected 3 interrupted runs, got %d", n)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Expected shutdown to return once the runs were cancelled")
+	}
+
+	// Every run has finished by the time shutdown returns
+	for _, id := range ids {
+		if run, _ := reg.get(id); run.State != runCancelled {
+			t.Errorf("Expected run %d to be cancelled, got %s", id, run.State)
+		}
+	}
+
+	// A run that ignores cancellation only holds shutdown up until ctx is done
+	release := make(chan struct{})
+	defer close(release)
+	reg.launch("mutex", nil, func(ctx context.Context) map[string]int64 {
+		<-release
+		return nil
+	})
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	reg.shutdown(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected shutdown to give up after its timeout, took %v", elapsed)
+	}
+}
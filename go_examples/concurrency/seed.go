@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+)
+
+// Context keys for a run's base seed and a worker's random source
+type (
+	seedKey       struct{}
+	workerRandKey struct{}
+)
+
+// withSeed makes the workers started under ctx draw from random sources
+// derived from seed, so the run can be repeated exactly. A nil seed leaves
+// them on the global source.
+func withSeed(ctx context.Context, seed *int64) context.Context {
+	if seed == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, seedKey{}, *seed)
+}
+
+// withWorkerRand gives worker its own random source derived from the run's
+// seed, or returns ctx unchanged for an unseeded run
+func withWorkerRand(ctx context.Context, worker int) context.Context {
+	seed, ok := ctx.Value(seedKey{}).(int64)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, workerRandKey{}, rand.New(rand.NewSource(workerSeed(seed, worker))))
+}
+
+// workerRand returns the worker's random source, or nil for an unseeded run.
+// A source isn't safe for concurrent use, so only the worker may draw from it.
+func workerRand(ctx context.Context) *rand.Rand {
+	rng, _ := ctx.Value(workerRandKey{}).(*rand.Rand)
+	return rng
+}
+
+// workerSeed derives worker's seed from the run's, spreading neighbouring
+// workers across the seed space
+func workerSeed(seed int64, worker int) int64 {
+	return seed ^ int64(uint64(worker+1)*0x9E3779B97F4A7C15)
+}
+
+// randInt63n draws from rng, or from the global source when rng is nil
+func randInt63n(rng *rand.Rand, n int64) int64 {
+	if rng == nil {
+		return rand.Int63n(n)
+	}
+	return rng.Int63n(n)
+}
+
+// randIntn draws from rng, or from the global source when rng is nil
+func randIntn(rng *rand.Rand, n int) int {
+	if rng == nil {
+		return rand.Intn(n)
+	}
+	return rng.Intn(n)
+}
+
+// seedParam parses the optional ?seed=N parameter, returning nil when it is
+// absent
+func seedParam(r *http.Request) (*int64, error) {
+	value := r.URL.Query().Get("seed")
+	if value == "" {
+		return nil, nil
+	}
+
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed parameter: %q is not a number", value)
+	}
+	return &seed, nil
+}
+
+// withSeedParam adds seed, if any, to a run's recorded parameters
+func withSeedParam(params map[string]int, seed *int64) map[string]int {
+	if seed != nil {
+		params["seed"] = int(*seed)
+	}
+	return params
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerRand(t *testing.T) {
+	if rng := workerRand(withWorkerRand(context.Background(), 0)); rng != nil {
+		t.Error("Expected no random source for an unseeded run")
+	}
+
+	seed := int64(42)
+	ctx := withSeed(context.Background(), &seed)
+	draw := func(worker int) []int64 {
+		rng := workerRand(withWorkerRand(ctx, worker))
+		values := make([]int64, 5)
+		for i := range values {
+			values[i] = rng.Int63()
+		}
+		return values
+	}
+	if a, b := draw(1), draw(1); !reflect.DeepEqual(a, b) {
+		t.Errorf("Expected the same worker of the same seed to repeat its draws, got %v and %v", a, b)
+	}
+	if a, b := draw(1), draw(2); reflect.DeepEqual(a, b) {
+		t.Errorf("Expected workers to get different sources, both drew %v", a)
+	}
+}
+
+// recordSleeps runs f with the demos' sleeps recorded instead of slept and
+// returns their durations sorted, since workers interleave differently each run
+func recordSleeps(f func()) []time.Duration {
+	var mutex sync.Mutex
+	var slept []time.Duration
+	oldSleep := sleep
+	sleep = func(d time.Duration) {
+		mutex.Lock()
+		slept = append(slept, d)
+		mutex.Unlock()
+	}
+	defer func() { sleep = oldSleep }()
+
+	f()
+	sort.Slice(slept, func(i, j int) bool { return slept[i] < slept[j] })
+	return slept
+}
+
+func TestSeededDemoSleeps(t *testing.T) {
+	cfg := DemoConfig{PreLockSleepMax: 5 * time.Millisecond, HoldSleepMax: 10 * time.Millisecond, ReadSleepMax: 3 * time.Millisecond}
+	demos := []struct {
+		name string
+		run  func(ctx context.Context)
+	}{
+		{"mutex", func(ctx context.Context) {
+			basicResource = &SharedResource{
+				data: make(map[string]int),
+			}
+			runMutexDemo(ctx, cfg, 6, 20)
+		}},
+		{"semaphore", func(ctx context.Context) { runSemaphoreDemo(ctx, 6, 2, 20) }},
+		{"atomic", func(ctx context.Context) { runAtomicDemo(ctx, 6, 20) }},
+		{"sharded", func(ctx context.Context) { runShardedDemo(ctx, NewShardedResource(4), 6, 20) }},
+	}
+
+	for _, demo := range demos {
+		run := func(seed int64) []time.Duration {
+			return recordSleeps(func() { demo.run(withSeed(context.Background(), &seed)) })
+		}
+
+		first, second := run(7), run(7)
+		if len(first) == 0 || !reflect.DeepEqual(first, second) {
+			t.Errorf("%s: expected identical sleeps from two runs with the same seed, got %v and %v", demo.name, first, second)
+		}
+		if other := run(8); reflect.DeepEqual(first, other) {
+			t.Errorf("%s: expected a different seed to draw different sleeps, both gave %v", demo.name, first)
+		}
+	}
+}
+
+func TestSeededTimerStormDelays(t *testing.T) {
+	delays := func(seed int64) []time.Duration {
+		return timerStormDelays(withSeed(context.Background(), &seed), 50, time.Second)
+	}
+
+	first := delays(3)
+	if !reflect.DeepEqual(first, delays(3)) {
+		t.Error("Expected the same delays from the same seed")
+	}
+	if reflect.DeepEqual(first, delays(4)) {
+		t.Errorf("Expected a different seed to draw different delays, both gave %v", first)
+	}
+	for _, delay := range first {
+		if delay < 0 || delay > time.Second {
+			t.Errorf("Delay %v outside the window", delay)
+		}
+	}
+}
+
+func TestSeededChannelDemoItems(t *testing.T) {
+	// Record every item produced, in whatever order the consumers get them
+	var mutex sync.Mutex
+	var items []int
+	oldConsumer := consumer
+	consumer = func(ctx context.Context, wg *sync.WaitGroup, id int, work <-chan int, results chan<- int) {
+		defer wg.Done()
+		for item := range work {
+			mutex.Lock()
+			items = append(items, item)
+			mutex.Unlock()
+			results <- item
+		}
+	}
+	defer func() { consumer = oldConsumer }()
+
+	run := func(seed int64) []int {
+		items = nil
+		runChannelDemo(withSeed(context.Background(), &seed), 2, 2, 20)
+		sort.Ints(items)
+		return items
+	}
+
+	first, second := run(1), run(1)
+	if len(first) != 40 || !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same 40 items from two runs with the same seed, got %v and %v", first, second)
+	}
+	if other := run(2); reflect.DeepEqual(first, other) {
+		t.Errorf("Expected a different seed to produce different items, both gave %v", first)
+	}
+}
+
+func TestSeedParam(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutex-demo", mutexDemoHandler)
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+
+	req := httptest.NewRequest("GET", "/mutex-demo?workers=3&iterations=5&preSleepMs=0&holdMs=0&readMs=0&seed=-12", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	run := waitForRun(t, mux, recorder.Header().Get("Location"))
+	if run.State != runCompleted || run.Params["seed"] != -12 {
+		t.Errorf("Expected a completed run recording seed -12, got %+v", run)
+	}
+
+	// Unseeded runs don't record one
+	req = httptest.NewRequest("GET", "/mutex-demo?workers=3&iterations=5&preSleepMs=0&holdMs=0&readMs=0", nil)
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	run = waitForRun(t, mux, recorder.Header().Get("Location"))
+	if _, ok := run.Params["seed"]; ok {
+		t.Errorf("Expected no seed for an unseeded run, got %v", run.Params)
+	}
+
+	for _, tc := range []struct {
+		url     string
+		handler http.HandlerFunc
+	}{
+		{"/mutex-demo?seed=lucky", mutexDemoHandler},
+		{"/rwmutex-demo?seed=1.5", rwMutexDemoHandler},
+		{"/channel-demo?seed=x", channelDemoHandler},
+		{"/syncmap-resource-demo?seed=x", syncMapResourceDemoHandler},
+		{"/trace-run?demo=channel&seed=x", traceRunHandler},
+		{"/semaphore-demo?seed=x", semaphoreDemoHandler},
+		{"/workerpool-demo?seed=x", workerPoolDemoHandler},
+		{"/sharded-demo?seed=x", shardedDemoHandler},
+		{"/atomic-demo?seed=x", atomicDemoHandler},
+		{"/timer-storm?seed=x", timerStormHandler},
+	} {
+		recorder := httptest.NewRecorder()
+		tc.handler(recorder, httptest.NewRequest("GET", tc.url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, tc.url, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runSemaphoreDemo runs numWorkers workers that each acquire one of permits
+// slots iterations times, hold it for a random duration and release it, until
+// done or ctx is cancelled. It returns the total time workers spent waiting
+// for a permit and the iterations completed.
+func runSemaphoreDemo(ctx context.Context, numWorkers, permits, iterations int) (time.Duration, int64) {
+	return semaphoreDemo(ctx, numWorkers, permits, iterations, func(ctx context.Context) {
+		sleepUpTo(workerRand(ctx), 5*time.Millisecond)
+	})
+}
+
+// semaphoreDemo bounds concurrent calls to work with a buffered-channel
+// semaphore of the given size and returns the aggregate wait for permits and
+// the iterations completed. A worker waiting for a permit gives up once ctx
+// is done. work gets the worker's context, carrying its random source in a
+// seeded run.
+func semaphoreDemo(ctx context.Context, numWorkers, permits, iterations int, work func(ctx context.Context)) (time.Duration, int64) {
+	slog.InfoContext(ctx, "Starting semaphore demo", "workers", numWorkers, "permits", permits)
+
+	// Each buffered slot is a permit; sending acquires and receiving releases
+	sem := make(chan struct{}, permits)
+	var totalWait, completed int64
+	var demoWg sync.WaitGroup
+
+	for i := 0; i < numWorkers; i++ {
+		demoWg.Add(1)
+		go withWorkerLabel(ctx, i, func(ctx context.Context) {
+			defer demoWg.Done()
+			for j := 0; j < iterations; j++ {
+				start := time.Now()
+				// Blocks while all permits are held, which shows up in the block profile
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					atomic.AddInt64(&totalWait, int64(time.Since(start)))
+					return
+				}
+				atomic.AddInt64(&totalWait, int64(time.Since(start)))
+
+				work(ctx)
+				<-sem
+				atomic.AddInt64(&completed, 1)
+			}
+		})
+	}
+
+	demoWg.Wait()
+
+	wait := time.Duration(atomic.LoadInt64(&totalWait))
+	slog.InfoContext(ctx, "Semaphore demo completed", "permitWait", wait, "iterations", completed)
+	return wait, completed
+}
+
+// HTTP handler that starts the semaphore contention demo as a run whose
+// result reports the workers' total wait for permits. With sync=true the
+// response is the finished run.
+func semaphoreDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, iterations, err := workerParams(r, 20)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	permits, err := queryInt(r, "permits", 3, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wait, err := queryBool(r, "sync")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params := map[string]int{"workers": numWorkers, "permits": permits, "iterations": iterations}
+	id := demoRuns.launch("semaphore", withSeedParam(params, seed), func(ctx context.Context) map[string]int64 {
+		permitWait, completed := runSemaphoreDemo(withSeed(ctx, seed), numWorkers, permits, iterations)
+		return map[string]int64{"permitWaitNs": int64(permitWait), "iterations": completed}
+	})
+
+	if wait {
+		run, ok := demoRuns.wait(r.Context(), id)
+		if !ok {
+			http.Error(w, fmt.Sprintf("Run %d did not finish", id), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/runs/%d", id))
+		writeJSON(w, run)
+		return
+	}
+	startedRun(w, id)
+
+	fmt.Fprintf(w, "Started semaphore demo with %d workers, %d permits, %d iterations each\n",
+		numWorkers, permits, iterations)
+}
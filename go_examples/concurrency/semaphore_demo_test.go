@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pprofviz/examples/internal/testutil"
+)
+
+func TestSemaphoreDemoPermits(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	testCases := []struct {
+		workers int
+		permits int
+	}{
+		{1, 1},
+		{10, 1},
+		{10, 3},
+		{4, 8},
+	}
+
+	for _, tc := range testCases {
+		var inFlight, maxInFlight, calls int32
+		work := func(context.Context) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				peak := atomic.LoadInt32(&maxInFlight)
+				if n <= peak || atomic.CompareAndSwapInt32(&maxInFlight, peak, n) {
+					break
+				}
+			}
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}
+
+		semaphoreDemo(context.Background(), tc.workers, tc.permits, 5, work)
+
+		if peak := atomic.LoadInt32(&maxInFlight); peak > int32(tc.permits) {
+			t.Errorf("workers=%d permits=%d: %d workers held permits at once", tc.workers, tc.permits, peak)
+		}
+		if n := atomic.LoadInt32(&calls); n != int32(tc.workers*5) {
+			t.Errorf("workers=%d permits=%d: expected %d iterations, got %d", tc.workers, tc.permits, tc.workers*5, n)
+		}
+	}
+}
+
+func TestSemaphoreDemoWait(t *testing.T) {
+	// One permit and ten workers holding it for 1ms each must queue
+	wait, completed := semaphoreDemo(context.Background(), 10, 1, 2, func(context.Context) { time.Sleep(time.Millisecond) })
+	if wait <= 0 || completed != 20 {
+		t.Errorf("Expected workers to wait for permits over 20 iterations, got %v over %d", wait, completed)
+	}
+
+	// With a permit per worker nobody should wait long
+	if wait, _ := runSemaphoreDemo(context.Background(), 2, 2, 3); wait > time.Second {
+		t.Errorf("Expected little waiting with a permit per worker, got %v", wait)
+	}
+}
+
+func TestSemaphoreDemoCancel(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	// Workers queued behind a long-held permit give up on cancellation
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	wait, completed := semaphoreDemo(ctx, 4, 1, 100, func(context.Context) { time.Sleep(20 * time.Millisecond) })
+	if completed >= 400 || wait <= 0 {
+		t.Errorf("Expected a cancelled demo to stop early having waited, got %d iterations and %v", completed, wait)
+	}
+}
+
+func TestSemaphoreDemoRunResult(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	semaphoreDemoHandler(recorder, httptest.NewRequest("GET", "/semaphore-demo?workers=6&permits=1&iterations=2&sync=true", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var run demoRun
+	if err := json.Unmarshal(recorder.Body.Bytes(), &run); err != nil {
+		t.Fatalf("Failed to decode run: %v", err)
+	}
+	if run.Demo != "semaphore" || run.State != runCompleted || run.Params["permits"] != 1 {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+	if run.Result["iterations"] != 12 || run.Result["permitWaitNs"] <= 0 {
+		t.Errorf("Expected 12 iterations and the time spent waiting for the one permit, got %v", run.Result)
+	}
+}
+
+func TestSemaphoreDemoHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Custom params", "/semaphore-demo?workers=2&permits=1&iterations=1", http.StatusOK, "2 workers, 1 permits, 1 iterations each"},
+		{"Invalid sync", "/semaphore-demo?sync=maybe", http.StatusBadRequest, "invalid sync parameter"},
+		{"Zero permits", "/semaphore-demo?permits=0", http.StatusBadRequest, "invalid permits parameter"},
+		{"Invalid workers", "/semaphore-demo?workers=many", http.StatusBadRequest, "invalid workers parameter"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			recorder := httptest.NewRecorder()
+
+			semaphoreDemoHandler(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected body to contain %q, got: %s", tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
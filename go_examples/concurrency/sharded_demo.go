@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Upper bound for the number of shards in the sharded demo
+const maxShards = 256
+
+// resourceShard is one independently locked slice of a ShardedResource
+type resourceShard struct {
+	mutex sync.Mutex
+	data  map[string]int
+	hits  int // Lock acquisitions, reads and writes
+}
+
+// ShardedResource spreads keys over shards that each have their own mutex,
+// so workers touching different shards don't contend
+type ShardedResource struct {
+	shards []*resourceShard
+}
+
+// NewShardedResource creates a resource with n shards
+func NewShardedResource(n int) *ShardedResource {
+	r := &ShardedResource{shards: make([]*resourceShard, n)}
+	for i := range r.shards {
+		r.shards[i] = &resourceShard{data: make(map[string]int)}
+	}
+	return r
+}
+
+// shardIndex hashes key to a shard with FNV-1a
+func (r *ShardedResource) shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(r.shards)))
+}
+
+// write increments key, holding its shard's lock for a random duration drawn
+// from rng like writeWithMutex
+func (r *ShardedResource) write(rng *rand.Rand, key string) {
+	shard := r.shards[r.shardIndex(key)]
+	shard.mutex.Lock()
+	// Critical section - intentionally sleep while holding the lock to create contention
+	sleepUpTo(rng, 10*time.Millisecond)
+	shard.data[key] = shard.data[key] + 1
+	shard.hits++
+	shard.mutex.Unlock()
+}
+
+// read looks key up under its shard's lock
+func (r *ShardedResource) read(key string) int {
+	shard := r.shards[r.shardIndex(key)]
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	shard.hits++
+	return shard.data[key]
+}
+
+// Data merges the contents of every shard
+func (r *ShardedResource) Data() map[string]int {
+	data := make(map[string]int)
+	for _, shard := range r.shards {
+		shard.mutex.Lock()
+		for key, value := range shard.data {
+			data[key] = value
+		}
+		shard.mutex.Unlock()
+	}
+	return data
+}
+
+// shardedReport summarizes a sharded demo run
+type shardedReport struct {
+	Shards     int     `json:"shards"`
+	Workers    int     `json:"workers"`
+	Iterations int     `json:"iterations"`
+	WallMs     float64 `json:"wallMs"`
+	ShardHits  []int   `json:"shardHits"`
+	Writes     int     `json:"writes"`
+}
+
+// runShardedDemo runs the mutex demo's workload (1/3 writers, 2/3 readers,
+// same keys and sleeps) against a ShardedResource. With one shard it matches
+// runMutexDemo.
+func runShardedDemo(ctx context.Context, resource *ShardedResource, numWorkers, iterations int) shardedReport {
+	slog.InfoContext(ctx, "Starting sharded demo", "shards", len(resource.shards), "workers", numWorkers, "iterations", iterations)
+
+	start := time.Now()
+	var shardWg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		shardWg.Add(1)
+		id := i
+		go withWorkerLabel(ctx, id, func(ctx context.Context) {
+			defer shardWg.Done()
+			rng := workerRand(ctx)
+			for j := 0; j < iterations; j++ {
+				if id%3 == 0 {
+					// Simulate some work before acquiring the lock
+					sleepUpTo(rng, 5*time.Millisecond)
+					resource.write(rng, workerKey(id))
+				} else {
+					sleepUpTo(rng, 3*time.Millisecond)
+					resource.read(workerKey(id % 5))
+				}
+			}
+		})
+	}
+	shardWg.Wait()
+
+	report := shardedReport{
+		Shards:     len(resource.shards),
+		Workers:    numWorkers,
+		Iterations: iterations,
+		WallMs:     float64(time.Since(start)) / float64(time.Millisecond),
+		ShardHits:  make([]int, len(resource.shards)),
+	}
+	for i, shard := range resource.shards {
+		shard.mutex.Lock()
+		report.ShardHits[i] = shard.hits
+		for _, value := range shard.data {
+			report.Writes += value
+		}
+		shard.mutex.Unlock()
+	}
+
+	slog.InfoContext(ctx, "Sharded demo completed", "wallMs", report.WallMs)
+	return report
+}
+
+// HTTP handler that runs the sharded demo and returns its report as JSON
+func shardedDemoHandler(w http.ResponseWriter, r *http.Request) {
+	shards, err := queryInt(r, "shards", 8, 1, maxShards)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	numWorkers, iterations, err := workerParams(r, 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runShardedDemo(withSeed(r.Context(), seed), NewShardedResource(shards), numWorkers, iterations))
+}
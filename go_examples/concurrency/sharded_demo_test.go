@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"pprofviz/examples/internal/testutil"
+)
+
+func TestShardedDemoSingleShard(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	numWorkers, iterations := 7, 5
+
+	resource := NewShardedResource(1)
+	report := runShardedDemo(context.Background(), resource, numWorkers, iterations)
+
+	// Like runMutexDemo, every third worker writes its own key once per iteration
+	expected := map[string]int{}
+	for id := 0; id < numWorkers; id += 3 {
+		expected[workerKey(id)] = iterations
+	}
+	if data := resource.Data(); !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected the mutex demo's data %v, got %v", expected, data)
+	}
+	if report.Writes != 3*iterations {
+		t.Errorf("Expected %d writes, got %d", 3*iterations, report.Writes)
+	}
+	if report.ShardHits[0] != numWorkers*iterations {
+		t.Errorf("Expected every operation on the single shard (%d), got %d", numWorkers*iterations, report.ShardHits[0])
+	}
+}
+
+func TestShardedResourceDistribution(t *testing.T) {
+	// 0.1% critical values of the chi-square distribution with shards-1
+	// degrees of freedom
+	for _, tc := range []struct {
+		shards   int
+		critical float64
+	}{
+		{2, 10.83},
+		{8, 24.32},
+		{16, 37.70},
+	} {
+		shards := tc.shards
+		resource := NewShardedResource(shards)
+		keys := 2000
+		counts := make([]int, shards)
+		for i := 0; i < keys; i++ {
+			counts[resource.shardIndex(workerKey(i))]++
+		}
+
+		// Chi-square against a uniform spread
+		expected := float64(keys) / float64(shards)
+		chiSquare := 0.0
+		for _, count := range counts {
+			diff := float64(count) - expected
+			chiSquare += diff * diff / expected
+		}
+		if chiSquare > tc.critical {
+			t.Errorf("shards=%d: worker keys spread unevenly (chi-square %.1f > %.2f): %v", shards, chiSquare, tc.critical, counts)
+		}
+	}
+}
+
+func TestShardedDemoHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/sharded-demo?shards=4&workers=6&iterations=3", nil)
+	recorder := httptest.NewRecorder()
+
+	shardedDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var report shardedReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if report.Shards != 4 || len(report.ShardHits) != 4 {
+		t.Errorf("Expected 4 shards, got %+v", report)
+	}
+	total := 0
+	for _, hits := range report.ShardHits {
+		total += hits
+	}
+	if total != 6*3 {
+		t.Errorf("Expected %d shard hits, got %d", 6*3, total)
+	}
+
+	for _, url := range []string{"/sharded-demo?shards=0", "/sharded-demo?shards=1000"} {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+		shardedDemoHandler(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", url, http.StatusBadRequest, recorder.Code)
+		}
+	}
+}
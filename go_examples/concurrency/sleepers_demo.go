@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// Hard cap on /sleepers goroutines asleep at once, across requests
+const maxSleepers = 200000
+
+// Rough memory cost of one sleeping goroutine: its starting stack plus the
+// runtime's bookkeeping
+const sleeperBytes = 4 << 10
+
+// Memory the sleepers may take the process up to when GOMEMLIMIT isn't set
+const defaultSleepersMemory = 1 << 30
+
+// Goroutines started by /sleepers that haven't woken yet
+var sleeping int64
+
+// sleeperSites are the distinct places sleepers sleep at, so the goroutine
+// profile groups them into a few large buckets rather than one
+var sleeperSites = []func(time.Duration){sleepIdle, sleepPolling, sleepBackoff, sleepTimeout}
+
+//go:noinline
+func sleepIdle(d time.Duration) { time.Sleep(d) }
+
+//go:noinline
+func sleepPolling(d time.Duration) { time.Sleep(d) }
+
+//go:noinline
+func sleepBackoff(d time.Duration) { time.Sleep(d) }
+
+//go:noinline
+func sleepTimeout(d time.Duration) { time.Sleep(d) }
+
+// sleepersResult reports a /sleepers request
+type sleepersResult struct {
+	Created          int   `json:"created"`
+	Seconds          int   `json:"seconds"`
+	Sites            int   `json:"sites"`
+	Sleeping         int64 `json:"sleeping"` // Including earlier requests' sleepers
+	GoroutinesBefore int   `json:"goroutinesBefore"`
+	GoroutinesAfter  int   `json:"goroutinesAfter"`
+}
+
+// sleepersMemoryLimit returns GOMEMLIMIT, or defaultSleepersMemory when unset
+func sleepersMemoryLimit() int64 {
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		return limit
+	}
+	return defaultSleepersMemory
+}
+
+// sleepersFit reports whether count more sleepers fit in limit bytes, given
+// the process already has inUse bytes from the OS
+func sleepersFit(count int, inUse uint64, limit int64) bool {
+	return inUse+uint64(count)*sleeperBytes <= uint64(limit)
+}
+
+// startSleepers starts count goroutines sleeping for duration, spread across
+// sleeperSites, and returns how many sleepers are now asleep. It starts none
+// if that would take the sleepers past maxSleepers.
+func startSleepers(count int, duration time.Duration) (int64, error) {
+	if n := atomic.AddInt64(&sleeping, int64(count)); n > maxSleepers {
+		atomic.AddInt64(&sleeping, -int64(count))
+		return 0, fmt.Errorf("%d sleepers would exceed the limit of %d", n, maxSleepers)
+	}
+
+	for i := 0; i < count; i++ {
+		go func(sleep func(time.Duration)) {
+			defer atomic.AddInt64(&sleeping, -1)
+			sleep(duration)
+		}(sleeperSites[i%len(sleeperSites)])
+	}
+	return atomic.LoadInt64(&sleeping), nil
+}
+
+// HTTP handler that starts count goroutines sleeping for the given seconds
+// and returns straight away, to stress goroutine profiles and their rendering
+func sleepersHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := queryInt(r, "count", 10000, 1, maxSleepers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seconds, err := queryInt(r, "seconds", 30, 1, maxGoroutineDemoSeconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	if limit := sleepersMemoryLimit(); !sleepersFit(count, memStats.Sys, limit) {
+		http.Error(w, fmt.Sprintf("%d sleepers would take the process past its %d MB memory limit", count, limit>>20),
+			http.StatusServiceUnavailable)
+		return
+	}
+
+	before := runtime.NumGoroutine()
+	total, err := startSleepers(count, time.Duration(seconds)*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	writeJSON(w, sleepersResult{
+		Created:          count,
+		Seconds:          seconds,
+		Sites:            len(sleeperSites),
+		Sleeping:         total,
+		GoroutinesBefore: before,
+		GoroutinesAfter:  runtime.NumGoroutine(),
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSleepersHandler(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	recorder := httptest.NewRecorder()
+	sleepersHandler(recorder, httptest.NewRequest("GET", "/sleepers?count=200&seconds=1", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var result sleepersResult
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode sleepers result: %v", err)
+	}
+	if result.Created != 200 || result.Sleeping < 200 || result.GoroutinesAfter < result.GoroutinesBefore+200 {
+		t.Errorf("Expected 200 more goroutines, got %+v", result)
+	}
+
+	// They all wake after a second
+	if n := waitForGoroutines(5*time.Second, func(n int) bool { return n <= baseline }); n > baseline {
+		t.Errorf("Expected goroutines back to %d after the sleepers woke, got %d", baseline, n)
+	}
+	if n := atomic.LoadInt64(&sleeping); n != 0 {
+		t.Errorf("Expected no sleepers left, got %d", n)
+	}
+}
+
+func TestSleepersHandlerLimits(t *testing.T) {
+	for _, url := range []string{"/sleepers?count=0", "/sleepers?count=200001", "/sleepers?seconds=0", "/sleepers?seconds=601"} {
+		recorder := httptest.NewRecorder()
+		sleepersHandler(recorder, httptest.NewRequest("GET", url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+
+	// The cap counts sleepers from earlier requests too
+	atomic.AddInt64(&sleeping, maxSleepers-10)
+	defer atomic.AddInt64(&sleeping, -(maxSleepers - 10))
+	recorder := httptest.NewRecorder()
+	sleepersHandler(recorder, httptest.NewRequest("GET", "/sleepers?count=11&seconds=1", nil))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d past the cap, got %d", http.StatusTooManyRequests, recorder.Code)
+	}
+}
+
+func TestSleepersFit(t *testing.T) {
+	if !sleepersFit(1000, 10<<20, 20<<20) {
+		t.Error("Expected 1000 sleepers to fit in 10MB of headroom")
+	}
+	if sleepersFit(maxSleepers, 100<<20, 256<<20) {
+		t.Error("Expected the cap's worth of sleepers not to fit in 156MB of headroom")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How long the starvation demo's receiver spends on each value
+const starvationReceiveDelay = time.Millisecond
+
+// Bounds for the starvation demo's duration parameter
+const (
+	minStarvationDuration = 10 * time.Millisecond
+	maxStarvationDuration = time.Minute
+)
+
+// starvationStats summarizes a starvation demo run
+type starvationStats struct {
+	Senders    int     `json:"senders"`
+	DurationMs float64 `json:"durationMs"`
+	Received   int64   `json:"received"`
+	Sends      []int64 `json:"sends"` // Successful sends by each sender
+	MinSends   int64   `json:"minSends"`
+	MaxSends   int64   `json:"maxSends"`
+	BlockedMs  float64 `json:"blockedMs"` // Time all senders spent waiting to send
+}
+
+// runStarvationDemo has senders compete on an unbuffered channel drained by a
+// single slow receiver for the given duration
+func runStarvationDemo(senders int, duration time.Duration) starvationStats {
+	return starvationDemo(senders, duration, func() {
+		time.Sleep(starvationReceiveDelay)
+	})
+}
+
+// starvationDemo runs the starvation demo with receive as the receiver's work
+// for each value. Every sender blocks in the same select until the receiver
+// is ready, so the block profile shows one long-running send site, and the
+// per-sender counts show how evenly the runtime hands out the receiver.
+func starvationDemo(senders int, duration time.Duration, receive func()) starvationStats {
+	slog.Info("Starting starvation demo", "senders", senders, "duration", duration)
+
+	values := make(chan int)
+	stop := make(chan struct{})
+	sends := make([]int64, senders)
+	blocked := make([]time.Duration, senders)
+	var senderWg sync.WaitGroup
+
+	for i := 0; i < senders; i++ {
+		senderWg.Add(1)
+		go func(id int) {
+			defer senderWg.Done()
+			// Each sender only writes its own slots
+			for {
+				start := time.Now()
+				select {
+				case values <- id:
+					blocked[id] += time.Since(start)
+					sends[id]++
+				case <-stop:
+					blocked[id] += time.Since(start)
+					return
+				}
+			}
+		}(i)
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	var received int64
+receive:
+	for {
+		select {
+		case <-values:
+			received++
+			receive()
+		case <-timer.C:
+			break receive
+		}
+	}
+	close(stop)
+	senderWg.Wait()
+	elapsed := time.Since(start)
+
+	stats := starvationStats{
+		Senders:    senders,
+		DurationMs: float64(elapsed) / float64(time.Millisecond),
+		Received:   received,
+		Sends:      sends,
+		MinSends:   sends[0],
+		MaxSends:   sends[0],
+	}
+	var totalBlocked time.Duration
+	for i, n := range sends {
+		stats.MinSends = min(stats.MinSends, n)
+		stats.MaxSends = max(stats.MaxSends, n)
+		totalBlocked += blocked[i]
+	}
+	stats.BlockedMs = float64(totalBlocked) / float64(time.Millisecond)
+
+	slog.Info("Starvation demo completed", "received", received, "minSends", stats.MinSends, "maxSends", stats.MaxSends)
+	return stats
+}
+
+// HTTP handler that runs the starvation demo and returns its summary as JSON
+func starvationDemoHandler(w http.ResponseWriter, r *http.Request) {
+	senders, err := queryInt(r, "senders", 4, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	duration, err := queryDuration(r, "duration", 5*time.Second, minStarvationDuration, maxStarvationDuration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runStarvationDemo(senders, duration))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sumSends totals the per-sender send counts
+func sumSends(sends []int64) int64 {
+	var total int64
+	for _, n := range sends {
+		total += n
+	}
+	return total
+}
+
+func TestStarvationDemo(t *testing.T) {
+	stats := starvationDemo(4, 200*time.Millisecond, func() { time.Sleep(time.Millisecond) })
+
+	if len(stats.Sends) != 4 {
+		t.Fatalf("Expected counts for 4 senders, got %v", stats.Sends)
+	}
+	if total := sumSends(stats.Sends); total != stats.Received || total == 0 {
+		t.Errorf("Expected sends to equal receives, got %d sends and %d receives", total, stats.Received)
+	}
+	if stats.MinSends > stats.MaxSends || stats.MaxSends == 0 {
+		t.Errorf("Unexpected send range %d to %d", stats.MinSends, stats.MaxSends)
+	}
+
+	// The receiver can only be mid-receive when the duration runs out
+	if stats.DurationMs < 200 || stats.DurationMs > 400 {
+		t.Errorf("Expected the demo to run for about 200ms, took %vms", stats.DurationMs)
+	}
+
+	// With four senders and one receiver, most of the time is spent waiting
+	if stats.BlockedMs < 200 {
+		t.Errorf("Expected senders to block for well over the duration, got %vms", stats.BlockedMs)
+	}
+}
+
+func TestStarvationDemoSingleSender(t *testing.T) {
+	stats := starvationDemo(1, 50*time.Millisecond, func() {})
+
+	if stats.Sends[0] != stats.Received || stats.MinSends != stats.MaxSends {
+		t.Errorf("Expected a single sender to account for every receive, got %+v", stats)
+	}
+}
+
+func TestStarvationDemoHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/starvation-demo?senders=3&duration=100ms", nil)
+	recorder := httptest.NewRecorder()
+
+	starvationDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var stats starvationStats
+	if err := json.Unmarshal(recorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if stats.Senders != 3 || len(stats.Sends) != 3 || sumSends(stats.Sends) != stats.Received {
+		t.Errorf("Unexpected summary: %+v", stats)
+	}
+
+	for _, url := range []string{
+		"/starvation-demo?senders=0",
+		"/starvation-demo?duration=5",
+		"/starvation-demo?duration=1ms",
+		"/starvation-demo?duration=2h",
+	} {
+		req = httptest.NewRequest("GET", url, nil)
+		recorder = httptest.NewRecorder()
+		starvationDemoHandler(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+}
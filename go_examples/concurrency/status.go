@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"pprofviz/examples/internal/memstatus"
+	"pprofviz/examples/internal/profrate"
+	"pprofviz/examples/internal/server"
+)
+
+// demoCounters holds the shared counters incremented by the lock demos' writers
+type demoCounters struct {
+	Mutex   int `json:"mutex"`
+	RWMutex int `json:"rwmutex"`
+}
+
+// appStatus is the state reported by /status and /status.json
+type appStatus struct {
+	Goroutines       int            `json:"goroutines"`
+	LeakedGoroutines int            `json:"leakedGoroutines"`
+	LeakedTickers    int            `json:"leakedTickers"`
+	Counters         demoCounters   `json:"counters"`
+	ActiveRuns       []demoRun      `json:"activeRuns"`
+	Profiling        profrate.Rates `json:"profiling"`
+	memstatus.Stats
+}
+
+// statusSnapshot gathers the app status once so the text and JSON views
+// always agree
+func statusSnapshot() appStatus {
+	active := []demoRun{}
+	for _, run := range demoRuns.list() {
+		if run.State == runRunning {
+			active = append(active, run)
+		}
+	}
+
+	var counters demoCounters
+	basicResource.mutex.Lock()
+	counters.Mutex = basicResource.counter
+	basicResource.mutex.Unlock()
+	rwResource.rwMutex.RLock()
+	counters.RWMutex = rwResource.counter
+	rwResource.rwMutex.RUnlock()
+
+	return appStatus{
+		Goroutines:       runtime.NumGoroutine(),
+		LeakedGoroutines: leakedGoroutineCount(),
+		LeakedTickers:    leakedTickerCount(),
+		Counters:         counters,
+		ActiveRuns:       active,
+		Profiling:        profrate.Current(),
+		Stats:            memstatus.Read(),
+	}
+}
+
+// HTTP handler that prints the app status for humans, or serves it as JSON
+// when the request asks for it
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	if server.WantsJSON(r) {
+		statusJSONHandler(w, r)
+		return
+	}
+	status := statusSnapshot()
+
+	fmt.Fprintf(w, "Concurrency App Status\n")
+	fmt.Fprintf(w, "--------------------\n")
+	fmt.Fprintf(w, "Goroutines: %d\n", status.Goroutines)
+	fmt.Fprintf(w, "Leaked goroutines: %d\n", status.LeakedGoroutines)
+	fmt.Fprintf(w, "Leaked tickers: %d\n", status.LeakedTickers)
+	fmt.Fprintf(w, "Active demo runs: %d\n", len(status.ActiveRuns))
+	fmt.Fprintf(w, "Mutex demo counter: %d\n", status.Counters.Mutex)
+	fmt.Fprintf(w, "RWMutex demo counter: %d\n", status.Counters.RWMutex)
+
+	status.Stats.WriteText(w)
+
+	fmt.Fprintf(w, "Block profile rate: %d\n", status.Profiling.Block)
+	fmt.Fprintf(w, "Mutex profile fraction: %d\n", status.Profiling.Mutex)
+}
+
+// HTTP handler that serves the app status as JSON for scripts
+func statusJSONHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, statusSnapshot())
+}
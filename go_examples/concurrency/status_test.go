@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusJSON(t *testing.T) {
+	// Keep a run active for the duration of the test
+	release := make(chan struct{})
+	defer close(release)
+	id := demoRuns.launch("mutex", map[string]int{"workers": 1}, func(ctx context.Context) map[string]int64 {
+		<-release
+		return nil
+	})
+
+	recorder := httptest.NewRecorder()
+	statusJSONHandler(recorder, httptest.NewRequest("GET", "/status.json", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+
+	// Every field must be present, even when zero
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(recorder.Body.Bytes(), &fields); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	for _, name := range []string{"goroutines", "leakedGoroutines", "leakedTickers", "counters", "allocMiB", "totalAllocMiB", "sysMiB", "numGC", "activeRuns", "profiling"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("Missing %s in status: %s", name, recorder.Body.String())
+		}
+	}
+
+	var status appStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if status.Goroutines <= 0 || status.LeakedGoroutines < 0 {
+		t.Errorf("Expected positive goroutine counts, got %+v", status)
+	}
+	if status.AllocMiB <= 0 || status.SysMiB <= 0 || status.TotalAllocMiB < status.AllocMiB {
+		t.Errorf("Unexpected memory stats: %+v", status.Stats)
+	}
+	if status.Profiling.Block < 0 || status.Profiling.Mutex < 0 {
+		t.Errorf("Expected non-negative profiling rates, got %+v", status.Profiling)
+	}
+
+	found := false
+	for _, run := range status.ActiveRuns {
+		if run.State != runRunning {
+			t.Errorf("Expected only running runs, got %+v", run)
+		}
+		found = found || run.ID == id
+	}
+	if !found {
+		t.Errorf("Expected run %d among active runs, got %+v", id, status.ActiveRuns)
+	}
+}
+
+func TestStatusText(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	statusHandler(recorder, httptest.NewRequest("GET", "/status", nil))
+
+	for _, line := range []string{"Goroutines: ", "Leaked goroutines: ", "Leaked tickers: ", "Active demo runs: ", "Mutex demo counter: ", "Alloc: ", "NumGC: ", "Block profile rate: "} {
+		if !strings.Contains(recorder.Body.String(), line) {
+			t.Errorf("Expected status to contain %q, got:\n%s", line, recorder.Body.String())
+		}
+	}
+}
+
+func TestStatusNegotiation(t *testing.T) {
+	testCases := []struct {
+		name   string
+		url    string
+		accept string
+		json   bool
+	}{
+		{"Default", "/status", "", false},
+		{"Accept header", "/status", "application/json", true},
+		{"Format parameter", "/status?format=json", "", true},
+		{"Browser", "/status", "text/html,application/xhtml+xml,*/*;q=0.8", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			recorder := httptest.NewRecorder()
+			statusHandler(recorder, req)
+
+			var status appStatus
+			isJSON := json.Unmarshal(recorder.Body.Bytes(), &status) == nil
+			if isJSON != tc.json {
+				t.Fatalf("Expected JSON %v, got:\n%s", tc.json, recorder.Body.String())
+			}
+			if tc.json && (status.Goroutines <= 0 || recorder.Header().Get("Content-Type") != "application/json") {
+				t.Errorf("Unexpected JSON status: %s", recorder.Body.String())
+			}
+			if !tc.json && !strings.HasPrefix(recorder.Body.String(), "Concurrency App Status") {
+				t.Errorf("Expected the text status, got:\n%s", recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// Upper bound for /stress's seconds parameter
+const maxStressSeconds = 300
+
+// stressDemo is one demo a stress run starts, sized down so several fit in
+// one process side by side
+type stressDemo struct {
+	name   string
+	params map[string]int
+	run    func(ctx context.Context) map[string]int64
+}
+
+// stressDemos returns the demos a stress run starts. Their sizes would keep
+// them running well past duration, which cuts each of them short.
+func stressDemos(duration time.Duration, cpuSpin bool) []stressDemo {
+	withDuration := func(run func(ctx context.Context) map[string]int64) func(ctx context.Context) map[string]int64 {
+		return func(ctx context.Context) map[string]int64 {
+			ctx, cancel := context.WithTimeout(ctx, duration)
+			defer cancel()
+			return run(ctx)
+		}
+	}
+
+	const lockWorkers, channelItems = 6, maxDemoParam
+	demos := []stressDemo{
+		{"mutex", lockDemoParams(lockWorkers, maxDemoParam, defaultDemoConfig),
+			withDuration(mutexDemoRun(defaultDemoConfig, lockWorkers, maxDemoParam, nil))},
+		{"rwmutex", lockDemoParams(lockWorkers, maxDemoParam, defaultDemoConfig),
+			withDuration(rwMutexDemoRun(defaultDemoConfig, lockWorkers, maxDemoParam, nil))},
+		{"channel", map[string]int{"producers": 2, "consumers": 3, "items": channelItems,
+			"workbuf": defaultChannelBuffer, "resultbuf": defaultChannelBuffer, "pool": 0},
+			withDuration(func(ctx context.Context) map[string]int64 {
+				return runChannelDemoBuffered(ctx, 2, 3, channelItems, defaultChannelBuffer, defaultChannelBuffer, 0).result()
+			})},
+	}
+	if cpuSpin {
+		// Leave most processors to the other demos
+		goroutines := max(1, runtime.GOMAXPROCS(0)/2)
+		demos = append(demos, stressDemo{"cpuspin", map[string]int{"goroutines": goroutines},
+			func(ctx context.Context) map[string]int64 {
+				stats := runCPUSpinDemo(ctx, goroutines, duration)
+				return map[string]int64{"goroutines": int64(stats.Goroutines), "iterations": stats.Iterations}
+			}})
+	}
+	return demos
+}
+
+// startStress launches the stress demos as child runs of a parent "stress"
+// run and returns the parent's ID. The parent finishes once every child has,
+// with a count of the children in each state, and cancelling it cancels them.
+func startStress(reg *runRegistry, duration time.Duration, cpuSpin bool) int {
+	demos := stressDemos(duration, cpuSpin)
+	params := map[string]int{"seconds": int(duration / time.Second), "cpuspin": 0}
+	if cpuSpin {
+		params["cpuspin"] = 1
+	}
+
+	// The parent learns its children's IDs once they are launched
+	children := make(chan []int, 1)
+	parent := reg.launch("stress", params, func(ctx context.Context) map[string]int64 {
+		ids := <-children
+		stop := context.AfterFunc(ctx, func() {
+			for _, id := range ids {
+				reg.cancel(id)
+			}
+		})
+		defer stop()
+
+		result := map[string]int64{"children": int64(len(ids))}
+		for _, id := range ids {
+			if run, ok := reg.wait(context.Background(), id); ok {
+				result[string(run.State)]++
+			}
+		}
+		slog.InfoContext(ctx, "Stress run completed", "children", len(ids), "completed", result[string(runCompleted)])
+		return result
+	})
+
+	ids := make([]int, 0, len(demos))
+	for _, demo := range demos {
+		id := reg.launch(demo.name, demo.params, demo.run)
+		reg.link(parent, id)
+		ids = append(ids, id)
+	}
+	children <- ids
+
+	slog.Info("Started stress run", "run", parent, "children", ids, "duration", duration)
+	return parent
+}
+
+// HTTP handler that starts every contention demo at once for a while
+func stressHandler(w http.ResponseWriter, r *http.Request) {
+	seconds, err := queryInt(r, "seconds", 30, 1, maxStressSeconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cpuSpin, err := queryBool(r, "cpuspin")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := startStress(demoRuns, time.Duration(seconds)*time.Second, cpuSpin)
+	startedRun(w, id)
+
+	demos := "mutex, RWMutex and channel demos"
+	if cpuSpin {
+		demos = "mutex, RWMutex, channel and CPU spin demos"
+	}
+	fmt.Fprintf(w, "Started the %s for %d seconds as children of run %d\n", demos, seconds, id)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pprofviz/examples/internal/testutil"
+)
+
+func TestStress(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	reg := newRunRegistry(maxRuns)
+	id := startStress(reg, time.Second, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	parent, ok := reg.wait(ctx, id)
+	if !ok {
+		t.Fatal("Stress run didn't finish within 30s")
+	}
+	if parent.Demo != "stress" || parent.State != runCompleted {
+		t.Fatalf("Expected a completed stress run, got %+v", parent)
+	}
+	if len(parent.Children) != 4 || parent.Result["children"] != 4 {
+		t.Fatalf("Expected 4 children, got %v and result %v", parent.Children, parent.Result)
+	}
+	if parent.Result[string(runCompleted)] != 4 {
+		t.Errorf("Expected the parent to count 4 completed children, got %v", parent.Result)
+	}
+
+	demos := map[string]bool{}
+	for _, child := range parent.Children {
+		run, ok := reg.wait(ctx, child)
+		if !ok {
+			t.Fatalf("Child run %d not found", child)
+		}
+		if run.State != runCompleted || run.Parent != id {
+			t.Errorf("Expected child %d completed under run %d, got %+v", child, id, run)
+		}
+		demos[run.Demo] = true
+	}
+	for _, demo := range []string{"mutex", "rwmutex", "channel", "cpuspin"} {
+		if !demos[demo] {
+			t.Errorf("Expected a %s child run, got %v", demo, demos)
+		}
+	}
+}
+
+func TestStressCancel(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	reg := newRunRegistry(maxRuns)
+	id := startStress(reg, time.Minute, false)
+	if err := reg.cancel(id); err != nil {
+		t.Fatalf("Failed to cancel stress run: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	parent, ok := reg.wait(ctx, id)
+	if !ok || parent.State != runCancelled {
+		t.Fatalf("Expected a cancelled stress run, got %+v (%v)", parent, ok)
+	}
+	if len(parent.Children) != 3 || parent.Result[string(runCancelled)] != 3 {
+		t.Errorf("Expected 3 cancelled children, got %v and result %v", parent.Children, parent.Result)
+	}
+}
+
+func TestStressHandlerParams(t *testing.T) {
+	for _, query := range []string{"seconds=0", "seconds=301", "seconds=abc", "cpuspin=maybe"} {
+		req := httptest.NewRequest("GET", "/stress?"+query, nil)
+		recorder := httptest.NewRecorder()
+		stressHandler(recorder, req)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, recorder.Code)
+		}
+	}
+}
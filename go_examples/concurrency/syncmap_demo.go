@@ -0,0 +1,149 @@
+package main
+
+import (
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mapRunReport summarizes one map implementation in the sync.Map demo
+type mapRunReport struct {
+	WallMs    float64        `json:"wallMs"`
+	Reads     int64          `json:"reads"`
+	Writes    int64          `json:"writes"`
+	Contended int64          `json:"contended"` // Operations that found another worker in the way
+	Counts    map[string]int `json:"counts"`    // Final write count per key
+}
+
+// syncMapReport compares a mutex-guarded map with a sync.Map
+type syncMapReport struct {
+	Workers    int          `json:"workers"`
+	Iterations int          `json:"iterations"`
+	ReadRatio  float64      `json:"readRatio"`
+	MutexMap   mapRunReport `json:"mutexMap"`
+	SyncMap    mapRunReport `json:"syncMap"`
+}
+
+// mapAccess performs the demo's access pattern for worker id: each iteration
+// reads a shared key with probability readRatio, otherwise increments the
+// worker's own key. Seeding by id gives both implementations identical runs.
+func mapAccess(numWorkers, iterations int, readRatio float64, read func(key string), write func(key string)) time.Duration {
+	start := time.Now()
+	var mapWg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		mapWg.Add(1)
+		go func(id int) {
+			defer mapWg.Done()
+			ops := rand.New(rand.NewSource(int64(id)))
+			for j := 0; j < iterations; j++ {
+				if ops.Float64() < readRatio {
+					read(workerKey(id % 5))
+				} else {
+					write(workerKey(id))
+				}
+			}
+		}(i)
+	}
+	mapWg.Wait()
+	return time.Since(start)
+}
+
+// runMutexMap runs the access pattern against a SharedResource map
+func runMutexMap(numWorkers, iterations int, readRatio float64) mapRunReport {
+	resource := &SharedResource{data: make(map[string]int)}
+	var report mapRunReport
+
+	// TryLock first so waits can be counted before blocking on Lock
+	lock := func() {
+		if !resource.mutex.TryLock() {
+			atomic.AddInt64(&report.Contended, 1)
+			resource.mutex.Lock()
+		}
+	}
+
+	wall := mapAccess(numWorkers, iterations, readRatio, func(key string) {
+		lock()
+		_ = resource.data[key]
+		resource.mutex.Unlock()
+		atomic.AddInt64(&report.Reads, 1)
+	}, func(key string) {
+		lock()
+		resource.data[key] = resource.data[key] + 1
+		resource.counter++
+		resource.mutex.Unlock()
+		atomic.AddInt64(&report.Writes, 1)
+	})
+
+	report.WallMs = float64(wall) / float64(time.Millisecond)
+	report.Counts = resource.data
+	return report
+}
+
+// runSyncMap runs the access pattern against a sync.Map of *int64 counters
+func runSyncMap(numWorkers, iterations int, readRatio float64) mapRunReport {
+	var data sync.Map
+	var report mapRunReport
+
+	wall := mapAccess(numWorkers, iterations, readRatio, func(key string) {
+		if counter, ok := data.Load(key); ok {
+			_ = atomic.LoadInt64(counter.(*int64))
+		}
+		atomic.AddInt64(&report.Reads, 1)
+	}, func(key string) {
+		counter, ok := data.Load(key)
+		if !ok {
+			var loaded bool
+			counter, loaded = data.LoadOrStore(key, new(int64))
+			if loaded {
+				// Another worker stored the key between Load and LoadOrStore
+				atomic.AddInt64(&report.Contended, 1)
+			}
+		}
+		atomic.AddInt64(counter.(*int64), 1)
+		atomic.AddInt64(&report.Writes, 1)
+	})
+
+	report.WallMs = float64(wall) / float64(time.Millisecond)
+	report.Counts = make(map[string]int)
+	data.Range(func(key, counter interface{}) bool {
+		report.Counts[key.(string)] = int(atomic.LoadInt64(counter.(*int64)))
+		return true
+	})
+	return report
+}
+
+// runSyncMapDemo runs the same access pattern against a mutex-guarded map
+// and a sync.Map, one after the other so their profiles don't overlap
+func runSyncMapDemo(numWorkers, iterations int, readRatio float64) syncMapReport {
+	slog.Info("Starting sync.Map demo", "workers", numWorkers, "iterations", iterations, "readRatio", readRatio)
+
+	report := syncMapReport{
+		Workers:    numWorkers,
+		Iterations: iterations,
+		ReadRatio:  readRatio,
+		MutexMap:   runMutexMap(numWorkers, iterations, readRatio),
+		SyncMap:    runSyncMap(numWorkers, iterations, readRatio),
+	}
+
+	slog.Info("sync.Map demo completed", "mutexMapMs", report.MutexMap.WallMs, "syncMapMs", report.SyncMap.WallMs)
+	return report
+}
+
+// HTTP handler that runs the sync.Map comparison and returns its report as JSON
+func syncMapDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, iterations, err := workerParams(r, 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	readRatio, err := queryFloat(r, "readRatio", 0.9, 0, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runSyncMapDemo(numWorkers, iterations, readRatio))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSyncMapDemoCountsMatch(t *testing.T) {
+	testCases := []struct {
+		workers    int
+		iterations int
+		readRatio  float64
+	}{
+		{1, 10, 0},
+		{8, 200, 0.5},
+		{12, 100, 0.9},
+		{4, 50, 1},
+	}
+
+	for _, tc := range testCases {
+		report := runSyncMapDemo(tc.workers, tc.iterations, tc.readRatio)
+
+		if !reflect.DeepEqual(report.MutexMap.Counts, report.SyncMap.Counts) {
+			t.Errorf("workers=%d readRatio=%v: counts differ: mutex map %v, sync.Map %v",
+				tc.workers, tc.readRatio, report.MutexMap.Counts, report.SyncMap.Counts)
+		}
+
+		for name, run := range map[string]mapRunReport{"mutex map": report.MutexMap, "sync.Map": report.SyncMap} {
+			if total := run.Reads + run.Writes; total != int64(tc.workers*tc.iterations) {
+				t.Errorf("%s: expected %d operations, got %d", name, tc.workers*tc.iterations, total)
+			}
+			written := 0
+			for _, count := range run.Counts {
+				written += count
+			}
+			if int64(written) != run.Writes {
+				t.Errorf("%s: counts total %d, expected %d writes", name, written, run.Writes)
+			}
+		}
+	}
+}
+
+func TestSyncMapDemoHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/syncmap-demo?workers=3&iterations=20&readRatio=0.25", nil)
+	recorder := httptest.NewRecorder()
+
+	syncMapDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var report syncMapReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if report.Workers != 3 || report.Iterations != 20 || report.ReadRatio != 0.25 {
+		t.Errorf("Parameters not applied: %+v", report)
+	}
+
+	for _, url := range []string{"/syncmap-demo?readRatio=1.5", "/syncmap-demo?readRatio=most", "/syncmap-demo?readRatio=NaN"} {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+		syncMapDemoHandler(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", url, http.StatusBadRequest, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"pprofviz/examples/internal/profrate"
+)
+
+// SyncMapResource is the lock-free counterpart of SharedResource: the map is
+// a sync.Map of *int64 counters and the total is an atomic counter, so its
+// workers never show up in the mutex profile
+type SyncMapResource struct {
+	data    sync.Map // Worker key to *int64
+	counter int64
+}
+
+// sync.Map backed resource shared by the sync.Map resource demo runs
+var syncMapResource = &SyncMapResource{}
+
+// Write to the sync.Map resource, doing the same work as writeWithMutex
+func writeWithSyncMap(ctx context.Context, wg *sync.WaitGroup, completed *int64, cfg DemoConfig, id int, iterations int) {
+	defer wg.Done()
+	rng := workerRand(ctx)
+
+	for i := 0; i < iterations; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Simulate some work before updating the data
+		sleepUpTo(rng, cfg.PreLockSleepMax)
+
+		// The mutex writers do this while holding the lock
+		sleepUpTo(rng, cfg.HoldSleepMax)
+
+		counter, _ := syncMapResource.data.LoadOrStore(workerKey(id), new(int64))
+		atomic.AddInt64(counter.(*int64), 1)
+		atomic.AddInt64(&syncMapResource.counter, 1)
+		atomic.AddInt64(completed, 1)
+	}
+}
+
+// Read from the sync.Map resource, doing the same work as readWithMutex
+func readWithSyncMap(ctx context.Context, wg *sync.WaitGroup, completed *int64, cfg DemoConfig, id int, iterations int) {
+	defer wg.Done()
+	rng := workerRand(ctx)
+
+	for i := 0; i < iterations; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Simulate some work before reading
+		sleepUpTo(rng, cfg.ReadSleepMax)
+
+		// Read from a limited set of keys
+		if counter, ok := syncMapResource.data.Load(workerKey(id % 5)); ok {
+			_ = atomic.LoadInt64(counter.(*int64))
+		}
+		_ = atomic.LoadInt64(&syncMapResource.counter)
+		atomic.AddInt64(completed, 1)
+	}
+}
+
+// Run the mutex demo's workload against the sync.Map resource until done or
+// ctx is cancelled, returning the final counter value and the number of
+// worker iterations completed
+func runSyncMapResourceDemo(ctx context.Context, cfg DemoConfig, numWorkers, iterations int) (counter int64, completed int64) {
+	slog.InfoContext(ctx, "Starting sync.Map resource demo", "workers", numWorkers, "iterations", iterations)
+
+	var wg sync.WaitGroup
+
+	// Same mix of readers and writers as the mutex demo
+	withDemoLabel(ctx, "syncmap", func(ctx context.Context) {
+		for i := 0; i < numWorkers; i++ {
+			wg.Add(1)
+			withWorkerLabel(ctx, i, func(ctx context.Context) {
+				if i%3 == 0 {
+					// 1/3 of workers write
+					go writeWithSyncMap(ctx, &wg, &completed, cfg, i, iterations)
+				} else {
+					// 2/3 of workers read
+					go readWithSyncMap(ctx, &wg, &completed, cfg, i, iterations)
+				}
+			})
+		}
+	})
+
+	wg.Wait()
+	counter = atomic.LoadInt64(&syncMapResource.counter)
+
+	slog.InfoContext(ctx, "sync.Map resource demo completed", "counter", counter)
+	return counter, completed
+}
+
+// HTTP handler that starts the sync.Map resource demo
+func syncMapResourceDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, iterations, err := workerParams(r, 10)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg, err := demoConfigParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := demoRuns.launch("syncmap", withSeedParam(lockDemoParams(numWorkers, iterations, cfg), seed),
+		func(ctx context.Context) map[string]int64 {
+			counter, completed := runSyncMapResourceDemo(withSeed(ctx, seed), cfg, numWorkers, iterations)
+			return map[string]int64{"counter": counter, "iterations": completed}
+		})
+	startedRun(w, id)
+
+	fmt.Fprintf(w, "Started sync.Map resource demo with %d workers, %d iterations each (mutex profile fraction %d)\n",
+		numWorkers, iterations, profrate.MutexFraction())
+}
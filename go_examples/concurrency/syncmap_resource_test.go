@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSyncMapResourceDemo(t *testing.T) {
+	// Reset the global resource
+	syncMapResource = &SyncMapResource{}
+
+	numWorkers := 5
+	iterations := 10
+
+	counter, completed := runSyncMapResourceDemo(context.Background(), DemoConfig{}, numWorkers, iterations)
+
+	// Workers 0 and 3 write, and atomic increments are never lost
+	expectedCounter := int64((numWorkers + 2) / 3 * iterations)
+	if counter != expectedCounter || atomic.LoadInt64(&syncMapResource.counter) != expectedCounter {
+		t.Errorf("Expected counter %d, got %d", expectedCounter, counter)
+	}
+	if completed != int64(numWorkers*iterations) {
+		t.Errorf("Expected %d iterations completed, got %d", numWorkers*iterations, completed)
+	}
+
+	// Each writer has its own key
+	keys := 0
+	syncMapResource.data.Range(func(key, value interface{}) bool {
+		keys++
+		if n := atomic.LoadInt64(value.(*int64)); n != int64(iterations) {
+			t.Errorf("Expected %s to be written %d times, got %d", key, iterations, n)
+		}
+		return true
+	})
+	if keys != 2 {
+		t.Errorf("Expected 2 keys in the map, got %d", keys)
+	}
+}
+
+func TestSyncMapResourceDemoHandler(t *testing.T) {
+	syncMapResource = &SyncMapResource{}
+	recorder := httptest.NewRecorder()
+
+	syncMapResourceDemoHandler(recorder, httptest.NewRequest("GET", "/syncmap-resource-demo?workers=3&iterations=5&holdMs=0", nil))
+
+	location := recorder.Header().Get("Location")
+	if location == "" {
+		t.Fatalf("Expected a run location, got %q", recorder.Body.String())
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/runs/", runsHandler(demoRuns))
+	run := waitForRun(t, mux, location)
+	if run.Demo != "syncmap" || run.State != runCompleted || run.Result["counter"] != 5 || run.Result["iterations"] != 15 {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+
+	recorder = httptest.NewRecorder()
+	syncMapResourceDemoHandler(recorder, httptest.NewRequest("GET", "/syncmap-resource-demo?workers=0", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid workers, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
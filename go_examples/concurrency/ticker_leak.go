@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Bounds for the ticker leak interval in milliseconds
+const (
+	minTickerLeakIntervalMs = 1
+	maxTickerLeakIntervalMs = 60000
+)
+
+// leakedTicker is a ticker and the goroutine reading it, left running by
+// the ticker leak demo
+type leakedTicker struct {
+	ticker  *time.Ticker
+	release chan struct{} // Never closed until the leak is stopped
+	done    chan struct{} // Closed when the goroutine exits
+}
+
+// Tickers deliberately leaked by /ticker-leak
+var (
+	tickerLeakMutex sync.Mutex
+	leakedTickers   []leakedTicker
+)
+
+// startTickerLeak starts count goroutines that each select on their own
+// ticker forever without stopping it, the way a forgotten ticker.Stop leaks
+// in real code. It returns how many tickers are now outstanding.
+func startTickerLeak(count int, interval time.Duration) int {
+	tickerLeakMutex.Lock()
+	defer tickerLeakMutex.Unlock()
+
+	for i := 0; i < count; i++ {
+		lt := leakedTicker{
+			ticker:  time.NewTicker(interval),
+			release: make(chan struct{}),
+			done:    make(chan struct{}),
+		}
+		go func() {
+			defer close(lt.done)
+			for {
+				select {
+				case <-lt.ticker.C:
+					// Wakes every interval for as long as the ticker runs
+				case <-lt.release:
+					return
+				}
+			}
+		}()
+		leakedTickers = append(leakedTickers, lt)
+	}
+	return len(leakedTickers)
+}
+
+// stopTickerLeak stops every leaked ticker, waits for the goroutines reading
+// them to exit and returns how many were stopped
+func stopTickerLeak() int {
+	tickerLeakMutex.Lock()
+	stopping := leakedTickers
+	leakedTickers = nil
+	tickerLeakMutex.Unlock()
+
+	for _, lt := range stopping {
+		lt.ticker.Stop()
+		close(lt.release)
+	}
+	for _, lt := range stopping {
+		<-lt.done
+	}
+	return len(stopping)
+}
+
+// leakedTickerCount returns the number of leaked tickers still outstanding
+func leakedTickerCount() int {
+	tickerLeakMutex.Lock()
+	defer tickerLeakMutex.Unlock()
+	return len(leakedTickers)
+}
+
+// HTTP handler that leaks count tickers per call
+func tickerLeakHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := queryInt(r, "count", 10, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	intervalMs, err := queryInt(r, "interval_ms", 100, minTickerLeakIntervalMs, maxTickerLeakIntervalMs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outstanding := startTickerLeak(count, time.Duration(intervalMs)*time.Millisecond)
+
+	fmt.Fprintf(w, "Leaked %d tickers (%d outstanding)\n", count, outstanding)
+}
+
+// HTTP handler that stops all leaked tickers
+func tickerLeakStopHandler(w http.ResponseWriter, r *http.Request) {
+	stopped := stopTickerLeak()
+
+	fmt.Fprintf(w, "Stopped %d leaked tickers\n", stopped)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTickerLeak(t *testing.T) {
+	defer stopTickerLeak()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ticker-leak", tickerLeakHandler)
+	mux.HandleFunc("/ticker-leak/stop", tickerLeakStopHandler)
+
+	baseline := runtime.NumGoroutine()
+
+	// Each call leaks more tickers on top of the previous ones
+	for i, expected := range []string{"Leaked 20 tickers (20 outstanding)", "Leaked 20 tickers (40 outstanding)"} {
+		req := httptest.NewRequest("GET", "/ticker-leak?count=20&interval_ms=5", nil)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Call %d: expected body to contain %q, got: %s", i+1, expected, recorder.Body.String())
+		}
+	}
+
+	if n := leakedTickerCount(); n != 40 {
+		t.Errorf("Expected 40 outstanding leaked tickers, got %d", n)
+	}
+	if n := statusSnapshot().LeakedTickers; n != 40 {
+		t.Errorf("Expected /status to report 40 leaked tickers, got %d", n)
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n >= baseline+40 }); n < baseline+40 {
+		t.Errorf("Expected at least %d goroutines after leaking, got %d", baseline+40, n)
+	}
+
+	req := httptest.NewRequest("POST", "/ticker-leak/stop", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+
+	if !strings.Contains(recorder.Body.String(), "Stopped 40 leaked tickers") {
+		t.Errorf("Unexpected stop response: %s", recorder.Body.String())
+	}
+	if n := statusSnapshot().LeakedTickers; n != 0 {
+		t.Errorf("Expected no outstanding leaked tickers after stop, got %d", n)
+	}
+	if n := waitForGoroutines(time.Second, func(n int) bool { return n <= baseline+5 }); n > baseline+5 {
+		t.Errorf("Expected goroutine count to return near %d after stop, got %d", baseline, n)
+	}
+}
+
+func TestTickerLeakInvalidParams(t *testing.T) {
+	for _, url := range []string{"/ticker-leak?count=0", "/ticker-leak?interval_ms=0"} {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+
+		tickerLeakHandler(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+	if n := leakedTickerCount(); n != 0 {
+		t.Errorf("Expected no tickers leaked for invalid parameters, got %d", n)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bounds for /timer-storm. Pending timers are cheap, so the count goes well
+// past maxDemoParam, but the request lasts for the whole window.
+const (
+	maxTimerStormTimers   = 1000000
+	maxTimerStormWindowMs = 60000
+)
+
+// A timer counts as late once its callback starts this long after its deadline
+const timerLateAfter = 10 * time.Millisecond
+
+// Keeps the timer callbacks' work live so the compiler can't drop it
+var timerStormSink uint64
+
+// timerStormStats summarizes a timer storm run
+type timerStormStats struct {
+	Timers     int     `json:"timers"`
+	WindowMs   int     `json:"windowMs"`
+	Fired      int64   `json:"fired"`
+	Stopped    int64   `json:"stopped"` // Cancelled before firing
+	Late       int64   `json:"late"`    // Fired more than timerLateAfter past their deadline
+	MaxLateMs  float64 `json:"maxLateMs"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+// timerStormDelays draws numTimers random delays within window. The storm
+// draws from one source, so a seeded run takes worker 0's.
+func timerStormDelays(ctx context.Context, numTimers int, window time.Duration) []time.Duration {
+	rng := workerRand(withWorkerRand(ctx, 0))
+	delays := make([]time.Duration, numTimers)
+	for i := range delays {
+		delays[i] = time.Duration(randInt63n(rng, int64(window)+1))
+	}
+	return delays
+}
+
+// runTimerStorm schedules numTimers time.AfterFunc callbacks at random delays
+// within window and waits for them all to fire, or stops those still pending
+// once ctx is cancelled. Each callback does a few nanoseconds of work, so a
+// CPU profile taken meanwhile is dominated by the runtime's timer handling.
+func runTimerStorm(ctx context.Context, numTimers int, window time.Duration) timerStormStats {
+	slog.InfoContext(ctx, "Starting timer storm", "timers", numTimers, "window", window)
+
+	var fired, late, maxLate int64
+	var wg sync.WaitGroup
+	timers := make([]*time.Timer, numTimers)
+	delays := timerStormDelays(ctx, numTimers, window)
+	start := time.Now()
+
+	withDemoLabel(ctx, "timerstorm", func(ctx context.Context) {
+		for i := range timers {
+			delay := delays[i]
+			deadline := start.Add(delay)
+			wg.Add(1)
+			timers[i] = time.AfterFunc(delay, func() {
+				defer wg.Done()
+				lateness := time.Since(deadline)
+
+				state := uint64(lateness) | 1
+				for j := 0; j < 8; j++ {
+					state ^= state << 13
+					state ^= state >> 7
+					state ^= state << 17
+				}
+				atomic.AddUint64(&timerStormSink, state)
+
+				atomic.AddInt64(&fired, 1)
+				if lateness > timerLateAfter {
+					atomic.AddInt64(&late, 1)
+				}
+				for {
+					old := atomic.LoadInt64(&maxLate)
+					if int64(lateness) <= old || atomic.CompareAndSwapInt64(&maxLate, old, int64(lateness)) {
+						break
+					}
+				}
+			})
+		}
+	})
+
+	// Stop the pending timers if cancelled; those already firing finish
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	var stopped int64
+	select {
+	case <-done:
+	case <-ctx.Done():
+		for _, timer := range timers {
+			if timer.Stop() {
+				stopped++
+				wg.Done()
+			}
+		}
+		<-done
+	}
+	elapsed := time.Since(start)
+
+	stats := timerStormStats{
+		Timers:     numTimers,
+		WindowMs:   int(window / time.Millisecond),
+		Fired:      fired,
+		Stopped:    stopped,
+		Late:       late,
+		MaxLateMs:  float64(maxLate) / float64(time.Millisecond),
+		DurationMs: float64(elapsed) / float64(time.Millisecond),
+	}
+	slog.InfoContext(ctx, "Timer storm completed", "fired", fired, "late", late, "stopped", stopped, "elapsed", elapsed)
+	return stats
+}
+
+// HTTP handler that runs a timer storm and returns its summary as JSON
+func timerStormHandler(w http.ResponseWriter, r *http.Request) {
+	numTimers, err := queryInt(r, "timers", 10000, 1, maxTimerStormTimers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	windowMs, err := queryInt(r, "windowMs", 1000, 1, maxTimerStormWindowMs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runTimerStorm(withSeed(r.Context(), seed), numTimers, time.Duration(windowMs)*time.Millisecond))
+}
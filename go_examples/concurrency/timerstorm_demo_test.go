@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimerStormAllFire(t *testing.T) {
+	stats := runTimerStorm(context.Background(), 2000, 100*time.Millisecond)
+
+	if stats.Fired != 2000 || stats.Stopped != 0 {
+		t.Errorf("Expected all 2000 timers to fire, got %+v", stats)
+	}
+	if stats.Late < 0 || stats.Late > stats.Fired || stats.MaxLateMs < 0 {
+		t.Errorf("Unexpected lateness: %+v", stats)
+	}
+	if stats.DurationMs < 0 || stats.DurationMs > 100+1000 {
+		t.Errorf("Expected the storm to last about its window, took %vms", stats.DurationMs)
+	}
+}
+
+func TestTimerStormCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	stats := runTimerStorm(ctx, 1000, time.Minute)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected cancellation to end the storm, took %v", elapsed)
+	}
+	if stats.Fired+stats.Stopped != 1000 || stats.Stopped == 0 {
+		t.Errorf("Expected every timer to have fired or been stopped, got %+v", stats)
+	}
+}
+
+func TestTimerStormHandler(t *testing.T) {
+	const windowMs = 200
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	timerStormHandler(recorder, httptest.NewRequest("GET", "/timer-storm?timers=5000&windowMs=200", nil))
+	elapsed := time.Since(start)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	if elapsed > windowMs*time.Millisecond+2*time.Second {
+		t.Errorf("Expected the handler to return within about %dms, took %v", windowMs, elapsed)
+	}
+	var stats timerStormStats
+	if err := json.Unmarshal(recorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode timer storm stats: %v", err)
+	}
+	if stats.Timers != 5000 || stats.WindowMs != windowMs || stats.Fired != 5000 {
+		t.Errorf("Unexpected timer storm stats: %+v", stats)
+	}
+
+	for _, url := range []string{"/timer-storm?timers=0", "/timer-storm?timers=1000001", "/timer-storm?windowMs=0", "/timer-storm?windowMs=x"} {
+		recorder := httptest.NewRecorder()
+		timerStormHandler(recorder, httptest.NewRequest("GET", url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime/trace"
+	"sync"
+	"time"
+)
+
+// Upper bound on how long /trace-run records
+const maxTraceRunSeconds = 60
+
+// Only one execution trace can be recorded at a time
+var traceCaptureMutex sync.Mutex
+
+var errTraceRunning = errors.New("an execution trace is already being recorded")
+
+// traceDemo returns the demo named by ?demo= with its parameters read from the
+// request, ready to run until done or ctx is cancelled
+func traceDemo(r *http.Request) (func(ctx context.Context), error) {
+	seed, err := seedParam(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch demo := r.URL.Query().Get("demo"); demo {
+	case "", "mutex", "rwmutex":
+		numWorkers, iterations, err := workerParams(r, 10)
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := demoConfigParams(r)
+		if err != nil {
+			return nil, err
+		}
+		if demo == "rwmutex" {
+			return func(ctx context.Context) { runRWMutexDemo(withSeed(ctx, seed), cfg, numWorkers, iterations) }, nil
+		}
+		return func(ctx context.Context) { runMutexDemo(withSeed(ctx, seed), cfg, numWorkers, iterations) }, nil
+
+	case "channel":
+		numProducers, err := queryInt(r, "producers", 3, 1, maxDemoParam)
+		if err != nil {
+			return nil, err
+		}
+		numConsumers, err := queryInt(r, "consumers", 5, 1, maxDemoParam)
+		if err != nil {
+			return nil, err
+		}
+		itemsPerProducer, err := queryInt(r, "items", 50, 1, maxDemoParam)
+		if err != nil {
+			return nil, err
+		}
+		workBuf, resultBuf, err := channelBufferParams(r)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) {
+			runChannelDemoBuffered(withSeed(ctx, seed), numProducers, numConsumers, itemsPerProducer, workBuf, resultBuf, 0)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid demo parameter: %q is not mutex, rwmutex or channel", demo)
+	}
+}
+
+// traceRun records an execution trace of run to w, stopping run after limit.
+// It fails with errTraceRunning if another trace is being recorded.
+func traceRun(w io.Writer, run func(ctx context.Context), limit time.Duration) error {
+	if !traceCaptureMutex.TryLock() {
+		return errTraceRunning
+	}
+	defer traceCaptureMutex.Unlock()
+
+	// Also fails if a capture from /debug/pprof/trace is running
+	if err := trace.Start(w); err != nil {
+		return errTraceRunning
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), limit)
+	defer cancel()
+	run(ctx)
+	trace.Stop()
+	return nil
+}
+
+// HTTP handler that runs one demo under an execution trace and returns the
+// trace as a download. The demo stops after ?seconds=N if it hasn't finished.
+func traceRunHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := traceDemo(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seconds, err := queryInt(r, "seconds", 5, 1, maxTraceRunSeconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Record to a temporary file so a failed capture doesn't leave a
+	// half-written response
+	f, err := os.CreateTemp("", "trace-run-*.trace")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := traceRun(f, run, time.Duration(seconds)*time.Second); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	demo := r.URL.Query().Get("demo")
+	if demo == "" {
+		demo = "mutex"
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", demo+".trace"))
+	io.Copy(w, f)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime/trace"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceRunHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/trace-run?demo=mutex&workers=3&iterations=10&holdMs=1&seconds=5", nil)
+	recorder := httptest.NewRecorder()
+	start := time.Now()
+
+	traceRunHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	// The demo finishes well before the time limit, which ends the trace
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the trace to stop when the demo completed, took %v", elapsed)
+	}
+	if !strings.HasPrefix(recorder.Body.String(), "go 1.") {
+		t.Errorf("Expected an execution trace, got %d bytes starting %q", recorder.Body.Len(), recorder.Body.String()[:min(recorder.Body.Len(), 16)])
+	}
+	if cd := recorder.Header().Get("Content-Disposition"); cd != `attachment; filename="mutex.trace"` {
+		t.Errorf("Unexpected Content-Disposition %q", cd)
+	}
+}
+
+func TestTraceRunHandlerConcurrent(t *testing.T) {
+	// Far more items than the channel demo gets through before the time limit
+	first := make(chan *httptest.ResponseRecorder)
+	go func() {
+		recorder := httptest.NewRecorder()
+		traceRunHandler(recorder, httptest.NewRequest("GET", "/trace-run?demo=channel&producers=1&consumers=1&items=10000&seconds=1", nil))
+		first <- recorder
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !trace.IsEnabled() {
+		if time.Now().After(deadline) {
+			t.Fatal("First trace capture did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	recorder := httptest.NewRecorder()
+	traceRunHandler(recorder, httptest.NewRequest("GET", "/trace-run?demo=mutex", nil))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a concurrent capture, got %d", http.StatusConflict, recorder.Code)
+	}
+
+	if recorder := <-first; recorder.Code != http.StatusOK || recorder.Body.Len() == 0 {
+		t.Errorf("Expected the first capture to succeed, got status %d with %d bytes", recorder.Code, recorder.Body.Len())
+	}
+}
+
+func TestTraceRunHandlerInvalidParams(t *testing.T) {
+	for _, url := range []string{"/trace-run?demo=deadlock", "/trace-run?seconds=0", "/trace-run?seconds=600", "/trace-run?demo=channel&producers=0"} {
+		recorder := httptest.NewRecorder()
+		traceRunHandler(recorder, httptest.NewRequest("GET", url, nil))
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// workerPoolStats summarizes a worker pool demo run
+type workerPoolStats struct {
+	Workers         int     `json:"workers"`
+	QueueSize       int     `json:"queue"`
+	Jobs            int     `json:"jobs"`
+	MaxQueueDepth   int     `json:"maxQueueDepth"`
+	SubmitBlockedMs float64 `json:"submitBlockedMs"`
+	WorkerJobs      []int   `json:"workerJobs"` // Jobs completed by each worker
+}
+
+// runWorkerPoolDemo submits jobs to a fixed pool of workers through a bounded
+// queue. The submitter blocks whenever the queue is full, which isolates
+// submit-side blocking in the block profile.
+func runWorkerPoolDemo(ctx context.Context, numWorkers, queueSize, jobs int) workerPoolStats {
+	return workerPoolDemo(ctx, numWorkers, queueSize, jobs, func(ctx context.Context) {
+		sleepUpTo(workerRand(ctx), 3*time.Millisecond)
+	})
+}
+
+// workerPoolDemo runs the worker pool demo with work as each job. work gets
+// the worker's context, carrying its random source in a seeded run.
+func workerPoolDemo(ctx context.Context, numWorkers, queueSize, jobs int, work func(ctx context.Context)) workerPoolStats {
+	slog.InfoContext(ctx, "Starting worker pool demo", "workers", numWorkers, "queue", queueSize, "jobs", jobs)
+
+	stats := workerPoolStats{
+		Workers:    numWorkers,
+		QueueSize:  queueSize,
+		Jobs:       jobs,
+		WorkerJobs: make([]int, numWorkers),
+	}
+	queue := make(chan int, queueSize)
+	var poolWg sync.WaitGroup
+
+	for i := 0; i < numWorkers; i++ {
+		poolWg.Add(1)
+		id := i
+		go withWorkerLabel(ctx, id, func(ctx context.Context) {
+			defer poolWg.Done()
+			// Each worker only writes its own slot
+			for range queue {
+				work(ctx)
+				stats.WorkerJobs[id]++
+			}
+		})
+	}
+
+	var blocked time.Duration
+	for job := 0; job < jobs; job++ {
+		select {
+		case queue <- job:
+		default:
+			// Queue is full, so this send blocks until a worker takes a job
+			start := time.Now()
+			queue <- job
+			blocked += time.Since(start)
+		}
+		if depth := len(queue); depth > stats.MaxQueueDepth {
+			stats.MaxQueueDepth = depth
+		}
+	}
+
+	close(queue)
+	poolWg.Wait()
+
+	stats.SubmitBlockedMs = float64(blocked) / float64(time.Millisecond)
+	slog.InfoContext(ctx, "Worker pool demo completed", "submitterBlocked", blocked)
+	return stats
+}
+
+// HTTP handler that runs the worker pool demo and returns its summary as JSON
+func workerPoolDemoHandler(w http.ResponseWriter, r *http.Request) {
+	numWorkers, err := queryInt(r, "workers", 4, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	queueSize, err := queryInt(r, "queue", 10, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jobs, err := queryInt(r, "jobs", 200, 1, maxDemoParam)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	seed, err := seedParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, runWorkerPoolDemo(withSeed(r.Context(), seed), numWorkers, queueSize, jobs))
+}
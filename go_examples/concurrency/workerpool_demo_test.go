@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pprofviz/examples/internal/testutil"
+)
+
+// sumJobs totals the per-worker job counts
+func sumJobs(workerJobs []int) int {
+	total := 0
+	for _, n := range workerJobs {
+		total += n
+	}
+	return total
+}
+
+func TestWorkerPoolDemoSmallQueue(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+
+	// Slow workers and a single slot keep the submitter blocked
+	stats := workerPoolDemo(context.Background(), 2, 1, 20, func(context.Context) { time.Sleep(time.Millisecond) })
+
+	if total := sumJobs(stats.WorkerJobs); total != 20 {
+		t.Errorf("Expected 20 jobs completed, got %d (%v)", total, stats.WorkerJobs)
+	}
+	if stats.MaxQueueDepth > 1 {
+		t.Errorf("Queue depth %d exceeds the queue size of 1", stats.MaxQueueDepth)
+	}
+	if stats.SubmitBlockedMs <= 0 {
+		t.Errorf("Expected the submitter to block with a queue of 1, got %vms", stats.SubmitBlockedMs)
+	}
+}
+
+func TestWorkerPoolDemoLargeQueue(t *testing.T) {
+	// Workers can't start until the submitter is done, so every job is queued
+	start := make(chan struct{})
+	done := make(chan workerPoolStats)
+	go func() {
+		done <- workerPoolDemo(context.Background(), 3, 100, 30, func(context.Context) { <-start })
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(start)
+
+	var stats workerPoolStats
+	select {
+	case stats = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Worker pool demo did not finish")
+	}
+
+	if total := sumJobs(stats.WorkerJobs); total != 30 {
+		t.Errorf("Expected 30 jobs completed, got %d (%v)", total, stats.WorkerJobs)
+	}
+	if len(stats.WorkerJobs) != 3 {
+		t.Errorf("Expected counts for 3 workers, got %d", len(stats.WorkerJobs))
+	}
+	if stats.SubmitBlockedMs != 0 {
+		t.Errorf("Expected no submitter blocking with a queue larger than the job count, got %vms", stats.SubmitBlockedMs)
+	}
+	// Each worker may hold one job, the rest stay queued
+	if stats.MaxQueueDepth < 27 {
+		t.Errorf("Expected the queue to fill to at least 27, got %d", stats.MaxQueueDepth)
+	}
+}
+
+func TestWorkerPoolDemoHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/workerpool-demo?workers=2&queue=1&jobs=10", nil)
+	recorder := httptest.NewRecorder()
+
+	workerPoolDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	var stats workerPoolStats
+	if err := json.Unmarshal(recorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if stats.Workers != 2 || stats.QueueSize != 1 || stats.Jobs != 10 || sumJobs(stats.WorkerJobs) != 10 {
+		t.Errorf("Unexpected summary: %+v", stats)
+	}
+
+	req = httptest.NewRequest("GET", "/workerpool-demo?queue=0", nil)
+	recorder = httptest.NewRecorder()
+	workerPoolDemoHandler(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for queue=0, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -4,4 +4,5 @@ go 1.21
 
 require (
 	github.com/google/pprof v0.0.0-20230510130704-1e33b62df51a
-)
\ No newline at end of file
+	golang.org/x/sync v0.7.0
+)
@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/pprof/profile"
+)
+
+// SampleDelta is the change in a function's flat (self) value between two
+// profiles. Delta is positive when the function grew.
+type SampleDelta struct {
+	Function string `json:"function"`
+	Before   int64  `json:"before"`
+	After    int64  `json:"after"`
+	Delta    int64  `json:"delta"`
+}
+
+// DiffProfiles compares two profiles of the same kind, such as heap profiles
+// taken before and after /start-leak, using the default sample type of b
+// (inuse_space for heap profiles). See DiffProfilesBy.
+func DiffProfiles(a, b *profile.Profile) ([]SampleDelta, error) {
+	return DiffProfilesBy(a, b, defaultSampleType(b))
+}
+
+// DiffProfilesBy aligns the samples of a and b by leaf function and returns
+// every function whose flat value of sampleType changed, largest absolute
+// change first
+func DiffProfilesBy(a, b *profile.Profile, sampleType string) ([]SampleDelta, error) {
+	before, unitBefore, err := flatValues(a, sampleType)
+	if err != nil {
+		return nil, fmt.Errorf("first profile: %w", err)
+	}
+	after, unitAfter, err := flatValues(b, sampleType)
+	if err != nil {
+		return nil, fmt.Errorf("second profile: %w", err)
+	}
+	if unitBefore != unitAfter {
+		return nil, fmt.Errorf("%s is measured in %s and %s", sampleType, unitBefore, unitAfter)
+	}
+
+	var deltas []SampleDelta
+	for name, value := range after {
+		if delta := value - before[name]; delta != 0 {
+			deltas = append(deltas, SampleDelta{Function: name, Before: before[name], After: value, Delta: delta})
+		}
+	}
+	for name, value := range before {
+		if _, ok := after[name]; !ok && value != 0 {
+			deltas = append(deltas, SampleDelta{Function: name, Before: value, Delta: -value})
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		di, dj := abs64(deltas[i].Delta), abs64(deltas[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return deltas[i].Function < deltas[j].Function
+	})
+	return deltas, nil
+}
+
+// flatValues sums the named sample type per leaf function and returns the
+// sums with the sample type's unit
+func flatValues(p *profile.Profile, sampleType string) (map[string]int64, string, error) {
+	index, err := SampleIndex(p, sampleType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	values := make(map[string]int64)
+	for _, sample := range p.Sample {
+		names := stackNames(sample)
+		if len(names) == 0 {
+			continue
+		}
+		values[names[len(names)-1]] += sample.Value[index]
+	}
+	return values, p.SampleType[index].Unit, nil
+}
+
+// defaultSampleType is the sample type pprof shows for p when none is chosen:
+// the profile's declared default, otherwise the last sample type
+func defaultSampleType(p *profile.Profile) string {
+	if p.DefaultSampleType != "" {
+		return p.DefaultSampleType
+	}
+	if len(p.SampleType) == 0 {
+		return ""
+	}
+	return p.SampleType[len(p.SampleType)-1].Type
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestDiffProfiles(t *testing.T) {
+	heapTypes := []string{"alloc_objects", "alloc_space", "inuse_objects", "inuse_space"}
+	before := newTestProfile(heapTypes,
+		testSample{stack: []string{"main.main", "main.createLargeObject"}, values: []int64{1, 1024, 1, 1024}},
+		testSample{stack: []string{"main.main", "main.randomString"}, values: []int64{10, 500, 2, 100}},
+		testSample{stack: []string{"main.main", "main.handler", "main.scratch"}, values: []int64{3, 300, 3, 300}},
+	)
+	after := newTestProfile(heapTypes,
+		// The leak grew by a known amount across two call sites
+		testSample{stack: []string{"main.main", "main.createLargeObject"}, values: []int64{5, 5120, 5, 5120}},
+		testSample{stack: []string{"main.leak", "main.createLargeObject"}, values: []int64{1, 1000, 1, 1000}},
+		testSample{stack: []string{"main.main", "main.randomString"}, values: []int64{20, 1000, 2, 100}},
+		testSample{stack: []string{"main.main", "main.newBuffer"}, values: []int64{1, 50, 1, 50}},
+	)
+
+	deltas, err := DiffProfiles(before, after)
+	if err != nil {
+		t.Fatalf("DiffProfiles failed: %v", err)
+	}
+
+	// inuse_space is the last sample type; unchanged randomString is omitted
+	expected := []SampleDelta{
+		{Function: "main.createLargeObject", Before: 1024, After: 6120, Delta: 5096},
+		{Function: "main.scratch", Before: 300, After: 0, Delta: -300},
+		{Function: "main.newBuffer", Before: 0, After: 50, Delta: 50},
+	}
+	if !reflect.DeepEqual(deltas, expected) {
+		t.Errorf("DiffProfiles = %+v, expected %+v", deltas, expected)
+	}
+
+	deltas, err = DiffProfilesBy(before, after, "alloc_space")
+	if err != nil {
+		t.Fatalf("DiffProfilesBy failed: %v", err)
+	}
+	if len(deltas) != 4 || deltas[0].Function != "main.createLargeObject" || deltas[0].Delta != 5096 {
+		t.Fatalf("Expected createLargeObject to lead alloc_space deltas, got %+v", deltas)
+	}
+	if deltas[1].Function != "main.randomString" || deltas[1].Delta != 500 {
+		t.Errorf("Expected randomString alloc_space delta 500, got %+v", deltas[1])
+	}
+}
+
+func TestDiffProfilesDefaultSampleType(t *testing.T) {
+	types := []string{"alloc_space", "inuse_space"}
+	before := newTestProfile(types, testSample{stack: []string{"main.f"}, values: []int64{100, 10}})
+	after := newTestProfile(types, testSample{stack: []string{"main.f"}, values: []int64{300, 10}})
+	after.DefaultSampleType = "alloc_space"
+
+	deltas, err := DiffProfiles(before, after)
+	if err != nil {
+		t.Fatalf("DiffProfiles failed: %v", err)
+	}
+	if len(deltas) != 1 || deltas[0].Delta != 200 {
+		t.Errorf("Expected a single alloc_space delta of 200, got %+v", deltas)
+	}
+}
+
+func TestDiffProfilesErrors(t *testing.T) {
+	heap := newTestProfile([]string{"inuse_space"}, testSample{stack: []string{"main.f"}, values: []int64{1}})
+	cpu := newTestProfile([]string{"samples", "cpu"}, testSample{stack: []string{"main.f"}, values: []int64{1, 1}})
+
+	if _, err := DiffProfilesBy(heap, cpu, "inuse_space"); err == nil {
+		t.Error("Expected an error when the second profile lacks the sample type")
+	}
+	if _, err := DiffProfilesBy(cpu, heap, "inuse_space"); err == nil {
+		t.Error("Expected an error when the first profile lacks the sample type")
+	}
+
+	// Same sample type name with different units can't be compared
+	other := newTestProfile([]string{"inuse_space"}, testSample{stack: []string{"main.f"}, values: []int64{1}})
+	other.SampleType[0] = &profile.ValueType{Type: "inuse_space", Unit: "kilobytes"}
+	if _, err := DiffProfiles(heap, other); err == nil {
+		t.Error("Expected an error for mismatched units")
+	}
+}
@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/pprof/profile"
+)
+
+// FlameNode is one frame of a flame graph in the {name, value, children}
+// shape consumed by d3-flame-graph. Value is the frame's total including its
+// children and Self the part spent in the frame itself.
+type FlameNode struct {
+	Name     string       `json:"name"`
+	Value    int64        `json:"value"`
+	Self     int64        `json:"self"`
+	Children []*FlameNode `json:"children,omitempty"`
+}
+
+// FlameTree merges the stacks of a profile by function name into a flame
+// graph rooted at "root", using the sample type at sampleIndex as the value
+func FlameTree(p *profile.Profile, sampleIndex int) (*FlameNode, error) {
+	if sampleIndex < 0 || sampleIndex >= len(p.SampleType) {
+		return nil, fmt.Errorf("sample index %d out of range for %d sample types", sampleIndex, len(p.SampleType))
+	}
+
+	root := &FlameNode{Name: "root"}
+	for _, sample := range p.Sample {
+		value := sample.Value[sampleIndex]
+		if value == 0 {
+			continue
+		}
+
+		root.Value += value
+		node := root
+		for _, name := range stackNames(sample) {
+			node = node.child(name)
+			node.Value += value
+		}
+		node.Self += value
+	}
+
+	root.sort()
+	return root, nil
+}
+
+// FlameJSON encodes the flame graph of a profile, as built by FlameTree, in
+// the JSON format consumed by d3-flame-graph
+func FlameJSON(p *profile.Profile, sampleIndex int) ([]byte, error) {
+	root, err := FlameTree(p, sampleIndex)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(root)
+}
+
+// child returns the child frame with the given name, creating it if needed
+func (n *FlameNode) child(name string) *FlameNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	c := &FlameNode{Name: name}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+// sort orders children by name so output is stable across runs
+func (n *FlameNode) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
+
+// stackNames returns the function names of a sample from the outermost
+// caller to the leaf, expanding inlined frames
+func stackNames(sample *profile.Sample) []string {
+	var names []string
+	for i := len(sample.Location) - 1; i >= 0; i-- {
+		lines := sample.Location[i].Line
+		for j := len(lines) - 1; j >= 0; j-- {
+			if lines[j].Function != nil {
+				names = append(names, lines[j].Function.Name)
+			}
+		}
+	}
+	return names
+}
+
+// SampleIndex returns the index of the named sample type, e.g. "alloc_space"
+func SampleIndex(p *profile.Profile, sampleType string) (int, error) {
+	for i, st := range p.SampleType {
+		if st.Type == sampleType {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("profile has no %s samples", sampleType)
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFlameTree(t *testing.T) {
+	p := newTestProfile([]string{"samples", "cpu"},
+		testSample{stack: []string{"main.main", "main.search", "main.containsIgnoreCase"}, values: []int64{3, 30}},
+		testSample{stack: []string{"main.main", "main.search"}, values: []int64{1, 10}},
+		testSample{stack: []string{"main.main", "main.loadtest"}, values: []int64{2, 20}},
+	)
+
+	root, err := FlameTree(p, 1)
+	if err != nil {
+		t.Fatalf("FlameTree returned error: %v", err)
+	}
+
+	if root.Value != 60 {
+		t.Errorf("Expected root value 60, got %d", root.Value)
+	}
+	if len(root.Children) != 1 || root.Children[0].Name != "main.main" {
+		t.Fatalf("Expected single main.main child, got %+v", root.Children)
+	}
+
+	mainNode := root.Children[0]
+	if len(mainNode.Children) != 2 {
+		t.Fatalf("Expected 2 children under main.main, got %d", len(mainNode.Children))
+	}
+
+	// Children are sorted by name
+	loadtest, search := mainNode.Children[0], mainNode.Children[1]
+	if loadtest.Name != "main.loadtest" || loadtest.Value != 20 {
+		t.Errorf("Unexpected loadtest node: %+v", loadtest)
+	}
+	if search.Name != "main.search" || search.Value != 40 {
+		t.Errorf("Unexpected search node: %+v", search)
+	}
+	if len(search.Children) != 1 || search.Children[0].Value != 30 {
+		t.Errorf("Unexpected search children: %+v", search.Children)
+	}
+}
+
+func TestFlameTreeInvalidIndex(t *testing.T) {
+	p := newTestProfile([]string{"samples"})
+
+	if _, err := FlameTree(p, 1); err == nil {
+		t.Error("Expected error for out of range sample index")
+	}
+}
+
+func TestFlameJSON(t *testing.T) {
+	p := newTestProfile([]string{"samples", "cpu"},
+		testSample{stack: []string{"main.main", "main.search", "main.containsIgnoreCase"}, values: []int64{3, 30}},
+		testSample{stack: []string{"main.main", "main.search"}, values: []int64{1, 10}},
+		testSample{stack: []string{"main.main", "main.loadtest"}, values: []int64{2, 20}},
+		testSample{stack: []string{"main.worker", "main.loadtest"}, values: []int64{4, 40}},
+	)
+
+	for index, expectedTotal := range []int64{10, 100} {
+		data, err := FlameJSON(p, index)
+		if err != nil {
+			t.Fatalf("FlameJSON returned error: %v", err)
+		}
+
+		var root FlameNode
+		if err := json.Unmarshal(data, &root); err != nil {
+			t.Fatalf("Failed to decode flame graph JSON: %v", err)
+		}
+
+		// Every sample ends in exactly one frame, so self values sum to the total
+		var selfSum int64
+		var check func(n *FlameNode)
+		check = func(n *FlameNode) {
+			selfSum += n.Self
+			childSum := int64(0)
+			for _, c := range n.Children {
+				childSum += c.Value
+				check(c)
+			}
+			if n.Value != n.Self+childSum {
+				t.Errorf("Sample %d: %s total %d != self %d + children %d", index, n.Name, n.Value, n.Self, childSum)
+			}
+		}
+		check(&root)
+
+		if root.Name != "root" || root.Value != expectedTotal || selfSum != expectedTotal {
+			t.Errorf("Sample %d: expected root total and self sum %d, got %d and %d",
+				index, expectedTotal, root.Value, selfSum)
+		}
+	}
+
+	// Stacks are merged by path, so main.loadtest appears under both callers
+	data, err := FlameJSON(p, 1)
+	if err != nil {
+		t.Fatalf("FlameJSON returned error: %v", err)
+	}
+	var root FlameNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("Failed to decode flame graph JSON: %v", err)
+	}
+	if len(root.Children) != 2 || root.Children[1].Name != "main.worker" || root.Children[1].Children[0].Self != 40 {
+		t.Errorf("Unexpected top-level frames: %s", data)
+	}
+}
+
+func TestFlameJSONInvalidIndex(t *testing.T) {
+	p := newTestProfile([]string{"samples"})
+
+	if _, err := FlameJSON(p, -1); err == nil {
+		t.Error("Expected error for out of range sample index")
+	}
+}
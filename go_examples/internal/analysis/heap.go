@@ -0,0 +1,90 @@
+package analysis
+
+import "github.com/google/pprof/profile"
+
+// AllocInuseRatio returns, per function, the fraction of the bytes allocated
+// beneath it that are still in use. Values near 1 mark retained memory (a
+// leak candidate) while values near 0 mark allocate-and-free churn. Functions
+// are credited with every allocation in their subtree so the ratios line up
+// with the frames of a flame graph. Returns nil for profiles that lack
+// alloc_space and inuse_space samples.
+func AllocInuseRatio(p *profile.Profile) map[string]float64 {
+	allocIndex, err := SampleIndex(p, "alloc_space")
+	if err != nil {
+		return nil
+	}
+	inuseIndex, err := SampleIndex(p, "inuse_space")
+	if err != nil {
+		return nil
+	}
+
+	alloc := make(map[string]int64)
+	inuse := make(map[string]int64)
+	for _, sample := range p.Sample {
+		// Count each function once per sample so recursion isn't double counted
+		seen := make(map[string]bool)
+		for _, name := range stackNames(sample) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			alloc[name] += sample.Value[allocIndex]
+			inuse[name] += sample.Value[inuseIndex]
+		}
+	}
+
+	ratios := make(map[string]float64, len(alloc))
+	for name, allocated := range alloc {
+		if allocated > 0 {
+			ratios[name] = float64(inuse[name]) / float64(allocated)
+		}
+	}
+	return ratios
+}
+
+// RatioNode is one frame of a heap profile's allocation flame graph, as built
+// by RatioTree. Value is the bytes allocated beneath the frame and Ratio its
+// function's AllocInuseRatio.
+type RatioNode struct {
+	Name     string       `json:"name"`
+	Value    int64        `json:"value"`
+	Ratio    float64      `json:"ratio"`
+	Children []*RatioNode `json:"children,omitempty"`
+}
+
+// RatioTree builds the alloc_space flame graph of a heap profile with each
+// frame annotated by AllocInuseRatio, so frames can be colored from retained
+// to churned. The root isn't a function, so its ratio is the byte-weighted
+// ratio of its children.
+func RatioTree(p *profile.Profile) (*RatioNode, error) {
+	allocIndex, err := SampleIndex(p, "alloc_space")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := SampleIndex(p, "inuse_space"); err != nil {
+		return nil, err
+	}
+	tree, err := FlameTree(p, allocIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	root := newRatioNode(tree, AllocInuseRatio(p))
+	var weighted float64
+	for _, child := range root.Children {
+		weighted += child.Ratio * float64(child.Value)
+	}
+	if root.Value > 0 {
+		root.Ratio = weighted / float64(root.Value)
+	}
+	return root, nil
+}
+
+// newRatioNode converts node and its children, looking up each function's ratio
+func newRatioNode(node *FlameNode, ratios map[string]float64) *RatioNode {
+	n := &RatioNode{Name: node.Name, Value: node.Value, Ratio: ratios[node.Name]}
+	for _, child := range node.Children {
+		n.Children = append(n.Children, newRatioNode(child, ratios))
+	}
+	return n
+}
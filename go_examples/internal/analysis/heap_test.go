@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+// testSample is a synthetic sample whose stack is listed from the outermost
+// caller to the leaf
+type testSample struct {
+	stack  []string
+	values []int64
+}
+
+// newTestProfile builds a profile with one location per function name
+func newTestProfile(sampleTypes []string, samples ...testSample) *profile.Profile {
+	p := &profile.Profile{}
+	for _, typ := range sampleTypes {
+		unit := "count"
+		switch {
+		case strings.HasSuffix(typ, "space"):
+			unit = "bytes"
+		case typ == "cpu" || typ == "delay":
+			unit = "nanoseconds"
+		}
+		p.SampleType = append(p.SampleType, &profile.ValueType{Type: typ, Unit: unit})
+	}
+
+	locations := make(map[string]*profile.Location)
+	for _, s := range samples {
+		sample := &profile.Sample{Value: s.values}
+		for i := len(s.stack) - 1; i >= 0; i-- {
+			name := s.stack[i]
+			loc, ok := locations[name]
+			if !ok {
+				id := uint64(len(locations) + 1)
+				fn := &profile.Function{ID: id, Name: name, Filename: name + ".go"}
+				loc = &profile.Location{ID: id, Line: []profile.Line{{Function: fn, Line: 1}}}
+				locations[name] = loc
+				p.Function = append(p.Function, fn)
+				p.Location = append(p.Location, loc)
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		p.Sample = append(p.Sample, sample)
+	}
+	return p
+}
+
+func TestAllocInuseRatio(t *testing.T) {
+	heapTypes := []string{"alloc_objects", "alloc_space", "inuse_objects", "inuse_space"}
+	p := newTestProfile(heapTypes,
+		// Retained: everything allocated is still live
+		testSample{stack: []string{"main.main", "main.createLargeObject"}, values: []int64{4, 4096, 4, 4096}},
+		// Churn: only a small fraction survives
+		testSample{stack: []string{"main.main", "main.randomString"}, values: []int64{100, 10000, 1, 100}},
+	)
+
+	ratios := AllocInuseRatio(p)
+
+	expected := map[string]float64{
+		"main.createLargeObject": 1,
+		"main.randomString":      0.01,
+		"main.main":              4196.0 / 14096.0,
+	}
+	for name, want := range expected {
+		got, ok := ratios[name]
+		if !ok {
+			t.Errorf("Missing ratio for %s", name)
+			continue
+		}
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("AllocInuseRatio[%s] = %f, expected %f", name, got, want)
+		}
+	}
+}
+
+func TestAllocInuseRatioNonHeapProfile(t *testing.T) {
+	p := newTestProfile([]string{"samples", "cpu"},
+		testSample{stack: []string{"main.main"}, values: []int64{1, 10000000}},
+	)
+
+	if ratios := AllocInuseRatio(p); ratios != nil {
+		t.Errorf("Expected nil ratios for a CPU profile, got %v", ratios)
+	}
+}
+
+func TestRatioTree(t *testing.T) {
+	heapTypes := []string{"alloc_objects", "alloc_space", "inuse_objects", "inuse_space"}
+	p := newTestProfile(heapTypes,
+		testSample{stack: []string{"main.main", "main.createLargeObject"}, values: []int64{4, 4096, 4, 4096}},
+		testSample{stack: []string{"main.main", "main.randomString"}, values: []int64{100, 10000, 1, 100}},
+	)
+
+	root, err := RatioTree(p)
+	if err != nil {
+		t.Fatalf("RatioTree failed: %v", err)
+	}
+	if root.Value != 14096 || len(root.Children) != 1 {
+		t.Fatalf("Expected one child of a 14096 byte root, got %+v", root)
+	}
+	if math.Abs(root.Ratio-4196.0/14096.0) > 1e-9 {
+		t.Errorf("Expected the root's ratio weighted by its children's bytes, got %f", root.Ratio)
+	}
+
+	mainFrame := root.Children[0]
+	if mainFrame.Name != "main.main" || len(mainFrame.Children) != 2 {
+		t.Fatalf("Expected main.main with two children, got %+v", mainFrame)
+	}
+	// Children are sorted by name
+	retained, churned := mainFrame.Children[0], mainFrame.Children[1]
+	if retained.Name != "main.createLargeObject" || retained.Value != 4096 || retained.Ratio != 1 {
+		t.Errorf("Unexpected retained frame %+v", retained)
+	}
+	if churned.Name != "main.randomString" || churned.Value != 10000 || math.Abs(churned.Ratio-0.01) > 1e-9 {
+		t.Errorf("Unexpected churned frame %+v", churned)
+	}
+}
+
+func TestRatioTreeNonHeapProfile(t *testing.T) {
+	p := newTestProfile([]string{"samples", "cpu"},
+		testSample{stack: []string{"main.main"}, values: []int64{1, 10000000}},
+	)
+
+	if _, err := RatioTree(p); err == nil || !strings.Contains(err.Error(), "alloc_space") {
+		t.Errorf("Expected an error naming the missing alloc_space samples, got %v", err)
+	}
+}
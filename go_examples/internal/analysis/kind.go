@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// Profile kinds returned by DetectProfileKind
+const (
+	KindCPU       = "cpu"
+	KindHeap      = "heap"
+	KindAllocs    = "allocs"
+	KindBlock     = "block"
+	KindMutex     = "mutex"
+	KindGoroutine = "goroutine"
+	KindUnknown   = "unknown"
+)
+
+// kindSampleTypes is the sample type shown by default for each kind
+var kindSampleTypes = map[string]string{
+	KindCPU:       "cpu",
+	KindHeap:      "inuse_space",
+	KindAllocs:    "alloc_space",
+	KindBlock:     "delay",
+	KindMutex:     "delay",
+	KindGoroutine: "goroutine",
+}
+
+// DetectProfileKind classifies a profile written by runtime/pprof from its
+// sample types, so an uploaded file needs no label. Heap and allocs profiles
+// share their sample types and differ in their declared default. Block and
+// mutex profiles share theirs too, so they are told apart by their stacks:
+// mutex samples end in the unlock that released the contended lock. An
+// empty contention profile is reported as block.
+func DetectProfileKind(p *profile.Profile) string {
+	has := make(map[string]bool, len(p.SampleType))
+	for _, st := range p.SampleType {
+		has[st.Type] = true
+	}
+
+	switch {
+	case has["cpu"] && has["samples"]:
+		return KindCPU
+	case has["alloc_space"] && has["inuse_space"]:
+		if p.DefaultSampleType == "alloc_space" {
+			return KindAllocs
+		}
+		return KindHeap
+	case has["contentions"] && has["delay"]:
+		if unlockSamples(p)*2 > len(p.Sample) {
+			return KindMutex
+		}
+		return KindBlock
+	case has["goroutine"] && len(p.SampleType) == 1:
+		return KindGoroutine
+	}
+	return KindUnknown
+}
+
+// unlockSamples counts the samples whose leaf function is an unlock
+func unlockSamples(p *profile.Profile) int {
+	count := 0
+	for _, sample := range p.Sample {
+		names := stackNames(sample)
+		if len(names) == 0 {
+			continue
+		}
+		leaf := names[len(names)-1]
+		if strings.HasSuffix(leaf, "Unlock") || leaf == "runtime.unlock" {
+			count++
+		}
+	}
+	return count
+}
+
+// DefaultSampleIndex returns the index of the sample type to display for p:
+// the usual one for its kind, such as inuse_space for a heap profile, or
+// for unknown kinds the one pprof shows by default
+func DefaultSampleIndex(p *profile.Profile) (int, error) {
+	if sampleType, ok := kindSampleTypes[DetectProfileKind(p)]; ok {
+		return SampleIndex(p, sampleType)
+	}
+	return SampleIndex(p, defaultSampleType(p))
+}
@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestDetectProfileKind(t *testing.T) {
+	heapTypes := []string{"alloc_objects", "alloc_space", "inuse_objects", "inuse_space"}
+	contentionTypes := []string{"contentions", "delay"}
+
+	allocs := newTestProfile(heapTypes, testSample{stack: []string{"main.main", "main.alloc"}, values: []int64{1, 64, 0, 0}})
+	allocs.DefaultSampleType = "alloc_space"
+
+	testCases := []struct {
+		name        string
+		profile     *profile.Profile
+		kind        string
+		sampleIndex int
+	}{
+		{"cpu", newTestProfile([]string{"samples", "cpu"},
+			testSample{stack: []string{"main.main", "main.spin"}, values: []int64{3, 30000000}}), KindCPU, 1},
+		{"heap", newTestProfile(heapTypes,
+			testSample{stack: []string{"main.main", "main.alloc"}, values: []int64{1, 64, 1, 64}}), KindHeap, 3},
+		{"allocs", allocs, KindAllocs, 1},
+		{"block", newTestProfile(contentionTypes,
+			testSample{stack: []string{"main.worker", "sync.(*Mutex).Lock"}, values: []int64{4, 1000}},
+			testSample{stack: []string{"main.consumer", "runtime.chanrecv1"}, values: []int64{2, 500}}), KindBlock, 1},
+		{"mutex", newTestProfile(contentionTypes,
+			testSample{stack: []string{"main.worker", "sync.(*Mutex).Unlock"}, values: []int64{4, 1000}},
+			testSample{stack: []string{"main.reader", "sync.(*RWMutex).RUnlock"}, values: []int64{1, 200}},
+			testSample{stack: []string{"runtime.findRunnable", "runtime.unlock"}, values: []int64{1, 10}}), KindMutex, 1},
+		{"empty contention", newTestProfile(contentionTypes), KindBlock, 1},
+		{"goroutine", newTestProfile([]string{"goroutine"},
+			testSample{stack: []string{"main.main", "runtime.gopark"}, values: []int64{12}}), KindGoroutine, 0},
+		{"unknown", newTestProfile([]string{"threadcreate"},
+			testSample{stack: []string{"runtime.newm"}, values: []int64{5}}), KindUnknown, 0},
+	}
+
+	for _, tc := range testCases {
+		if kind := DetectProfileKind(tc.profile); kind != tc.kind {
+			t.Errorf("%s: DetectProfileKind = %s, expected %s", tc.name, kind, tc.kind)
+		}
+		if index, err := DefaultSampleIndex(tc.profile); err != nil || index != tc.sampleIndex {
+			t.Errorf("%s: DefaultSampleIndex = %d, %v, expected %d", tc.name, index, err, tc.sampleIndex)
+		}
+	}
+
+	if _, err := DefaultSampleIndex(&profile.Profile{}); err == nil {
+		t.Error("Expected an error for a profile without sample types")
+	}
+}
+
+// lookupProfile parses the named runtime/pprof profile of this process
+func lookupProfile(t *testing.T, name string) *profile.Profile {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+		t.Fatalf("Failed to write the %s profile: %v", name, err)
+	}
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Failed to parse the %s profile: %v", name, err)
+	}
+	return p
+}
+
+func TestDetectProfileKindRuntimeProfiles(t *testing.T) {
+	// Contend on a mutex so both contention profiles have samples
+	runtime.SetBlockProfileRate(1)
+	defer runtime.SetBlockProfileRate(0)
+	defer runtime.SetMutexProfileFraction(runtime.SetMutexProfileFraction(1))
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				mutex.Lock()
+				time.Sleep(time.Millisecond)
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, name := range []string{KindHeap, KindAllocs, KindBlock, KindMutex, KindGoroutine} {
+		if kind := DetectProfileKind(lookupProfile(t, name)); kind != name {
+			t.Errorf("The runtime's %s profile was detected as %s", name, kind)
+		}
+	}
+}
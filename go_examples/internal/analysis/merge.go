@@ -0,0 +1,72 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// MergeProfiles combines profiles of the same kind, such as CPU profiles
+// captured from several instances of the webservice, into one profile whose
+// samples are the sums of theirs. It delegates to profile.Merge, but reports
+// which profile doesn't match the first and accepts profiles without a period
+// type, as synthetic ones often are.
+func MergeProfiles(profiles ...*profile.Profile) (*profile.Profile, error) {
+	if len(profiles) == 0 {
+		return nil, errors.New("no profiles to merge")
+	}
+	for i, p := range profiles {
+		if p == nil {
+			return nil, fmt.Errorf("profile %d is nil", i)
+		}
+	}
+	for i, p := range profiles[1:] {
+		if err := sameSampleTypes(profiles[0], p); err != nil {
+			return nil, fmt.Errorf("profile %d can't be merged with profile 0: %w", i+1, err)
+		}
+	}
+
+	// profile.Merge compares period types without checking for nil
+	srcs := make([]*profile.Profile, len(profiles))
+	for i, p := range profiles {
+		if p.PeriodType == nil {
+			p = p.Copy()
+			p.PeriodType = &profile.ValueType{}
+		}
+		srcs[i] = p
+	}
+	return profile.Merge(srcs)
+}
+
+// sameSampleTypes checks that b records the same sample and period types as a
+func sameSampleTypes(a, b *profile.Profile) error {
+	if formatValueType(a.PeriodType) != formatValueType(b.PeriodType) {
+		return fmt.Errorf("period type %s doesn't match %s",
+			formatValueType(b.PeriodType), formatValueType(a.PeriodType))
+	}
+	if formatValueTypes(a.SampleType) != formatValueTypes(b.SampleType) {
+		return fmt.Errorf("sample types %s don't match %s",
+			formatValueTypes(b.SampleType), formatValueTypes(a.SampleType))
+	}
+	return nil
+}
+
+// formatValueType formats a value type as pprof does, e.g. "cpu/nanoseconds"
+func formatValueType(vt *profile.ValueType) string {
+	if vt == nil || (vt.Type == "" && vt.Unit == "") {
+		return "none"
+	}
+	return vt.Type + "/" + vt.Unit
+}
+
+// formatValueTypes formats a list of sample types, e.g.
+// "[samples/count cpu/nanoseconds]"
+func formatValueTypes(types []*profile.ValueType) string {
+	names := make([]string, len(types))
+	for i, vt := range types {
+		names[i] = formatValueType(vt)
+	}
+	return "[" + strings.Join(names, " ") + "]"
+}
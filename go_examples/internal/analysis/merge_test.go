@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestMergeProfiles(t *testing.T) {
+	cpuTypes := []string{"samples", "cpu"}
+	a := newTestProfile(cpuTypes,
+		testSample{stack: []string{"main.main", "main.search"}, values: []int64{3, 30}},
+		testSample{stack: []string{"main.main", "main.loadtest"}, values: []int64{1, 10}},
+	)
+	b := newTestProfile(cpuTypes,
+		testSample{stack: []string{"main.main", "main.search"}, values: []int64{5, 50}},
+		testSample{stack: []string{"main.main", "main.compute"}, values: []int64{2, 20}},
+	)
+	c := newTestProfile(cpuTypes,
+		testSample{stack: []string{"main.main", "main.search"}, values: []int64{1, 10}},
+	)
+
+	merged, err := MergeProfiles(a, b, c)
+	if err != nil {
+		t.Fatalf("MergeProfiles failed: %v", err)
+	}
+
+	// Identical stacks are summed into one sample
+	for _, sampleType := range cpuTypes {
+		values, _, err := flatValues(merged, sampleType)
+		if err != nil {
+			t.Fatalf("Merged profile lacks %s: %v", sampleType, err)
+		}
+		scale := int64(1)
+		if sampleType == "cpu" {
+			scale = 10
+		}
+		expected := map[string]int64{"main.search": 9 * scale, "main.loadtest": 1 * scale, "main.compute": 2 * scale}
+		for name, value := range expected {
+			if values[name] != value {
+				t.Errorf("Expected %s %s %d, got %d", name, sampleType, value, values[name])
+			}
+		}
+	}
+	if len(merged.Sample) != 3 {
+		t.Errorf("Expected 3 distinct stacks, got %d samples", len(merged.Sample))
+	}
+
+	// The inputs are left alone
+	if a.PeriodType != nil || len(a.Sample) != 2 || a.Sample[0].Value[0] != 3 {
+		t.Errorf("Expected the first profile to be unchanged, got %v", a)
+	}
+}
+
+func TestMergeProfilesErrors(t *testing.T) {
+	cpu := newTestProfile([]string{"samples", "cpu"}, testSample{stack: []string{"main.f"}, values: []int64{1, 1}})
+	heap := newTestProfile([]string{"inuse_space"}, testSample{stack: []string{"main.f"}, values: []int64{1}})
+	kilobytes := newTestProfile([]string{"inuse_space"}, testSample{stack: []string{"main.f"}, values: []int64{1}})
+	kilobytes.SampleType[0] = &profile.ValueType{Type: "inuse_space", Unit: "kilobytes"}
+	sampled := newTestProfile([]string{"samples", "cpu"}, testSample{stack: []string{"main.f"}, values: []int64{1, 1}})
+	sampled.PeriodType = &profile.ValueType{Type: "cpu", Unit: "nanoseconds"}
+
+	tests := []struct {
+		name     string
+		profiles []*profile.Profile
+		expected string
+	}{
+		{"No profiles", nil, "no profiles to merge"},
+		{"Nil profile", []*profile.Profile{cpu, nil}, "profile 1 is nil"},
+		{"Different sample types", []*profile.Profile{cpu, cpu, heap},
+			"profile 2 can't be merged with profile 0: sample types [inuse_space/bytes] don't match [samples/count cpu/nanoseconds]"},
+		{"Different units", []*profile.Profile{heap, kilobytes},
+			"profile 1 can't be merged with profile 0: sample types [inuse_space/kilobytes] don't match [inuse_space/bytes]"},
+		{"Different period types", []*profile.Profile{cpu, sampled},
+			"profile 1 can't be merged with profile 0: period type cpu/nanoseconds doesn't match none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MergeProfiles(tt.profiles...)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
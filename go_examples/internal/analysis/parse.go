@@ -0,0 +1,40 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/google/pprof/profile"
+)
+
+// gzipMagic starts every gzip stream, and so every .pb.gz profile
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ParseProfile reads a profile in the pprof format from r, gzip-compressed, as
+// the runtime and /debug/pprof write them, or not, as WriteUncompressed does.
+// A truncated or corrupt gzip stream is reported as such rather than as a
+// malformed profile.
+func ParseProfile(r io.Reader) (*profile.Profile, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing profile: %w", err)
+		}
+		defer gz.Close()
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing profile: %w", err)
+		}
+		return profile.ParseData(data)
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, fmt.Errorf("reading profile: %w", err)
+	}
+	return profile.ParseData(data)
+}
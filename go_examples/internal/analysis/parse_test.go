@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseProfile(t *testing.T) {
+	p := newTestProfile([]string{"samples", "cpu"},
+		testSample{stack: []string{"main.main", "main.spin"}, values: []int64{3, 30000000}},
+		testSample{stack: []string{"main.main", "main.wait"}, values: []int64{1, 10000000}})
+
+	var gzipped, plain bytes.Buffer
+	if err := p.Write(&gzipped); err != nil {
+		t.Fatalf("Failed to write gzipped profile: %v", err)
+	}
+	if err := p.WriteUncompressed(&plain); err != nil {
+		t.Fatalf("Failed to write uncompressed profile: %v", err)
+	}
+	if bytes.Equal(gzipped.Bytes(), plain.Bytes()) {
+		t.Fatal("Expected the gzipped and uncompressed encodings to differ")
+	}
+
+	fromGzip, err := ParseProfile(&gzipped)
+	if err != nil {
+		t.Fatalf("Failed to parse gzipped profile: %v", err)
+	}
+	fromPlain, err := ParseProfile(&plain)
+	if err != nil {
+		t.Fatalf("Failed to parse uncompressed profile: %v", err)
+	}
+	if fromGzip.String() != fromPlain.String() {
+		t.Errorf("Expected equal profiles, got\n%s\nand\n%s", fromGzip, fromPlain)
+	}
+	if len(fromGzip.Sample) != 2 || fromGzip.Sample[0].Value[1] != 30000000 {
+		t.Errorf("Unexpected samples: %v", fromGzip.Sample)
+	}
+}
+
+func TestParseProfileErrors(t *testing.T) {
+	var gzipped bytes.Buffer
+	if err := newTestProfile([]string{"samples"}).Write(&gzipped); err != nil {
+		t.Fatalf("Failed to write profile: %v", err)
+	}
+	truncated := gzipped.Bytes()[:gzipped.Len()-4]
+
+	for name, data := range map[string][]byte{
+		"empty":           nil,
+		"gzip magic only": gzipMagic,
+		"truncated gzip":  truncated,
+		"not a profile":   []byte("not a profile"),
+	} {
+		if _, err := ParseProfile(bytes.NewReader(data)); err == nil {
+			t.Errorf("Expected an error for %s input", name)
+		}
+	}
+}
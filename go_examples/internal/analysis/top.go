@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/google/pprof/profile"
+)
+
+// FuncStat is one row of a top table, as `go tool pprof -top` prints it. Flat
+// is the value of samples whose leaf is the function and Cum that of samples
+// with the function anywhere on the stack; the percentages are of the
+// profile's total.
+type FuncStat struct {
+	Function    string  `json:"function"`
+	File        string  `json:"file"`
+	Flat        int64   `json:"flat"`
+	FlatPercent float64 `json:"flatPercent"`
+	Cum         int64   `json:"cum"`
+	CumPercent  float64 `json:"cumPercent"`
+}
+
+// TopFunctions returns the n functions with the highest flat value of the
+// sample type at sampleIndex, ties broken by cumulative value and then name,
+// or every function when n isn't positive. A function that recurses counts
+// once towards its own cumulative value per sample. It returns nil when
+// sampleIndex is out of range.
+func TopFunctions(p *profile.Profile, sampleIndex, n int) []FuncStat {
+	if sampleIndex < 0 || sampleIndex >= len(p.SampleType) {
+		return nil
+	}
+
+	stats := make(map[string]*FuncStat)
+	stat := func(fn *profile.Function) *FuncStat {
+		s, ok := stats[fn.Name]
+		if !ok {
+			s = &FuncStat{Function: fn.Name, File: fn.Filename}
+			stats[fn.Name] = s
+		}
+		return s
+	}
+
+	// pprof sums absolute values, so the percentages of a diff profile's
+	// negative samples still add up to at most 100
+	var total int64
+	for _, sample := range p.Sample {
+		value := sample.Value[sampleIndex]
+		total += abs64(value)
+
+		seen := make(map[string]bool)
+		leaf := true
+		for _, loc := range sample.Location {
+			for _, line := range loc.Line {
+				if line.Function == nil {
+					continue
+				}
+				s := stat(line.Function)
+				if leaf {
+					s.Flat += value
+					leaf = false
+				}
+				if !seen[s.Function] {
+					s.Cum += value
+					seen[s.Function] = true
+				}
+			}
+		}
+	}
+
+	top := make([]FuncStat, 0, len(stats))
+	for _, s := range stats {
+		if total != 0 {
+			s.FlatPercent = 100 * float64(s.Flat) / float64(total)
+			s.CumPercent = 100 * float64(s.Cum) / float64(total)
+		}
+		top = append(top, *s)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Flat != top[j].Flat {
+			return top[i].Flat > top[j].Flat
+		}
+		if top[i].Cum != top[j].Cum {
+			return top[i].Cum > top[j].Cum
+		}
+		return top[i].Function < top[j].Function
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
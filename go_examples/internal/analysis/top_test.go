@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTopFunctions(t *testing.T) {
+	p := newTestProfile([]string{"samples", "cpu"},
+		testSample{stack: []string{"main.searchHandler", "main.containsIgnoreCase", "main.toLower"}, values: []int64{50, 500}},
+		testSample{stack: []string{"main.searchHandler", "main.containsIgnoreCase"}, values: []int64{20, 200}},
+		testSample{stack: []string{"main.searchHandler", "encoding/json.Marshal"}, values: []int64{20, 200}},
+		// A recursive walk counts once towards walk's cum
+		testSample{stack: []string{"main.walk", "main.walk", "main.walk"}, values: []int64{10, 100}},
+	)
+
+	top := TopFunctions(p, 1, 0)
+	want := []struct {
+		name      string
+		flat, cum int64
+	}{
+		{"main.toLower", 500, 500},
+		{"main.containsIgnoreCase", 200, 700},
+		{"encoding/json.Marshal", 200, 200},
+		{"main.walk", 100, 100},
+		{"main.searchHandler", 0, 900},
+	}
+	if len(top) != len(want) {
+		t.Fatalf("Expected %d functions, got %+v", len(want), top)
+	}
+	for i, w := range want {
+		if top[i].Function != w.name || top[i].Flat != w.flat || top[i].Cum != w.cum {
+			t.Errorf("Row %d: expected %s flat %d cum %d, got %+v", i, w.name, w.flat, w.cum, top[i])
+		}
+	}
+
+	// The total is 1000, so percentages are a tenth of the values
+	for _, s := range top {
+		if math.Abs(s.FlatPercent-float64(s.Flat)/10) > 1e-9 || math.Abs(s.CumPercent-float64(s.Cum)/10) > 1e-9 {
+			t.Errorf("Unexpected percentages for %s: flat %.2f%%, cum %.2f%%", s.Function, s.FlatPercent, s.CumPercent)
+		}
+	}
+	if top[0].File != "main.toLower.go" {
+		t.Errorf("Expected the function's file, got %q", top[0].File)
+	}
+
+	if top := TopFunctions(p, 1, 2); len(top) != 2 || top[1].Function != "main.containsIgnoreCase" {
+		t.Errorf("Expected the first 2 rows, got %+v", top)
+	}
+	if top := TopFunctions(p, 2, 5); top != nil {
+		t.Errorf("Expected nil for an out of range sample index, got %+v", top)
+	}
+	if top := TopFunctions(newTestProfile([]string{"samples"}), 0, 5); len(top) != 0 {
+		t.Errorf("Expected no rows for an empty profile, got %+v", top)
+	}
+}
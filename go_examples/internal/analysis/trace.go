@@ -0,0 +1,230 @@
+// Package analysis turns raw profiling data captured from the example
+// applications into the summaries shown by the visualizer.
+package analysis
+
+import "sort"
+
+// EventKind identifies a goroutine state transition in an execution trace
+type EventKind int
+
+const (
+	// EventCreate marks a goroutine being created; Peer is the creator
+	EventCreate EventKind = iota
+	// EventBlock marks a goroutine parking; Reason describes what it waits on
+	EventBlock
+	// EventUnblock marks a goroutine becoming runnable; Peer is the goroutine
+	// that woke it, or 0 when it was woken by the runtime (timers, netpoller)
+	EventUnblock
+	// EventEnd marks a goroutine exiting
+	EventEnd
+)
+
+// Event is a single goroutine transition extracted from an execution trace.
+// Times are nanoseconds relative to the start of the trace.
+type Event struct {
+	Time      int64
+	Kind      EventKind
+	Goroutine uint64
+	Peer      uint64
+	Reason    string
+}
+
+// BlockInterval is a period during which a goroutine was parked
+type BlockInterval struct {
+	Start     int64
+	End       int64
+	Reason    string
+	Unblocker uint64
+}
+
+// GoroutineTimeline is the lifetime of a single goroutine within a trace
+type GoroutineTimeline struct {
+	ID      uint64
+	Creator uint64
+	Start   int64
+	End     int64
+	Blocks  []BlockInterval
+}
+
+// TraceSummary indexes the goroutine timelines of one execution trace
+type TraceSummary struct {
+	Start      int64
+	End        int64
+	Goroutines map[uint64]*GoroutineTimeline
+}
+
+// NewTraceSummary builds per-goroutine timelines from trace events. Events
+// need not be sorted. Goroutines without a create event already existed when
+// the trace started and begin at its first event; goroutines still alive when
+// it stopped end at its last event. A block without a matching unblock (the
+// trace was stopped, or events were dropped) is closed at the goroutine's
+// next event or at its end.
+func NewTraceSummary(events []Event) *TraceSummary {
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	summary := &TraceSummary{
+		Goroutines: make(map[uint64]*GoroutineTimeline),
+	}
+	if len(sorted) == 0 {
+		return summary
+	}
+	summary.Start = sorted[0].Time
+	summary.End = sorted[len(sorted)-1].Time
+
+	created := make(map[uint64]bool)
+	ended := make(map[uint64]bool)
+	open := make(map[uint64]*BlockInterval)
+
+	timeline := func(id uint64, at int64) *GoroutineTimeline {
+		g, ok := summary.Goroutines[id]
+		if !ok {
+			g = &GoroutineTimeline{ID: id, Start: at}
+			summary.Goroutines[id] = g
+		}
+		return g
+	}
+
+	closeBlock := func(g *GoroutineTimeline, at int64, unblocker uint64) {
+		if b, ok := open[g.ID]; ok {
+			b.End = at
+			b.Unblocker = unblocker
+			g.Blocks = append(g.Blocks, *b)
+			delete(open, g.ID)
+		}
+	}
+
+	for _, ev := range sorted {
+		g := timeline(ev.Goroutine, ev.Time)
+		if ev.Peer != 0 {
+			timeline(ev.Peer, ev.Time)
+		}
+		switch ev.Kind {
+		case EventCreate:
+			g.Creator = ev.Peer
+			g.Start = ev.Time
+			created[g.ID] = true
+		case EventBlock:
+			closeBlock(g, ev.Time, 0)
+			open[g.ID] = &BlockInterval{Start: ev.Time, Reason: ev.Reason}
+		case EventUnblock:
+			closeBlock(g, ev.Time, ev.Peer)
+		case EventEnd:
+			closeBlock(g, ev.Time, 0)
+			g.End = ev.Time
+			ended[g.ID] = true
+		}
+	}
+
+	for id, g := range summary.Goroutines {
+		if !created[id] {
+			g.Start = summary.Start
+		}
+		if !ended[id] {
+			closeBlock(g, summary.End, 0)
+			g.End = summary.End
+		}
+	}
+
+	return summary
+}
+
+// Segment is one piece of a critical path. Blocked segments are waits that
+// could not be attributed to another goroutine; Reason holds what the
+// goroutine on the path was waiting for when the time was attributed.
+type Segment struct {
+	Goroutine uint64
+	Start     int64
+	End       int64
+	Blocked   bool
+	Reason    string
+}
+
+// CriticalPath reconstructs the chain of work that determined when the given
+// goroutine completed. Time the goroutine spent running is attributed to it;
+// time it spent blocked is attributed to the goroutine that unblocked it (for
+// example the producer whose send woke a consumer waiting on a channel), and
+// time before a goroutine existed is attributed to its creator. Attribution
+// recurses through those goroutines over the same window, so the returned
+// segments are in chronological order and cover the goroutine's lifetime.
+//
+// The result is only as good as the trace: waits ended by the runtime rather
+// than another goroutine (sleeps, timers, network) remain blocked segments,
+// goroutines that existed before the trace started are cut off at its start,
+// and dropped events leave gaps attributed to the goroutine itself.
+func CriticalPath(t *TraceSummary, goroutineID uint64) []Segment {
+	g, ok := t.Goroutines[goroutineID]
+	if !ok {
+		return nil
+	}
+
+	var path []Segment
+	t.attribute(g, g.Start, g.End, "", len(t.Goroutines), &path)
+
+	// Attribution walks backwards in time
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// attribute appends, newest first, the segments covering [from, to] on the
+// path through g. depth bounds recursion on inconsistent traces.
+func (t *TraceSummary) attribute(g *GoroutineTimeline, from, to int64, reason string, depth int, path *[]Segment) {
+	if from >= to {
+		return
+	}
+
+	cursor := to
+	for i := len(g.Blocks) - 1; i >= 0 && cursor > from; i-- {
+		b := g.Blocks[i]
+		if b.Start >= cursor || b.End <= from {
+			continue
+		}
+
+		// Running between the end of this block and the cursor
+		if b.End < cursor {
+			appendSegment(path, Segment{Goroutine: g.ID, Start: b.End, End: cursor, Reason: reason})
+		}
+
+		start, end := max(b.Start, from), min(b.End, cursor)
+		unblocker, ok := t.Goroutines[b.Unblocker]
+		if ok && b.Unblocker != g.ID && depth > 0 {
+			t.attribute(unblocker, start, end, b.Reason, depth-1, path)
+		} else {
+			appendSegment(path, Segment{Goroutine: g.ID, Start: start, End: end, Blocked: true, Reason: b.Reason})
+		}
+		cursor = start
+	}
+
+	// Running since the goroutine started (or since the window opened)
+	if cursor > max(g.Start, from) {
+		appendSegment(path, Segment{Goroutine: g.ID, Start: max(g.Start, from), End: cursor, Reason: reason})
+		cursor = max(g.Start, from)
+	}
+
+	// Before the goroutine existed the path runs through its creator
+	if cursor > from {
+		creator, ok := t.Goroutines[g.Creator]
+		if ok && g.Creator != g.ID && depth > 0 {
+			t.attribute(creator, from, cursor, reason, depth-1, path)
+		}
+	}
+}
+
+// appendSegment adds seg to a newest-first path, merging it with the previous
+// segment when both describe the same state of the same goroutine
+func appendSegment(path *[]Segment, seg Segment) {
+	if n := len(*path); n > 0 {
+		last := &(*path)[n-1]
+		if last.Goroutine == seg.Goroutine && last.Blocked == seg.Blocked &&
+			last.Reason == seg.Reason && last.Start == seg.End {
+			last.Start = seg.Start
+			return
+		}
+	}
+	*path = append(*path, seg)
+}
@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCriticalPathFollowsUnblocker(t *testing.T) {
+	// A consumer waiting on the work channel is woken by a producer's send
+	events := []Event{
+		{Time: 0, Kind: EventCreate, Goroutine: 2, Peer: 1},
+		{Time: 0, Kind: EventCreate, Goroutine: 3, Peer: 1},
+		{Time: 10, Kind: EventBlock, Goroutine: 3, Reason: "chan receive"},
+		{Time: 50, Kind: EventUnblock, Goroutine: 3, Peer: 2},
+		{Time: 55, Kind: EventEnd, Goroutine: 2},
+		{Time: 60, Kind: EventEnd, Goroutine: 3},
+	}
+
+	path := CriticalPath(NewTraceSummary(events), 3)
+
+	expected := []Segment{
+		{Goroutine: 3, Start: 0, End: 10},
+		{Goroutine: 2, Start: 10, End: 50, Reason: "chan receive"},
+		{Goroutine: 3, Start: 50, End: 60},
+	}
+	if !reflect.DeepEqual(path, expected) {
+		t.Errorf("Unexpected critical path:\nExpected: %+v\nGot: %+v", expected, path)
+	}
+}
+
+func TestCriticalPathRuntimeWakeup(t *testing.T) {
+	// Sleeps are ended by the runtime, so the wait stays on the goroutine
+	events := []Event{
+		{Time: 0, Kind: EventCreate, Goroutine: 2, Peer: 1},
+		{Time: 5, Kind: EventBlock, Goroutine: 2, Reason: "sleep"},
+		{Time: 25, Kind: EventUnblock, Goroutine: 2},
+		{Time: 30, Kind: EventEnd, Goroutine: 2},
+	}
+
+	path := CriticalPath(NewTraceSummary(events), 2)
+
+	expected := []Segment{
+		{Goroutine: 2, Start: 0, End: 5},
+		{Goroutine: 2, Start: 5, End: 25, Blocked: true, Reason: "sleep"},
+		{Goroutine: 2, Start: 25, End: 30},
+	}
+	if !reflect.DeepEqual(path, expected) {
+		t.Errorf("Unexpected critical path:\nExpected: %+v\nGot: %+v", expected, path)
+	}
+}
+
+func TestCriticalPathThroughCreator(t *testing.T) {
+	// The unblocker did not exist for the whole wait, so its creator's
+	// work before the spawn is on the path too
+	events := []Event{
+		{Time: 0, Kind: EventCreate, Goroutine: 3, Peer: 1},
+		{Time: 10, Kind: EventBlock, Goroutine: 3, Reason: "sync.Mutex.Lock"},
+		{Time: 30, Kind: EventCreate, Goroutine: 4, Peer: 1},
+		{Time: 50, Kind: EventUnblock, Goroutine: 3, Peer: 4},
+		{Time: 60, Kind: EventEnd, Goroutine: 3},
+		{Time: 60, Kind: EventEnd, Goroutine: 1},
+	}
+
+	path := CriticalPath(NewTraceSummary(events), 3)
+
+	expected := []Segment{
+		{Goroutine: 3, Start: 0, End: 10},
+		{Goroutine: 1, Start: 10, End: 30, Reason: "sync.Mutex.Lock"},
+		{Goroutine: 4, Start: 30, End: 50, Reason: "sync.Mutex.Lock"},
+		{Goroutine: 3, Start: 50, End: 60},
+	}
+	if !reflect.DeepEqual(path, expected) {
+		t.Errorf("Unexpected critical path:\nExpected: %+v\nGot: %+v", expected, path)
+	}
+}
+
+func TestCriticalPathIncompleteTrace(t *testing.T) {
+	// The trace stopped while goroutine 2 was still blocked
+	events := []Event{
+		{Time: 0, Kind: EventCreate, Goroutine: 2, Peer: 1},
+		{Time: 10, Kind: EventBlock, Goroutine: 2, Reason: "chan send"},
+		{Time: 40, Kind: EventEnd, Goroutine: 1},
+	}
+	summary := NewTraceSummary(events)
+
+	path := CriticalPath(summary, 2)
+
+	expected := []Segment{
+		{Goroutine: 2, Start: 0, End: 10},
+		{Goroutine: 2, Start: 10, End: 40, Blocked: true, Reason: "chan send"},
+	}
+	if !reflect.DeepEqual(path, expected) {
+		t.Errorf("Unexpected critical path:\nExpected: %+v\nGot: %+v", expected, path)
+	}
+
+	if path := CriticalPath(summary, 99); path != nil {
+		t.Errorf("Expected nil path for unknown goroutine, got %+v", path)
+	}
+}
@@ -0,0 +1,45 @@
+// Package logging sets up log/slog for the example servers. Operational and
+// demo progress logs go to stderr at a level taken from the LOG_LEVEL
+// environment variable, so they can be turned down while profiling and kept
+// apart from the servers' own output on stdout.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// EnvVar names the environment variable holding the log level
+const EnvVar = "LOG_LEVEL"
+
+// ParseLevel parses a level name such as debug, info, warn or error, in any
+// case. An empty value means info.
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if value == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return 0, fmt.Errorf("invalid %s %q: must be debug, info, warn or error", EnvVar, value)
+	}
+	return level, nil
+}
+
+// New returns a logger writing text records at level and above to w
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Setup makes a logger at the level in value, writing to stderr, the default
+// for slog and the log package, and returns the level
+func Setup(value string) (slog.Level, error) {
+	level, err := ParseLevel(value)
+	if err != nil {
+		return 0, err
+	}
+	slog.SetDefault(New(os.Stderr, level))
+	return level, nil
+}
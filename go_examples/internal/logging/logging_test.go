@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for value, want := range map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"Warn":    slog.LevelWarn,
+		" error ": slog.LevelError,
+	} {
+		if level, err := ParseLevel(value); err != nil || level != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; expected %v", value, level, err, want)
+		}
+	}
+
+	if _, err := ParseLevel("loud"); err == nil || !strings.Contains(err.Error(), EnvVar) {
+		t.Errorf("Expected an error naming %s for an unknown level, got %v", EnvVar, err)
+	}
+}
+
+func TestNewFiltersLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelWarn)
+	logger.Info("hidden")
+	logger.Warn("shown", "items", 3)
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "msg=shown items=3") {
+		t.Errorf("Expected only the warning, got %q", out)
+	}
+}
@@ -0,0 +1,49 @@
+// Package memstatus snapshots the Go runtime memory stats that every example
+// app reports on its /status page, so the text and JSON views share field
+// names across apps.
+package memstatus
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// BytesPerMiB converts the runtime's byte counts to the MiB /status reports
+const BytesPerMiB = 1024 * 1024
+
+// Stats holds the runtime.MemStats fields reported by /status. Apps embed it
+// in their status struct, so its fields sit at the top level of the JSON next
+// to the app's own.
+type Stats struct {
+	AllocMiB      float64 `json:"allocMiB"`
+	TotalAllocMiB float64 `json:"totalAllocMiB"`
+	SysMiB        float64 `json:"sysMiB"`
+	NumGC         uint32  `json:"numGC"`
+}
+
+// Read snapshots the runtime's memory stats
+func Read() Stats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return FromMemStats(&m)
+}
+
+// FromMemStats converts stats already read from the runtime
+func FromMemStats(m *runtime.MemStats) Stats {
+	return Stats{
+		AllocMiB:      float64(m.Alloc) / BytesPerMiB,
+		TotalAllocMiB: float64(m.TotalAlloc) / BytesPerMiB,
+		SysMiB:        float64(m.Sys) / BytesPerMiB,
+		NumGC:         m.NumGC,
+	}
+}
+
+// WriteText prints the stats as the "Name: value" lines of the text /status
+// page, with sizes in whole MiB
+func (s Stats) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Alloc: %d MiB\n", uint64(s.AllocMiB))
+	fmt.Fprintf(w, "TotalAlloc: %d MiB\n", uint64(s.TotalAllocMiB))
+	fmt.Fprintf(w, "Sys: %d MiB\n", uint64(s.SysMiB))
+	fmt.Fprintf(w, "NumGC: %d\n", s.NumGC)
+}
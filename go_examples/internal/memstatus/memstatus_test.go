@@ -0,0 +1,50 @@
+package memstatus
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+func TestFromMemStats(t *testing.T) {
+	m := runtime.MemStats{Alloc: 3 * BytesPerMiB / 2, TotalAlloc: 10 * BytesPerMiB, Sys: 15 * BytesPerMiB, NumGC: 4}
+
+	expected := Stats{AllocMiB: 1.5, TotalAllocMiB: 10, SysMiB: 15, NumGC: 4}
+	if stats := FromMemStats(&m); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestRead(t *testing.T) {
+	stats := Read()
+	if stats.AllocMiB <= 0 || stats.SysMiB <= 0 || stats.TotalAllocMiB < stats.AllocMiB {
+		t.Errorf("Unexpected memory stats: %+v", stats)
+	}
+}
+
+func TestEmbeddedJSON(t *testing.T) {
+	status := struct {
+		Goroutines int `json:"goroutines"`
+		Stats
+	}{2, Stats{AllocMiB: 1.5, TotalAllocMiB: 10, SysMiB: 15, NumGC: 4}}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("Failed to encode status: %v", err)
+	}
+	expected := `{"goroutines":2,"allocMiB":1.5,"totalAllocMiB":10,"sysMiB":15,"numGC":4}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	Stats{AllocMiB: 1.5, TotalAllocMiB: 10, SysMiB: 15.9, NumGC: 4}.WriteText(&buf)
+
+	expected := "Alloc: 1 MiB\nTotalAlloc: 10 MiB\nSys: 15 MiB\nNumGC: 4\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
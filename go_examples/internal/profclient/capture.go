@@ -0,0 +1,73 @@
+// Package profclient pulls profiles from the net/http/pprof endpoints of a
+// running example server.
+package profclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Profile types served under /debug/pprof/. The value is whether the
+// endpoint samples over a duration and so accepts the seconds parameter.
+var profileTypes = map[string]bool{
+	"profile":   true,
+	"trace":     true,
+	"heap":      false,
+	"block":     false,
+	"mutex":     false,
+	"goroutine": false,
+}
+
+// Capture fetches a profile of the given type from the server at baseURL and
+// returns the raw bytes (protobuf, or the binary trace format for "trace").
+// seconds sets the sampling duration for the profile and trace types and is
+// ignored for the others; 0 uses the server default.
+func Capture(ctx context.Context, baseURL, profileType string, seconds int) ([]byte, error) {
+	timed, ok := profileTypes[profileType]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile type %q", profileType)
+	}
+	if seconds < 0 {
+		return nil, fmt.Errorf("invalid seconds %d", seconds)
+	}
+
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/debug/pprof/" + profileType
+	if timed && seconds > 0 {
+		endpoint += "?" + url.Values{"seconds": {strconv.Itoa(seconds)}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s profile: %w", profileType, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("capturing %s profile: %s: %s",
+			profileType, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// CaptureToFile captures a profile like Capture and writes it to path
+func CaptureToFile(ctx context.Context, baseURL, profileType string, seconds int, path string) error {
+	data, err := Capture(ctx, baseURL, profileType, seconds)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,120 @@
+package profclient
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Gzip magic followed by arbitrary bytes, standing in for a real profile
+var cannedProfile = []byte{0x1f, 0x8b, 0x08, 0x00, 0xde, 0xad, 0xbe, 0xef, 0x00, 0xff}
+
+// newPprofServer serves cannedProfile for every /debug/pprof/ request and
+// records the last request URI
+func newPprofServer(t *testing.T, lastURI *string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*lastURI = r.URL.RequestURI()
+		if !strings.HasPrefix(r.URL.Path, "/debug/pprof/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(cannedProfile)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCapture(t *testing.T) {
+	var lastURI string
+	server := newPprofServer(t, &lastURI)
+
+	testCases := []struct {
+		profileType string
+		seconds     int
+		expectedURI string
+	}{
+		{"profile", 5, "/debug/pprof/profile?seconds=5"},
+		{"profile", 0, "/debug/pprof/profile"},
+		{"trace", 2, "/debug/pprof/trace?seconds=2"},
+		{"heap", 5, "/debug/pprof/heap"},
+		{"block", 5, "/debug/pprof/block"},
+		{"mutex", 0, "/debug/pprof/mutex"},
+		{"goroutine", 3, "/debug/pprof/goroutine"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profileType, func(t *testing.T) {
+			// A trailing slash on the base URL must not double up
+			data, err := Capture(context.Background(), server.URL+"/", tc.profileType, tc.seconds)
+			if err != nil {
+				t.Fatalf("Capture failed: %v", err)
+			}
+			if !bytes.Equal(data, cannedProfile) {
+				t.Errorf("Expected canned profile bytes, got %x", data)
+			}
+			if lastURI != tc.expectedURI {
+				t.Errorf("Expected request to %s, got %s", tc.expectedURI, lastURI)
+			}
+		})
+	}
+}
+
+func TestCaptureErrors(t *testing.T) {
+	var lastURI string
+	server := newPprofServer(t, &lastURI)
+
+	if _, err := Capture(context.Background(), server.URL, "allocz", 0); err == nil {
+		t.Error("Expected an error for an unknown profile type")
+	}
+	if _, err := Capture(context.Background(), server.URL, "profile", -1); err == nil {
+		t.Error("Expected an error for negative seconds")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "profiling disabled", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	_, err := Capture(context.Background(), failing.URL, "heap", 0)
+	if err == nil || !strings.Contains(err.Error(), "profiling disabled") {
+		t.Errorf("Expected error carrying the server message, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Capture(ctx, server.URL, "heap", 0); err == nil {
+		t.Error("Expected an error for a cancelled context")
+	}
+}
+
+func TestCaptureToFile(t *testing.T) {
+	var lastURI string
+	server := newPprofServer(t, &lastURI)
+	path := filepath.Join(t.TempDir(), "heap.pb.gz")
+
+	if err := CaptureToFile(context.Background(), server.URL, "heap", 0, path); err != nil {
+		t.Fatalf("CaptureToFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read captured file: %v", err)
+	}
+	if !bytes.Equal(data, cannedProfile) {
+		t.Errorf("Expected file to hold canned profile bytes, got %x", data)
+	}
+
+	// A failed capture must not create the file
+	missing := filepath.Join(t.TempDir(), "missing.pb.gz")
+	if err := CaptureToFile(context.Background(), server.URL, "allocz", 0, missing); err == nil {
+		t.Error("Expected an error for an unknown profile type")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("Expected no file after a failed capture, got %v", err)
+	}
+}
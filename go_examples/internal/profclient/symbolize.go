@@ -0,0 +1,118 @@
+package profclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// Maximum addresses sent in one /debug/pprof/symbol request, keeping the
+// request body well under typical server limits
+var symbolBatchSize = 1000
+
+// Symbolize resolves the locations of p that have an address but no function
+// information by posting the addresses to the server's /debug/pprof/symbol
+// endpoint, and returns how many were resolved. When the server has no symbol
+// endpoint the profile is left with raw addresses and no error is returned.
+func Symbolize(ctx context.Context, baseURL string, p *profile.Profile) (int, error) {
+	var pending []*profile.Location
+	for _, loc := range p.Location {
+		if len(loc.Line) == 0 && loc.Address != 0 {
+			pending = append(pending, loc)
+		}
+	}
+
+	functions := make(map[string]*profile.Function)
+	for _, fn := range p.Function {
+		functions[fn.Name] = fn
+	}
+
+	resolved := 0
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/debug/pprof/symbol"
+	for start := 0; start < len(pending); start += symbolBatchSize {
+		batch := pending[start:min(start+symbolBatchSize, len(pending))]
+		names, err := lookupSymbols(ctx, endpoint, batch)
+		if err != nil {
+			return resolved, err
+		}
+		if names == nil {
+			// No symbol endpoint, keep the raw addresses
+			return resolved, nil
+		}
+
+		for _, loc := range batch {
+			name, ok := names[loc.Address]
+			if !ok {
+				continue
+			}
+			fn, ok := functions[name]
+			if !ok {
+				fn = &profile.Function{ID: uint64(len(p.Function) + 1), Name: name, SystemName: name}
+				p.Function = append(p.Function, fn)
+				functions[name] = fn
+			}
+			loc.Line = []profile.Line{{Function: fn}}
+			resolved++
+		}
+	}
+	return resolved, nil
+}
+
+// lookupSymbols posts addresses in the pprof symbol protocol format
+// ("0x1+0x2+...") and parses the "address name" response lines. It returns
+// nil names without an error when the endpoint doesn't exist.
+func lookupSymbols(ctx context.Context, endpoint string, locations []*profile.Location) (map[uint64]string, error) {
+	addresses := make([]string, len(locations))
+	for i, loc := range locations {
+		addresses[i] = fmt.Sprintf("%#x", loc.Address)
+	}
+	body := strings.NewReader(strings.Join(addresses, "+"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading symbols: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("symbolizing: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	names := make(map[uint64]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Lines are the address, a space or tab, then the name. The
+		// "num_symbols: 1" header and anything else that doesn't start with
+		// an address is skipped.
+		line := strings.TrimSpace(scanner.Text())
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			continue
+		}
+		address, err := strconv.ParseUint(line[:i], 0, 64)
+		if err != nil {
+			continue
+		}
+		names[address] = strings.TrimSpace(line[i+1:])
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,152 @@
+package profclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+)
+
+// Symbols known to the fake symbol endpoint
+var testSymbols = map[uint64]string{
+	0x1000: "main.main",
+	0x2000: "main.worker",
+	0x3000: "runtime.goexit",
+}
+
+// newSymbolServer mimics /debug/pprof/symbol, answering in the
+// "hex<tab>name" format and counting requests
+func newSymbolServer(t *testing.T, requests *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/symbol" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		*requests++
+		body, _ := io.ReadAll(r.Body)
+
+		fmt.Fprintln(w, "num_symbols: 1")
+		for _, word := range strings.Split(string(body), "+") {
+			address, err := strconv.ParseUint(word, 0, 64)
+			if err != nil {
+				continue
+			}
+			if name, ok := testSymbols[address]; ok {
+				fmt.Fprintf(w, "%#x\t%s\n", address, name)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newUnsymbolizedProfile builds a CPU profile whose locations only carry
+// addresses, plus one already symbolized location
+func newUnsymbolizedProfile(addresses ...uint64) *profile.Profile {
+	known := &profile.Function{ID: 1, Name: "main.main"}
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		Function:   []*profile.Function{known},
+		Location:   []*profile.Location{{ID: 1, Address: 0x1000, Line: []profile.Line{{Function: known}}}},
+	}
+	for i, address := range addresses {
+		p.Location = append(p.Location, &profile.Location{ID: uint64(i + 2), Address: address})
+	}
+	p.Sample = []*profile.Sample{{Location: p.Location, Value: []int64{10}}}
+	return p
+}
+
+// locationName returns the function name of loc, or "" when unsymbolized
+func locationName(loc *profile.Location) string {
+	if len(loc.Line) == 0 || loc.Line[0].Function == nil {
+		return ""
+	}
+	return loc.Line[0].Function.Name
+}
+
+func TestSymbolize(t *testing.T) {
+	var requests int
+	server := newSymbolServer(t, &requests)
+
+	// 0x1000 repeats the already known main.main; 0x9999 is unknown to the server
+	p := newUnsymbolizedProfile(0x2000, 0x3000, 0x1000, 0x9999)
+	resolved, err := Symbolize(context.Background(), server.URL, p)
+	if err != nil {
+		t.Fatalf("Symbolize failed: %v", err)
+	}
+	if resolved != 3 {
+		t.Errorf("Expected 3 locations resolved, got %d", resolved)
+	}
+
+	expected := []string{"main.main", "main.worker", "runtime.goexit", "main.main", ""}
+	for i, loc := range p.Location {
+		if name := locationName(loc); name != expected[i] {
+			t.Errorf("Location %#x: expected %q, got %q", loc.Address, expected[i], name)
+		}
+	}
+
+	// main.main is reused rather than duplicated
+	if len(p.Function) != 3 {
+		t.Errorf("Expected 3 functions, got %d", len(p.Function))
+	}
+	if p.Location[3].Line[0].Function != p.Location[0].Line[0].Function {
+		t.Error("Expected both main.main locations to share a function")
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Errorf("Symbolized profile is invalid: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single symbol request, got %d", requests)
+	}
+}
+
+func TestSymbolizeBatches(t *testing.T) {
+	defer func(size int) { symbolBatchSize = size }(symbolBatchSize)
+	symbolBatchSize = 2
+
+	var requests int
+	server := newSymbolServer(t, &requests)
+
+	p := newUnsymbolizedProfile(0x2000, 0x3000, 0x2000, 0x3000, 0x2000)
+	resolved, err := Symbolize(context.Background(), server.URL, p)
+	if err != nil {
+		t.Fatalf("Symbolize failed: %v", err)
+	}
+	if resolved != 5 || requests != 3 {
+		t.Errorf("Expected 5 locations resolved in 3 requests, got %d in %d", resolved, requests)
+	}
+}
+
+func TestSymbolizeFallback(t *testing.T) {
+	// No symbol endpoint: raw addresses are kept
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	p := newUnsymbolizedProfile(0x2000, 0x3000)
+	resolved, err := Symbolize(context.Background(), missing.URL, p)
+	if err != nil || resolved != 0 {
+		t.Fatalf("Expected fallback without error, got %d resolved and %v", resolved, err)
+	}
+	for _, loc := range p.Location[1:] {
+		if name := locationName(loc); name != "" || loc.Address == 0 {
+			t.Errorf("Expected raw address to be kept, got %#x %q", loc.Address, name)
+		}
+	}
+
+	// Other failures are reported
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "symbolizer crashed", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if _, err := Symbolize(context.Background(), failing.URL, newUnsymbolizedProfile(0x2000)); err == nil ||
+		!strings.Contains(err.Error(), "symbolizer crashed") {
+		t.Errorf("Expected error carrying the server message, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Package profrate manages the runtime profiling rates of the example
+// servers so they can be tuned over HTTP without restarting.
+package profrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCPUHz is the sampling rate runtime/pprof uses for CPU profiles
+	DefaultCPUHz = 100
+	// MaxCPUHz bounds the sampling rate; beyond this the signal overhead
+	// distorts the profile more than the extra samples help
+	MaxCPUHz = 1000
+)
+
+// ErrCaptureInProgress is returned when the CPU rate is changed mid-capture
+var ErrCaptureInProgress = errors.New("cpu profile capture in progress")
+
+// CPUProfiler coordinates CPU profile captures so the sampling rate is
+// never changed while a capture is running. It must be the only code in the
+// process starting CPU profiles.
+type CPUProfiler struct {
+	mutex     sync.Mutex
+	hz        int
+	capturing bool
+}
+
+// NewCPUProfiler creates a coordinator sampling at DefaultCPUHz
+func NewCPUProfiler() *CPUProfiler {
+	return &CPUProfiler{hz: DefaultCPUHz}
+}
+
+// Hz returns the rate the next capture will sample at
+func (c *CPUProfiler) Hz() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hz
+}
+
+// SetHz changes the rate for subsequent captures and returns the previous
+// rate. It fails while a capture is running.
+func (c *CPUProfiler) SetHz(hz int) (int, error) {
+	if hz < 1 || hz > MaxCPUHz {
+		return 0, fmt.Errorf("cpu profile rate must be between 1 and %d Hz", MaxCPUHz)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.capturing {
+		return 0, ErrCaptureInProgress
+	}
+	old := c.hz
+	c.hz = hz
+	return old, nil
+}
+
+// begin marks a capture as running and returns the rate to use
+func (c *CPUProfiler) begin() (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.capturing {
+		return 0, ErrCaptureInProgress
+	}
+	c.capturing = true
+	return c.hz, nil
+}
+
+// end marks the running capture as finished
+func (c *CPUProfiler) end() {
+	c.mutex.Lock()
+	c.capturing = false
+	c.mutex.Unlock()
+}
+
+// Start starts a CPU profile written to w at the configured rate, such as
+// one covering a whole run, and marks it as capturing until the returned stop
+// is called: ProfileHandler refuses captures and the rate can't be changed
+// meanwhile.
+func (c *CPUProfiler) Start(w io.Writer) (stop func(), err error) {
+	hz, err := c.begin()
+	if err != nil {
+		return nil, err
+	}
+	if err := startCPUProfile(w, hz); err != nil {
+		c.end()
+		return nil, err
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		c.end()
+	}, nil
+}
+
+// startCPUProfile starts a CPU profile sampling at hz
+func startCPUProfile(w io.Writer, hz int) error {
+	if hz != DefaultCPUHz {
+		// pprof.StartCPUProfile always asks for 100Hz. Setting the rate
+		// first makes the runtime keep ours; it logs a warning about the
+		// second request which is expected. The runtime ignores the rate
+		// while another profile is running, and StartCPUProfile then fails,
+		// so there is nothing to undo on failure.
+		runtime.SetCPUProfileRate(hz)
+	}
+	return pprof.StartCPUProfile(w)
+}
+
+// ProfileHandler serves /debug/pprof/profile at the configured rate, taking
+// the same seconds parameter as net/http/pprof's handler
+func (c *CPUProfiler) ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	seconds := int64(30)
+	if value := r.FormValue("seconds"); value != "" {
+		var err error
+		seconds, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || seconds <= 0 {
+			profileError(w, http.StatusBadRequest, "bad seconds")
+			return
+		}
+	}
+	duration := time.Duration(seconds) * time.Second
+	if srv, ok := r.Context().Value(http.ServerContextKey).(*http.Server); ok && srv.WriteTimeout > 0 && duration >= srv.WriteTimeout {
+		profileError(w, http.StatusBadRequest, "profile duration exceeds server's WriteTimeout")
+		return
+	}
+
+	hz, err := c.begin()
+	if err != nil {
+		profileError(w, http.StatusConflict, err.Error())
+		return
+	}
+	defer c.end()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := startCPUProfile(w, hz); err != nil {
+		// Only code outside the profiler can have started a profile
+		profileError(w, http.StatusInternalServerError, "Could not enable CPU profiling: "+err.Error())
+		return
+	}
+	select {
+	case <-time.After(duration):
+	case <-r.Context().Done():
+	}
+	pprof.StopCPUProfile()
+}
+
+// profileError reports a failed capture the way net/http/pprof does, so
+// go tool pprof shows the message instead of a parse error
+func profileError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("X-Go-Pprof", "1")
+	w.Header().Del("Content-Disposition")
+	http.Error(w, message, status)
+}
+
+// Response for rate changes
+type rateChange struct {
+	Old int `json:"old"`
+	New int `json:"new"`
+}
+
+// ConfigHandler reads (GET) or changes (POST ?hz=N, or ?reset=true to go
+// back to DefaultCPUHz) the CPU profile rate. The rate can only be changed
+// while no capture is running.
+func (c *CPUProfiler) ConfigHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, map[string]int{"hz": c.Hz(), "default": DefaultCPUHz})
+
+	case http.MethodPost:
+		hz := DefaultCPUHz
+		if r.URL.Query().Get("reset") != "true" {
+			var err error
+			hz, err = strconv.Atoi(r.URL.Query().Get("hz"))
+			if err != nil {
+				http.Error(w, "invalid hz parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		old, err := c.SetHz(hz)
+		if errors.Is(err, ErrCaptureInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, rateChange{Old: old, New: hz})
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
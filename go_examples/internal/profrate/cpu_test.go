@@ -0,0 +1,114 @@
+package profrate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestConfigHandler(t *testing.T) {
+	cpu := NewCPUProfiler()
+
+	testCases := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+		expectedHz     int
+	}{
+		{"Raise rate", "POST", "/config/cpu-hz?hz=500", http.StatusOK, 500},
+		{"Missing rate", "POST", "/config/cpu-hz", http.StatusBadRequest, 500},
+		{"Zero rate", "POST", "/config/cpu-hz?hz=0", http.StatusBadRequest, 500},
+		{"Rate above cap", "POST", "/config/cpu-hz?hz=100000", http.StatusBadRequest, 500},
+		{"Reset", "POST", "/config/cpu-hz?reset=true", http.StatusOK, DefaultCPUHz},
+		{"Unsupported method", "DELETE", "/config/cpu-hz", http.StatusMethodNotAllowed, DefaultCPUHz},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			recorder := httptest.NewRecorder()
+
+			cpu.ConfigHandler(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+			if hz := cpu.Hz(); hz != tc.expectedHz {
+				t.Errorf("Expected rate %d Hz, got %d", tc.expectedHz, hz)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/config/cpu-hz", nil)
+	recorder := httptest.NewRecorder()
+	cpu.ConfigHandler(recorder, req)
+
+	var setting map[string]int
+	if err := json.Unmarshal(recorder.Body.Bytes(), &setting); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if setting["hz"] != DefaultCPUHz || setting["default"] != DefaultCPUHz {
+		t.Errorf("Unexpected setting: %v", setting)
+	}
+}
+
+func TestProfileHandlerAppliesRate(t *testing.T) {
+	cpu := NewCPUProfiler()
+	if _, err := cpu.SetHz(500); err != nil {
+		t.Fatalf("SetHz returned error: %v", err)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		req := httptest.NewRequest("GET", "/debug/pprof/profile?seconds=1", nil)
+		recorder := httptest.NewRecorder()
+		cpu.ProfileHandler(recorder, req)
+		done <- recorder
+	}()
+
+	// Wait for the capture to start, then try to change the rate under it
+	deadline := time.Now().Add(time.Second)
+	for {
+		cpu.mutex.Lock()
+		capturing := cpu.capturing
+		cpu.mutex.Unlock()
+		if capturing {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Capture did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	req := httptest.NewRequest("POST", "/config/cpu-hz?hz=200", nil)
+	recorder := httptest.NewRecorder()
+	cpu.ConfigHandler(recorder, req)
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status %d while capturing, got %d", http.StatusConflict, recorder.Code)
+	}
+
+	result := <-done
+	if result.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, result.Code, result.Body.String())
+	}
+
+	p, err := profile.Parse(result.Body)
+	if err != nil {
+		t.Fatalf("Failed to parse captured profile: %v", err)
+	}
+	// The sampling period is reported in nanoseconds
+	if expected := int64(time.Second / 500); p.Period != expected {
+		t.Errorf("Expected sampling period %d, got %d", expected, p.Period)
+	}
+
+	// The rate can be changed again once the capture has finished
+	if _, err := cpu.SetHz(DefaultCPUHz); err != nil {
+		t.Errorf("SetHz after capture returned error: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package profrate
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// The runtime has no getter for the block profile rate, so track it here
+var (
+	blockMutex sync.Mutex
+	blockRate  int
+)
+
+// SetBlockRate applies a block profile rate (see runtime.SetBlockProfileRate)
+// and returns the previous one. 0 disables block profiling.
+func SetBlockRate(rate int) int {
+	blockMutex.Lock()
+	defer blockMutex.Unlock()
+
+	old := blockRate
+	blockRate = rate
+	runtime.SetBlockProfileRate(rate)
+	return old
+}
+
+// BlockRate returns the block profile rate last applied with SetBlockRate
+func BlockRate() int {
+	blockMutex.Lock()
+	defer blockMutex.Unlock()
+	return blockRate
+}
+
+// SetMutexFraction applies a mutex profile fraction (see
+// runtime.SetMutexProfileFraction) and returns the previous one. 0 disables
+// mutex profiling.
+func SetMutexFraction(fraction int) int {
+	return runtime.SetMutexProfileFraction(fraction)
+}
+
+// MutexFraction returns the active mutex profile fraction
+func MutexFraction() int {
+	// A negative fraction reads the current value without changing it
+	return runtime.SetMutexProfileFraction(-1)
+}
+
+// Rates are the effective block and mutex profiling settings
+type Rates struct {
+	Block int `json:"block"`
+	Mutex int `json:"mutex"`
+}
+
+// Current returns the effective block and mutex profiling settings
+func Current() Rates {
+	return Rates{Block: BlockRate(), Mutex: MutexFraction()}
+}
+
+// formRate parses an optional non-negative rate form value
+func formRate(r *http.Request, name string) (rate int, ok bool, err error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return 0, false, nil
+	}
+	rate, err = strconv.Atoi(value)
+	if err != nil || rate < 0 || rate > math.MaxInt32 {
+		return 0, false, fmt.Errorf("invalid %s value %q: must be between 0 and %d", name, value, math.MaxInt32)
+	}
+	return rate, true, nil
+}
+
+// Handler serves /debug/profrate. GET returns the effective rates; POST sets
+// the block profile rate and/or mutex profile fraction from the "block" and
+// "mutex" form values and returns the rates now in effect.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, Current())
+
+	case http.MethodPost:
+		// Validate both values before applying either
+		block, setBlock, err := formRate(r, "block")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mutex, setMutex, err := formRate(r, "mutex")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !setBlock && !setMutex {
+			http.Error(w, "missing block or mutex value", http.StatusBadRequest)
+			return
+		}
+
+		if setBlock {
+			SetBlockRate(block)
+		}
+		if setMutex {
+			SetMutexFraction(mutex)
+		}
+		writeJSON(w, Current())
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,86 @@
+package profrate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSetBlockRate(t *testing.T) {
+	defer SetBlockRate(0)
+
+	SetBlockRate(0)
+	if old := SetBlockRate(1000); old != 0 {
+		t.Errorf("Expected previous rate 0, got %d", old)
+	}
+	if rate := BlockRate(); rate != 1000 {
+		t.Errorf("Expected block rate 1000, got %d", rate)
+	}
+}
+
+func TestSetMutexFraction(t *testing.T) {
+	previous := SetMutexFraction(3)
+	defer SetMutexFraction(previous)
+
+	if fraction := MutexFraction(); fraction != 3 {
+		t.Errorf("Expected mutex fraction 3, got %d", fraction)
+	}
+	// Reading must not change the value
+	if fraction := MutexFraction(); fraction != 3 {
+		t.Errorf("Expected mutex fraction to stay 3, got %d", fraction)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	defer SetBlockRate(0)
+	defer SetMutexFraction(SetMutexFraction(0))
+
+	testCases := []struct {
+		name           string
+		method         string
+		url            string
+		form           url.Values
+		expectedStatus int
+		expected       Rates
+	}{
+		{"Set both in query", "POST", "/debug/profrate?block=1&mutex=5", nil, http.StatusOK, Rates{Block: 1, Mutex: 5}},
+		{"Set block in form body", "POST", "/debug/profrate", url.Values{"block": {"100"}}, http.StatusOK, Rates{Block: 100, Mutex: 5}},
+		{"Set mutex only", "POST", "/debug/profrate?mutex=0", nil, http.StatusOK, Rates{Block: 100, Mutex: 0}},
+		{"Negative block", "POST", "/debug/profrate?block=-1&mutex=2", nil, http.StatusBadRequest, Rates{Block: 100, Mutex: 0}},
+		{"Invalid mutex", "POST", "/debug/profrate?block=2&mutex=x", nil, http.StatusBadRequest, Rates{Block: 100, Mutex: 0}},
+		{"Nothing to set", "POST", "/debug/profrate", nil, http.StatusBadRequest, Rates{Block: 100, Mutex: 0}},
+		{"Read", "GET", "/debug/profrate", nil, http.StatusOK, Rates{Block: 100, Mutex: 0}},
+		{"Unsupported method", "PUT", "/debug/profrate", nil, http.StatusMethodNotAllowed, Rates{Block: 100, Mutex: 0}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.form.Encode()))
+			if tc.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			recorder := httptest.NewRecorder()
+
+			Handler(recorder, req)
+
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if current := Current(); current != tc.expected {
+				t.Errorf("Expected rates %+v, got %+v", tc.expected, current)
+			}
+			if recorder.Code == http.StatusOK {
+				var body Rates
+				if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+					t.Fatalf("Invalid JSON response: %v", err)
+				}
+				if body != tc.expected {
+					t.Errorf("Expected response %+v, got %+v", tc.expected, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Package randseed seeds math/rand for the example servers. A fixed seed from
+// the -seed flag or the SEED environment variable makes their generated data
+// and allocation patterns repeat across runs, so profiles of two runs differ
+// only where the code does.
+package randseed
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+)
+
+// EnvVar names the environment variable holding a fixed seed
+const EnvVar = "SEED"
+
+// Flag defines the -seed flag on the default flag set, defaulting to $SEED
+func Flag() *string {
+	return flag.String("seed", os.Getenv(EnvVar),
+		"fixed math/rand seed for reproducible data (default $"+EnvVar+", or the current time when unset)")
+}
+
+// Parse returns the seed in value, or one taken from the current time when
+// value is empty, and whether it was fixed
+func Parse(value string) (seed int64, fixed bool, err error) {
+	if value == "" {
+		return time.Now().UnixNano(), false, nil
+	}
+
+	seed, err = strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid seed %q: must be an integer", value)
+	}
+	return seed, true, nil
+}
+
+// Apply seeds math/rand's global source with the seed Parse returns for value
+func Apply(value string) (seed int64, fixed bool, err error) {
+	seed, fixed, err = Parse(value)
+	if err != nil {
+		return 0, false, err
+	}
+	rand.Seed(seed)
+	return seed, fixed, nil
+}
+
+// Describe formats the effective seed for a startup log line, saying how to
+// repeat the run when the seed wasn't fixed
+func Describe(seed int64, fixed bool) string {
+	if fixed {
+		return fmt.Sprintf("Random seed: %d (fixed)", seed)
+	}
+	return fmt.Sprintf("Random seed: %d (from the clock; set -seed=%d or %s=%d to repeat this run)", seed, seed, EnvVar, seed)
+}
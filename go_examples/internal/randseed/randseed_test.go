@@ -0,0 +1,57 @@
+package randseed
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	seed, fixed, err := Parse("-42")
+	if err != nil || !fixed || seed != -42 {
+		t.Errorf("Expected fixed seed -42, got %d, %v, %v", seed, fixed, err)
+	}
+
+	if _, fixed, err := Parse(""); err != nil || fixed {
+		t.Errorf("Expected a time-based seed for an empty value, got fixed=%v, %v", fixed, err)
+	}
+
+	for _, value := range []string{"abc", "1.5", "99999999999999999999"} {
+		if _, _, err := Parse(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestApplyRepeats(t *testing.T) {
+	draw := func() []int {
+		if _, _, err := Apply("7"); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		values := make([]int, 5)
+		for i := range values {
+			values[i] = rand.Intn(1000)
+		}
+		return values
+	}
+
+	first, second := draw(), draw()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same draws after applying the same seed, got %v and %v", first, second)
+		}
+	}
+
+	if _, _, err := Apply("seven"); err == nil {
+		t.Error("Expected an error for a non-numeric seed")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if s := Describe(5, true); !strings.Contains(s, "5 (fixed)") {
+		t.Errorf("Unexpected description of a fixed seed: %s", s)
+	}
+	if s := Describe(123, false); !strings.Contains(s, "SEED=123") {
+		t.Errorf("Expected a time-based seed's description to say how to repeat it, got %s", s)
+	}
+}
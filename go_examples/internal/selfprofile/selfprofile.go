@@ -0,0 +1,88 @@
+// Package selfprofile writes CPU and heap profiles of an example server to
+// files, like the -cpuprofile and -memprofile flags of go test. It suits runs
+// too short to scrape /debug/pprof, or tools that only read profile files.
+package selfprofile
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"pprofviz/examples/internal/profrate"
+)
+
+// Files names the profiles to write; an empty name skips that profile
+type Files struct {
+	CPU string // CPU profile covering the whole run
+	Mem string // Heap profile taken at exit
+}
+
+// Flags defines -cpuprofile and -memprofile on the default flag set
+func Flags() *Files {
+	files := &Files{}
+	files.Register(flag.CommandLine)
+	return files
+}
+
+// Register defines -cpuprofile and -memprofile on fs
+func (f *Files) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.CPU, "cpuprofile", "",
+		"write a CPU profile of the whole run to this file (/debug/pprof/profile is unavailable meanwhile)")
+	fs.StringVar(&f.Mem, "memprofile", "", "write a heap profile to this file at exit")
+}
+
+// Start starts the CPU profile, if any, through cpu, the server's only way of
+// starting CPU profiles, so its /debug/pprof/profile refuses captures until
+// the run ends. The returned stop ends the profile and writes the heap
+// profile, if any; call it once, after the server has shut down.
+func (f *Files) Start(cpu *profrate.CPUProfiler) (stop func() error, err error) {
+	var cpuFile *os.File
+	var stopCPU func()
+	if f.CPU != "" {
+		cpuFile, err = os.Create(f.CPU)
+		if err != nil {
+			return nil, fmt.Errorf("creating CPU profile: %w", err)
+		}
+		if stopCPU, err = cpu.Start(cpuFile); err != nil {
+			cpuFile.Close()
+			return nil, fmt.Errorf("starting CPU profile: %w", err)
+		}
+	}
+
+	return func() error {
+		var errs []error
+		if cpuFile != nil {
+			stopCPU()
+			if err := cpuFile.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("writing CPU profile: %w", err))
+			}
+		}
+		if f.Mem != "" {
+			if err := writeHeapProfile(f.Mem); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// writeHeapProfile writes the heap profile to path, after a GC so it
+// reflects the live heap at exit
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating heap profile: %w", err)
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("writing heap profile: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("writing heap profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package selfprofile
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+
+	"pprofviz/examples/internal/profrate"
+)
+
+// sink keeps the busy loop from being optimized away
+var sink uint64
+
+// spin keeps a CPU busy for d
+func spin(d time.Duration) {
+	for deadline := time.Now().Add(d); time.Now().Before(deadline); {
+		for i := 0; i < 1000; i++ {
+			sink = sink*31 + uint64(i)
+		}
+	}
+}
+
+func TestStartWritesProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cpuPath := filepath.Join(dir, "cpu.pprof")
+	memPath := filepath.Join(dir, "mem.pprof")
+
+	var files Files
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	files.Register(fs)
+	if err := fs.Parse([]string{"-cpuprofile", cpuPath, "-memprofile", memPath}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	stop, err := files.Start(profrate.NewCPUProfiler())
+	if err != nil {
+		t.Fatalf("Failed to start profiling: %v", err)
+	}
+	spin(200 * time.Millisecond)
+	if err := stop(); err != nil {
+		t.Fatalf("Failed to stop profiling: %v", err)
+	}
+
+	for _, path := range []string{cpuPath, memPath} {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Expected a profile at %s: %v", path, err)
+		}
+		p, err := profile.Parse(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", path, err)
+		}
+		if len(p.SampleType) == 0 {
+			t.Errorf("Expected sample types in %s", path)
+		}
+	}
+}
+
+func TestStartNoFiles(t *testing.T) {
+	var files Files
+	stop, err := files.Start(profrate.NewCPUProfiler())
+	if err != nil {
+		t.Fatalf("Failed to start with no profiles: %v", err)
+	}
+	if err := stop(); err != nil {
+		t.Errorf("Expected stopping with no profiles to succeed, got %v", err)
+	}
+}
+
+func TestStartBadPath(t *testing.T) {
+	files := Files{CPU: filepath.Join(t.TempDir(), "missing", "cpu.pprof")}
+	if _, err := files.Start(profrate.NewCPUProfiler()); err == nil {
+		t.Error("Expected an error creating a CPU profile in a missing directory")
+	}
+}
+
+// spinBeforeCapture and spinAfterCapture spin under their own names, so a
+// profile shows which of them it sampled
+func spinBeforeCapture(d time.Duration) { spin(d) }
+func spinAfterCapture(d time.Duration)  { spin(d) }
+
+// spinTime sums the CPU time of samples with the named function on the stack
+func spinTime(p *profile.Profile, name string) time.Duration {
+	var total int64
+	for _, s := range p.Sample {
+		for _, loc := range s.Location {
+			for _, line := range loc.Line {
+				if strings.HasSuffix(line.Function.Name, "."+name) {
+					total += s.Value[1]
+				}
+			}
+		}
+	}
+	return time.Duration(total)
+}
+
+func TestStartWithProfileHandler(t *testing.T) {
+	cpuPath := filepath.Join(t.TempDir(), "cpu.pprof")
+	cpu := profrate.NewCPUProfiler()
+	if _, err := cpu.SetHz(200); err != nil {
+		t.Fatalf("SetHz returned error: %v", err)
+	}
+
+	files := Files{CPU: cpuPath}
+	stop, err := files.Start(cpu)
+	if err != nil {
+		t.Fatalf("Failed to start profiling: %v", err)
+	}
+
+	const spinFor = 300 * time.Millisecond
+	spinBeforeCapture(spinFor)
+
+	// A capture over HTTP is refused rather than stopping the run's profile
+	recorder := httptest.NewRecorder()
+	cpu.ProfileHandler(recorder, httptest.NewRequest("GET", "/debug/pprof/profile?seconds=1", nil))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status %d while the run is profiled, got %d: %s", http.StatusConflict, recorder.Code, recorder.Body.String())
+	}
+	if _, err := cpu.SetHz(profrate.DefaultCPUHz); err == nil {
+		t.Error("Expected the rate to be fixed while the run is profiled")
+	}
+
+	spinAfterCapture(spinFor)
+	if err := stop(); err != nil {
+		t.Fatalf("Failed to stop profiling: %v", err)
+	}
+
+	f, err := os.Open(cpuPath)
+	if err != nil {
+		t.Fatalf("Expected a profile at %s: %v", cpuPath, err)
+	}
+	defer f.Close()
+	p, err := profile.Parse(f)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", cpuPath, err)
+	}
+	if expected := int64(time.Second / 200); p.Period != expected {
+		t.Errorf("Expected the configured sampling period %d, got %d", expected, p.Period)
+	}
+
+	// Sampling kept going after the refused capture. The two spins last
+	// equally long, so they get similar shares of samples however loaded
+	// the machine is, while a reset rate would leave the second with few.
+	before, after := spinTime(p, "spinBeforeCapture"), spinTime(p, "spinAfterCapture")
+	if before == 0 || after < before/3 {
+		t.Errorf("Expected the profile to cover the spinning after the refused capture, got %v before and %v after", before, after)
+	}
+
+	// Captures work again once the run's profile has stopped
+	if _, err := cpu.SetHz(profrate.DefaultCPUHz); err != nil {
+		t.Errorf("SetHz after the run's profile returned error: %v", err)
+	}
+}
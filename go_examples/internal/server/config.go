@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv. The timeouts take Go
+// durations such as 5s or 500ms.
+const (
+	ReadTimeoutEnv  = "HTTP_READ_TIMEOUT"
+	WriteTimeoutEnv = "HTTP_WRITE_TIMEOUT"
+	IdleTimeoutEnv  = "HTTP_IDLE_TIMEOUT"
+	H2CEnv          = "HTTP_H2C"
+)
+
+// Config tunes the connection handling of a server, so load tests can keep
+// connection churn out of goroutine and block profiles. The zero Config
+// keeps net/http's defaults: no timeouts and HTTP/1 only.
+type Config struct {
+	ReadTimeout  time.Duration // Reading a whole request, body included
+	WriteTimeout time.Duration // From the end of the request headers to the end of the response
+	IdleTimeout  time.Duration // Keep-alive connections waiting for their next request
+	H2C          bool          // Also serve HTTP/2 without TLS (h2c)
+}
+
+// ConfigFromEnv reads a Config from the environment variables above through
+// getenv, leaving unset ones at their zero value
+func ConfigFromEnv(getenv func(string) string) (Config, error) {
+	var c Config
+	timeouts := []struct {
+		name string
+		dest *time.Duration
+	}{
+		{ReadTimeoutEnv, &c.ReadTimeout},
+		{WriteTimeoutEnv, &c.WriteTimeout},
+		{IdleTimeoutEnv, &c.IdleTimeout},
+	}
+	for _, timeout := range timeouts {
+		value := getenv(timeout.name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid %s %q: must be a non-negative duration such as 5s", timeout.name, value)
+		}
+		*timeout.dest = d
+	}
+
+	if value := getenv(H2CEnv); value != "" {
+		h2c, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s %q: must be true or false", H2CEnv, value)
+		}
+		c.H2C = h2c
+	}
+	return c, nil
+}
+
+// String describes c for a startup log line
+func (c Config) String() string {
+	describe := func(d time.Duration) string {
+		if d == 0 {
+			return "none"
+		}
+		return d.String()
+	}
+	return fmt.Sprintf("read timeout %s, write timeout %s, idle timeout %s, h2c %t",
+		describe(c.ReadTimeout), describe(c.WriteTimeout), describe(c.IdleTimeout), c.H2C)
+}
+
+// newServer returns an http.Server for handler configured by c
+func (c Config) newServer(handler http.Handler) (*http.Server, error) {
+	srv := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+		IdleTimeout:  c.IdleTimeout,
+	}
+	if c.H2C {
+		if err := enableH2C(srv); err != nil {
+			return nil, err
+		}
+	}
+	return srv, nil
+}
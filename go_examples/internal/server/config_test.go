@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	env := map[string]string{
+		ReadTimeoutEnv:  "5s",
+		WriteTimeoutEnv: "250ms",
+		H2CEnv:          "true",
+	}
+	c, err := ConfigFromEnv(func(name string) string { return env[name] })
+	expected := Config{ReadTimeout: 5 * time.Second, WriteTimeout: 250 * time.Millisecond, H2C: true}
+	if err != nil || c != expected {
+		t.Errorf("Expected %+v, got %+v, %v", expected, c, err)
+	}
+
+	if c, err := ConfigFromEnv(func(string) string { return "" }); err != nil || c != (Config{}) {
+		t.Errorf("Expected the zero Config with nothing set, got %+v, %v", c, err)
+	}
+
+	for name, value := range map[string]string{
+		ReadTimeoutEnv:  "5",
+		WriteTimeoutEnv: "-1s",
+		IdleTimeoutEnv:  "soon",
+		H2CEnv:          "maybe",
+	} {
+		getenv := func(n string) string {
+			if n == name {
+				return value
+			}
+			return ""
+		}
+		if _, err := ConfigFromEnv(getenv); err == nil {
+			t.Errorf("Expected an error for %s=%s", name, value)
+		}
+	}
+}
+
+func TestServeWriteTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "done")
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Config{WriteTimeout: 100 * time.Millisecond}.Serve(ctx, ln, mux, 5*time.Second)
+	}()
+	defer func() {
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	}()
+
+	// Each request gets its own connection so the slow one can't affect the other
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	resp, err := client.Get("http://" + addr + "/fast")
+	if err != nil {
+		t.Fatalf("Expected a handler within the write timeout to succeed, got %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = client.Get("http://" + addr + "/slow")
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("Expected an error from a handler outlasting the write timeout, got %s", resp.Status)
+	}
+}
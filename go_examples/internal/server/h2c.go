@@ -0,0 +1,15 @@
+//go:build go1.24
+
+package server
+
+import "net/http"
+
+// enableH2C makes srv accept HTTP/2 over cleartext connections as well as
+// HTTP/1
+func enableH2C(srv *http.Server) error {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	srv.Protocols = &protocols
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build go1.24
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeH2C(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/proto", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Config{H2C: true}.Serve(ctx, ln, mux, 5*time.Second)
+	}()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	get := func(client *http.Client) string {
+		resp, err := client.Get("http://" + addr + "/proto")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	// A client speaking HTTP/2 without TLS gets HTTP/2, others still HTTP/1.1
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	if proto := get(&http.Client{Transport: &http.Transport{Protocols: &protocols}}); proto != "HTTP/2.0" {
+		t.Errorf("Expected an h2c request to be served over HTTP/2.0, got %s", proto)
+	}
+	if proto := get(http.DefaultClient); proto != "HTTP/1.1" {
+		t.Errorf("Expected a plain request to be served over HTTP/1.1, got %s", proto)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !go1.24
+
+package server
+
+import (
+	"errors"
+	"net/http"
+)
+
+// enableH2C fails: net/http only serves h2c itself from Go 1.24
+func enableH2C(*http.Server) error {
+	return errors.New("h2c needs a server built with Go 1.24 or later")
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WantsJSON reports whether r asked for a JSON response, either with
+// ?format=json or an Accept header listing application/json. An explicit
+// format parameter wins over the header, so ?format=text always gets text.
+func WantsJSON(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "json"
+	}
+
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil || mediaType != "application/json" {
+				continue
+			}
+			// q=0 marks the type as not acceptable
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWantsJSON(t *testing.T) {
+	testCases := []struct {
+		url      string
+		accept   string
+		expected bool
+	}{
+		{"/status", "", false},
+		{"/status", "text/plain", false},
+		{"/status", "*/*", false},
+		{"/status", "application/json", true},
+		{"/status", "text/html, application/json;q=0.9", true},
+		{"/status", "application/json;q=0", false},
+		{"/status", "application/json;q=0.000", false},
+		{"/status?format=json", "", true},
+		{"/status?format=json", "text/plain", true},
+		{"/status?format=text", "application/json", false},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.url, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		if got := WantsJSON(req); got != tc.expected {
+			t.Errorf("WantsJSON(%s, Accept %q) = %v, expected %v", tc.url, tc.accept, got, tc.expected)
+		}
+	}
+}
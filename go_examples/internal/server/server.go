@@ -0,0 +1,72 @@
+// Package server runs the example HTTP servers with graceful shutdown, so
+// in-flight profile captures finish and demo goroutines wind down before the
+// process exits. It also holds small request helpers the servers share.
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long shutdown waits for active requests
+// and drain functions. It is long enough for a default 30 second CPU profile
+// capture to complete.
+const DefaultShutdownTimeout = 35 * time.Second
+
+// ListenAndServe serves handler on addr until ctx is done, then shuts down
+// gracefully as described for Serve
+func ListenAndServe(ctx context.Context, addr string, handler http.Handler, timeout time.Duration, drain ...func(context.Context)) error {
+	return Config{}.ListenAndServe(ctx, addr, handler, timeout, drain...)
+}
+
+// Serve serves handler on ln until ctx is done. It then stops accepting
+// connections, waits for active requests to complete and runs each drain
+// function, all within timeout. It returns nil after a clean shutdown.
+func Serve(ctx context.Context, ln net.Listener, handler http.Handler, timeout time.Duration, drain ...func(context.Context)) error {
+	return Config{}.Serve(ctx, ln, handler, timeout, drain...)
+}
+
+// ListenAndServe is the package-level ListenAndServe with the server tuned by c
+func (c Config) ListenAndServe(ctx context.Context, addr string, handler http.Handler, timeout time.Duration, drain ...func(context.Context)) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return c.Serve(ctx, ln, handler, timeout, drain...)
+}
+
+// Serve is the package-level Serve with the server tuned by c
+func (c Config) Serve(ctx context.Context, ln net.Listener, handler http.Handler, timeout time.Duration, drain ...func(context.Context)) error {
+	srv, err := c.newServer(handler)
+	if err != nil {
+		return err
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		// The server failed before shutdown was requested
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err = srv.Shutdown(shutdownCtx)
+	for _, d := range drain {
+		d(shutdownCtx)
+	}
+
+	if serveErr := <-served; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeGracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+
+	drained := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, ln, mux, 5*time.Second, func(context.Context) { close(drained) })
+	}()
+
+	// Start a request that is still running when shutdown begins
+	type result struct {
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			slow <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		slow <- result{string(body), err}
+	}()
+	<-started
+
+	cancel()
+
+	// New connections are refused once shutdown has started
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("Server still accepting connections after shutdown started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Shutdown waits for the active request
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned before the active request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if r := <-slow; r.err != nil || r.body != "done" {
+		t.Errorf("Expected the active request to complete, got %q, %v", r.body, r.err)
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after shutdown")
+	}
+	select {
+	case <-drained:
+	default:
+		t.Error("Expected the drain function to run")
+	}
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, ln, handler, 100*time.Millisecond)
+	}()
+
+	go http.Get("http://" + ln.Addr().String())
+	<-started
+	cancel()
+
+	// A request outliving the timeout is reported rather than waited on forever
+	select {
+	case err := <-served:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve ignored the shutdown timeout")
+	}
+}
+
+func TestListenAndServeBadAddress(t *testing.T) {
+	if err := ListenAndServe(context.Background(), "256.0.0.1:bad", http.NotFoundHandler(), time.Second); err == nil {
+		t.Error("Expected an error for an invalid address")
+	}
+}
@@ -0,0 +1,56 @@
+// Package testutil holds helpers shared by the example apps' tests.
+package testutil
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"testing"
+	"time"
+)
+
+// How long AssertNoGoroutineLeak waits for goroutines to exit, and how many
+// more than at the start it tolerates once that time is up
+const (
+	leakSettle = 2 * time.Second
+	leakSlack  = 2
+)
+
+// AssertNoGoroutineLeak fails t if, once t and its cleanups have finished,
+// more goroutines are running than when it was called.
+//
+// runtime.NumGoroutine counts the whole process, so the check is inherently
+// racy: goroutines a test stopped may take a moment to exit, and the runtime
+// or an earlier test's stragglers can add a goroutine of their own. The check
+// therefore polls for up to two seconds for the count to settle and allows a
+// slack of two goroutines. Tests calling it must not run in parallel with
+// others, which would start goroutines of their own.
+func AssertNoGoroutineLeak(t testing.TB) {
+	t.Helper()
+	baseline := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		if n, ok := settleGoroutines(baseline+leakSlack, leakSettle); !ok {
+			t.Errorf("Goroutine leak: %d goroutines running after the test, %d before\n%s",
+				n, baseline, goroutineDump())
+		}
+	})
+}
+
+// settleGoroutines polls until at most limit goroutines are running or
+// timeout has passed, returning the last count and whether it was in limit
+func settleGoroutines(limit int, timeout time.Duration) (int, bool) {
+	deadline := time.Now().Add(timeout)
+	n := runtime.NumGoroutine()
+	for n > limit && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		n = runtime.NumGoroutine()
+	}
+	return n, n <= limit
+}
+
+// goroutineDump returns the stacks of every goroutine, grouped by stack
+func goroutineDump() string {
+	var buf bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&buf, 1)
+	return buf.String()
+}
@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingTB captures the errors and cleanups of a test so the helper's
+// failures can be checked without failing the real test
+type recordingTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// finish runs the cleanups like the end of a test would
+func (r *recordingTB) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func TestAssertNoGoroutineLeakClean(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	AssertNoGoroutineLeak(tb)
+
+	// A goroutine that exits shortly after the test body is given time to settle
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(done)
+	}()
+
+	tb.finish()
+	if len(tb.errors) != 0 {
+		t.Errorf("Expected no leak to be reported, got %v", tb.errors)
+	}
+	<-done
+}
+
+func TestAssertNoGoroutineLeakDetects(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	AssertNoGoroutineLeak(tb)
+
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < leakSlack+3; i++ {
+		go func() { <-release }()
+	}
+
+	start := time.Now()
+	tb.finish()
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "Goroutine leak") {
+		t.Fatalf("Expected one leak report, got %v", tb.errors)
+	}
+	if !strings.Contains(tb.errors[0], "TestAssertNoGoroutineLeakDetects") {
+		t.Errorf("Expected the report to include the leaked goroutines' stacks, got %s", tb.errors[0])
+	}
+	if elapsed := time.Since(start); elapsed < leakSettle {
+		t.Errorf("Expected the check to wait %v for goroutines to settle, took %v", leakSettle, elapsed)
+	}
+}
+
+func TestSettleGoroutines(t *testing.T) {
+	if n, ok := settleGoroutines(runtime.NumGoroutine()+10, time.Second); !ok {
+		t.Errorf("Expected %d goroutines to be within the limit", n)
+	}
+	if _, ok := settleGoroutines(0, 20*time.Millisecond); ok {
+		t.Error("Expected a limit of 0 goroutines never to be met")
+	}
+}
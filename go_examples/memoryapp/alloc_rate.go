@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	// Upper bounds for /alloc-rate so a typo can't exhaust the machine
+	maxAllocMBPerSec = 1024
+	maxAllocSeconds  = 300
+
+	// Size of each discarded slice and how often the allocator catches up
+	// with its target
+	allocChunkBytes = 64 * 1024
+	allocTick       = 10 * time.Millisecond
+)
+
+// runAllocRate allocates and immediately discards slices at bytesPerSec for
+// duration, or until ctx is cancelled, and returns the bytes allocated
+func runAllocRate(ctx context.Context, bytesPerSec int64, duration time.Duration) int64 {
+	start := time.Now()
+	deadline := start.Add(duration)
+	target := int64(float64(bytesPerSec) * duration.Seconds())
+
+	ticker := time.NewTicker(allocTick)
+	defer ticker.Stop()
+
+	var total int64
+	for {
+		now := time.Now()
+		due := target
+		if now.Before(deadline) {
+			due = int64(float64(bytesPerSec) * now.Sub(start).Seconds())
+		}
+		for total < due {
+			chunk := make([]byte, min(allocChunkBytes, due-total))
+			chunk[len(chunk)-1] = 1 // Touch the memory so it is really committed
+			total += int64(len(chunk))
+		}
+		if total >= target {
+			return total
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return total
+		}
+	}
+}
+
+// HTTP handler that generates sustained allocation pressure for the allocs
+// profile and reports how much was allocated once the run is over
+func allocRateHandler(w http.ResponseWriter, r *http.Request) {
+	mbPerSec, seconds := 50, 10
+	params := []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"mb_per_sec", &mbPerSec, maxAllocMBPerSec},
+		{"seconds", &seconds, maxAllocSeconds},
+	}
+	for _, p := range params {
+		param := r.URL.Query().Get(p.name)
+		if param == "" {
+			continue
+		}
+		if _, err := fmt.Sscanf(param, "%d", p.value); err != nil || *p.value < 1 || *p.value > p.max {
+			http.Error(w, fmt.Sprintf("Invalid %s parameter (must be between 1 and %d)", p.name, p.max), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Allocate on a separate goroutine; the request context stops it early
+	// if the client goes away
+	start := time.Now()
+	done := make(chan int64)
+	go func() {
+		done <- runAllocRate(r.Context(), int64(mbPerSec)*1024*1024, time.Duration(seconds)*time.Second)
+	}()
+	total := <-done
+	elapsed := time.Since(start)
+
+	// Nothing allocated is still referenced, so hand it back to the OS
+	debug.FreeOSMemory()
+
+	fmt.Fprintf(w, "Allocated %d bytes in %v (target %d MB/s for %ds)\n",
+		total, elapsed.Round(time.Millisecond), mbPerSec, seconds)
+}
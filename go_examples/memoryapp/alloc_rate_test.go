@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllocRateHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/alloc-rate?mb_per_sec=20&seconds=1", nil)
+	recorder := httptest.NewRecorder()
+
+	start := time.Now()
+	allocRateHandler(recorder, req)
+	elapsed := time.Since(start)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if elapsed > 1500*time.Millisecond {
+		t.Errorf("Expected the handler to return shortly after 1s, took %v", elapsed)
+	}
+
+	var total int64
+	if _, err := fmt.Sscanf(recorder.Body.String(), "Allocated %d bytes", &total); err != nil {
+		t.Fatalf("Failed to parse response %q: %v", recorder.Body.String(), err)
+	}
+	target := int64(20 * 1024 * 1024)
+	if total < target*9/10 || total > target*11/10 {
+		t.Errorf("Expected about %d bytes allocated, got %d", target, total)
+	}
+}
+
+func TestAllocRateHandlerInvalidParams(t *testing.T) {
+	for _, url := range []string{
+		"/alloc-rate?mb_per_sec=0",
+		"/alloc-rate?mb_per_sec=5000",
+		"/alloc-rate?seconds=-1",
+		"/alloc-rate?seconds=forever",
+	} {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+		allocRateHandler(recorder, req)
+
+		if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "Invalid") {
+			t.Errorf("Expected 400 for %s, got %d: %s", url, recorder.Code, recorder.Body.String())
+		}
+	}
+}
+
+func TestRunAllocRateCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	total := runAllocRate(ctx, 10*1024*1024, time.Minute)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected cancellation to stop the allocator, took %v", elapsed)
+	}
+	// About 100ms worth of a 10 MB/s rate
+	if total <= 0 || total > 10*1024*1024 {
+		t.Errorf("Expected a partial allocation, got %d bytes", total)
+	}
+}
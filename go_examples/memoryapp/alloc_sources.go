@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+
+	"github.com/google/pprof/profile"
+
+	"pprofviz/examples/internal/analysis"
+)
+
+// Profile label key and values naming the logical caller behind an allocation
+const (
+	sourceLabel   = "source"
+	leakSource    = "leak"
+	requestSource = "request"
+	poolSource    = "pool"
+)
+
+// The function each source's allocations happen under. The runtime records
+// goroutine labels in CPU and goroutine profiles but not in heap or allocs
+// profiles, so labeledAllocs finds the source of an allocs sample from its
+// stack instead.
+var allocSources = []struct {
+	source   string
+	function string
+}{
+	{leakSource, funcName(simulateMemoryLeak)},
+	{requestSource, funcName(memoryHandler)},
+	{poolSource, funcName(NewObjectPool)},
+}
+
+// funcName returns the name profiles record for f. Test binaries name package
+// main's functions by import path rather than main, so it isn't hard-coded.
+func funcName(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// withSource calls f with the source label set to source, so CPU and
+// goroutine samples taken while it allocates carry the label
+func withSource(ctx context.Context, source string, f func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels(sourceLabel, source), f)
+}
+
+// sampleSource returns the source of the innermost frame in s's stack that
+// belongs to one of allocSources, or "" if none does. Closures are matched by
+// their enclosing function, e.g. main.simulateMemoryLeak.func1 under
+// main.simulateMemoryLeak.
+func sampleSource(s *profile.Sample) string {
+	for _, loc := range s.Location {
+		for _, line := range loc.Line {
+			if line.Function == nil {
+				continue
+			}
+			name := line.Function.Name
+			for _, as := range allocSources {
+				if name == as.function || strings.HasPrefix(name, as.function+".") {
+					return as.source
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// labeledAllocs runs a GC, so recent allocations are included, and returns
+// the allocs profile with a source label on every sample allocated under one
+// of allocSources
+func labeledAllocs() (*profile.Profile, error) {
+	runtime.GC()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("allocs").WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	p, err := analysis.ParseProfile(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range p.Sample {
+		if source := sampleSource(s); source != "" {
+			if s.Label == nil {
+				s.Label = make(map[string][]string)
+			}
+			s.Label[sourceLabel] = []string{source}
+		}
+	}
+	return p, nil
+}
+
+// HTTP handler that serves the allocs profile with source labels, for
+// filtering with go tool pprof -tagfocus=source=leak
+func labeledAllocsHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := labeledAllocs()
+	if err != nil {
+		http.Error(w, "Failed to capture allocs profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="allocs-labeled"`)
+	p.Write(w)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestLabeledAllocs(t *testing.T) {
+	id := startLeak(leakEvery(10 * time.Millisecond))
+	defer stopLeak(id, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := waitLeakTicks(ctx, 3); err != nil {
+		t.Fatalf("Leak simulation didn't tick: %v", err)
+	}
+
+	// Several megabytes per request, so the default sampling rate records them
+	for i := 0; i < 3; i++ {
+		memoryHandler(httptest.NewRecorder(), httptest.NewRequest("GET", "/allocate?size=4194304", nil))
+	}
+
+	recorder := httptest.NewRecorder()
+	labeledAllocsHandler(recorder, httptest.NewRequest("GET", "/allocs-labeled", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	p, err := profile.Parse(recorder.Body)
+	if err != nil {
+		t.Fatalf("Failed to parse labeled allocs profile: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range p.Sample {
+		for _, source := range s.Label[sourceLabel] {
+			seen[source] = true
+		}
+	}
+	for _, source := range []string{leakSource, requestSource} {
+		if !seen[source] {
+			t.Errorf("Expected samples labeled %s=%s, got %v", sourceLabel, source, seen)
+		}
+	}
+}
+
+func TestSampleSource(t *testing.T) {
+	// Named like the profile of whichever binary runs the test
+	pkg := strings.TrimSuffix(funcName(memoryHandler), "memoryHandler")
+	stack := func(names ...string) *profile.Sample {
+		s := &profile.Sample{}
+		for _, name := range names {
+			name = strings.Replace(name, "main.", pkg, 1)
+			s.Location = append(s.Location, &profile.Location{
+				Line: []profile.Line{{Function: &profile.Function{Name: name}}},
+			})
+		}
+		return s
+	}
+
+	tests := []struct {
+		sample   *profile.Sample
+		expected string
+	}{
+		{stack("main.createLargeObjectSized", "main.simulateMemoryLeak.func1.1", "runtime/pprof.Do"), leakSource},
+		{stack("main.randomString", "main.memoryHandler.func1", "main.memoryHandler"), requestSource},
+		{stack("main.NewObjectPool.func1", "sync.(*Pool).Get", "main.poolHandler.func1.1"), poolSource},
+		{stack("main.createLargeObjectSized", "main.simulateBoundedCache.func1"), ""},
+		// A prefix of another function's name isn't a match
+		{stack("main.memoryHandlerV2"), ""},
+	}
+	for _, test := range tests {
+		if source := sampleSource(test.sample); source != test.expected {
+			t.Errorf("Expected source %q for %s, got %q",
+				test.expected, test.sample.Location[0].Line[0].Function.Name, source)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// Limits for /allocate unless MAX_ALLOCATE_BYTES or MAX_ALLOCATE_IN_FLIGHT
+// override them, so a mistyped size or a burst of requests can't exhaust
+// memory mid-profile
+const (
+	defaultMaxAllocateBytes    = 256 * 1024 * 1024
+	defaultMaxAllocateInFlight = 4
+)
+
+// allocLimiter caps the size of each /allocate request and how many may
+// allocate at once
+type allocLimiter struct {
+	maxBytes int
+	slots    chan struct{} // One buffered entry per request in flight
+}
+
+// newAllocLimiter creates a limiter allowing requests of up to maxBytes,
+// maxInFlight at a time
+func newAllocLimiter(maxBytes, maxInFlight int) *allocLimiter {
+	return &allocLimiter{
+		maxBytes: maxBytes,
+		slots:    make(chan struct{}, maxInFlight),
+	}
+}
+
+// tryAcquire takes a slot without waiting, reporting false if every slot is
+// in use. A successful call must be followed by release.
+func (l *allocLimiter) tryAcquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees a slot taken by tryAcquire
+func (l *allocLimiter) release() {
+	<-l.slots
+}
+
+// The limiter memoryHandler uses, replaced by main with the configured limits
+var allocateLimits = newAllocLimiter(defaultMaxAllocateBytes, defaultMaxAllocateInFlight)
+
+// envInt reads a positive integer setting from the environment, falling back
+// to def
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		slog.Warn("Ignoring invalid setting", "name", name, "value", value, "default", def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// useAllocLimits swaps in a limiter for the rest of the test
+func useAllocLimits(t *testing.T, limits *allocLimiter) {
+	saved := allocateLimits
+	allocateLimits = limits
+	t.Cleanup(func() { allocateLimits = saved })
+}
+
+func TestAllocateSizeCap(t *testing.T) {
+	useAllocLimits(t, newAllocLimiter(1024, 1))
+
+	tests := []struct {
+		url      string
+		expected int
+	}{
+		{"/allocate?size=1024", http.StatusOK},
+		{"/allocate?size=1025", http.StatusBadRequest},
+		{"/allocate?size=10000000000", http.StatusBadRequest},
+		{"/allocate?size=0", http.StatusBadRequest},
+		{"/allocate?size=-1", http.StatusBadRequest},
+		// The default 1MB is over this limit too
+		{"/allocate", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		memoryHandler(recorder, httptest.NewRequest("GET", test.url, nil))
+		if recorder.Code != test.expected {
+			t.Errorf("Expected status %d for %s, got %d: %s", test.expected, test.url, recorder.Code, recorder.Body.String())
+		}
+		if test.expected == http.StatusBadRequest && !strings.Contains(recorder.Body.String(), "between 1 and 1024") {
+			t.Errorf("Expected the limit in the error for %s, got %q", test.url, recorder.Body.String())
+		}
+	}
+}
+
+func TestAllocateConcurrencyLimit(t *testing.T) {
+	limits := newAllocLimiter(defaultMaxAllocateBytes, 2)
+	useAllocLimits(t, limits)
+
+	// Occupy every slot, as two slow allocations would
+	for i := 0; i < 2; i++ {
+		if !limits.tryAcquire() {
+			t.Fatalf("Expected slot %d to be free", i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			memoryHandler(recorder, httptest.NewRequest("GET", "/allocate?size=1024", nil))
+			codes[i] = recorder.Code
+		}(i)
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusTooManyRequests {
+			t.Errorf("Expected request %d to get status %d, got %d", i, http.StatusTooManyRequests, code)
+		}
+	}
+
+	// A freed slot admits the next request, which gives it back when done
+	limits.release()
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		memoryHandler(recorder, httptest.NewRequest("GET", "/allocate?size=1024", nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status %d with a free slot, got %d", http.StatusOK, recorder.Code)
+		}
+	}
+	if len(limits.slots) != 1 {
+		t.Errorf("Expected only the test's slot in use, got %d", len(limits.slots))
+	}
+}
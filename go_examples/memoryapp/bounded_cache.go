@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How often the bounded cache simulation adds an item
+const boundedInterval = 5 * time.Second
+
+// The running bounded cache simulation's stop function, nil when none runs
+var (
+	boundedMutex sync.Mutex
+	boundedStop  func()
+)
+
+// Errors for starting and stopping the bounded cache simulation
+var (
+	errBoundedRunning    = errors.New("bounded cache simulation already running")
+	errBoundedNotRunning = errors.New("bounded cache simulation not running")
+)
+
+// startBounded starts the bounded cache simulation. Only one runs at a time,
+// since they would share and evict each other's entries.
+func startBounded(interval time.Duration, maxItems int) error {
+	boundedMutex.Lock()
+	defer boundedMutex.Unlock()
+	if boundedStop != nil {
+		return errBoundedRunning
+	}
+	boundedStop = simulateBoundedCache(interval, maxItems)
+	return nil
+}
+
+// stopBounded stops the bounded cache simulation, leaving its entries in the
+// cache, and reports whether one was running
+func stopBounded() bool {
+	boundedMutex.Lock()
+	stop := boundedStop
+	boundedStop = nil
+	boundedMutex.Unlock()
+
+	if stop == nil {
+		return false
+	}
+	stop()
+	return true
+}
+
+// HTTP handler that starts the bounded cache simulation, holding at most
+// ?max=N items (default 100)
+func startBoundedHandler(w http.ResponseWriter, r *http.Request) {
+	maxItems := 100
+	if maxParam := r.URL.Query().Get("max"); maxParam != "" {
+		if _, err := fmt.Sscanf(maxParam, "%d", &maxItems); err != nil || maxItems < 1 {
+			http.Error(w, "Invalid max parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := startBounded(boundedInterval, maxItems); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	fmt.Fprintf(w, "Started bounded cache simulation (adding items every %v, max %d items)\n", boundedInterval, maxItems)
+}
+
+// HTTP handler that stops the bounded cache simulation
+func stopBoundedHandler(w http.ResponseWriter, r *http.Request) {
+	if !stopBounded() {
+		http.Error(w, errBoundedNotRunning.Error(), http.StatusConflict)
+		return
+	}
+	fmt.Fprintln(w, "Stopped bounded cache simulation")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pprofviz/examples/internal/testutil"
+)
+
+func TestBoundedHandlers(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+	defer stopBounded()
+
+	steps := []struct {
+		name           string
+		handler        http.HandlerFunc
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Stop before start", stopBoundedHandler, "/stop-bounded", http.StatusConflict, "not running"},
+		{"Invalid max", startBoundedHandler, "/start-bounded?max=0", http.StatusBadRequest, "Invalid max"},
+		{"Start", startBoundedHandler, "/start-bounded?max=5", http.StatusOK, "max 5 items"},
+		{"Start again", startBoundedHandler, "/start-bounded", http.StatusConflict, "already running"},
+		{"Stop", stopBoundedHandler, "/stop-bounded", http.StatusOK, "Stopped"},
+		{"Double stop", stopBoundedHandler, "/stop-bounded", http.StatusConflict, "not running"},
+		{"Restart", startBoundedHandler, "/start-bounded", http.StatusOK, "max 100 items"},
+	}
+	for _, step := range steps {
+		recorder := httptest.NewRecorder()
+		step.handler(recorder, httptest.NewRequest("POST", step.url, nil))
+
+		if recorder.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", step.name, step.expectedStatus, recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), step.expectedBody) {
+			t.Errorf("%s: expected body to contain %q, got: %s", step.name, step.expectedBody, recorder.Body.String())
+		}
+	}
+
+	// Stopping on shutdown leaves nothing running
+	if !stopBounded() {
+		t.Error("Expected the restarted simulation to be running")
+	}
+	if stopBounded() {
+		t.Error("Expected no simulation running after stopping")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+)
+
+// cacheClearing is the /clear-cache response. AllocAfter is read after the
+// forced GC when there was one, so BytesReclaimed is what the GC freed;
+// without one the cleared entries are usually still counted.
+type cacheClearing struct {
+	ItemsRemoved   int    `json:"itemsRemoved"`
+	GC             bool   `json:"gc"`
+	AllocBefore    uint64 `json:"allocBefore"`
+	AllocAfter     uint64 `json:"allocAfter"`
+	BytesReclaimed int64  `json:"bytesReclaimed"` // AllocBefore - AllocAfter, negative if the heap grew
+}
+
+// clearCache empties globalCache, whoever added the entries, and returns how
+// many there were
+func clearCache() int {
+	return clearCacheMeasured(false).ItemsRemoved
+}
+
+// clearCacheMeasured empties globalCache, optionally forces a GC, and
+// reports the heap before and after
+func clearCacheMeasured(gc bool) cacheClearing {
+	var m runtime.MemStats
+
+	cacheMutex.Lock()
+	runtime.ReadMemStats(&m)
+	result := cacheClearing{ItemsRemoved: len(globalCache), GC: gc, AllocBefore: m.Alloc}
+	globalCache = make(map[string]*LargeObject)
+	cacheOrder = nil
+	cacheMutex.Unlock()
+
+	if gc {
+		runtime.GC()
+	}
+	runtime.ReadMemStats(&m)
+	result.AllocAfter = m.Alloc
+	result.BytesReclaimed = int64(result.AllocBefore) - int64(result.AllocAfter)
+	return result
+}
+
+// HTTP handler that empties the cache, forcing a GC with ?gc=true, and
+// returns the items removed and the heap before and after as JSON
+func clearCacheHandler(w http.ResponseWriter, r *http.Request) {
+	gc := r.URL.Query().Get("gc") == "true"
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clearCacheMeasured(gc))
+}
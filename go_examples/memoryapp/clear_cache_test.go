@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClearCacheHandler(t *testing.T) {
+	// Retain 16MB so the GC's effect stands out from the test's own garbage
+	cacheMutex.Lock()
+	for i := 0; i < 16; i++ {
+		globalCache[fmt.Sprintf("clear-cache-test-%d", i)] = createLargeObjectSized(i, 0, 1024*1024, 1)
+	}
+	items := len(globalCache)
+	cacheMutex.Unlock()
+
+	recorder := httptest.NewRecorder()
+	clearCacheHandler(recorder, httptest.NewRequest("POST", "/clear-cache?gc=true", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+	var result cacheClearing
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if result.ItemsRemoved != items || !result.GC {
+		t.Errorf("Expected %d items removed with a GC, got %+v", items, result)
+	}
+	if result.AllocAfter >= result.AllocBefore || result.BytesReclaimed < 8*1024*1024 {
+		t.Errorf("Expected the GC to reclaim most of the 16MB cleared, got %+v", result)
+	}
+
+	cacheMutex.RLock()
+	remaining := len(globalCache)
+	cacheMutex.RUnlock()
+	if remaining != 0 {
+		t.Errorf("Expected an empty cache, got %d items", remaining)
+	}
+
+	// Without a GC nothing is freed yet, but the cache is still emptied
+	recorder = httptest.NewRecorder()
+	clearCacheHandler(recorder, httptest.NewRequest("POST", "/clear-cache", nil))
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if result.ItemsRemoved != 0 || result.GC {
+		t.Errorf("Expected nothing left to remove and no GC, got %+v", result)
+	}
+}
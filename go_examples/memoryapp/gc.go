@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// gcReport is the /gc response. The cache survives the collection, so
+// AllocAfter is roughly what it retains and BytesReclaimed the garbage the
+// requests and simulations left behind.
+type gcReport struct {
+	AllocBefore    uint64 `json:"allocBefore"`
+	AllocAfter     uint64 `json:"allocAfter"`
+	BytesReclaimed int64  `json:"bytesReclaimed"` // AllocBefore - AllocAfter, negative if the heap grew meanwhile
+	CacheItems     int    `json:"cacheItems"`
+	NumGC          uint32 `json:"numGC"`
+}
+
+// collectGarbage forces a GC, returning as much memory to the OS as it can,
+// and reports the heap before and after
+func collectGarbage() gcReport {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	report := gcReport{AllocBefore: m.Alloc}
+
+	// FreeOSMemory runs a GC of its own before releasing memory
+	runtime.GC()
+	debug.FreeOSMemory()
+
+	runtime.ReadMemStats(&m)
+	report.AllocAfter = m.Alloc
+	report.BytesReclaimed = int64(report.AllocBefore) - int64(report.AllocAfter)
+	report.NumGC = m.NumGC
+
+	cacheMutex.RLock()
+	report.CacheItems = len(globalCache)
+	cacheMutex.RUnlock()
+	return report
+}
+
+// HTTP handler that forces a GC and returns the memory it reclaimed as JSON
+func gcHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collectGarbage())
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"runtime/debug"
+	"testing"
+)
+
+// Keeps the test's garbage from being optimized away
+var gcTestSink [][]byte
+
+func TestGCHandler(t *testing.T) {
+	// A cached object survives the collection
+	cacheMutex.Lock()
+	globalCache["gc-test"] = createLargeObjectSized(1, 0, 1024, 1)
+	cacheMutex.Unlock()
+	defer func() {
+		cacheMutex.Lock()
+		delete(globalCache, "gc-test")
+		cacheMutex.Unlock()
+	}()
+
+	// Hold off automatic collections so the garbage is still there for /gc
+	defer debug.SetGCPercent(debug.SetGCPercent(-1))
+	for i := 0; i < 32; i++ {
+		gcTestSink = append(gcTestSink, make([]byte, 1024*1024))
+	}
+	gcTestSink = nil
+
+	recorder := httptest.NewRecorder()
+	gcHandler(recorder, httptest.NewRequest("POST", "/gc", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+	var report gcReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if report.AllocAfter >= report.AllocBefore || report.BytesReclaimed < 16*1024*1024 {
+		t.Errorf("Expected the GC to reclaim most of 32MB of garbage, got %+v", report)
+	}
+	if report.CacheItems == 0 {
+		t.Errorf("Expected the cache to survive the GC, got %+v", report)
+	}
+	if report.NumGC == 0 {
+		t.Errorf("Expected a GC to have run, got %+v", report)
+	}
+}
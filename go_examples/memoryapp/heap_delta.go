@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/google/pprof/profile"
+
+	"pprofviz/examples/internal/analysis"
+)
+
+// Upper bounds for /heap-delta so a typo can't hold a request for hours
+const (
+	maxHeapDeltaTicks = 60
+	maxHeapDeltaTop   = 100
+)
+
+// Closed and replaced each time the leak simulation adds an object, so any
+// number of waiters can see the tick
+var (
+	leakTickMutex sync.Mutex
+	leakTick      = make(chan struct{})
+)
+
+// notifyLeakTick wakes everyone waiting for the next leak tick
+func notifyLeakTick() {
+	leakTickMutex.Lock()
+	defer leakTickMutex.Unlock()
+	close(leakTick)
+	leakTick = make(chan struct{})
+}
+
+// waitLeakTicks waits until the leak simulation has added n objects, or ctx is done
+func waitLeakTicks(ctx context.Context, n int) error {
+	for i := 0; i < n; i++ {
+		leakTickMutex.Lock()
+		tick := leakTick
+		leakTickMutex.Unlock()
+
+		select {
+		case <-tick:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// captureHeap runs a GC, so inuse reflects live objects, and returns the
+// parsed heap profile
+func captureHeap() (*profile.Profile, error) {
+	runtime.GC()
+	var buf bytes.Buffer
+	if err := pprof.WriteHeapProfile(&buf); err != nil {
+		return nil, err
+	}
+	return analysis.ParseProfile(&buf)
+}
+
+// heapDelta is the /heap-delta response: the allocation sites whose in-use
+// memory grew the most over the leak ticks
+type heapDelta struct {
+	Ticks   int                    `json:"ticks"`
+	Growing []analysis.SampleDelta `json:"growing"` // In bytes, largest growth first
+}
+
+// HTTP handler that captures a heap profile, waits for ?ticks=N ticks of the
+// running leak simulation, captures another and returns the ?top=N sites
+// whose in-use bytes grew the most
+func heapDeltaHandler(w http.ResponseWriter, r *http.Request) {
+	ticks, top := 3, 10
+	params := []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"ticks", &ticks, maxHeapDeltaTicks},
+		{"top", &top, maxHeapDeltaTop},
+	}
+	for _, p := range params {
+		param := r.URL.Query().Get(p.name)
+		if param == "" {
+			continue
+		}
+		if _, err := fmt.Sscanf(param, "%d", p.value); err != nil || *p.value < 1 || *p.value > p.max {
+			http.Error(w, fmt.Sprintf("Invalid %s parameter (must be between 1 and %d)", p.name, p.max), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !leakRunning() {
+		http.Error(w, "Memory leak simulation not running; start it with /start-leak", http.StatusConflict)
+		return
+	}
+
+	before, err := captureHeap()
+	if err != nil {
+		http.Error(w, "Failed to capture heap profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := waitLeakTicks(r.Context(), ticks); err != nil {
+		return
+	}
+	after, err := captureHeap()
+	if err != nil {
+		http.Error(w, "Failed to capture heap profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	deltas, err := analysis.DiffProfilesBy(before, after, "inuse_space")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := heapDelta{Ticks: ticks, Growing: []analysis.SampleDelta{}}
+	for _, d := range deltas {
+		if d.Delta > 0 && len(result.Growing) < top {
+			result.Growing = append(result.Growing, d)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeapDeltaHandler(t *testing.T) {
+	id := startLeak(leakEvery(20 * time.Millisecond))
+	defer stopLeak(id, true)
+
+	recorder := httptest.NewRecorder()
+	heapDeltaHandler(recorder, httptest.NewRequest("GET", "/heap-delta?ticks=3&top=5", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var delta heapDelta
+	if err := json.Unmarshal(recorder.Body.Bytes(), &delta); err != nil {
+		t.Fatalf("Failed to decode heap delta: %v", err)
+	}
+	if delta.Ticks != 3 || len(delta.Growing) == 0 || len(delta.Growing) > 5 {
+		t.Fatalf("Unexpected heap delta: %+v", delta)
+	}
+
+	// Each tick retains a tree of 1MB payloads allocated by createLargeObject
+	found := false
+	for _, site := range delta.Growing {
+		if site.Delta <= 0 {
+			t.Errorf("Expected only growing sites, got %+v", site)
+		}
+		found = found || strings.Contains(site.Function, "createLargeObject")
+	}
+	if !found {
+		t.Errorf("Expected createLargeObject among the growing sites, got %+v", delta.Growing)
+	}
+}
+
+func TestHeapDeltaHandlerErrors(t *testing.T) {
+	// Nothing ticks without the leak simulation
+	recorder := httptest.NewRecorder()
+	heapDeltaHandler(recorder, httptest.NewRequest("GET", "/heap-delta", nil))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status %d without a running leak, got %d", http.StatusConflict, recorder.Code)
+	}
+
+	for _, url := range []string{"/heap-delta?ticks=0", "/heap-delta?ticks=1000", "/heap-delta?top=many"} {
+		recorder := httptest.NewRecorder()
+		heapDeltaHandler(recorder, httptest.NewRequest("GET", url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, url, recorder.Code)
+		}
+	}
+}
+
+func TestWaitLeakTicks(t *testing.T) {
+	go func() {
+		for i := 0; i < 2; i++ {
+			time.Sleep(10 * time.Millisecond)
+			notifyLeakTick()
+		}
+	}()
+	if err := waitLeakTicks(context.Background(), 2); err != nil {
+		t.Errorf("Expected to see 2 ticks, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := waitLeakTicks(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("Expected the wait to time out, got %v", err)
+	}
+}
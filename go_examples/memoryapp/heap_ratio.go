@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+
+	"pprofviz/examples/internal/analysis"
+)
+
+// ratioFrame is a flame graph frame annotated for rendering
+type ratioFrame struct {
+	Name     string
+	Bytes    int64
+	Ratio    float64
+	InUse    float64 // Ratio as a percentage
+	Width    float64 // Percentage of the parent frame's width
+	Color    template.CSS
+	Children []*ratioFrame
+}
+
+// Flame graph of allocated bytes, each frame colored by how much of its
+// allocation is still in use: red frames retain memory, blue frames churn
+var heapRatioTemplate = template.Must(template.New("heap-ratio").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Heap retention flame graph</title>
+<style>
+body { font-family: sans-serif; margin: 16px; }
+.frame { box-sizing: border-box; overflow: hidden; }
+.label { font-size: 11px; white-space: nowrap; padding: 2px; border: 1px solid #fff; color: #fff; }
+.children { display: flex; }
+.legend span { padding: 2px 8px; color: #fff; }
+</style>
+</head>
+<body>
+<h1>Heap retention flame graph</h1>
+<p class="legend">Frame width is allocated bytes (alloc_space), color is inuse_space / alloc_space:
+<span style="background: hsl(0, 70%, 45%)">retained</span>
+<span style="background: hsl(220, 70%, 45%)">churned</span></p>
+{{template "frame" .}}
+</body>
+</html>
+{{define "frame"}}<div class="frame" style="width: {{printf "%.4f" .Width}}%">
+<div class="label" style="background: {{.Color}}" title="{{.Name}}: {{.Bytes}} bytes allocated, {{printf "%.0f" .InUse}}% in use">{{.Name}}</div>
+{{if .Children}}<div class="children">{{range .Children}}{{template "frame" .}}{{end}}</div>{{end}}
+</div>{{end}}`))
+
+// newRatioFrame converts a node of the heap's ratio tree into a renderable
+// frame sized relative to its parent
+func newRatioFrame(node *analysis.RatioNode, parentBytes int64) *ratioFrame {
+	frame := &ratioFrame{
+		Name:  node.Name,
+		Bytes: node.Value,
+		Ratio: node.Ratio,
+		InUse: node.Ratio * 100,
+		Width: 100,
+		// Interpolate hue from blue (churned) to red (retained)
+		Color: template.CSS(fmt.Sprintf("hsl(%.0f, 70%%, 45%%)", 220*(1-node.Ratio))),
+	}
+	if parentBytes > 0 {
+		frame.Width = 100 * float64(node.Value) / float64(parentBytes)
+	}
+	for _, child := range node.Children {
+		frame.Children = append(frame.Children, newRatioFrame(child, node.Value))
+	}
+	return frame
+}
+
+// HTTP handler that renders the current heap profile as a flame graph
+// colored by the inuse/alloc ratio of each function
+func heapRatioHandler(w http.ResponseWriter, r *http.Request) {
+	// Run a GC so inuse reflects live objects, as /debug/pprof/heap?gc=1 does
+	runtime.GC()
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		http.Error(w, "Failed to capture heap profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p, err := analysis.ParseProfile(&buf)
+	if err != nil {
+		http.Error(w, "Failed to parse heap profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tree, err := analysis.RatioTree(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := heapRatioTemplate.Execute(w, newRatioFrame(tree, 0)); err != nil {
+		slog.Error("Failed to render heap ratio view", "err", err)
+	}
+}
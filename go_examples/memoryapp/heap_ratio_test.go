@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHeapRatioHandler(t *testing.T) {
+	// Retain a large object so createLargeObject shows up as live memory
+	cacheMutex.Lock()
+	globalCache["heap-ratio-test"] = createLargeObject(1, 1)
+	cacheMutex.Unlock()
+	defer func() {
+		cacheMutex.Lock()
+		delete(globalCache, "heap-ratio-test")
+		cacheMutex.Unlock()
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/heap-ratio", heapRatioHandler)
+
+	req := httptest.NewRequest("GET", "/heap-ratio", nil)
+	recorder := httptest.NewRecorder()
+
+	mux.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, "Heap retention flame graph") {
+		t.Errorf("Expected rendered flame graph page, got: %s", body)
+	}
+	if !strings.Contains(body, "createLargeObject") {
+		t.Error("Expected createLargeObject frame in heap retention view")
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// leakConfig shapes a leak simulation: every Interval it retains a tree of
+// objects up to Depth levels deep, each with a Size-byte payload
+type leakConfig struct {
+	Interval time.Duration
+	Size     int
+	Depth    int
+}
+
+// Children per object in leaked trees
+const leakFanout = 3
+
+// Bounds for /start-leak's parameters. A full tree at the largest size and
+// depth is far over maxTreeBytes, which bounds each tick as it does
+// /allocate-tree.
+const (
+	minLeakInterval = 10 * time.Millisecond
+	maxLeakInterval = time.Hour
+	maxLeakSize     = 64 * 1024 * 1024
+	maxLeakDepth    = 5
+)
+
+// The simulation /start-leak runs without parameters: a tree of 1MB payloads
+// two levels deep every 5 seconds
+var defaultLeakConfig = leakConfig{Interval: 5 * time.Second, Size: 1024 * 1024, Depth: 2}
+
+// leakSimulation is the handle of a running leak simulation. It remembers the
+// cache keys the simulation added so they can be counted and purged later.
+type leakSimulation struct {
+	ID        int
+	Config    leakConfig
+	StartedAt time.Time
+
+	cancel   func()
+	stopOnce sync.Once
+
+	keysMutex sync.Mutex
+	keys      []string
+}
+
+// leakInfo describes a running leak simulation in /leaks
+type leakInfo struct {
+	ID         int       `json:"id"`
+	IntervalMs int64     `json:"intervalMs"`
+	SizeKb     int       `json:"sizeKb"`
+	Depth      int       `json:"depth"`
+	StartedAt  time.Time `json:"startedAt"`
+	Items      int       `json:"items"` // Entries it added that are still in the cache
+	Bytes      int       `json:"bytes"` // Payload bytes those entries retain
+}
+
+// Source of leak simulation IDs, starting from 1
+var leakIDs atomic.Int64
+
+func newLeakSimulation(cfg leakConfig) *leakSimulation {
+	return &leakSimulation{
+		ID:        int(leakIDs.Add(1)),
+		Config:    cfg,
+		StartedAt: time.Now(),
+	}
+}
+
+// stop halts the simulation and waits for its goroutine to exit. The entries
+// it added stay in the cache.
+func (l *leakSimulation) stop() {
+	l.stopOnce.Do(l.cancel)
+}
+
+// added records a key the simulation put in the cache
+func (l *leakSimulation) added(key string) {
+	l.keysMutex.Lock()
+	defer l.keysMutex.Unlock()
+	l.keys = append(l.keys, key)
+}
+
+// retained counts the simulation's entries still in the cache and the payload
+// bytes they hold. Entries cleared from the cache by other means don't count.
+func (l *leakSimulation) retained() (items, bytes int) {
+	l.keysMutex.Lock()
+	defer l.keysMutex.Unlock()
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+
+	for _, key := range l.keys {
+		if obj, ok := globalCache[key]; ok {
+			_, size := treeSize(obj)
+			items++
+			bytes += size
+		}
+	}
+	return items, bytes
+}
+
+// purge removes the simulation's entries from the cache and returns how many
+// were still there
+func (l *leakSimulation) purge() int {
+	l.keysMutex.Lock()
+	defer l.keysMutex.Unlock()
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	purged := 0
+	for _, key := range l.keys {
+		if _, ok := globalCache[key]; ok {
+			delete(globalCache, key)
+			purged++
+		}
+	}
+	l.keys = nil
+	return purged
+}
+
+// Running leak simulations by ID
+var (
+	leakMutex   sync.Mutex
+	activeLeaks = make(map[int]*leakSimulation)
+)
+
+// Errors for stopping leak simulations that aren't running
+var (
+	errLeakNotRunning = errors.New("memory leak simulation not running")
+	errLeakNotFound   = errors.New("no such memory leak simulation running")
+)
+
+// startLeak starts a leak simulation and returns its ID. Any number can run at
+// once, each with its own configuration.
+func startLeak(cfg leakConfig) int {
+	leak := simulateMemoryLeak(cfg)
+
+	leakMutex.Lock()
+	defer leakMutex.Unlock()
+	activeLeaks[leak.ID] = leak
+	return leak.ID
+}
+
+// stopLeak stops the leak simulation with the given ID, optionally purging
+// the entries it added, and returns how many entries were purged
+func stopLeak(id int, purge bool) (int, error) {
+	leakMutex.Lock()
+	leak, ok := activeLeaks[id]
+	delete(activeLeaks, id)
+	leakMutex.Unlock()
+	if !ok {
+		return 0, errLeakNotFound
+	}
+
+	leak.stop()
+	if !purge {
+		return 0, nil
+	}
+	return leak.purge(), nil
+}
+
+// stopAllLeaks stops every running leak simulation, optionally purging the
+// entries they added, and returns how many were stopped and entries purged
+func stopAllLeaks(purge bool) (stopped, purged int) {
+	leakMutex.Lock()
+	leaks := activeLeaks
+	activeLeaks = make(map[int]*leakSimulation)
+	leakMutex.Unlock()
+
+	for _, leak := range leaks {
+		leak.stop()
+		if purge {
+			purged += leak.purge()
+		}
+	}
+	return len(leaks), purged
+}
+
+// leakRunning reports whether any leak simulation is running
+func leakRunning() bool {
+	leakMutex.Lock()
+	defer leakMutex.Unlock()
+	return len(activeLeaks) > 0
+}
+
+// listLeaks describes the running leak simulations, oldest first
+func listLeaks() []leakInfo {
+	leakMutex.Lock()
+	leaks := make([]*leakSimulation, 0, len(activeLeaks))
+	for _, leak := range activeLeaks {
+		leaks = append(leaks, leak)
+	}
+	leakMutex.Unlock()
+
+	infos := make([]leakInfo, 0, len(leaks))
+	for _, leak := range leaks {
+		items, bytes := leak.retained()
+		infos = append(infos, leakInfo{
+			ID:         leak.ID,
+			IntervalMs: leak.Config.Interval.Milliseconds(),
+			SizeKb:     leak.Config.Size / 1024,
+			Depth:      leak.Config.Depth,
+			StartedAt:  leak.StartedAt,
+			Items:      items,
+			Bytes:      bytes,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// HTTP handler that starts another memory leak simulation, shaped by the
+// intervalMs, sizeKb and depth parameters
+func startLeakHandler(w http.ResponseWriter, r *http.Request) {
+	intervalMs := int(defaultLeakConfig.Interval / time.Millisecond)
+	sizeKb, depth := defaultLeakConfig.Size/1024, defaultLeakConfig.Depth
+	params := []struct {
+		name  string
+		value *int
+		min   int
+		max   int
+	}{
+		{"intervalMs", &intervalMs, int(minLeakInterval / time.Millisecond), int(maxLeakInterval / time.Millisecond)},
+		{"sizeKb", &sizeKb, 1, maxLeakSize / 1024},
+		{"depth", &depth, 0, maxLeakDepth},
+	}
+	for _, p := range params {
+		param := r.URL.Query().Get(p.name)
+		if param == "" {
+			continue
+		}
+		if _, err := fmt.Sscanf(param, "%d", p.value); err != nil || *p.value < p.min || *p.value > p.max {
+			http.Error(w, fmt.Sprintf("Invalid %s parameter (must be between %d and %d)", p.name, p.min, p.max), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cfg := leakConfig{Interval: time.Duration(intervalMs) * time.Millisecond, Size: sizeKb * 1024, Depth: depth}
+	if perTick := cfg.Size * maxTreeNodes(cfg.Depth, leakFanout); perTick > maxTreeBytes {
+		http.Error(w, fmt.Sprintf("Each tick could allocate up to %d bytes, over the %d byte limit", perTick, maxTreeBytes), http.StatusBadRequest)
+		return
+	}
+
+	id := startLeak(cfg)
+	fmt.Fprintf(w, "Started memory leak simulation %d (adding a tree of %dKB objects of depth %d every %v)\n",
+		id, sizeKb, depth, cfg.Interval)
+}
+
+// HTTP handler that stops the leak simulation given by ?id=N, or every one
+// without it. ?purge=true removes the entries the stopped simulations added
+// and ?clear=true empties the whole cache.
+func stopLeakHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	purge := query.Get("purge") == "true"
+	clearAll := query.Get("clear") == "true"
+
+	var purged int
+	if idParam := query.Get("id"); idParam != "" {
+		id, err := strconv.Atoi(idParam)
+		if err != nil || id < 1 {
+			http.Error(w, "Invalid id parameter (must be a positive integer)", http.StatusBadRequest)
+			return
+		}
+		if purged, err = stopLeak(id, purge); err != nil {
+			http.Error(w, fmt.Sprintf("%v: %d", err, id), http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, "Stopped memory leak simulation %d\n", id)
+	} else {
+		var stopped int
+		stopped, purged = stopAllLeaks(purge)
+		if stopped == 0 {
+			http.Error(w, errLeakNotRunning.Error(), http.StatusConflict)
+			return
+		}
+		fmt.Fprintf(w, "Stopped %d memory leak simulations\n", stopped)
+	}
+
+	if purge {
+		fmt.Fprintf(w, "Purged %d cache items\n", purged)
+	}
+	if clearAll {
+		fmt.Fprintf(w, "Cleared %d cache items\n", clearCache())
+	}
+}
+
+// HTTP handler that lists the running leak simulations as JSON
+func leaksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listLeaks())
+}
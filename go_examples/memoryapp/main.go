@@ -1,14 +1,27 @@
 package main
 
 import (
+        "context"
+        "flag"
         "fmt"
+        "log/slog"
         "math/rand"
         "net/http"
         "net/http/pprof"
+        "os"
+        "os/signal"
         "runtime"
         "strings"
         "sync"
+        "sync/atomic"
+        "syscall"
         "time"
+
+        "pprofviz/examples/internal/logging"
+        "pprofviz/examples/internal/profrate"
+        "pprofviz/examples/internal/randseed"
+        "pprofviz/examples/internal/selfprofile"
+        "pprofviz/examples/internal/server"
 )
 
 // A memory-intensive application that demonstrates different memory allocation patterns
@@ -26,10 +39,22 @@ type LargeObject struct {
 var globalCache = make(map[string]*LargeObject)
 var cacheMutex sync.RWMutex
 
-// Create a large object with nested children
+// Keys added by the bounded cache, oldest first, so eviction is deterministic
+var cacheOrder []string
+
+// Source of bounded cache key numbers, so a restarted simulation doesn't
+// overwrite the entries of the previous one
+var boundedKeys atomic.Int64
+
+// Create a large object with nested children, using a 1MB payload and up to 3 children per node
 func createLargeObject(id int, depth int) *LargeObject {
+        return createLargeObjectSized(id, depth, 1024*1024, 3)
+}
+
+// Create a large object with a size-byte payload and 1 to maxChildren children per node
+func createLargeObjectSized(id int, depth int, size int, maxChildren int) *LargeObject {
         // Create random data payload
-        data := make([]byte, 1024*1024) // 1MB of data
+        data := make([]byte, size)
         rand.Read(data)
 
         obj := &LargeObject{
@@ -41,10 +66,10 @@ func createLargeObject(id int, depth int) *LargeObject {
 
         // Create child objects (but limit depth to prevent stack overflow)
         if depth > 0 {
-                numChildren := rand.Intn(3) + 1
+                numChildren := rand.Intn(maxChildren) + 1
                 for i := 0; i < numChildren; i++ {
                         childID := id*10 + i
-                        child := createLargeObject(childID, depth-1)
+                        child := createLargeObjectSized(childID, depth-1, size, maxChildren)
                         obj.Children = append(obj.Children, child)
                 }
         }
@@ -52,33 +77,172 @@ func createLargeObject(id int, depth int) *LargeObject {
         return obj
 }
 
-// Simulate a memory leak by never cleaning up objects
-func simulateMemoryLeak(interval time.Duration) {
-        ticker := time.NewTicker(interval)
+// Count the objects in a tree and the bytes in their payloads
+func treeSize(obj *LargeObject) (nodes int, bytes int) {
+        nodes, bytes = 1, len(obj.Data)
+        for _, child := range obj.Children {
+                childNodes, childBytes := treeSize(child)
+                nodes += childNodes
+                bytes += childBytes
+        }
+        return nodes, bytes
+}
+
+// Count the objects in a full tree of the given depth and fanout, the most
+// createLargeObjectSized can create
+func maxTreeNodes(depth int, fanout int) int {
+        nodes, level := 0, 1
+        for i := 0; i <= depth; i++ {
+                nodes += level
+                level *= fanout
+        }
+        return nodes
+}
+
+// Limits for /allocate-tree so a request can't recurse or allocate without bound
+const (
+        maxTreeDepth  = 8
+        maxTreeFanout = 10
+        maxTreeBytes  = 512 * 1024 * 1024
+)
+
+// HTTP handler that allocates a tree of large objects with a configurable shape
+func allocateTreeHandler(w http.ResponseWriter, r *http.Request) {
+        size, depth, fanout := 1024*1024, 2, 3
+        params := []struct {
+                name  string
+                value *int
+                min   int
+                max   int
+        }{
+                {"size", &size, 1, maxTreeBytes},
+                {"depth", &depth, 0, maxTreeDepth},
+                {"fanout", &fanout, 1, maxTreeFanout},
+        }
+        for _, p := range params {
+                param := r.URL.Query().Get(p.name)
+                if param == "" {
+                        continue
+                }
+                if _, err := fmt.Sscanf(param, "%d", p.value); err != nil || *p.value < p.min || *p.value > p.max {
+                        http.Error(w, fmt.Sprintf("Invalid %s parameter (must be between %d and %d)", p.name, p.min, p.max), http.StatusBadRequest)
+                        return
+                }
+        }
+
+        // Children are random, so bound the worst case: a full tree of fanout^depth leaves
+        maxNodes := maxTreeNodes(depth, fanout)
+        if size*maxNodes > maxTreeBytes {
+                http.Error(w, fmt.Sprintf("Tree could allocate up to %d bytes, over the %d byte limit", size*maxNodes, maxTreeBytes), http.StatusBadRequest)
+                return
+        }
+
+        obj := createLargeObjectSized(1, depth, size, fanout)
+        nodes, bytes := treeSize(obj)
+
+        fmt.Fprintf(w, "Allocated tree: %d objects, %d bytes\n", nodes, bytes)
+        fmt.Fprintf(w, "Size: %d bytes, depth: %d, fanout: up to %d\n", size, depth, fanout)
+}
+
+// Simulate a memory leak by never cleaning up objects, adding a tree shaped by
+// cfg every cfg.Interval. The returned handle stops the simulation and tracks
+// the cache entries it adds.
+func simulateMemoryLeak(cfg leakConfig) *leakSimulation {
+        leak := newLeakSimulation(cfg)
+        ticker := time.NewTicker(cfg.Interval)
+        done := make(chan struct{})
+        exited := make(chan struct{})
         go func() {
+                defer close(exited)
                 var counter int
-                for range ticker.C {
+                for {
+                        select {
+                        case <-ticker.C:
+                        case <-done:
+                                return
+                        }
                         counter++
-                        key := fmt.Sprintf("leak-%d", counter)
-                        obj := createLargeObject(counter, 2)
+                        key := fmt.Sprintf("leak-%d-%d", leak.ID, counter)
+                        var obj *LargeObject
+                        withSource(context.Background(), leakSource, func(context.Context) {
+                                obj = createLargeObjectSized(counter, cfg.Depth, cfg.Size, leakFanout)
+                        })
 
                         cacheMutex.Lock()
                         globalCache[key] = obj
+                        cacheSize := len(globalCache)
                         cacheMutex.Unlock()
-
-                        // Print current cache size
-                        fmt.Printf("Cache size: %d items\n", len(globalCache))
-
-                        // Print memory stats
+                        leak.added(key)
+                        notifyLeakTick()
+
+                        // Log the cache size and memory stats. ReadMemStats stops
+                        // the world, so skip it when nothing would be logged.
+                        if !slog.Default().Enabled(context.Background(), slog.LevelInfo) {
+                                continue
+                        }
                         var m runtime.MemStats
                         runtime.ReadMemStats(&m)
-                        fmt.Printf("Alloc: %v MiB, TotalAlloc: %v MiB, Sys: %v MiB, NumGC: %v\n",
-                                m.Alloc/1024/1024,
-                                m.TotalAlloc/1024/1024,
-                                m.Sys/1024/1024,
-                                m.NumGC)
+                        slog.Info("Leak tick",
+                                "leak", leak.ID,
+                                "cacheSize", cacheSize,
+                                "allocMiB", m.Alloc/1024/1024,
+                                "totalAllocMiB", m.TotalAlloc/1024/1024,
+                                "sysMiB", m.Sys/1024/1024,
+                                "numGC", m.NumGC)
                 }
         }()
+
+        leak.cancel = func() {
+                ticker.Stop()
+                close(done)
+                <-exited
+        }
+        return leak
+}
+
+// Simulate a bounded cache that evicts its oldest entries once it has added
+// more than maxItems to globalCache, for comparison with the unbounded leak.
+// Entries added by anything else neither count nor get evicted. The returned
+// function stops the simulation.
+func simulateBoundedCache(interval time.Duration, maxItems int) (stop func()) {
+        ticker := time.NewTicker(interval)
+        done := make(chan struct{})
+        exited := make(chan struct{})
+        go func() {
+                defer close(exited)
+                for {
+                        select {
+                        case <-ticker.C:
+                        case <-done:
+                                return
+                        }
+                        n := int(boundedKeys.Add(1))
+                        key := fmt.Sprintf("bounded-%d", n)
+                        obj := createLargeObject(n, 2)
+
+                        cacheMutex.Lock()
+                        globalCache[key] = obj
+                        cacheOrder = append(cacheOrder, key)
+
+                        // Evict oldest first; only keys added here are evictable
+                        evicted := 0
+                        for len(cacheOrder) > maxItems {
+                                delete(globalCache, cacheOrder[0])
+                                cacheOrder = cacheOrder[1:]
+                                evicted++
+                        }
+                        cacheSize := len(globalCache)
+                        cacheMutex.Unlock()
+
+                        slog.Info("Bounded cache tick", "cacheSize", cacheSize, "maxItems", maxItems, "evicted", evicted)
+                }
+        }()
+
+        return func() {
+                ticker.Stop()
+                close(done)
+                <-exited
+        }
 }
 
 // HTTP handler that allocates memory on each request
@@ -91,18 +255,33 @@ func memoryHandler(w http.ResponseWriter, r *http.Request) {
                         return
                 }
         }
+        // Checked even for the default, which a low MAX_ALLOCATE_BYTES can be under
+        if size < 1 || size > allocateLimits.maxBytes {
+                http.Error(w, fmt.Sprintf("Invalid size parameter (must be between 1 and %d)", allocateLimits.maxBytes), http.StatusBadRequest)
+                return
+        }
 
-        // Allocate a large slice
-        data := make([]byte, size)
-        for i := 0; i < len(data); i++ {
-                data[i] = byte(rand.Intn(256))
+        // Turn away requests beyond the in-flight limit rather than queueing
+        // their allocations
+        if !allocateLimits.tryAcquire() {
+                http.Error(w, "Too many allocations in flight, try again shortly", http.StatusTooManyRequests)
+                return
         }
+        defer allocateLimits.release()
 
-        // Create some string allocations
         var builder strings.Builder
-        for i := 0; i < 1000; i++ {
-                builder.WriteString(fmt.Sprintf("Line %d: %s\n", i, randomString(100)))
-        }
+        withSource(r.Context(), requestSource, func(context.Context) {
+                // Allocate a large slice
+                data := make([]byte, size)
+                for i := 0; i < len(data); i++ {
+                        data[i] = byte(rand.Intn(256))
+                }
+
+                // Create some string allocations
+                for i := 0; i < 1000; i++ {
+                        builder.WriteString(fmt.Sprintf("Line %d: %s\n", i, randomString(100)))
+                }
+        })
 
         // Access global cache
         cacheMutex.RLock()
@@ -136,35 +315,120 @@ func randomString(length int) string {
 // ObjectPool demonstrates object reuse patterns
 type ObjectPool struct {
         pool sync.Pool
+
+        // Counters updated atomically
+        gets int64
+        news int64 // Gets the pool couldn't satisfy with a reused object
+        puts int64
+}
+
+// PoolStats reports how often an ObjectPool reused objects
+type PoolStats struct {
+        Gets       int64   `json:"gets"`
+        News       int64   `json:"news"`
+        Puts       int64   `json:"puts"`
+        ReuseRatio float64 `json:"reuseRatio"` // Fraction of gets served by a reused object
 }
 
 // NewObjectPool creates a new object pool
 func NewObjectPool() *ObjectPool {
-        return &ObjectPool{
-                pool: sync.Pool{
-                        New: func() interface{} {
-                                // Create a new object when the pool is empty
-                                return &LargeObject{
-                                        Data: make([]byte, 1024*1024), // 1MB buffer
-                                }
-                        },
-                },
+        p := &ObjectPool{}
+        p.pool.New = func() interface{} {
+                // Create a new object when the pool is empty
+                atomic.AddInt64(&p.news, 1)
+                return &LargeObject{
+                        Data: make([]byte, 1024*1024), // 1MB buffer
+                }
         }
+        return p
 }
 
 // Get retrieves an object from the pool
 func (p *ObjectPool) Get() *LargeObject {
+        atomic.AddInt64(&p.gets, 1)
         return p.pool.Get().(*LargeObject)
 }
 
 // Put returns an object to the pool
 func (p *ObjectPool) Put(obj *LargeObject) {
+        atomic.AddInt64(&p.puts, 1)
         p.pool.Put(obj)
 }
 
+// Stats returns the pool's counters. The garbage collector empties sync.Pool,
+// so news keeps growing slowly even when every object is returned.
+func (p *ObjectPool) Stats() PoolStats {
+        stats := PoolStats{
+                Gets: atomic.LoadInt64(&p.gets),
+                News: atomic.LoadInt64(&p.news),
+                Puts: atomic.LoadInt64(&p.puts),
+        }
+        if stats.Gets > 0 {
+                stats.ReuseRatio = float64(stats.Gets-stats.News) / float64(stats.Gets)
+        }
+        return stats
+}
+
+// HTTP handler that uses an object from pool and reports the pool's reuse stats
+func poolHandler(pool *ObjectPool) http.HandlerFunc {
+        return func(w http.ResponseWriter, r *http.Request) {
+                // Get an object from the pool
+                var obj *LargeObject
+                withSource(r.Context(), poolSource, func(context.Context) {
+                        obj = pool.Get()
+                })
+                
+                // Do something with the object
+                rand.Read(obj.Data)
+                obj.ID = rand.Intn(10000)
+                obj.Name = fmt.Sprintf("PooledObject-%d", obj.ID)
+                
+                // Return the object to the pool before reporting, so the
+                // stats include this request
+                pool.Put(obj)
+                stats := pool.Stats()
+                
+                fmt.Fprintf(w, "Used pooled object: %s (ID: %d, Size: %d bytes)\n", 
+                        obj.Name, obj.ID, len(obj.Data))
+                fmt.Fprintf(w, "Pool stats: %d gets, %d news, %d puts, reuse ratio %.2f\n",
+                        stats.Gets, stats.News, stats.Puts, stats.ReuseRatio)
+        }
+}
+
 func main() {
-        // Seed random number generator
-        rand.Seed(time.Now().UnixNano())
+        seedValue := randseed.Flag()
+        profiles := selfprofile.Flags()
+        flag.Parse()
+
+        // Leak ticks log at info level; LOG_LEVEL=warn silences them while profiling
+        if _, err := logging.Setup(os.Getenv(logging.EnvVar)); err != nil {
+                fmt.Fprintf(os.Stderr, "%v\n", err)
+                os.Exit(2)
+        }
+
+        // Seed random number generator; a fixed seed repeats the allocated data and tree shapes
+        seed, fixed, err := randseed.Apply(*seedValue)
+        if err != nil {
+                fmt.Fprintf(os.Stderr, "%v\n", err)
+                os.Exit(2)
+        }
+        fmt.Println(randseed.Describe(seed, fixed))
+
+        // Coordinates CPU profile captures and their sampling rate, including
+        // the -cpuprofile one
+        cpuProfiler := profrate.NewCPUProfiler()
+
+        // Profile the whole run to files when asked to
+        stopProfiles, err := profiles.Start(cpuProfiler)
+        if err != nil {
+                fmt.Fprintf(os.Stderr, "%v\n", err)
+                os.Exit(2)
+        }
+
+        // Bound /allocate's request size and concurrency
+        allocateLimits = newAllocLimiter(
+                envInt("MAX_ALLOCATE_BYTES", defaultMaxAllocateBytes),
+                envInt("MAX_ALLOCATE_IN_FLIGHT", defaultMaxAllocateInFlight))
 
         // Create an object pool for demonstration
         pool := NewObjectPool()
@@ -175,62 +439,90 @@ func main() {
         // Register pprof handlers
         mux.HandleFunc("/debug/pprof/", pprof.Index)
         mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-        mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+        mux.HandleFunc("/debug/pprof/profile", cpuProfiler.ProfileHandler)
         mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
         mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 
+        // CPU profile sampling rate, applied to the next capture
+        mux.HandleFunc("/config/cpu-hz", cpuProfiler.ConfigHandler)
+
+        // Block profile rate and mutex profile fraction
+        mux.HandleFunc("/debug/profrate", profrate.Handler)
+
         // Memory allocation handler
         mux.HandleFunc("/allocate", memoryHandler)
+        mux.HandleFunc("/allocate-tree", allocateTreeHandler)
+        mux.HandleFunc("/alloc-rate", allocRateHandler)
 
         // Pool demonstration
-        mux.HandleFunc("/pool", func(w http.ResponseWriter, r *http.Request) {
-                // Get an object from the pool
-                obj := pool.Get()
-                
-                // Do something with the object
-                rand.Read(obj.Data)
-                obj.ID = rand.Intn(10000)
-                obj.Name = fmt.Sprintf("PooledObject-%d", obj.ID)
-                
-                // Return the object to the pool when done
-                defer pool.Put(obj)
-                
-                fmt.Fprintf(w, "Used pooled object: %s (ID: %d, Size: %d bytes)\n", 
-                        obj.Name, obj.ID, len(obj.Data))
-        })
+        mux.HandleFunc("/pool", poolHandler(pool))
+        mux.HandleFunc("/pool-vs-alloc", poolVsAllocHandler(pool))
 
         // Memory leak simulation
-        mux.HandleFunc("/start-leak", func(w http.ResponseWriter, r *http.Request) {
-                go simulateMemoryLeak(5 * time.Second)
-                fmt.Fprintf(w, "Started memory leak simulation (adding items every 5 seconds)\n")
-        })
+        mux.HandleFunc("/start-leak", startLeakHandler)
+        mux.HandleFunc("/stop-leak", stopLeakHandler)
+        mux.HandleFunc("/leaks", leaksHandler)
+        mux.HandleFunc("/clear-cache", clearCacheHandler)
+        mux.HandleFunc("/gc", gcHandler)
+
+        // Bounded cache simulation (compare with /start-leak)
+        mux.HandleFunc("/start-bounded", startBoundedHandler)
+        mux.HandleFunc("/stop-bounded", stopBoundedHandler)
+
+        // Heap retention view (retained vs churned allocations)
+        mux.HandleFunc("/heap-ratio", heapRatioHandler)
+
+        // In-use heap growth over a number of leak ticks
+        mux.HandleFunc("/heap-delta", heapDeltaHandler)
+
+        // Allocs profile with each sample labeled by its source
+        mux.HandleFunc("/allocs-labeled", labeledAllocsHandler)
 
         // Status endpoint
-        mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-                cacheMutex.RLock()
-                cacheSize := len(globalCache)
-                cacheMutex.RUnlock()
-                
-                fmt.Fprintf(w, "Memory App Status\n")
-                fmt.Fprintf(w, "----------------\n")
-                fmt.Fprintf(w, "Cache size: %d items\n", cacheSize)
-                
-                var m runtime.MemStats
-                runtime.ReadMemStats(&m)
-                fmt.Fprintf(w, "Alloc: %v MiB\n", m.Alloc/1024/1024)
-                fmt.Fprintf(w, "TotalAlloc: %v MiB\n", m.TotalAlloc/1024/1024)
-                fmt.Fprintf(w, "Sys: %v MiB\n", m.Sys/1024/1024)
-                fmt.Fprintf(w, "NumGC: %v\n", m.NumGC)
-        })
+        mux.HandleFunc("/status", statusHandler)
+        mux.HandleFunc("/status.json", statusJSONHandler)
 
         // Start the server
         fmt.Println("Starting memory app server on :8081")
         fmt.Println("Available endpoints:")
-        fmt.Println("  /allocate - Allocate memory on demand")
+        fmt.Printf("  /allocate?size=N - Allocate memory on demand, at most %d bytes and %d requests at a time\n",
+                allocateLimits.maxBytes, cap(allocateLimits.slots))
+        fmt.Println("  /allocate-tree?size=N&depth=N&fanout=N - Allocate a tree of objects with a custom shape")
+        fmt.Println("  /alloc-rate?mb_per_sec=N&seconds=N - Allocate and discard memory at a steady rate (see /debug/pprof/allocs)")
         fmt.Println("  /pool - Demonstrate object pooling")
-        fmt.Println("  /start-leak - Start memory leak simulation")
+        fmt.Println("  /pool-vs-alloc?n=N&pool=B - Acquire N 1MB buffers from the pool or with make, reporting time and mallocs (JSON)")
+        fmt.Println("  /start-leak?intervalMs=N&sizeKb=N&depth=N - Start another memory leak simulation adding a tree of objects every interval")
+        fmt.Println("  /stop-leak?id=N&purge=true - Stop leak simulation N (all without id), optionally purging its items; clear=true empties the cache")
+        fmt.Println("  /leaks - List running leak simulations with the items and bytes they retain (JSON)")
+        fmt.Println("  /clear-cache?gc=true - Empty the cache, optionally forcing a GC, and report the heap before and after (JSON)")
+        fmt.Println("  /gc - Force a GC, return freed memory to the OS and report the heap before and after (JSON)")
+        fmt.Println("  /start-bounded?max=N - Start bounded cache simulation that evicts the oldest items")
+        fmt.Println("  /stop-bounded - Stop the bounded cache simulation, leaving its items in the cache")
+        fmt.Println("  /heap-ratio - View heap flame graph colored by inuse/alloc ratio")
+        fmt.Println("  /heap-delta?ticks=N&top=N - Allocation sites whose in-use memory grew over N ticks of the running leak simulation")
+        fmt.Println("  /allocs-labeled - Allocs profile with samples labeled source=leak, request or pool (use -tagfocus)")
+        fmt.Println("  /config/cpu-hz - GET or POST ?hz=N to change the CPU profile sampling rate")
+        fmt.Println("  /debug/profrate - GET or POST block=N and/or mutex=N to change the block and mutex profiling rates")
         fmt.Println("  /status - View memory stats")
+        fmt.Println("  /status.json - Cache size and memory stats as JSON")
         fmt.Println("  /debug/pprof/ - pprof endpoint")
         
-        http.ListenAndServe(":8081", mux)
+        // Stop on Ctrl-C or SIGTERM, letting in-flight requests finish and
+        // stopping the leak and bounded cache simulations
+        ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+        defer stop()
+        err = server.ListenAndServe(ctx, ":8081", mux, server.DefaultShutdownTimeout,
+                func(context.Context) {
+                        stopAllLeaks(false)
+                        stopBounded()
+                })
+
+        // Flush the profiles even if the server failed
+        if err := stopProfiles(); err != nil {
+                fmt.Fprintf(os.Stderr, "%v\n", err)
+        }
+        if err != nil {
+                fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+                os.Exit(1)
+        }
 }
\ No newline at end of file
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+	
+	"pprofviz/examples/internal/testutil"
 )
 
 func TestMemoryHandler(t *testing.T) {
@@ -97,12 +102,54 @@ func TestObjectPool(t *testing.T) {
 	}
 }
 
+func TestObjectPoolStats(t *testing.T) {
+	pool := NewObjectPool()
+	
+	// The first get always creates an object
+	pool.Put(pool.Get())
+	first := pool.Stats()
+	if first.Gets != 1 || first.News != 1 || first.Puts != 1 || first.ReuseRatio != 0 {
+		t.Errorf("Unexpected stats after one cycle: %+v", first)
+	}
+	
+	// Returned objects are reused, so later gets rarely create new ones
+	for i := 0; i < 100; i++ {
+		pool.Put(pool.Get())
+	}
+	stats := pool.Stats()
+	if stats.Gets != 101 || stats.Puts != 101 {
+		t.Errorf("Expected 101 gets and puts, got %+v", stats)
+	}
+	if stats.News < 1 || stats.News > stats.Gets {
+		t.Errorf("Expected between 1 and %d news, got %d", stats.Gets, stats.News)
+	}
+	if stats.ReuseRatio <= first.ReuseRatio {
+		t.Errorf("Expected the reuse ratio to increase from %.2f, got %.2f", first.ReuseRatio, stats.ReuseRatio)
+	}
+}
+
+func TestPoolHandler(t *testing.T) {
+	pool := NewObjectPool()
+	handler := poolHandler(pool)
+	
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/pool", nil)
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		
+		expected := "Pool stats: " + strconv.Itoa(i+1) + " gets"
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Request %d: expected body to contain %q, got: %s", i+1, expected, recorder.Body.String())
+		}
+	}
+}
+
 func TestLargeObjectCreation(t *testing.T) {
 	// Test creating a large object with different depths
 	depths := []int{0, 1, 2}
 	
 	for _, depth := range depths {
-		t.Run("Depth="+string('0'+depth), func(t *testing.T) {
+		t.Run("Depth="+strconv.Itoa(depth), func(t *testing.T) {
 			obj := createLargeObject(1, depth)
 			
 			// Basic validation
@@ -140,26 +187,362 @@ func TestLargeObjectCreation(t *testing.T) {
 	}
 }
 
+func TestCreateLargeObjectSized(t *testing.T) {
+	size := 64 * 1024
+	
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	obj := createLargeObjectSized(1, 3, size, 4)
+	runtime.ReadMemStats(&after)
+	
+	nodes, bytes := treeSize(obj)
+	
+	// A depth 3 tree has between 4 (a chain) and 1+4+16+64 nodes
+	if nodes < 4 || nodes > 85 {
+		t.Fatalf("Expected between 4 and 85 nodes, got %d", nodes)
+	}
+	if bytes != size*nodes {
+		t.Errorf("Expected %d payload bytes for %d nodes, got %d", size*nodes, nodes, bytes)
+	}
+	
+	// Payloads dominate the allocation; allow slack for the object headers and names
+	allocated := int(after.TotalAlloc - before.TotalAlloc)
+	if allocated < size*nodes || allocated > size*nodes+nodes*1024+64*1024 {
+		t.Errorf("Expected roughly %d bytes allocated, got %d", size*nodes, allocated)
+	}
+}
+
+func TestAllocateTreeHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Single node", "/allocate-tree?size=1024&depth=0", http.StatusOK, "Allocated tree: 1 objects, 1024 bytes"},
+		{"Custom shape", "/allocate-tree?size=1024&depth=2&fanout=2", http.StatusOK, "depth: 2, fanout: up to 2"},
+		{"Depth too large", "/allocate-tree?depth=100", http.StatusBadRequest, "Invalid depth parameter"},
+		{"Invalid fanout", "/allocate-tree?fanout=0", http.StatusBadRequest, "Invalid fanout parameter"},
+		{"Invalid size", "/allocate-tree?size=big", http.StatusBadRequest, "Invalid size parameter"},
+		{"Over byte limit", "/allocate-tree?size=10485760&depth=8&fanout=10", http.StatusBadRequest, "over the"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			recorder := httptest.NewRecorder()
+			
+			allocateTreeHandler(recorder, req)
+			
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected body to contain %q, got: %s", tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestSimulateBoundedCache(t *testing.T) {
+	interval := 10 * time.Millisecond
+	maxItems := 3
+	
+	// Reset the global cache for this test, with entries the bounded cache
+	// didn't add and must leave alone
+	cacheMutex.Lock()
+	globalCache = map[string]*LargeObject{"other-1": {ID: 1}, "other-2": {ID: 2}}
+	cacheOrder = nil
+	cacheMutex.Unlock()
+	
+	stop := simulateBoundedCache(interval, maxItems)
+	defer stop()
+	
+	// Wait until the cache has filled past maxItems and evicted the oldest entry
+	first := ""
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cacheMutex.RLock()
+		if first == "" && len(cacheOrder) > 0 {
+			first = cacheOrder[0]
+		}
+		_, hasFirst := globalCache[first]
+		cacheMutex.RUnlock()
+		
+		if first != "" && !hasFirst {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for the oldest entry to be evicted")
+		}
+		time.Sleep(interval)
+	}
+	
+	// The bounded cache's own entries should now stay at maxItems, next to
+	// the others
+	for i := 0; i < 5; i++ {
+		cacheMutex.RLock()
+		own, cacheSize := len(cacheOrder), len(globalCache)
+		_, hasOther1 := globalCache["other-1"]
+		_, hasOther2 := globalCache["other-2"]
+		cacheMutex.RUnlock()
+		
+		if own != maxItems || cacheSize != maxItems+2 {
+			t.Errorf("Expected %d bounded entries in a cache of %d, got %d in %d", maxItems, maxItems+2, own, cacheSize)
+		}
+		if !hasOther1 || !hasOther2 {
+			t.Error("Expected entries the bounded cache didn't add to be kept")
+		}
+		time.Sleep(interval * 2)
+	}
+}
+
 func TestSimulateMemoryLeak(t *testing.T) {
+	// stop waits for the leak goroutine, so nothing may outlive the test
+	testutil.AssertNoGoroutineLeak(t)
+	
 	// Use a short interval for testing
 	interval := 50 * time.Millisecond
 	
 	// Reset the global cache for this test
+	cacheMutex.Lock()
 	globalCache = make(map[string]*LargeObject)
+	cacheMutex.Unlock()
 	
 	// Start the leak simulation
-	simulateMemoryLeak(interval)
+	leak := simulateMemoryLeak(leakEvery(interval))
+	defer leak.stop()
 	
-	// Wait for a few intervals
-	time.Sleep(interval * 3)
+	// Wait until the cache has grown over a few ticks; under the race
+	// detector each tick's allocation can take longer than the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cacheMutex.RLock()
+		cacheSize := len(globalCache)
+		cacheMutex.RUnlock()
+		
+		if cacheSize >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected globalCache to grow, but size is only %d", cacheSize)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// leakEvery is the default leak simulation at a test-friendly interval
+func leakEvery(interval time.Duration) leakConfig {
+	cfg := defaultLeakConfig
+	cfg.Interval = interval
+	return cfg
+}
+
+// leakedItems counts the cache entries added by leak simulation id
+func leakedItems(id int) int {
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+	
+	prefix := fmt.Sprintf("leak-%d-", id)
+	count := 0
+	for key := range globalCache {
+		if strings.HasPrefix(key, prefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// waitLeakedItems waits until leak simulation id has added n cache entries
+func waitLeakedItems(t *testing.T, id, n int) {
+	t.Helper()
+	
+	deadline := time.Now().Add(5 * time.Second)
+	for leakedItems(id) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timeout waiting for leak %d to grow the cache to %d items", id, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStartStopLeak(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+	
+	interval := 10 * time.Millisecond
+	
+	cacheMutex.Lock()
+	globalCache = make(map[string]*LargeObject)
+	cacheMutex.Unlock()
+	
+	// Leaks run side by side, each with its own ID
+	first, second := startLeak(leakEvery(interval)), startLeak(leakEvery(interval))
+	defer stopAllLeaks(true)
+	if first == second {
+		t.Fatalf("Expected distinct leak IDs, got %d twice", first)
+	}
+	waitLeakedItems(t, first, 2)
+	waitLeakedItems(t, second, 2)
+	
+	leaks := listLeaks()
+	if len(leaks) != 2 || leaks[0].ID != first || leaks[1].ID != second {
+		t.Fatalf("Expected leaks %d and %d listed, got %+v", first, second, leaks)
+	}
+	for _, leak := range leaks {
+		if leak.Items < 2 || leak.Bytes < leak.Items*1024*1024 || leak.IntervalMs != 10 {
+			t.Errorf("Unexpected leak listing: %+v", leak)
+		}
+	}
+	
+	if _, err := stopLeak(first, false); err != nil {
+		t.Fatalf("stopLeak failed: %v", err)
+	}
+	if _, err := stopLeak(first, false); err != errLeakNotFound {
+		t.Errorf("Expected errLeakNotFound on double stop, got %v", err)
+	}
+	
+	// The stopped leak's entries stay but stop growing, while the other's grow
+	stoppedAt := leakedItems(first)
+	waitLeakedItems(t, second, leakedItems(second)+3)
+	if n := leakedItems(first); n != stoppedAt {
+		t.Errorf("Expected cache to stay at %d items from leak %d after stop, got %d", stoppedAt, first, n)
+	}
+	if leaks := listLeaks(); len(leaks) != 1 || leaks[0].ID != second {
+		t.Errorf("Expected only leak %d listed, got %+v", second, leaks)
+	}
+	
+	// Purging removes only the stopped leak's entries
+	purged, err := stopLeak(second, true)
+	if err != nil {
+		t.Fatalf("stopLeak failed: %v", err)
+	}
+	if purged < 5 || leakedItems(second) != 0 {
+		t.Errorf("Expected leak %d's items purged, purged %d and %d remain", second, purged, leakedItems(second))
+	}
+	if n := leakedItems(first); n != stoppedAt {
+		t.Errorf("Expected leak %d's %d items to survive another leak's purge, got %d", first, stoppedAt, n)
+	}
+	
+	if stopped, _ := stopAllLeaks(false); stopped != 0 {
+		t.Errorf("Expected no leaks left to stop, stopped %d", stopped)
+	}
+}
+
+func TestLeakHandlers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start-leak", startLeakHandler)
+	mux.HandleFunc("/stop-leak", stopLeakHandler)
+	mux.HandleFunc("/leaks", leaksHandler)
+	
+	// Leave no simulation running for other tests
+	defer stopAllLeaks(true)
+	
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Stop before start", "/stop-leak", http.StatusConflict, "not running"},
+		{"No leaks", "/leaks", http.StatusOK, "[]"},
+		{"Start", "/start-leak", http.StatusOK, "Started memory leak simulation"},
+		{"Start another", "/start-leak", http.StatusOK, "Started memory leak simulation"},
+		{"List", "/leaks", http.StatusOK, `"intervalMs":5000`},
+		{"Stop unknown", "/stop-leak?id=999999", http.StatusNotFound, "no such memory leak simulation"},
+		{"Invalid id", "/stop-leak?id=abc", http.StatusBadRequest, "Invalid id"},
+		{"Stop all and clear", "/stop-leak?clear=true", http.StatusOK, "Stopped 2 memory leak simulations"},
+		{"Double stop", "/stop-leak", http.StatusConflict, "not running"},
+		{"None left", "/leaks", http.StatusOK, "[]"},
+	}
+	
+	for _, tc := range testCases {
+		req := httptest.NewRequest("POST", tc.url, nil)
+		recorder := httptest.NewRecorder()
+		
+		mux.ServeHTTP(recorder, req)
+		
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.expectedStatus, recorder.Code)
+		}
+		if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+			t.Errorf("%s: expected body to contain %q, got: %s", tc.name, tc.expectedBody, recorder.Body.String())
+		}
+	}
 	
-	// Check that the cache is growing
 	cacheMutex.RLock()
 	cacheSize := len(globalCache)
 	cacheMutex.RUnlock()
+	if cacheSize != 0 {
+		t.Errorf("Expected an empty cache after stop with clear, got %d items", cacheSize)
+	}
+	
+	// A single leak stops by ID
+	id := startLeak(leakEvery(time.Hour))
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("POST", fmt.Sprintf("/stop-leak?id=%d&purge=true", id), nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), fmt.Sprintf("Stopped memory leak simulation %d\nPurged 0 cache items", id)) {
+		t.Errorf("Unexpected response stopping leak %d: %d %s", id, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestLeakGrowthRate(t *testing.T) {
+	testutil.AssertNoGoroutineLeak(t)
+	
+	// One 1KB object per tick, so the cache grows by a tick's worth of bytes
+	cfg := leakConfig{Interval: 20 * time.Millisecond, Size: 1024, Depth: 0}
+	start := time.Now()
+	id := startLeak(cfg)
+	defer stopLeak(id, true)
+	
+	time.Sleep(500 * time.Millisecond)
+	leaks := listLeaks()
+	elapsed := time.Since(start)
+	if len(leaks) != 1 || leaks[0].ID != id {
+		t.Fatalf("Expected leak %d listed, got %+v", id, leaks)
+	}
+	leak := leaks[0]
+	if leak.IntervalMs != 20 || leak.SizeKb != 1 || leak.Depth != 0 {
+		t.Errorf("Expected the leak's configuration in its listing, got %+v", leak)
+	}
+	
+	// Ticks can be dropped on a busy machine, but never added
+	expected := int(elapsed / cfg.Interval)
+	if leak.Items < expected*4/10 || leak.Items > expected+1 {
+		t.Errorf("Expected about %d items after %v, got %d", expected, elapsed, leak.Items)
+	}
+	if leak.Bytes != leak.Items*1024 {
+		t.Errorf("Expected %d bytes for %d 1KB items, got %d", leak.Items*1024, leak.Items, leak.Bytes)
+	}
+}
+
+func TestStartLeakParams(t *testing.T) {
+	defer stopAllLeaks(true)
+	
+	testCases := []struct {
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"intervalMs=5", http.StatusBadRequest, "Invalid intervalMs"},
+		{"sizeKb=0", http.StatusBadRequest, "Invalid sizeKb"},
+		{"sizeKb=65537", http.StatusBadRequest, "Invalid sizeKb"},
+		{"depth=6", http.StatusBadRequest, "Invalid depth"},
+		{"depth=abc", http.StatusBadRequest, "Invalid depth"},
+		{"sizeKb=65536&depth=2", http.StatusBadRequest, "byte limit"},
+		{"intervalMs=60000&sizeKb=4&depth=1", http.StatusOK, "4KB objects of depth 1 every 1m0s"},
+	}
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		startLeakHandler(recorder, httptest.NewRequest("POST", "/start-leak?"+tc.query, nil))
+		
+		if recorder.Code != tc.expectedStatus || !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+			t.Errorf("%s: expected %d with %q, got %d: %s", tc.query, tc.expectedStatus, tc.expectedBody, recorder.Code, recorder.Body.String())
+		}
+	}
 	
-	if cacheSize < 2 {
-		t.Errorf("Expected globalCache to grow, but size is only %d", cacheSize)
+	leaks := listLeaks()
+	if len(leaks) != 1 || leaks[0].IntervalMs != 60000 || leaks[0].SizeKb != 4 || leaks[0].Depth != 1 {
+		t.Errorf("Expected one leak with the requested configuration, got %+v", leaks)
 	}
 }
 
@@ -167,7 +550,7 @@ func TestRandomString(t *testing.T) {
 	lengths := []int{0, 10, 100}
 	
 	for _, length := range lengths {
-		t.Run("Length="+string(length), func(t *testing.T) {
+		t.Run("Length="+strconv.Itoa(length), func(t *testing.T) {
 			s := randomString(length)
 			
 			if len(s) != length {
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Upper bound for /pool-vs-alloc's n; the direct path allocates 1MB each time
+const maxPoolVsAllocN = 10000
+
+// Size of the buffers the direct path allocates, matching the pool's objects
+const poolVsAllocBytes = 1024 * 1024
+
+// Keeps the direct path's buffers escaping so the allocations aren't elided
+var poolVsAllocSink []byte
+
+// poolVsAlloc is the cost of n acquisitions through an ObjectPool or by
+// allocating directly. The MemStats deltas cover the whole process, so other
+// requests running at the same time inflate them.
+type poolVsAlloc struct {
+	N          int     `json:"n"`
+	Pool       bool    `json:"pool"`
+	DurationMs float64 `json:"durationMs"`
+	NsPerOp    float64 `json:"nsPerOp"`
+	Mallocs    uint64  `json:"mallocs"`    // Heap objects allocated
+	AllocBytes uint64  `json:"allocBytes"` // Heap bytes allocated
+	NumGC      uint32  `json:"numGC"`      // Collections that ran meanwhile
+	GCPauseNs  uint64  `json:"gcPauseNs"`
+}
+
+// runPoolVsAlloc acquires n 1MB buffers, through pool's Get and Put when
+// pool is non-nil and with make otherwise, touching each one. It collects
+// garbage first so earlier work doesn't trigger a collection during the run.
+func runPoolVsAlloc(pool *ObjectPool, n int) poolVsAlloc {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	for i := 0; i < n; i++ {
+		if pool != nil {
+			obj := pool.Get()
+			obj.Data[i%len(obj.Data)] = byte(i)
+			pool.Put(obj)
+		} else {
+			data := make([]byte, poolVsAllocBytes)
+			data[i%len(data)] = byte(i)
+			poolVsAllocSink = data
+		}
+	}
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	poolVsAllocSink = nil
+
+	return poolVsAlloc{
+		N:          n,
+		Pool:       pool != nil,
+		DurationMs: float64(elapsed) / float64(time.Millisecond),
+		NsPerOp:    float64(elapsed.Nanoseconds()) / float64(n),
+		Mallocs:    after.Mallocs - before.Mallocs,
+		AllocBytes: after.TotalAlloc - before.TotalAlloc,
+		NumGC:      after.NumGC - before.NumGC,
+		GCPauseNs:  after.PauseTotalNs - before.PauseTotalNs,
+	}
+}
+
+// HTTP handler that compares acquiring n buffers through pool with
+// allocating them directly, for a profilable view of what pooling saves
+func poolVsAllocHandler(pool *ObjectPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := 1000
+		if param := r.URL.Query().Get("n"); param != "" {
+			if _, err := fmt.Sscanf(param, "%d", &n); err != nil || n < 1 || n > maxPoolVsAllocN {
+				http.Error(w, fmt.Sprintf("Invalid n parameter (must be between 1 and %d)", maxPoolVsAllocN), http.StatusBadRequest)
+				return
+			}
+		}
+		usePool := true
+		if param := r.URL.Query().Get("pool"); param != "" {
+			var err error
+			if usePool, err = strconv.ParseBool(param); err != nil {
+				http.Error(w, "Invalid pool parameter (must be true or false)", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var result poolVsAlloc
+		if usePool {
+			result = runPoolVsAlloc(pool, n)
+		} else {
+			result = runPoolVsAlloc(nil, n)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPoolVsAlloc(t *testing.T) {
+	const n = 200
+	pooled := runPoolVsAlloc(NewObjectPool(), n)
+	direct := runPoolVsAlloc(nil, n)
+
+	if !pooled.Pool || direct.Pool || pooled.N != n || direct.N != n {
+		t.Errorf("Unexpected results: pooled %+v, direct %+v", pooled, direct)
+	}
+	if direct.Mallocs < n || direct.AllocBytes < n*poolVsAllocBytes {
+		t.Errorf("Expected at least %d 1MB allocations on the direct path, got %+v", n, direct)
+	}
+	if pooled.Mallocs >= direct.Mallocs {
+		t.Errorf("Expected fewer mallocs through the pool, got %d pooled vs %d direct", pooled.Mallocs, direct.Mallocs)
+	}
+}
+
+func TestPoolVsAllocHandler(t *testing.T) {
+	handler := poolVsAllocHandler(NewObjectPool())
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/pool-vs-alloc?n=50&pool=false", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var result poolVsAlloc
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if result.N != 50 || result.Pool || result.Mallocs < 50 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	for _, url := range []string{"/pool-vs-alloc?n=0", "/pool-vs-alloc?n=10001", "/pool-vs-alloc?n=x", "/pool-vs-alloc?pool=maybe"} {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", url, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", url, recorder.Code)
+		}
+	}
+}
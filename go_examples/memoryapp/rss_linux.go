@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readRSS returns the process's current and peak resident set size in bytes,
+// the VmRSS and VmHWM lines of /proc/self/status
+func readRSS() (current, peak uint64, err error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	return parseProcStatus(f)
+}
+
+// parseProcStatus reads VmRSS and VmHWM, given in kB, from the contents of a
+// /proc/<pid>/status file
+func parseProcStatus(r io.Reader) (current, peak uint64, err error) {
+	var foundRSS, foundHWM bool
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || (name != "VmRSS" && name != "VmHWM") {
+			continue
+		}
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		if name == "VmRSS" {
+			current, foundRSS = kb*1024, true
+		} else {
+			peak, foundHWM = kb*1024, true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	if !foundRSS || !foundHWM {
+		return 0, 0, fmt.Errorf("no VmRSS and VmHWM lines in process status")
+	}
+	return current, peak, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProcStatus(t *testing.T) {
+	status := "Name:\tmemoryapp\nVmPeak:\t  812340 kB\nVmHWM:\t   20480 kB\nVmRSS:\t   10240 kB\nThreads:\t8\n"
+	current, peak, err := parseProcStatus(strings.NewReader(status))
+	if err != nil || current != 10240*1024 || peak != 20480*1024 {
+		t.Errorf("Expected RSS 10MiB and peak 20MiB, got %d, %d, %v", current, peak, err)
+	}
+
+	for _, status := range []string{"Name:\tmemoryapp\n", "VmHWM:\t20480 kB\nVmRSS:\tlots kB\n"} {
+		if _, _, err := parseProcStatus(strings.NewReader(status)); err == nil {
+			t.Errorf("Expected an error parsing %q", status)
+		}
+	}
+}
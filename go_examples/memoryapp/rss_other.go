@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// readRSS fails: resident set size is only read from /proc on Linux
+func readRSS() (current, peak uint64, err error) {
+	return 0, 0, errors.New("resident set size is only available on Linux")
+}
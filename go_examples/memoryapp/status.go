@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+
+	"pprofviz/examples/internal/memstatus"
+	"pprofviz/examples/internal/server"
+)
+
+// appStatus is the state reported by /status and /status.json
+type appStatus struct {
+	Goroutines int `json:"goroutines"`
+	CacheItems int `json:"cacheItems"`
+	memstatus.Stats
+
+	// Resident set size as the OS sees it, which drifts from SysMiB with
+	// fragmentation and returned pages. Omitted where it can't be read.
+	RSSMiB     float64 `json:"rssMiB,omitempty"`
+	PeakRSSMiB float64 `json:"peakRSSMiB,omitempty"`
+}
+
+// statusSnapshot gathers the app status once so the text and JSON views
+// always agree
+func statusSnapshot() appStatus {
+	cacheMutex.RLock()
+	cacheSize := len(globalCache)
+	cacheMutex.RUnlock()
+
+	rss, peakRSS, err := readRSS()
+	if err != nil {
+		slog.Debug("Failed to read resident set size", "err", err)
+	}
+
+	return appStatus{
+		Goroutines: runtime.NumGoroutine(),
+		CacheItems: cacheSize,
+		Stats:      memstatus.Read(),
+		RSSMiB:     float64(rss) / memstatus.BytesPerMiB,
+		PeakRSSMiB: float64(peakRSS) / memstatus.BytesPerMiB,
+	}
+}
+
+// HTTP handler that prints the app status for humans, or serves it as JSON
+// when the request asks for it
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	if server.WantsJSON(r) {
+		statusJSONHandler(w, r)
+		return
+	}
+	status := statusSnapshot()
+
+	fmt.Fprintf(w, "Memory App Status\n")
+	fmt.Fprintf(w, "----------------\n")
+	fmt.Fprintf(w, "Cache size: %d items\n", status.CacheItems)
+	fmt.Fprintf(w, "Goroutines: %d\n", status.Goroutines)
+
+	status.Stats.WriteText(w)
+	if status.RSSMiB != 0 {
+		fmt.Fprintf(w, "RSS: %d MiB (peak %d MiB)\n", uint64(status.RSSMiB), uint64(status.PeakRSSMiB))
+	}
+}
+
+// HTTP handler that serves the app status as JSON for scripts such as
+// cmd/statuswatch
+func statusJSONHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statusSnapshot())
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStatusHandlers(t *testing.T) {
+	cacheMutex.Lock()
+	saved := globalCache
+	globalCache = map[string]*LargeObject{"a": {}, "b": {}}
+	cacheMutex.Unlock()
+	defer func() {
+		cacheMutex.Lock()
+		globalCache = saved
+		cacheMutex.Unlock()
+	}()
+
+	recorder := httptest.NewRecorder()
+	statusJSONHandler(recorder, httptest.NewRequest("GET", "/status.json", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+	var status appStatus
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if status.CacheItems != 2 || status.Goroutines <= 0 || status.SysMiB <= 0 || status.TotalAllocMiB < status.AllocMiB {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	// Text stays the default for /status
+	recorder = httptest.NewRecorder()
+	statusHandler(recorder, httptest.NewRequest("GET", "/status", nil))
+
+	for _, line := range []string{"Cache size: 2 items", "Goroutines: ", "Alloc: ", "TotalAlloc: ", "Sys: ", "NumGC: "} {
+		if !strings.Contains(recorder.Body.String(), line) {
+			t.Errorf("Expected %q in the status text, got: %s", line, recorder.Body.String())
+		}
+	}
+}
+
+func TestStatusNegotiation(t *testing.T) {
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/status?format=json", nil),
+		func() *http.Request {
+			req := httptest.NewRequest("GET", "/status", nil)
+			req.Header.Set("Accept", "application/json")
+			return req
+		}(),
+	} {
+		recorder := httptest.NewRecorder()
+		statusHandler(recorder, req)
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(recorder.Body.Bytes(), &fields); err != nil {
+			t.Fatalf("%s: expected JSON, got: %s", req.URL, recorder.Body.String())
+		}
+		for _, name := range []string{"goroutines", "cacheItems", "allocMiB", "totalAllocMiB", "sysMiB", "numGC"} {
+			if _, ok := fields[name]; !ok {
+				t.Errorf("%s: missing %s in status: %s", req.URL, name, recorder.Body.String())
+			}
+		}
+	}
+}
+
+func TestStatusRSS(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	statusJSONHandler(recorder, httptest.NewRequest("GET", "/status.json", nil))
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(recorder.Body.Bytes(), &fields); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	_, hasRSS := fields["rssMiB"]
+	_, hasPeak := fields["peakRSSMiB"]
+
+	snapshot := statusSnapshot()
+	text := httptest.NewRecorder()
+	statusHandler(text, httptest.NewRequest("GET", "/status", nil))
+
+	if runtime.GOOS != "linux" {
+		if hasRSS || hasPeak || snapshot.RSSMiB != 0 {
+			t.Errorf("Expected no RSS off Linux, got %s", recorder.Body.String())
+		}
+		return
+	}
+	if !hasRSS || !hasPeak {
+		t.Fatalf("Expected rssMiB and peakRSSMiB in the status, got %s", recorder.Body.String())
+	}
+	if snapshot.RSSMiB == 0 || snapshot.PeakRSSMiB < snapshot.RSSMiB {
+		t.Errorf("Expected a non-zero RSS no larger than its peak, got %+v", snapshot)
+	}
+	if !strings.Contains(text.Body.String(), "RSS: ") {
+		t.Errorf("Expected RSS in the status text, got: %s", text.Body.String())
+	}
+}
@@ -1,16 +1,28 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"net/http/pprof"
 	"os"
-	"runtime"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"pprofviz/examples/internal/logging"
+	"pprofviz/examples/internal/profrate"
+	"pprofviz/examples/internal/randseed"
+	"pprofviz/examples/internal/selfprofile"
+	"pprofviz/examples/internal/server"
 )
 
 // Product represents a product data model
@@ -27,13 +39,18 @@ type Product struct {
 // Database is a simple in-memory database
 type Database struct {
 	products map[int]Product
+	nextID   int // ID given to the next inserted product
 	mutex    sync.RWMutex
+	index    *searchIndex // Built lazily by SearchIndex, nil when stale
+	cache    *searchCache // /api/search results, emptied by every write
 }
 
 // NewDatabase creates a new database with sample data
 func NewDatabase() *Database {
 	db := &Database{
 		products: make(map[int]Product),
+		nextID:   1001,
+		cache:    newSearchCache(searchCacheSize),
 	}
 
 	// Generate sample products
@@ -53,6 +70,205 @@ func NewDatabase() *Database {
 	return db
 }
 
+// PutProduct adds or replaces a product and invalidates the search index
+// and cache
+func (db *Database) PutProduct(product Product) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.products[product.ID] = product
+	if product.ID >= db.nextID {
+		db.nextID = product.ID + 1
+	}
+	db.index = nil
+	db.cache.invalidate()
+}
+
+// InsertProduct stores product under the next free ID and returns it with
+// the ID set, invalidating the search index and cache
+func (db *Database) InsertProduct(product Product) Product {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	product.ID = db.nextID
+	db.nextID++
+	db.products[product.ID] = product
+	db.index = nil
+	db.cache.invalidate()
+	return product
+}
+
+// Count returns the number of products
+func (db *Database) Count() int {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return len(db.products)
+}
+
+// Page returns up to limit products ordered by ID, skipping the first offset,
+// along with the total number of products
+func (db *Database) Page(offset, limit int) ([]Product, int) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	ids := make([]int, 0, len(db.products))
+	for id := range db.products {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	total := len(ids)
+	if offset >= total {
+		return []Product{}, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	products := make([]Product, 0, end-offset)
+	for _, id := range ids[offset:end] {
+		products = append(products, db.products[id])
+	}
+	return products, total
+}
+
+// Products looks up each of ids under a single read lock, returning the
+// products found in the order requested and the IDs that weren't
+func (db *Database) Products(ids []int) (products []Product, missing []int) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	products = make([]Product, 0, len(ids))
+	missing = []int{}
+	for _, id := range ids {
+		if product, ok := db.products[id]; ok {
+			products = append(products, product)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return products, missing
+}
+
+// Pagination bounds for /api/products
+const (
+	defaultProductsLimit = 50
+	maxProductsLimit     = 1000
+)
+
+// Largest request body accepted when creating a product
+const maxProductBodyBytes = 1 << 20
+
+// productsHandler serves a page of products (?limit=N&offset=N) ordered by
+// ID, with the full product count in the X-Total-Count header. POST creates
+// a product from a JSON body instead.
+func productsHandler(db *Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost:
+			createProduct(db, w, r)
+			return
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit, offset := defaultProductsLimit, 0
+		if param := r.URL.Query().Get("limit"); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 1 || n > maxProductsLimit {
+				http.Error(w, fmt.Sprintf("Invalid limit parameter (must be between 1 and %d)", maxProductsLimit), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if param := r.URL.Query().Get("offset"); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid offset parameter (must be 0 or more)", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+
+		products, total := db.Page(offset, limit)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		json.NewEncoder(w).Encode(products)
+	}
+}
+
+// createProduct decodes a product from the request body, stores it under a
+// new ID and responds with 201 and the stored product. Any ID in the body
+// is ignored.
+func createProduct(db *Database, w http.ResponseWriter, r *http.Request) {
+	var product Product
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxProductBodyBytes)).Decode(&product); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid product: %v", err), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(product.Name) == "" {
+		http.Error(w, "Invalid product: name is required", http.StatusBadRequest)
+		return
+	}
+	if product.Price < 0 {
+		http.Error(w, "Invalid product: price must be 0 or more", http.StatusBadRequest)
+		return
+	}
+
+	product = db.InsertProduct(product)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/products/%d", product.ID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(product)
+}
+
+// Most IDs accepted by one /api/products/batch request
+const maxBatchIDs = 500
+
+// productBatch is the /api/products/batch response
+type productBatch struct {
+	Products []Product `json:"products"`
+	Missing  []int     `json:"missing"` // Requested IDs with no product
+}
+
+// productsBatchHandler serves the products with the IDs listed in
+// ?ids=1,2,3, in the order listed, along with the IDs that weren't found
+func productsBatchHandler(db *Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param := r.URL.Query().Get("ids")
+		if param == "" {
+			http.Error(w, "Missing ids parameter (comma-separated product IDs)", http.StatusBadRequest)
+			return
+		}
+		fields := strings.Split(param, ",")
+		if len(fields) > maxBatchIDs {
+			http.Error(w, fmt.Sprintf("Too many ids (at most %d per request)", maxBatchIDs), http.StatusBadRequest)
+			return
+		}
+		ids := make([]int, 0, len(fields))
+		for _, field := range fields {
+			id, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid id %q", field), http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, id)
+		}
+		
+		products, missing := db.Products(ids)
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(productBatch{Products: products, Missing: missing})
+	}
+}
+
 // generateRandomText generates a random text of n characters
 func generateRandomText(n int) string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
@@ -76,35 +292,60 @@ func randomCategories(categories []string) []string {
 }
 
 func main() {
-	// Seed random number generator
-	rand.Seed(time.Now().UnixNano())
+	flag.BoolVar(&traceRegionsEnabled, "trace-regions", false,
+		"annotate /api/search and /api/loadtest with execution trace regions")
+	seedValue := randseed.Flag()
+	profiles := selfprofile.Flags()
+	flag.Parse()
+	
+	// Logs, including the log package's, go through slog at LOG_LEVEL
+	if _, err := logging.Setup(os.Getenv(logging.EnvVar)); err != nil {
+		log.Fatal(err)
+	}
+	
+	// Seed random number generator; a fixed seed generates the same products every run
+	seed, fixed, err := randseed.Apply(*seedValue)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(randseed.Describe(seed, fixed))
+	
+	// Coordinates CPU profile captures and their sampling rate, including
+	// the -cpuprofile one
+	cpuProfiler := profrate.NewCPUProfiler()
+
+	// Profile the whole run to files when asked to
+	stopProfiles, err := profiles.Start(cpuProfiler)
+	if err != nil {
+		log.Fatal(err)
+	}
 	
 	// Create a new database
 	db := NewDatabase()
-	
+
 	// Create a new server mux
 	mux := http.NewServeMux()
 	
 	// Add pprof handlers
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/profile", cpuProfiler.ProfileHandler)
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	
+	// CPU profile sampling rate, applied to the next capture
+	mux.HandleFunc("/config/cpu-hz", cpuProfiler.ConfigHandler)
+	
+	// Block profile rate and mutex profile fraction
+	mux.HandleFunc("/debug/profrate", profrate.Handler)
+	
+	// Request latency histograms for the API endpoints
+	metrics := NewLatencyMetrics()
+	mux.HandleFunc("/metrics", metrics.Handler)
+	
 	// API endpoints
-	mux.HandleFunc("/api/products", func(w http.ResponseWriter, r *http.Request) {
-		db.mutex.RLock()
-		defer db.mutex.RUnlock()
-		
-		products := make([]Product, 0, len(db.products))
-		for _, product := range db.products {
-			products = append(products, product)
-		}
-		
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(products)
-	})
+	mux.HandleFunc("/api/products", metrics.Middleware(productsHandler(db)))
+	mux.HandleFunc("/api/products/batch", metrics.Middleware(productsBatchHandler(db)))
 	
 	mux.HandleFunc("/api/products/", func(w http.ResponseWriter, r *http.Request) {
 		id := r.URL.Path[len("/api/products/"):]
@@ -124,82 +365,15 @@ func main() {
 		json.NewEncoder(w).Encode(product)
 	})
 	
-	// Search endpoint (CPU intensive)
-	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query().Get("q")
-		if query == "" {
-			http.Error(w, "Missing query parameter", http.StatusBadRequest)
-			return
-		}
-		
-		db.mutex.RLock()
-		defer db.mutex.RUnlock()
-		
-		results := make([]Product, 0)
-		
-		// Intentionally inefficient search to generate CPU load
-		for _, product := range db.products {
-			// Simple string matching
-			matches := false
-			
-			// Check name
-			if containsIgnoreCase(product.Name, query) {
-				matches = true
-			}
-			
-			// Check description (inefficient)
-			if containsIgnoreCase(product.Description, query) {
-				matches = true
-			}
-			
-			// Check categories (inefficient)
-			for _, category := range product.Categories {
-				if containsIgnoreCase(category, query) {
-					matches = true
-					break
-				}
-			}
-			
-			if matches {
-				results = append(results, product)
-			}
-		}
-		
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(results)
-	})
+	// Search endpoint (CPU intensive with mode=scan, indexed with mode=index)
+	mux.HandleFunc("/api/search", metrics.Middleware(searchHandler(db)))
 	
 	// Load test endpoint
-	mux.HandleFunc("/api/loadtest", func(w http.ResponseWriter, r *http.Request) {
-		iterations := 1000000
-		result := 0
-		
-		// CPU-bound work
-		for i := 0; i < iterations; i++ {
-			result += i * i
-		}
-		
-		// Memory allocation
-		data := make([]byte, 10*1024*1024) // 10MB
-		for i := range data {
-			data[i] = byte(rand.Intn(256))
-		}
-		
-		fmt.Fprintf(w, "Load test completed: %d\n", result)
-	})
+	mux.HandleFunc("/api/loadtest", metrics.Middleware(loadTestHandler))
 	
 	// Status endpoint
-	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "Server is running\n")
-		fmt.Fprintf(w, "NumGoroutine: %d\n", runtime.NumGoroutine())
-		
-		var m runtime.MemStats
-		runtime.ReadMemStats(&m)
-		fmt.Fprintf(w, "Alloc: %v MiB\n", m.Alloc/1024/1024)
-		fmt.Fprintf(w, "TotalAlloc: %v MiB\n", m.TotalAlloc/1024/1024)
-		fmt.Fprintf(w, "Sys: %v MiB\n", m.Sys/1024/1024)
-		fmt.Fprintf(w, "NumGC: %v\n", m.NumGC)
-	})
+	mux.HandleFunc("/status", statusHandler(db))
+	mux.HandleFunc("/status.json", statusJSONHandler(db))
 	
 	// Get the port from environment or use default
 	port := os.Getenv("PORT")
@@ -207,38 +381,125 @@ func main() {
 		port = "8080"
 	}
 	
+	// Connection timeouts and h2c, so load tests can keep connection churn out of the profiles
+	serverConfig, err := server.ConfigFromEnv(os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	
 	// Start the server
 	serverAddr := ":" + port
-	fmt.Printf("Starting server on %s\n", serverAddr)
+	fmt.Printf("Starting server on %s (%s)\n", serverAddr, serverConfig)
 	fmt.Printf("pprof enabled at /debug/pprof/\n")
-	log.Fatal(http.ListenAndServe(serverAddr, mux))
+	
+	// Stop on Ctrl-C or SIGTERM, letting in-flight requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := serverConfig.ListenAndServe(ctx, serverAddr, mux, server.DefaultShutdownTimeout)
+	
+	// Flush the profiles even if the server failed
+	if err := stopProfiles(); err != nil {
+		log.Print(err)
+	}
+	if serveErr != nil {
+		log.Fatal(serveErr)
+	}
+}
+
+// Bounds for /api/loadtest parameters
+const (
+	defaultLoadTestIters = 1000000
+	maxLoadTestIters     = 2000000000 // Fits in int on 32-bit platforms
+	defaultLoadTestAlloc = 10 * 1024 * 1024 // 10MB
+	maxLoadTestAlloc     = 1024 * 1024 * 1024
+)
+
+// How many load test iterations run between checks for cancellation
+const loadTestCheckEvery = 1 << 20
+
+// runLoadTest does iters iterations of CPU-bound work followed by an alloc
+// byte allocation filled with random data, returning the computed result and
+// the elapsed wall time. It gives up with ctx's error once ctx is done, so a
+// long test stops when its client goes away.
+func runLoadTest(ctx context.Context, iters, alloc int) (int, time.Duration, error) {
+	start := time.Now()
+	result := 0
+
+	// CPU-bound work
+	var err error
+	traceRegion(ctx, "loadtest-cpu", func() {
+		for i := 0; i < iters; i++ {
+			if i%loadTestCheckEvery == 0 {
+				if err = ctx.Err(); err != nil {
+					return
+				}
+			}
+			result += i * i
+		}
+	})
+	if err != nil {
+		return result, time.Since(start), err
+	}
+
+	// Memory allocation
+	traceRegion(ctx, "loadtest-alloc", func() {
+		data := make([]byte, alloc)
+		for i := range data {
+			data[i] = byte(rand.Intn(256))
+		}
+	})
+
+	return result, time.Since(start), nil
 }
 
-// containsIgnoreCase checks if a string contains a substring, ignoring case
+// loadTestHandler runs a load test sized by ?iters=N (default 1,000,000) and
+// ?alloc=BYTES (default 10MB), so CPU profiles can be captured at a chosen
+// intensity
+func loadTestHandler(w http.ResponseWriter, r *http.Request) {
+	iters, alloc := defaultLoadTestIters, defaultLoadTestAlloc
+	if param := r.URL.Query().Get("iters"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxLoadTestIters {
+			http.Error(w, fmt.Sprintf("Invalid iters parameter (must be between 1 and %d)", maxLoadTestIters), http.StatusBadRequest)
+			return
+		}
+		iters = n
+	}
+	if param := r.URL.Query().Get("alloc"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 0 || n > maxLoadTestAlloc {
+			http.Error(w, fmt.Sprintf("Invalid alloc parameter (must be between 0 and %d bytes)", maxLoadTestAlloc), http.StatusBadRequest)
+			return
+		}
+		alloc = n
+	}
+
+	result, elapsed, err := runLoadTest(r.Context(), iters, alloc)
+	if err != nil {
+		// The client went away, so there is no one to answer
+		traceLog(r.Context(), "loadtest", "cancelled after %v: %v", elapsed, err)
+		return
+	}
+	traceLog(r.Context(), "loadtest", "%d iterations, %d bytes in %v", iters, alloc, elapsed)
+
+	fmt.Fprintf(w, "Load test completed: %d\n", result)
+	fmt.Fprintf(w, "Iterations: %d, allocated: %d bytes\n", iters, alloc)
+	fmt.Fprintf(w, "Elapsed: %v\n", elapsed)
+}
+
+// containsIgnoreCase checks if a string contains a substring, ignoring case.
+// Lowering is Unicode-aware, so accented and non-Latin queries match too.
 func containsIgnoreCase(s, substr string) bool {
 	s, substr = toLower(s), toLower(substr)
 	return contains(s, substr)
 }
 
-// toLower converts a string to lowercase
+// toLower converts a string to lowercase, including non-ASCII letters
 func toLower(s string) string {
-	result := make([]byte, len(s))
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c >= 'A' && c <= 'Z' {
-			c += 32
-		}
-		result[i] = c
-	}
-	return string(result)
+	return strings.ToLower(s)
 }
 
 // contains checks if a string contains a substring
 func contains(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
\ No newline at end of file
+	return strings.Contains(s, substr)
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"pprofviz/examples/internal/randseed"
 )
 
 func TestProductsEndpoint(t *testing.T) {
@@ -16,40 +21,92 @@ func TestProductsEndpoint(t *testing.T) {
 	mux := http.NewServeMux()
 	
 	// Register the products endpoint
-	mux.HandleFunc("/api/products", func(w http.ResponseWriter, r *http.Request) {
-		db.mutex.RLock()
-		defer db.mutex.RUnlock()
-		
-		products := make([]Product, 0, len(db.products))
-		for _, product := range db.products {
-			products = append(products, product)
-		}
-		
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(products)
-	})
+	mux.HandleFunc("/api/products", productsHandler(db))
 	
-	// Create a request to the products endpoint
-	req := httptest.NewRequest("GET", "/api/products", nil)
-	recorder := httptest.NewRecorder()
-	
-	// Serve the request
-	mux.ServeHTTP(recorder, req)
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedIDs    []int // First and last product IDs, nil for an empty page
+		expectedLen    int
+	}{
+		{"Default limit", "/api/products", http.StatusOK, []int{1, 50}, 50},
+		{"Custom limit and offset", "/api/products?limit=10&offset=20", http.StatusOK, []int{21, 30}, 10},
+		{"Last partial page", "/api/products?limit=30&offset=990", http.StatusOK, []int{991, 1000}, 10},
+		{"Out of range offset", "/api/products?offset=5000", http.StatusOK, nil, 0},
+		{"Invalid limit", "/api/products?limit=0", http.StatusBadRequest, nil, 0},
+		{"Limit too large", "/api/products?limit=5000", http.StatusBadRequest, nil, 0},
+		{"Negative offset", "/api/products?offset=-1", http.StatusBadRequest, nil, 0},
+	}
 	
-	// Check the status code
-	if recorder.Code != http.StatusOK {
-		t.Errorf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			recorder := httptest.NewRecorder()
+			
+			// Serve the request
+			mux.ServeHTTP(recorder, req)
+			
+			// Check the status code
+			if recorder.Code != tc.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+			
+			if total := recorder.Header().Get("X-Total-Count"); total != "1000" {
+				t.Errorf("Expected X-Total-Count 1000, got %q", total)
+			}
+			
+			// Decode the response; an empty page must be [] rather than null
+			if tc.expectedLen == 0 && strings.TrimSpace(recorder.Body.String()) != "[]" {
+				t.Errorf("Expected an empty array, got %s", recorder.Body.String())
+			}
+			var products []Product
+			if err := json.Unmarshal(recorder.Body.Bytes(), &products); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			
+			if len(products) != tc.expectedLen {
+				t.Fatalf("Expected %d products, got %d", tc.expectedLen, len(products))
+			}
+			if tc.expectedIDs != nil {
+				if products[0].ID != tc.expectedIDs[0] || products[len(products)-1].ID != tc.expectedIDs[1] {
+					t.Errorf("Expected products %d to %d, got %d to %d", tc.expectedIDs[0], tc.expectedIDs[1],
+						products[0].ID, products[len(products)-1].ID)
+				}
+				for i := 1; i < len(products); i++ {
+					if products[i].ID != products[i-1].ID+1 {
+						t.Errorf("Products not ordered by ID at index %d", i)
+						break
+					}
+				}
+			}
+		})
 	}
+}
+
+func TestNewDatabaseSeeded(t *testing.T) {
+	// The same fixed seed generates the same products
+	newSeeded := func(seed string) *Database {
+		if _, _, err := randseed.Apply(seed); err != nil {
+			t.Fatalf("Failed to apply seed: %v", err)
+		}
+		return NewDatabase()
+	}
+	first, second := newSeeded("42"), newSeeded("42")
 	
-	// Decode the response
-	var products []Product
-	if err := json.Unmarshal(recorder.Body.Bytes(), &products); err != nil {
-		t.Errorf("Failed to decode response: %v", err)
+	for id, product := range first.products {
+		other := second.products[id]
+		if product.Description != other.Description || product.Price != other.Price {
+			t.Fatalf("Product %d differs between databases with the same seed: %+v and %+v", id, product, other)
+		}
 	}
 	
-	// Verify the response
-	if len(products) != 1000 {
-		t.Errorf("Expected 1000 products, got %d", len(products))
+	other := newSeeded("43")
+	if first.products[1].Description == other.products[1].Description {
+		t.Error("Expected a different seed to generate different descriptions")
 	}
 }
 
@@ -61,44 +118,7 @@ func TestSearchEndpoint(t *testing.T) {
 	mux := http.NewServeMux()
 	
 	// Register the search endpoint
-	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query().Get("q")
-		if query == "" {
-			http.Error(w, "Missing query parameter", http.StatusBadRequest)
-			return
-		}
-		
-		db.mutex.RLock()
-		defer db.mutex.RUnlock()
-		
-		results := make([]Product, 0)
-		
-		for _, product := range db.products {
-			matches := false
-			
-			if containsIgnoreCase(product.Name, query) {
-				matches = true
-			}
-			
-			if containsIgnoreCase(product.Description, query) {
-				matches = true
-			}
-			
-			for _, category := range product.Categories {
-				if containsIgnoreCase(category, query) {
-					matches = true
-					break
-				}
-			}
-			
-			if matches {
-				results = append(results, product)
-			}
-		}
-		
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(results)
-	})
+	mux.HandleFunc("/api/search", searchHandler(db))
 	
 	testCases := []struct {
 		name          string
@@ -186,6 +206,12 @@ func TestContainsIgnoreCase(t *testing.T) {
 		{"", "test", false},
 		{"test", "", true},
 		{"AbCdEf", "CDe", true},
+		{"Café Crème", "CAFÉ", true},
+		{"ÉCLAIR", "éclair", true},
+		{"Москва", "МОСК", true},
+		{"Αθήνα", "ΑΘΉΝΑ", true},
+		{"東京タワー", "タワー", true},
+		{"Café", "cafe", false},
 	}
 	
 	for _, tc := range testCases {
@@ -208,6 +234,10 @@ func TestToLower(t *testing.T) {
 		{"123", "123"},
 		{"HeLlO123", "hello123"},
 		{"", ""},
+		{"ÀÉÎÕÜ", "àéîõü"},
+		{"ПРИВЕТ", "привет"},
+		{"ΣΟΦΙΑ", "σοφια"},
+		{"日本", "日本"},
 	}
 	
 	for _, tc := range testCases {
@@ -230,6 +260,8 @@ func TestContains(t *testing.T) {
 		{"", "test", false},
 		{"test", "", true},
 		{"abcdef", "cde", true},
+		{"crème brûlée", "brûlée", true},
+		{"naïve", "naive", false},
 	}
 	
 	for _, tc := range testCases {
@@ -239,4 +271,227 @@ func TestContains(t *testing.T) {
 				tc.s, tc.substr, result, tc.expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestRunLoadTestScalesWithIters(t *testing.T) {
+	_, small, _ := runLoadTest(context.Background(), 1, 0)
+	_, large, _ := runLoadTest(context.Background(), 50000000, 0)
+	
+	if large <= small {
+		t.Errorf("Expected 50M iterations to take longer than 1, got %v vs %v", large, small)
+	}
+	
+	// The result is the sum of squares below iters
+	if result, _, _ := runLoadTest(context.Background(), 4, 0); result != 0+1+4+9 {
+		t.Errorf("Expected result 14 for 4 iterations, got %d", result)
+	}
+}
+
+func TestRunLoadTestStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	
+	// Far more iterations than finish before the deadline
+	_, elapsed, err := runLoadTest(ctx, maxLoadTestIters, maxLoadTestAlloc)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("Expected the load test to stop soon after the deadline, took %v", elapsed)
+	}
+}
+
+func TestLoadTestHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Custom params", "/api/loadtest?iters=4&alloc=1024", http.StatusOK, "Load test completed: 14"},
+		{"Reports sizes", "/api/loadtest?iters=10&alloc=0", http.StatusOK, "Iterations: 10, allocated: 0 bytes"},
+		{"Zero iters", "/api/loadtest?iters=0", http.StatusBadRequest, "Invalid iters parameter"},
+		{"Invalid iters", "/api/loadtest?iters=lots", http.StatusBadRequest, "Invalid iters parameter"},
+		{"Negative alloc", "/api/loadtest?alloc=-1", http.StatusBadRequest, "Invalid alloc parameter"},
+		{"Alloc too large", "/api/loadtest?alloc=99999999999", http.StatusBadRequest, "Invalid alloc parameter"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.url, nil)
+			recorder := httptest.NewRecorder()
+			
+			loadTestHandler(recorder, req)
+			
+			if recorder.Code != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, recorder.Code)
+			}
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected body to contain %q, got: %s", tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	db := NewDatabase()
+	handler := productsHandler(db)
+	
+	body := `{"id": 7, "name": "Profiling Handbook", "price": 39.5, "categories": ["Books"]}`
+	req := httptest.NewRequest("POST", "/api/products", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body.String())
+	}
+	var created Product
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	// The ID in the body is ignored in favour of the next free one
+	if created.ID != 1001 || created.Name != "Profiling Handbook" || created.Price != 39.5 {
+		t.Errorf("Unexpected created product: %+v", created)
+	}
+	if location := recorder.Header().Get("Location"); location != "/api/products/1001" {
+		t.Errorf("Expected Location /api/products/1001, got %q", location)
+	}
+	
+	// The new product is listed and searchable
+	products, total := db.Page(1000, 10)
+	if total != 1001 || len(products) != 1 || products[0].Name != "Profiling Handbook" {
+		t.Errorf("Expected the new product at the end of the listing, got %d products: %+v", total, products)
+	}
+	if results := db.SearchIndex("handbook"); len(results) != 1 {
+		t.Errorf("Expected the new product in search results, got %d", len(results))
+	}
+	
+	// IDs keep counting up
+	req = httptest.NewRequest("POST", "/api/products", strings.NewReader(`{"name": "Free Sticker", "price": 0}`))
+	recorder = httptest.NewRecorder()
+	handler(recorder, req)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil || created.ID != 1002 {
+		t.Errorf("Expected product 1002, got %+v (%v)", created, err)
+	}
+}
+
+func TestCreateProductInvalid(t *testing.T) {
+	db := NewDatabase()
+	handler := productsHandler(db)
+	
+	testCases := []struct {
+		name         string
+		body         string
+		expectedBody string
+	}{
+		{"Malformed JSON", `{"name": "Broken"`, "Invalid product"},
+		{"Wrong type", `{"name": "Broken", "price": "cheap"}`, "Invalid product"},
+		{"Missing name", `{"price": 10}`, "name is required"},
+		{"Blank name", `{"name": "  ", "price": 10}`, "name is required"},
+		{"Negative price", `{"name": "Refund", "price": -1}`, "price must be 0 or more"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/products", strings.NewReader(tc.body))
+			recorder := httptest.NewRecorder()
+			handler(recorder, req)
+			
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+			}
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected body to contain %q, got: %s", tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+	
+	if _, total := db.Page(0, 1); total != 1000 {
+		t.Errorf("Expected no products inserted, got %d", total)
+	}
+	
+	req := httptest.NewRequest("DELETE", "/api/products", nil)
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d for DELETE, got %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+}
+
+func TestProductsBatch(t *testing.T) {
+	db := NewDatabase()
+	handler := productsBatchHandler(db)
+	
+	req := httptest.NewRequest("GET", "/api/products/batch?ids=3,2000,1,%201000,-5", nil)
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+	var batch productBatch
+	if err := json.Unmarshal(recorder.Body.Bytes(), &batch); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	
+	// Found products keep the requested order
+	var ids []int
+	for _, product := range batch.Products {
+		ids = append(ids, product.ID)
+	}
+	if len(ids) != 3 || ids[0] != 3 || ids[1] != 1 || ids[2] != 1000 {
+		t.Errorf("Expected products 3, 1 and 1000, got %v", ids)
+	}
+	if len(batch.Missing) != 2 || batch.Missing[0] != 2000 || batch.Missing[1] != -5 {
+		t.Errorf("Expected 2000 and -5 missing, got %v", batch.Missing)
+	}
+	
+	// Nothing missing is still a list
+	recorder = httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/products/batch?ids=7", nil))
+	if !strings.Contains(recorder.Body.String(), `"missing":[]`) {
+		t.Errorf("Expected an empty missing list, got %s", recorder.Body.String())
+	}
+}
+
+func TestProductsBatchInvalid(t *testing.T) {
+	handler := productsBatchHandler(NewDatabase())
+	
+	ids := make([]string, maxBatchIDs+1)
+	for i := range ids {
+		ids[i] = "1"
+	}
+	
+	testCases := []struct {
+		name         string
+		url          string
+		expectedBody string
+	}{
+		{"No ids", "/api/products/batch", "Missing ids parameter"},
+		{"Not a number", "/api/products/batch?ids=1,two,3", "Invalid id"},
+		{"Empty entry", "/api/products/batch?ids=1,,3", "Invalid id"},
+		{"Over the cap", "/api/products/batch?ids=" + strings.Join(ids, ","), "Too many ids"},
+	}
+	
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler(recorder, httptest.NewRequest("GET", tc.url, nil))
+			
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+			}
+			if !strings.Contains(recorder.Body.String(), tc.expectedBody) {
+				t.Errorf("Expected body to contain %q, got: %s", tc.expectedBody, recorder.Body.String())
+			}
+		})
+	}
+	
+	// Exactly the cap is fine
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("GET", "/api/products/batch?ids="+strings.Join(ids[1:], ","), nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d for %d ids, got %d", http.StatusOK, maxBatchIDs, recorder.Code)
+	}
+}
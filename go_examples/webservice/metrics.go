@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Upper bounds of the latency histogram buckets; slower requests fall in a
+// final +Inf bucket
+var latencyBuckets = []time.Duration{
+	1 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// latencyHistogram counts request durations per bucket
+type latencyHistogram struct {
+	buckets  []uint64 // One per latencyBuckets entry plus +Inf, not cumulative
+	count    uint64
+	sumNanos uint64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{buckets: make([]uint64, len(latencyBuckets)+1)}
+}
+
+// observe records one request duration
+func (h *latencyHistogram) observe(d time.Duration) {
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	atomic.AddUint64(&h.buckets[i], 1)
+	atomic.AddUint64(&h.sumNanos, uint64(d))
+	atomic.AddUint64(&h.count, 1)
+}
+
+// LatencyMetrics records request durations into a histogram per path
+type LatencyMetrics struct {
+	mutex      sync.RWMutex
+	histograms map[string]*latencyHistogram
+}
+
+// NewLatencyMetrics creates an empty set of latency histograms
+func NewLatencyMetrics() *LatencyMetrics {
+	return &LatencyMetrics{histograms: make(map[string]*latencyHistogram)}
+}
+
+// histogram returns the histogram for path, creating it on first use
+func (m *LatencyMetrics) histogram(path string) *latencyHistogram {
+	m.mutex.RLock()
+	h, ok := m.histograms[path]
+	m.mutex.RUnlock()
+	if ok {
+		return h
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if h, ok = m.histograms[path]; !ok {
+		h = newLatencyHistogram()
+		m.histograms[path] = h
+	}
+	return h
+}
+
+// Middleware wraps a handler so the duration of each request is recorded
+// under the request path
+func (m *LatencyMetrics) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next(w, r)
+		m.histogram(r.URL.Path).observe(time.Since(start))
+	}
+}
+
+// Handler serves the histograms in the Prometheus text format, with
+// cumulative bucket counts and durations in seconds
+func (m *LatencyMetrics) Handler(w http.ResponseWriter, r *http.Request) {
+	m.mutex.RLock()
+	paths := make([]string, 0, len(m.histograms))
+	for path := range m.histograms {
+		paths = append(paths, path)
+	}
+	m.mutex.RUnlock()
+	sort.Strings(paths)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Request latency by path.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, path := range paths {
+		h := m.histogram(path)
+		label := strconv.Quote(path)
+
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += atomic.LoadUint64(&h.buckets[i])
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{path=%s,le=\"%g\"} %d\n", label, bound.Seconds(), cumulative)
+		}
+		cumulative += atomic.LoadUint64(&h.buckets[len(latencyBuckets)])
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{path=%s,le=\"+Inf\"} %d\n", label, cumulative)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{path=%s} %g\n", label,
+			time.Duration(atomic.LoadUint64(&h.sumNanos)).Seconds())
+		fmt.Fprintf(w, "http_request_duration_seconds_count{path=%s} %d\n", label, atomic.LoadUint64(&h.count))
+	}
+}
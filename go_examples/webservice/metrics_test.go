@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// metricLine returns the value of the metrics line starting with prefix
+func metricLine(t *testing.T, body, prefix string) string {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, prefix+" ") {
+			return strings.TrimPrefix(line, prefix+" ")
+		}
+	}
+	t.Fatalf("No %s line in metrics:\n%s", prefix, body)
+	return ""
+}
+
+func TestLatencyMiddleware(t *testing.T) {
+	db := NewDatabase()
+	metrics := NewLatencyMetrics()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", metrics.Middleware(searchHandler(db)))
+	mux.HandleFunc("/metrics", metrics.Handler)
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		return recorder.Body.String()
+	}
+
+	for i := 1; i <= 3; i++ {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/search?q=product", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 from search, got %d", recorder.Code)
+		}
+
+		body := scrape()
+		count := metricLine(t, body, `http_request_duration_seconds_count{path="/api/search"}`)
+		if count != strconv.Itoa(i) {
+			t.Errorf("Expected count %d after %d searches, got %s", i, i, count)
+		}
+		if inf := metricLine(t, body, `http_request_duration_seconds_bucket{path="/api/search",le="+Inf"}`); inf != count {
+			t.Errorf("Expected the +Inf bucket to match the count %s, got %s", count, inf)
+		}
+	}
+
+	// Only wrapped paths are recorded
+	if strings.Contains(scrape(), `path="/metrics"`) {
+		t.Error("Expected /metrics itself not to be recorded")
+	}
+}
+
+func TestLatencyHistogramBuckets(t *testing.T) {
+	h := newLatencyHistogram()
+	h.observe(500 * time.Microsecond)
+	h.observe(time.Millisecond)
+	h.observe(7 * time.Millisecond)
+	h.observe(time.Minute)
+
+	expected := map[int]uint64{0: 2, 2: 1, len(latencyBuckets): 1}
+	for i, n := range h.buckets {
+		if n != expected[i] {
+			t.Errorf("Bucket %d: expected %d, got %d", i, expected[i], n)
+		}
+	}
+	if h.count != 4 {
+		t.Errorf("Expected count 4, got %d", h.count)
+	}
+}
+
+func TestLatencyMetricsCumulative(t *testing.T) {
+	metrics := NewLatencyMetrics()
+	metrics.histogram("/api/loadtest").observe(3 * time.Millisecond)
+	metrics.histogram("/api/loadtest").observe(30 * time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	for le, expected := range map[string]string{"0.001": "0", "0.005": "1", "0.025": "1", "0.05": "2", "+Inf": "2"} {
+		prefix := `http_request_duration_seconds_bucket{path="/api/loadtest",le="` + le + `"}`
+		if got := metricLine(t, body, prefix); got != expected {
+			t.Errorf("Bucket le=%s: expected %s, got %s", le, expected, got)
+		}
+	}
+	if sum := metricLine(t, body, `http_request_duration_seconds_sum{path="/api/loadtest"}`); sum != "0.033" {
+		t.Errorf("Expected sum 0.033, got %s", sum)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// matchesQuery reports whether the product's name, description or any
+// category contains query, ignoring case
+func matchesQuery(product Product, query string) bool {
+	// Check name
+	if containsIgnoreCase(product.Name, query) {
+		return true
+	}
+
+	// Check description (inefficient)
+	if containsIgnoreCase(product.Description, query) {
+		return true
+	}
+
+	// Check categories (inefficient)
+	for _, category := range product.Categories {
+		if containsIgnoreCase(category, query) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchScan returns the products matching query by checking every product.
+// This is intentionally inefficient to generate CPU load.
+func (db *Database) SearchScan(query string) []Product {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	results := make([]Product, 0)
+	for _, product := range db.products {
+		if matchesQuery(product, query) {
+			results = append(results, product)
+		}
+	}
+	return results
+}
+
+// searchIndex maps each lowercased trigram in a product's searchable text to
+// the IDs of the products containing it. A product can only contain the
+// query if it contains every trigram of the query.
+type searchIndex struct {
+	trigrams map[string][]int // Product IDs in ascending order
+	ids      []int            // Every product ID in ascending order
+}
+
+// trigrams returns the distinct three-rune substrings of s
+func trigrams(s string) map[string]bool {
+	runes := []rune(toLower(s))
+	grams := make(map[string]bool)
+	for i := 0; i+3 <= len(runes); i++ {
+		grams[string(runes[i:i+3])] = true
+	}
+	return grams
+}
+
+// buildSearchIndex indexes the name, description and categories of products
+func buildSearchIndex(products map[int]Product) *searchIndex {
+	index := &searchIndex{trigrams: make(map[string][]int)}
+	for id := range products {
+		index.ids = append(index.ids, id)
+	}
+	sort.Ints(index.ids)
+
+	for _, id := range index.ids {
+		product := products[id]
+		grams := trigrams(product.Name)
+		for gram := range trigrams(product.Description) {
+			grams[gram] = true
+		}
+		for _, category := range product.Categories {
+			for gram := range trigrams(category) {
+				grams[gram] = true
+			}
+		}
+		for gram := range grams {
+			index.trigrams[gram] = append(index.trigrams[gram], id)
+		}
+	}
+	return index
+}
+
+// candidates returns the IDs of products containing every trigram of query.
+// Queries shorter than a trigram can't be narrowed, so every ID is returned.
+func (index *searchIndex) candidates(query string) []int {
+	grams := trigrams(query)
+	if len(grams) == 0 {
+		return index.ids
+	}
+
+	var result []int
+	first := true
+	for gram := range grams {
+		ids := index.trigrams[gram]
+		if first {
+			result = ids
+			first = false
+		} else {
+			result = intersectSorted(result, ids)
+		}
+		if len(result) == 0 {
+			return nil
+		}
+	}
+	return result
+}
+
+// intersectSorted returns the values present in both ascending slices
+func intersectSorted(a, b []int) []int {
+	result := make([]int, 0, min(len(a), len(b)))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// SearchIndex returns the same products as SearchScan, ordered by ID, using
+// a trigram index that is built on first use and rebuilt after products change
+func (db *Database) SearchIndex(query string) []Product {
+	for {
+		db.mutex.RLock()
+		if db.index != nil {
+			results := make([]Product, 0)
+			// Trigrams only narrow the candidates; confirm each match
+			for _, id := range db.index.candidates(query) {
+				if product := db.products[id]; matchesQuery(product, query) {
+					results = append(results, product)
+				}
+			}
+			db.mutex.RUnlock()
+			return results
+		}
+		db.mutex.RUnlock()
+
+		db.mutex.Lock()
+		if db.index == nil {
+			db.index = buildSearchIndex(db.products)
+		}
+		db.mutex.Unlock()
+	}
+}
+
+// searchHandler serves /api/search?q=...; mode=scan (the default) checks every
+// product, mode=index answers from the search index. Results are cached per
+// mode and lowercased query unless cache=off, which profiles the cold path;
+// the X-Search-Cache header says whether the cache answered.
+func searchHandler(db *Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		if query == "" {
+			http.Error(w, "Missing query parameter", http.StatusBadRequest)
+			return
+		}
+
+		var search func(string) []Product
+		var region string
+		mode := r.URL.Query().Get("mode")
+		switch mode {
+		case "", "scan":
+			mode, search, region = "scan", db.SearchScan, "search-scan"
+		case "index":
+			search, region = db.SearchIndex, "search-index"
+		default:
+			http.Error(w, "Invalid mode parameter (must be scan or index)", http.StatusBadRequest)
+			return
+		}
+
+		var useCache bool
+		switch r.URL.Query().Get("cache") {
+		case "", "on":
+			useCache = true
+		case "off":
+		default:
+			http.Error(w, "Invalid cache parameter (must be on or off)", http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		key := searchCacheKey(mode, query)
+		status := "off"
+		var results []Product
+		var cached bool
+		var generation uint64
+		if useCache {
+			results, generation, cached = db.cache.get(key)
+			status = "miss"
+		}
+		if cached {
+			status = "hit"
+		} else {
+			traceRegion(ctx, region, func() { results = search(query) })
+			if useCache {
+				db.cache.put(key, generation, results)
+			}
+		}
+		traceLog(ctx, "search", "query %q matched %d products (cache %s)", query, len(results), status)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Search-Cache", status)
+		json.NewEncoder(w).Encode(results)
+	}
+}
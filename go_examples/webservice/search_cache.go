@@ -0,0 +1,93 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Searches kept by the search result cache
+const searchCacheSize = 128
+
+// searchCache is a least recently used cache of search results keyed by mode
+// and lowercased query. It has its own mutex, so a cache hit never waits for
+// the database lock. Any product write empties it.
+type searchCache struct {
+	mutex      sync.Mutex
+	size       int
+	entries    map[string]*list.Element
+	order      *list.List // Of *searchCacheEntry, most recently used first
+	generation uint64     // Incremented by every invalidation
+}
+
+// searchCacheEntry is one cached search
+type searchCacheEntry struct {
+	key     string
+	results []Product
+}
+
+// newSearchCache returns an empty cache holding up to size searches
+func newSearchCache(size int) *searchCache {
+	return &searchCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// searchCacheKey is the cache key of query searched in mode
+func searchCacheKey(mode, query string) string {
+	return mode + "\x00" + toLower(query)
+}
+
+// get returns the cached results for key, which callers must not modify.
+// On a miss it returns the generation to pass to put.
+func (c *searchCache) get(key string) (results []Product, generation uint64, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, found := c.entries[key]; found {
+		c.order.MoveToFront(element)
+		return element.Value.(*searchCacheEntry).results, c.generation, true
+	}
+	return nil, c.generation, false
+}
+
+// put caches results for key, evicting the least recently used search when
+// full. Results computed before an invalidation are dropped, since the write
+// may have changed them.
+func (c *searchCache) put(key string, generation uint64, results []Product) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if element, found := c.entries[key]; found {
+		element.Value.(*searchCacheEntry).results = results
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&searchCacheEntry{key, results})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*searchCacheEntry).key)
+	}
+}
+
+// invalidate empties the cache
+func (c *searchCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// len returns the number of cached searches
+func (c *searchCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
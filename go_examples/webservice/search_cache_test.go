@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// search requests url from mux and returns the response body and its
+// X-Search-Cache header
+func search(t *testing.T, mux *http.ServeMux, url string) ([]byte, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", url, nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("%s: expected status %d, got %d", url, http.StatusOK, recorder.Code)
+	}
+	return recorder.Body.Bytes(), recorder.Header().Get("X-Search-Cache")
+}
+
+// decodeProducts decodes a search response body
+func decodeProducts(t *testing.T, body []byte) []Product {
+	t.Helper()
+	var results []Product
+	if err := json.Unmarshal(body, &results); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return results
+}
+
+func TestSearchCacheHit(t *testing.T) {
+	db := NewDatabase()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", searchHandler(db))
+
+	first, status := search(t, mux, "/api/search?q=Product%201")
+	if status != "miss" {
+		t.Errorf("Expected the first search to miss the cache, got %q", status)
+	}
+
+	// The key ignores case, so this is the same search
+	second, status := search(t, mux, "/api/search?q=PRODUCT%201")
+	if status != "hit" {
+		t.Errorf("Expected the repeated search to hit the cache, got %q", status)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("Expected a cache hit to return identical results")
+	}
+
+	// Modes are cached separately, and cache=off always searches
+	if _, status := search(t, mux, "/api/search?q=product%201&mode=index"); status != "miss" {
+		t.Errorf("Expected an index search to miss the scan's entry, got %q", status)
+	}
+	off, status := search(t, mux, "/api/search?q=product%201&cache=off")
+	if status != "off" {
+		t.Errorf("Expected cache=off to bypass the cache, got %q", status)
+	}
+	if len(productIDs(decodeProducts(t, off))) != len(productIDs(decodeProducts(t, first))) {
+		t.Error("Expected the uncached search to find the same products")
+	}
+
+	req := httptest.NewRequest("GET", "/api/search?q=product&cache=maybe", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid cache parameter, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestSearchCacheInvalidation(t *testing.T) {
+	db := NewDatabase()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", searchHandler(db))
+
+	body, _ := search(t, mux, "/api/search?q=zyzzyva")
+	if results := decodeProducts(t, body); len(results) != 0 {
+		t.Fatalf("Expected no results before the product exists, got %d", len(results))
+	}
+
+	// Inserting a product must drop the cached empty result
+	product := db.InsertProduct(Product{Name: "Zyzzyva Beetle"})
+
+	body, status := search(t, mux, "/api/search?q=zyzzyva")
+	if status != "miss" {
+		t.Errorf("Expected a miss after the insert, got %q", status)
+	}
+	if results := decodeProducts(t, body); len(results) != 1 || results[0].ID != product.ID {
+		t.Errorf("Expected the inserted product, got %v", productIDs(results))
+	}
+}
+
+func TestSearchCacheEviction(t *testing.T) {
+	cache := newSearchCache(2)
+	_, generation, _ := cache.get("a")
+	cache.put("a", generation, []Product{{ID: 1}})
+	cache.put("b", generation, []Product{{ID: 2}})
+
+	// Using a makes b the least recently used
+	if _, _, ok := cache.get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	cache.put("c", generation, []Product{{ID: 3}})
+	if _, _, ok := cache.get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if cache.len() != 2 {
+		t.Errorf("Expected 2 cached searches, got %d", cache.len())
+	}
+
+	// A search that started before an invalidation isn't cached
+	cache.invalidate()
+	cache.put("d", generation, nil)
+	if cache.len() != 0 {
+		t.Errorf("Expected a stale put to be dropped, got %d cached searches", cache.len())
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// productIDs returns the sorted IDs of products
+func productIDs(products []Product) []int {
+	ids := make([]int, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func TestSearchModesMatch(t *testing.T) {
+	db := NewDatabase()
+	db.PutProduct(Product{ID: 1001, Name: "Crème Brûlée Torch", Categories: []string{"Kitchen"}})
+
+	queries := []string{
+		"product",
+		"Product 12",
+		"ELECTRONICS",
+		"ab",
+		"a",
+		"brûlée",
+		"kitchen",
+		"no such product anywhere",
+	}
+	// A slice of a real description, so the index has to match mid-text
+	desc := db.products[7].Description
+	queries = append(queries, desc[50:60])
+
+	for _, query := range queries {
+		scan := productIDs(db.SearchScan(query))
+		index := productIDs(db.SearchIndex(query))
+		if !reflect.DeepEqual(scan, index) {
+			t.Errorf("Query %q: scan found %d products, index found %d", query, len(scan), len(index))
+		}
+	}
+}
+
+func TestSearchIndexInvalidation(t *testing.T) {
+	db := NewDatabase()
+
+	if results := db.SearchIndex("zyzzyva"); len(results) != 0 {
+		t.Fatalf("Expected no results before the product exists, got %d", len(results))
+	}
+
+	// Adding a product must drop the index built by the search above
+	db.PutProduct(Product{ID: 5000, Name: "Zyzzyva Beetle"})
+
+	results := db.SearchIndex("zyzzyva")
+	if len(results) != 1 || results[0].ID != 5000 {
+		t.Errorf("Expected the new product from the rebuilt index, got %v", productIDs(results))
+	}
+}
+
+func TestSearchHandlerModes(t *testing.T) {
+	db := NewDatabase()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", searchHandler(db))
+
+	var byMode [2][]int
+	for i, url := range []string{"/api/search?q=product%201", "/api/search?q=product%201&mode=index"} {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", url, http.StatusOK, recorder.Code)
+		}
+		var results []Product
+		if err := json.Unmarshal(recorder.Body.Bytes(), &results); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", url, err)
+		}
+		byMode[i] = productIDs(results)
+	}
+	if len(byMode[0]) == 0 || !reflect.DeepEqual(byMode[0], byMode[1]) {
+		t.Errorf("Expected identical non-empty results, scan %v, index %v", byMode[0], byMode[1])
+	}
+
+	req := httptest.NewRequest("GET", "/api/search?q=product&mode=fast", nil)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown mode, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
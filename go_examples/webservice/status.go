@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"pprofviz/examples/internal/memstatus"
+	"pprofviz/examples/internal/server"
+)
+
+// serviceStatus is the state reported by /status and /status.json
+type serviceStatus struct {
+	Goroutines int `json:"goroutines"`
+	Products   int `json:"products"`
+	memstatus.Stats
+}
+
+// statusSnapshot gathers the service status once so the text and JSON views
+// always agree
+func statusSnapshot(db *Database) serviceStatus {
+	return serviceStatus{
+		Goroutines: runtime.NumGoroutine(),
+		Products:   db.Count(),
+		Stats:      memstatus.Read(),
+	}
+}
+
+// statusHandler prints the service status for humans, or serves it as JSON
+// when the request asks for it
+func statusHandler(db *Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if server.WantsJSON(r) {
+			statusJSONHandler(db)(w, r)
+			return
+		}
+		status := statusSnapshot(db)
+
+		fmt.Fprintf(w, "Server is running\n")
+		fmt.Fprintf(w, "NumGoroutine: %d\n", status.Goroutines)
+		fmt.Fprintf(w, "Products: %d\n", status.Products)
+
+		status.Stats.WriteText(w)
+	}
+}
+
+// statusJSONHandler serves the service status as JSON for scripts
+func statusJSONHandler(db *Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statusSnapshot(db))
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusHandler(t *testing.T) {
+	db := NewDatabase()
+
+	// Text is the default
+	recorder := httptest.NewRecorder()
+	statusHandler(db)(recorder, httptest.NewRequest("GET", "/status", nil))
+
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "Server is running\n") {
+		t.Errorf("Expected the text status by default, got:\n%s", body)
+	}
+	for _, line := range []string{"NumGoroutine: ", "Products: 1000", "Alloc: ", "TotalAlloc: ", "Sys: ", "NumGC: "} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in the status text, got:\n%s", line, body)
+		}
+	}
+
+	// JSON when asked for, by header or parameter
+	for _, tc := range []struct {
+		url    string
+		accept string
+	}{
+		{"/status", "application/json"},
+		{"/status?format=json", ""},
+	} {
+		req := httptest.NewRequest("GET", tc.url, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		recorder := httptest.NewRecorder()
+		statusHandler(db)(recorder, req)
+
+		if ct := recorder.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: expected JSON content type, got %q", tc.url, ct)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(recorder.Body.Bytes(), &fields); err != nil {
+			t.Fatalf("%s: invalid JSON response: %v", tc.url, err)
+		}
+		for _, name := range []string{"goroutines", "products", "allocMiB", "totalAllocMiB", "sysMiB", "numGC"} {
+			if _, ok := fields[name]; !ok {
+				t.Errorf("%s: missing %s in status: %s", tc.url, name, recorder.Body.String())
+			}
+		}
+
+		var status serviceStatus
+		json.Unmarshal(recorder.Body.Bytes(), &status)
+		if status.Goroutines <= 0 || status.Products != 1000 || status.SysMiB <= 0 {
+			t.Errorf("%s: unexpected status %+v", tc.url, status)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// traceRegionsEnabled turns on the execution trace regions and log messages
+// below. It is set from the -trace-regions flag at startup.
+var traceRegionsEnabled bool
+
+// traceRegion runs f, inside a trace region called name when trace regions are
+// enabled, so captures from /debug/pprof/trace show which part of a handler
+// the time went to
+func traceRegion(ctx context.Context, name string, f func()) {
+	if !traceRegionsEnabled {
+		f()
+		return
+	}
+	trace.WithRegion(ctx, name, f)
+}
+
+// traceLog records a message in the execution trace when trace regions are
+// enabled
+func traceLog(ctx context.Context, category, format string, args ...interface{}) {
+	if !traceRegionsEnabled {
+		return
+	}
+	trace.Logf(ctx, category, format, args...)
+}
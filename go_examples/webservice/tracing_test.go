@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime/trace"
+	"testing"
+)
+
+// captureTrace records an execution trace while serving each URL with handler
+func captureTrace(t *testing.T, handler http.HandlerFunc, urls ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatalf("Failed to start trace: %v", err)
+	}
+	for _, url := range urls {
+		req := httptest.NewRequest("GET", url, nil)
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		if recorder.Code != http.StatusOK {
+			trace.Stop()
+			t.Fatalf("Expected status %d for %s, got %d", http.StatusOK, url, recorder.Code)
+		}
+	}
+	trace.Stop()
+	return buf.Bytes()
+}
+
+func TestTraceRegions(t *testing.T) {
+	defer func(enabled bool) { traceRegionsEnabled = enabled }(traceRegionsEnabled)
+	traceRegionsEnabled = true
+	db := NewDatabase()
+
+	// Region names and log messages are stored as strings in the trace
+	data := captureTrace(t, searchHandler(db), "/api/search?q=product", "/api/search?q=product&mode=index")
+	for _, name := range []string{"search-scan", "search-index", `query "product" matched`} {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Errorf("Expected %q in the search trace", name)
+		}
+	}
+
+	data = captureTrace(t, loadTestHandler, "/api/loadtest?iters=1000&alloc=1024")
+	for _, name := range []string{"loadtest-cpu", "loadtest-alloc", "1000 iterations"} {
+		if !bytes.Contains(data, []byte(name)) {
+			t.Errorf("Expected %q in the load test trace", name)
+		}
+	}
+}
+
+func TestTraceRegionsDisabled(t *testing.T) {
+	defer func(enabled bool) { traceRegionsEnabled = enabled }(traceRegionsEnabled)
+	traceRegionsEnabled = false
+
+	data := captureTrace(t, loadTestHandler, "/api/loadtest?iters=1000&alloc=1024")
+	if bytes.Contains(data, []byte("loadtest-cpu")) {
+		t.Error("Expected no trace regions without -trace-regions")
+	}
+}
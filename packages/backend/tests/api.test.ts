@@ -21,6 +21,12 @@ jest.mock('../../server/storage', () => ({
   }
 }));
 
+// Mock the heapratio command so tests don't need a Go toolchain
+const mockFromData = jest.fn();
+jest.mock('../../server/services/heap-ratio', () => ({
+  HeapRatio: jest.fn().mockImplementation(() => ({ fromData: mockFromData }))
+}));
+
 describe('API Routes', () => {
   let app: express.Express;
   let server: any;
@@ -162,6 +168,73 @@ describe('API Routes', () => {
     });
   });
 
+  describe('GET /api/profiles/:id/heap-ratio', () => {
+    const heapProfile = {
+      id: 2,
+      filename: 'sample_heap_profile.pprof',
+      originalFilename: 'heap.pprof',
+      profileType: 'heap',
+      size: 2048,
+      description: 'Sample heap profile',
+      metadata: { topFunctions: [] },
+      uploadedAt: new Date().toISOString(),
+      isSaved: false,
+      data: 'base64data'
+    };
+
+    it('should return the retention flame graph of a heap profile', async () => {
+      const tree = {
+        name: 'root',
+        value: 14096,
+        ratio: 0.3,
+        children: [
+          { name: 'main.createLargeObject', value: 4096, ratio: 1 },
+          { name: 'main.randomString', value: 10000, ratio: 0.01 }
+        ]
+      };
+
+      (storage.getProfile as jest.Mock).mockResolvedValue(heapProfile);
+      mockFromData.mockResolvedValue(tree);
+
+      const response = await request(app)
+        .get('/api/profiles/2/heap-ratio')
+        .expect(200);
+
+      expect(response.body).toEqual(tree);
+      expect(storage.getProfile).toHaveBeenCalledWith(2);
+      expect(mockFromData).toHaveBeenCalledWith('base64data');
+    });
+
+    it('should reject profiles that are not heap profiles', async () => {
+      (storage.getProfile as jest.Mock).mockResolvedValue({ ...heapProfile, profileType: 'cpu' });
+
+      await request(app)
+        .get('/api/profiles/2/heap-ratio')
+        .expect(400);
+
+      expect(mockFromData).not.toHaveBeenCalled();
+    });
+
+    it('should return 404 for non-existent profile', async () => {
+      (storage.getProfile as jest.Mock).mockResolvedValue(undefined);
+
+      await request(app)
+        .get('/api/profiles/999/heap-ratio')
+        .expect(404);
+
+      expect(mockFromData).not.toHaveBeenCalled();
+    });
+
+    it('should return 500 when the profile cannot be analyzed', async () => {
+      (storage.getProfile as jest.Mock).mockResolvedValue(heapProfile);
+      mockFromData.mockRejectedValue(new Error('profile has no inuse_space samples'));
+
+      await request(app)
+        .get('/api/profiles/2/heap-ratio')
+        .expect(500);
+    });
+  });
+
   describe('PATCH /api/profiles/:id', () => {
     it('should update a profile', async () => {
       const profileUpdate = {
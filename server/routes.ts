@@ -6,6 +6,7 @@ import fs from "fs";
 import path from "path";
 import { PprofParser } from "./services/pprof-parser";
 import { PprofCli } from "./services/pprof-cli";
+import { HeapRatio } from "./services/heap-ratio";
 import { insertProfileSchema, insertConnectionSchema } from "@shared/schema";
 import { z } from "zod";
 import { fromZodError } from "zod-validation-error";
@@ -32,6 +33,7 @@ export async function registerRoutes(app: express.Express): Promise<Server> {
   // Initialize pprof services
   const pprofParser = new PprofParser();
   const pprofCli = new PprofCli();
+  const heapRatio = new HeapRatio();
 
   // API routes - all prefixed with /api
   const apiRouter = express.Router();
@@ -84,6 +86,29 @@ export async function registerRoutes(app: express.Express): Promise<Server> {
     }
   });
 
+  // Retention flame graph of a heap profile: frames sized by allocated bytes
+  // and colored by how much of each function's allocation is still in use
+  apiRouter.get("/profiles/:id/heap-ratio", async (req: Request, res: Response) => {
+    try {
+      const id = parseInt(req.params.id);
+      const profile = await storage.getProfile(id);
+      
+      if (!profile) {
+        return res.status(404).json({ message: "Profile not found" });
+      }
+      
+      if (profile.profileType !== "heap") {
+        return res.status(400).json({ message: "The retention view needs a heap profile" });
+      }
+      
+      const tree = await heapRatio.fromData(profile.data);
+      res.json(tree);
+    } catch (error) {
+      console.error("Error building heap retention view:", error);
+      res.status(500).json({ message: "Failed to build heap retention view" });
+    }
+  });
+
   // Upload and parse pprof file
   apiRouter.post("/profiles/upload", upload.single("file"), async (req: Request, res: Response) => {
     try {
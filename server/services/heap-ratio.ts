@@ -0,0 +1,57 @@
+import { spawn } from 'child_process';
+import path from 'path';
+
+// One frame of a heap profile's allocation flame graph, as printed by
+// go_examples/cmd/heapratio. Value is the bytes allocated beneath the frame and
+// ratio the fraction of its function's allocations still in use.
+export interface RatioNode {
+  name: string;
+  value: number;
+  ratio: number;
+  children?: RatioNode[];
+}
+
+export class HeapRatio {
+  /**
+   * Build the retention flame graph of a base64 encoded heap profile by
+   * running the heapratio command, which computes it with AllocInuseRatio
+   */
+  async fromData(data: string): Promise<RatioNode> {
+    return new Promise((resolve, reject) => {
+      const childProcess = spawn('go', ['run', './cmd/heapratio'], {
+        cwd: path.join(process.cwd(), 'go_examples'),
+      });
+
+      const chunks: Buffer[] = [];
+      childProcess.stdout.on('data', (chunk) => {
+        chunks.push(Buffer.from(chunk));
+      });
+
+      const errorChunks: Buffer[] = [];
+      childProcess.stderr.on('data', (chunk) => {
+        errorChunks.push(Buffer.from(chunk));
+      });
+
+      childProcess.on('close', (code) => {
+        if (code !== 0) {
+          const errorOutput = Buffer.concat(errorChunks).toString().trim();
+          reject(new Error(errorOutput || `heapratio exited with code ${code}`));
+          return;
+        }
+
+        try {
+          resolve(JSON.parse(Buffer.concat(chunks).toString()));
+        } catch (error) {
+          reject(new Error(`Invalid heapratio output: ${(error as Error).message}`));
+        }
+      });
+
+      childProcess.on('error', (err) => {
+        reject(err);
+      });
+
+      // The profile goes in on stdin, so no temporary file is needed
+      childProcess.stdin.end(Buffer.from(data, 'base64'));
+    });
+  }
+}